@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func campaignsCommand() *cobra.Command {
+	var game string
+	var connectedOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "campaigns",
+		Short: "List the drop campaigns, ending soonest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			campaigns, err := client.Campaigns(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			listed := campaigns[:0]
+			for _, campaign := range campaigns {
+				if game != "" && !strings.EqualFold(campaign.Game.Name, game) {
+					continue
+				}
+				if connectedOnly && !campaign.Self.IsAccountConnected {
+					continue
+				}
+				listed = append(listed, campaign)
+			}
+			sort.SliceStable(listed, func(i, j int) bool { return listed[i].EndsAt.Before(listed[j].EndsAt) })
+			if jsonOutput {
+				return printJSON(listed)
+			}
+			if len(listed) == 0 {
+				fmt.Println("No campaigns")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "GAME\tCAMPAIGN\tSTATUS\tDROPS\tLINKED\tENDS")
+			for _, campaign := range listed {
+				linked := "no"
+				if campaign.Self.IsAccountConnected {
+					linked = "yes"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", campaign.Game.Name, campaign.Name, campaign.Status,
+					len(campaign.TimeBasedDrops), linked, formatTime(campaign.EndsAt))
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&game, "game", "", "only list the campaigns of this game")
+	cmd.Flags().BoolVar(&connectedOnly, "linked", false, "only list the campaigns the account is linked to")
+	return cmd
+}
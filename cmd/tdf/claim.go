@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func claimCommand() *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "claim [drop instance ID]",
+		Short: "Claim a completed drop, or every one with --all",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all == (len(args) == 1) {
+				return errors.New("give either a drop instance ID or --all")
+			}
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			if !all {
+				if err := client.ClaimDrop(cmd.Context(), args[0]); err != nil {
+					return err
+				}
+				if jsonOutput {
+					return printJSON(map[string]bool{"success": true})
+				}
+				fmt.Println("Drop claimed")
+				return nil
+			}
+
+			summary, err := client.ClaimPending(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(summary)
+			}
+			if summary.Found == 0 {
+				fmt.Println("No drops waiting to be claimed")
+				return nil
+			}
+			for _, drop := range summary.Drops {
+				if drop.Claimed {
+					fmt.Printf("Claimed %s (%s, %s)\n", drop.DropName, drop.CampaignName, drop.GameName)
+				} else {
+					fmt.Printf("Failed to claim %s (%s, %s): %s\n", drop.DropName, drop.CampaignName, drop.GameName, drop.Error)
+				}
+			}
+			if summary.Failed > 0 {
+				return fmt.Errorf("%d of %d drops could not be claimed", summary.Failed, summary.Found)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "claim every completed drop in the inventory")
+	return cmd
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func pauseCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pause",
+		Short: "Pause the miner, keeping its session to resume",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			if err := client.Pause(cmd.Context()); err != nil {
+				return err
+			}
+			return printDone("Miner paused")
+		},
+	}
+}
+
+func resumeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume",
+		Short: "Resume the paused miner",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			if err := client.Resume(cmd.Context()); err != nil {
+				return err
+			}
+			return printDone("Miner resumed")
+		},
+	}
+}
+
+func addGameCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add-game <name>",
+		Short: "Add a game to the priority list, e.g. tdf add-game \"Rust\"",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			// Unquoted names with spaces arrive as several arguments
+			name := strings.Join(args, " ")
+			if err := client.AddGame(cmd.Context(), name); err != nil {
+				return err
+			}
+			return printDone(fmt.Sprintf("Added %s to the priority games", name))
+		},
+	}
+}
+
+// printDone reports a command without a result of its own
+func printDone(message string) error {
+	if jsonOutput {
+		return printJSON(map[string]bool{"success": true})
+	}
+	fmt.Println(message)
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"twitchdropsfarmer/internal/apiclient"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+)
+
+// How often the login is checked while waiting for the code to be entered
+const loginPollInterval = 3 * time.Second
+
+func loginCommand() *cobra.Command {
+	var noQR bool
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log the server's primary account in to Twitch with a device code",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if accountID != "" {
+				return errors.New("login is for the primary account, add other accounts from the dashboard")
+			}
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			status, err := client.AuthStatus(ctx)
+			if err != nil {
+				return err
+			}
+			if status.IsLoggedIn {
+				return printLoggedIn(status, "Already logged in")
+			}
+
+			code, err := client.StartLogin(ctx)
+			if err != nil {
+				return err
+			}
+			if err := client.CompleteLogin(ctx, code.DeviceCode); err != nil {
+				return err
+			}
+			fmt.Printf("Open %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+			if !noQR {
+				printQR(code)
+			}
+
+			deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+			ticker := time.NewTicker(loginPollInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-ticker.C:
+				}
+
+				status, err := client.AuthStatus(ctx)
+				if err != nil {
+					return err
+				}
+				if status.IsLoggedIn {
+					return printLoggedIn(status, "Logged in")
+				}
+				if time.Now().After(deadline) {
+					return errors.New("the code expired before it was entered, run tdf login again")
+				}
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&noQR, "no-qr", false, "don't print the QR code")
+	return cmd
+}
+
+// printQR prints a QR code of the activation page with the code filled in, to scan with a phone
+func printQR(code *apiclient.DeviceCode) {
+	uri := code.VerificationURIComplete
+	if uri == "" {
+		uri = code.VerificationURI
+	}
+	qr, err := qrcode.New(uri, qrcode.Low)
+	if err != nil {
+		return
+	}
+	fmt.Println("or scan:")
+	fmt.Print(qr.ToSmallString(false))
+}
+
+func printLoggedIn(status *apiclient.AuthStatus, message string) error {
+	if jsonOutput {
+		return printJSON(status)
+	}
+	if status.User != nil {
+		message += " as " + status.User.Login
+	}
+	fmt.Println(message)
+	return nil
+}
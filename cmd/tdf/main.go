@@ -0,0 +1,77 @@
+// Command tdf controls a running TwitchDropsFarmer server over its REST API, e.g. tdf status, tdf pause, or
+// tdf claim --all.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"twitchdropsfarmer/internal/apiclient"
+
+	"github.com/spf13/cobra"
+)
+
+// Flags shared by every command
+var (
+	serverURL  string
+	apiKey     string
+	accountID  string
+	jsonOutput bool
+)
+
+func main() {
+	root := &cobra.Command{
+		Use:           "tdf",
+		Short:         "Control a running TwitchDropsFarmer server",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&serverURL, "server", envOr("TDF_SERVER", "http://localhost:8080"), "server URL, or TDF_SERVER")
+	root.PersistentFlags().StringVar(&apiKey, "api-key", os.Getenv("TDF_API_KEY"), "API key, or TDF_API_KEY; needed once WEB_PASSWORD or api_keys is set")
+	root.PersistentFlags().StringVar(&accountID, "account", "", "ID of the additional account to act on instead of the primary one")
+	root.PersistentFlags().BoolVar(&jsonOutput, "json", false, "print the API response as JSON")
+
+	root.AddCommand(
+		statusCommand(),
+		campaignsCommand(),
+		claimCommand(),
+		pauseCommand(),
+		resumeCommand(),
+		addGameCommand(),
+		loginCommand(),
+		tuiCommand(),
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := root.ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// newClient creates the API client from the shared flags
+func newClient() (*apiclient.Client, error) {
+	client, err := apiclient.New(serverURL, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return client.ForAccount(accountID), nil
+}
+
+// printJSON writes v indented to stdout, for --json
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
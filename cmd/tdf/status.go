@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func statusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show what the miner is doing",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			status, err := client.Status(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(status)
+			}
+
+			state := "stopped"
+			switch {
+			case status.Paused:
+				state = "paused"
+			case status.OutsideSchedule:
+				state = "outside the schedule"
+			case status.IsRunning:
+				state = "running"
+			}
+			fmt.Printf("Miner:    %s\n", state)
+			if campaign := status.CurrentCampaign; campaign != nil {
+				fmt.Printf("Campaign: %s (%s), ends %s\n", campaign.Name, campaign.Game.Name, formatTime(campaign.EndsAt))
+			}
+			if stream := status.CurrentStream; stream != nil {
+				watching := stream.UserName
+				if status.PointsOnly {
+					watching += ", for channel points"
+				}
+				fmt.Printf("Watching: %s\n", watching)
+			}
+			if status.ErrorMessage != "" {
+				fmt.Printf("Error:    %s\n", status.ErrorMessage)
+			}
+
+			var pending []int
+			for i, drop := range status.ActiveDrops {
+				if !drop.IsClaimed {
+					pending = append(pending, i)
+				}
+			}
+			if len(pending) == 0 {
+				return nil
+			}
+			sort.SliceStable(pending, func(i, j int) bool {
+				return status.ActiveDrops[pending[i]].RemainingMinutes < status.ActiveDrops[pending[j]].RemainingMinutes
+			})
+
+			fmt.Println()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "DROP\tGAME\tPROGRESS\tREMAINING")
+			for _, i := range pending {
+				drop := status.ActiveDrops[i]
+				fmt.Fprintf(w, "%s\t%s\t%d/%d min (%.0f%%)\t%s\n", drop.Name, drop.GameName,
+					drop.CurrentMinutes, drop.RequiredMinutes, drop.Progress*100, formatMinutes(drop.RemainingMinutes))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// formatMinutes writes minutes as hours and minutes, e.g. 2h 05m
+func formatMinutes(minutes int) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
+
+// formatTime writes a time in local time, leaving out the date when it is today
+func formatTime(t time.Time) string {
+	t = t.Local()
+	if now := time.Now(); t.Year() == now.Year() && t.YearDay() == now.YearDay() {
+		return "today " + t.Format("15:04")
+	}
+	return t.Format("Mon Jan 2 15:04")
+}
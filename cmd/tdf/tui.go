@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/apiclient"
+	"twitchdropsfarmer/internal/drops"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// How long to wait before connecting to the WebSocket again when it drops
+const tuiReconnectDelay = 3 * time.Second
+
+// Log entries kept for the log pane
+const tuiMaxLogs = 200
+
+var (
+	titleStyle   = lipgloss.NewStyle().Bold(true)
+	labelStyle   = lipgloss.NewStyle().Faint(true)
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	warnStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	runningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+)
+
+func tuiCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tui",
+		Short: "Follow the miner live in the terminal: status, drop progress, the campaign queue and logs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+
+			model := newTUIModel(ctx, client)
+			go model.follow()
+			_, err = tea.NewProgram(model, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+			if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+				return nil
+			}
+			return err
+		},
+	}
+}
+
+// Messages from the WebSocket goroutine and the key actions
+type (
+	connectedMsg    struct{}
+	disconnectedMsg struct{ err error }
+	statusMsg       *drops.MinerStatus
+	logMsg          drops.MinerLogEntry
+	logsMsg         []drops.MinerLogEntry
+	noticeMsg       string
+)
+
+type tuiModel struct {
+	ctx      context.Context
+	client   *apiclient.Client
+	messages chan tea.Msg
+
+	status    *drops.MinerStatus
+	logs      []drops.MinerLogEntry
+	connected bool
+	notice    string // result of the last action, or why the connection dropped
+	width     int
+	height    int
+	bar       progress.Model
+}
+
+func newTUIModel(ctx context.Context, client *apiclient.Client) *tuiModel {
+	return &tuiModel{
+		ctx:      ctx,
+		client:   client,
+		messages: make(chan tea.Msg, 100),
+		notice:   "Connecting to " + serverURL,
+		bar:      progress.New(progress.WithDefaultGradient(), progress.WithWidth(24), progress.WithoutPercentage()),
+	}
+}
+
+// follow keeps a WebSocket connection open until ctx is cancelled, reconnecting when it drops
+func (m *tuiModel) follow() {
+	for m.ctx.Err() == nil {
+		err := m.stream()
+		if m.ctx.Err() != nil {
+			return
+		}
+		m.send(disconnectedMsg{err: err})
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(tuiReconnectDelay):
+		}
+	}
+}
+
+// stream forwards the messages of one connection, starting with the recent logs
+func (m *tuiModel) stream() error {
+	stream, err := m.client.Subscribe(m.ctx, "status", "logs")
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	go func() {
+		<-m.ctx.Done()
+		stream.Close()
+	}()
+
+	m.send(connectedMsg{})
+	if logs, err := m.client.Logs(m.ctx, tuiMaxLogs); err == nil {
+		m.send(logsMsg(logs))
+	}
+
+	for {
+		message, err := stream.Next()
+		if err != nil {
+			return err
+		}
+		switch message.Type {
+		case "status_update":
+			var status drops.MinerStatus
+			if json.Unmarshal(message.Data, &status) == nil {
+				m.send(statusMsg(&status))
+			}
+		case "log":
+			var entry drops.MinerLogEntry
+			if json.Unmarshal(message.Data, &entry) == nil {
+				m.send(logMsg(entry))
+			}
+		case "shutting_down":
+			return errors.New("the server is shutting down")
+		}
+	}
+}
+
+func (m *tuiModel) send(msg tea.Msg) {
+	select {
+	case m.messages <- msg:
+	case <-m.ctx.Done():
+	}
+}
+
+// waitForMessage hands the next WebSocket message to Update
+func (m *tuiModel) waitForMessage() tea.Msg {
+	select {
+	case msg := <-m.messages:
+		return msg
+	case <-m.ctx.Done():
+		return nil
+	}
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return m.waitForMessage
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case "p":
+			return m, m.togglePause
+		case "c":
+			return m, m.claimAll
+		}
+		return m, nil
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+	case noticeMsg:
+		m.notice = string(msg)
+		return m, nil
+
+	case connectedMsg:
+		m.connected, m.notice = true, ""
+	case disconnectedMsg:
+		m.connected = false
+		m.notice = fmt.Sprintf("Disconnected, reconnecting in %s: %v", tuiReconnectDelay, msg.err)
+	case statusMsg:
+		m.status = msg
+	case logsMsg:
+		m.logs = msg
+	case logMsg:
+		m.logs = append(m.logs, drops.MinerLogEntry(msg))
+		if len(m.logs) > tuiMaxLogs {
+			m.logs = m.logs[len(m.logs)-tuiMaxLogs:]
+		}
+	}
+	return m, m.waitForMessage
+}
+
+func (m *tuiModel) togglePause() tea.Msg {
+	if m.status != nil && m.status.Paused {
+		if err := m.client.Resume(m.ctx); err != nil {
+			return noticeMsg("Failed to resume: " + err.Error())
+		}
+		return noticeMsg("Miner resumed")
+	}
+	if err := m.client.Pause(m.ctx); err != nil {
+		return noticeMsg("Failed to pause: " + err.Error())
+	}
+	return noticeMsg("Miner paused")
+}
+
+func (m *tuiModel) claimAll() tea.Msg {
+	summary, err := m.client.ClaimPending(m.ctx)
+	if err != nil {
+		return noticeMsg("Failed to claim: " + err.Error())
+	}
+	if summary.Found == 0 {
+		return noticeMsg("No drops waiting to be claimed")
+	}
+	return noticeMsg(fmt.Sprintf("Claimed %d of %d drops", summary.Claimed, summary.Found))
+}
+
+func (m *tuiModel) View() string {
+	var lines []string
+	lines = append(lines, m.headerLines()...)
+	lines = append(lines, "")
+	lines = append(lines, m.dropLines()...)
+	lines = append(lines, "")
+	lines = append(lines, m.queueLines()...)
+	lines = append(lines, "", titleStyle.Render("Logs"))
+
+	footer := labelStyle.Render("p pause/resume · c claim all · q quit")
+	if m.notice != "" {
+		footer = m.notice + "\n" + footer
+	}
+	footerHeight := strings.Count(footer, "\n") + 1
+
+	// The logs take the rest of the screen, the latest at the bottom
+	room := m.height - len(lines) - footerHeight - 1
+	if m.height == 0 {
+		room = 10
+	}
+	logs := m.logs
+	if room < 0 {
+		room = 0
+	}
+	if len(logs) > room {
+		logs = logs[len(logs)-room:]
+	}
+	for _, entry := range logs {
+		lines = append(lines, m.truncate(logLine(entry)))
+	}
+	for i := len(logs); i < room; i++ {
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines, "\n") + "\n" + footer
+}
+
+func (m *tuiModel) headerLines() []string {
+	connection := runningStyle.Render("connected")
+	if !m.connected {
+		connection = errorStyle.Render("disconnected")
+	}
+	header := []string{titleStyle.Render("TwitchDropsFarmer") + "  " + labelStyle.Render(serverURL) + "  " + connection}
+
+	status := m.status
+	if status == nil {
+		return append(header, labelStyle.Render("Waiting for the miner status"))
+	}
+
+	state := errorStyle.Render("stopped")
+	switch {
+	case status.Paused:
+		state = warnStyle.Render("paused")
+	case status.OutsideSchedule:
+		state = warnStyle.Render("outside the schedule")
+	case status.IsRunning:
+		state = runningStyle.Render("running")
+	}
+	header = append(header, labelStyle.Render("Miner:    ")+state)
+	if campaign := status.CurrentCampaign; campaign != nil {
+		header = append(header, labelStyle.Render("Campaign: ")+fmt.Sprintf("%s (%s), ends %s", campaign.Name, campaign.Game.Name, formatTime(campaign.EndsAt)))
+	}
+	if stream := status.CurrentStream; stream != nil {
+		watching := stream.UserName
+		if status.PointsOnly {
+			watching += ", for channel points"
+		}
+		header = append(header, labelStyle.Render("Watching: ")+watching)
+	}
+	if status.ErrorMessage != "" {
+		header = append(header, errorStyle.Render("Error:    "+status.ErrorMessage))
+	}
+	return header
+}
+
+func (m *tuiModel) dropLines() []string {
+	lines := []string{titleStyle.Render("Drops")}
+	if m.status == nil {
+		return lines
+	}
+
+	var pending []drops.ActiveDrop
+	for _, drop := range m.status.ActiveDrops {
+		if !drop.IsClaimed {
+			pending = append(pending, drop)
+		}
+	}
+	if len(pending) == 0 {
+		return append(lines, labelStyle.Render("  No drop in progress"))
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].RemainingMinutes < pending[j].RemainingMinutes })
+
+	for _, drop := range pending {
+		line := fmt.Sprintf("  %s %3.0f%%  %s  %s left", m.bar.ViewAs(drop.Progress), drop.Progress*100,
+			drop.Name, formatMinutes(drop.RemainingMinutes))
+		lines = append(lines, m.truncate(line))
+	}
+	return lines
+}
+
+// queueCampaign is a campaign of the forecast, with its pending drops together
+type queueCampaign struct {
+	position    int
+	name        string
+	game        string
+	drops       int
+	remaining   int // minutes of the furthest drop
+	completesAt *time.Time
+	atRisk      bool
+}
+
+func (m *tuiModel) queueLines() []string {
+	lines := []string{titleStyle.Render("Queue")}
+	if m.status == nil {
+		return lines
+	}
+
+	byID := make(map[string]*queueCampaign)
+	var queue []*queueCampaign
+	for _, forecast := range m.status.Forecast {
+		campaign := byID[forecast.CampaignID]
+		if campaign == nil {
+			campaign = &queueCampaign{position: forecast.QueuePosition, name: forecast.CampaignName, game: forecast.GameName}
+			byID[forecast.CampaignID] = campaign
+			queue = append(queue, campaign)
+		}
+		campaign.drops++
+		if forecast.RemainingMinutes > campaign.remaining {
+			campaign.remaining = forecast.RemainingMinutes
+		}
+		if forecast.CompletesAt != nil && (campaign.completesAt == nil || forecast.CompletesAt.After(*campaign.completesAt)) {
+			campaign.completesAt = forecast.CompletesAt
+		}
+		campaign.atRisk = campaign.atRisk || forecast.AtRisk
+	}
+	if len(queue) == 0 {
+		return append(lines, labelStyle.Render("  Nothing queued"))
+	}
+	sort.SliceStable(queue, func(i, j int) bool { return queue[i].position < queue[j].position })
+
+	for _, campaign := range queue {
+		line := fmt.Sprintf("  %d. %s (%s): %d drops, %s left", campaign.position, campaign.name, campaign.game,
+			campaign.drops, formatMinutes(campaign.remaining))
+		if campaign.completesAt != nil {
+			line += ", done " + formatTime(*campaign.completesAt)
+		}
+		line = m.truncate(line)
+		if campaign.atRisk {
+			line += warnStyle.Render(" at risk")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func logLine(entry drops.MinerLogEntry) string {
+	line := entry.Time.Local().Format("15:04:05") + " " + entry.Message
+	if entry.Channel != "" {
+		line += labelStyle.Render(" · " + entry.Channel)
+	}
+	switch entry.Level {
+	case "error", "fatal", "panic":
+		return errorStyle.Render(line)
+	case "warning":
+		return warnStyle.Render(line)
+	}
+	return line
+}
+
+// truncate cuts a line to the terminal width
+func (m *tuiModel) truncate(line string) string {
+	if m.width <= 0 {
+		return line
+	}
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(line)
+}
@@ -0,0 +1,390 @@
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/errreport"
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// Account is an additional Twitch account farmed alongside the primary one, with its own client and miner
+type Account struct {
+	ID     string
+	Client *twitch.Client
+	Miner  *drops.Miner
+	Store  storage.Store
+
+	stopValidation context.CancelFunc
+}
+
+// Login returns the account's Twitch login, empty if its token was rejected
+func (a *Account) Login() string {
+	if user := a.Client.GetUser(); user != nil {
+		return user.Login
+	}
+	return ""
+}
+
+// StartMiner starts the account's miner in the background
+func (a *Account) StartMiner() error {
+	if !a.Client.IsLoggedIn() {
+		return fmt.Errorf("account %s is not logged in", a.ID)
+	}
+	if a.Miner.IsRunning() {
+		return drops.ErrAlreadyRunning
+	}
+	a.startMiner()
+	return nil
+}
+
+// startMiner starts the miner whether or not the account is logged in, it waits for a login on its own
+func (a *Account) startMiner() {
+	go func() {
+		if err := a.Miner.Start(context.Background()); err != nil {
+			logrus.Errorf("Miner error for account %s: %v", a.ID, err)
+		}
+	}()
+}
+
+// Manager owns the additional accounts; their tokens live in config/tokens/ and their data in <data_dir>/accounts/<id>
+type Manager struct {
+	live     *config.Live
+	bus      *events.Bus
+	reporter *errreport.Reporter
+
+	mu       sync.RWMutex
+	accounts map[string]*Account
+	pending  map[string]*twitch.Client // logins in progress, keyed by device code
+	onAdd    func(*Account)
+}
+
+// NewManager creates an empty account manager whose miners publish on bus
+func NewManager(live *config.Live, bus *events.Bus) *Manager {
+	return &Manager{
+		live:     live,
+		bus:      bus,
+		accounts: make(map[string]*Account),
+		pending:  make(map[string]*twitch.Client),
+	}
+}
+
+// SetErrorReporter sets where the miners of accounts added from now on report repeated failures
+func (m *Manager) SetErrorReporter(reporter *errreport.Reporter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reporter = reporter
+}
+
+// OnAdd registers a callback run for every account added, including the ones restored by Load
+func (m *Manager) OnAdd(fn func(*Account)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onAdd = fn
+}
+
+// Load restores the accounts with a stored token and starts their miners
+func (m *Manager) Load() error {
+	ids, err := config.ListAccountTokens()
+	if err != nil {
+		return fmt.Errorf("failed to list account tokens: %w", err)
+	}
+
+	for _, id := range ids {
+		store, err := m.openStore(id)
+		if err != nil {
+			logrus.Errorf("Failed to restore account %s: %v", id, err)
+			continue
+		}
+		m.load(id, store)
+	}
+	return nil
+}
+
+// load adds the account with a stored token, using store for its data
+// An account whose token couldn't be validated is added logged out; its token validation loads the token again
+// and its miner waits until then, like the primary account's.
+func (m *Manager) load(id string, store storage.Store) {
+	cfg := m.live.Get()
+	client, err := twitch.NewAccountClient(twitch.NewClientProfile(cfg), id, cfg.ProxyFor(id))
+	if err != nil {
+		logrus.Errorf("Skipping account %s: %v", id, err)
+		return
+	}
+	if !client.IsLoggedIn() {
+		// A rejected token is deleted, one that's still there couldn't be checked, e.g. with the network down
+		if _, err := config.LoadAccountToken(id); err != nil {
+			logrus.Warnf("Skipping account %s, its stored token is no longer valid", id)
+			return
+		}
+		logrus.Warnf("Account %s is logged out until its stored token can be validated", id)
+	}
+	m.add(client, store)
+}
+
+// BeginLogin starts a device code login for a new account
+func (m *Manager) BeginLogin(ctx context.Context) (*twitch.DeviceCodeResponse, error) {
+	cfg := m.live.Get()
+	client, err := twitch.NewAccountClient(twitch.NewClientProfile(cfg), "", cfg.ProxyFor(""))
+	if err != nil {
+		return nil, err
+	}
+	client.SetAuthScopes(cfg.AuthScopes)
+
+	deviceResp, err := client.StartDeviceFlow(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.pending[deviceResp.DeviceCode] = client
+	m.mu.Unlock()
+
+	return deviceResp, nil
+}
+
+// IsPendingLogin reports whether deviceCode belongs to a login started with BeginLogin
+func (m *Manager) IsPendingLogin(deviceCode string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.pending[deviceCode]
+	return ok
+}
+
+// CompleteLogin waits for the user to authorize a device code from BeginLogin and adds the account
+// Logging in to an account that is already added just refreshes its token
+func (m *Manager) CompleteLogin(ctx context.Context, deviceCode string, interval int) (*Account, error) {
+	m.mu.Lock()
+	client, ok := m.pending[deviceCode]
+	delete(m.pending, deviceCode)
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown device code")
+	}
+
+	if err := client.PollForToken(ctx, deviceCode, interval); err != nil {
+		return nil, err
+	}
+
+	if existing := m.Get(client.AccountID()); existing != nil {
+		existing.Client.ReloadToken()
+		return existing, nil
+	}
+	store, err := m.openStore(client.AccountID())
+	if err != nil {
+		return nil, err
+	}
+	return m.add(client, store), nil
+}
+
+// openStore opens the store holding an account's data
+func (m *Manager) openStore(id string) (storage.Store, error) {
+	cfg := m.live.Get()
+	return storage.Open(storage.Options{
+		Backend:   cfg.StorageBackend,
+		Dir:       filepath.Join(cfg.DataDir, "accounts", id),
+		DSN:       cfg.DatabaseURL,
+		Namespace: "accounts/" + id,
+	})
+}
+
+// add creates the miner for a client with a stored token and starts it
+func (m *Manager) add(client *twitch.Client, store storage.Store) *Account {
+	id := client.AccountID()
+	cfg := m.live.Get()
+
+	client.SetDirectoryOptions(twitch.NewDirectoryOptions(cfg))
+	client.SetStreamQuality(cfg.StreamQuality)
+	client.SetAuthScopes(cfg.AuthScopes)
+	if err := client.SetProxy(cfg.ProxyFor(id)); err != nil {
+		logrus.Errorf("Ignoring proxy for account %s: %v", id, err)
+	}
+
+	client.SetReauthHandler(func(user *twitch.User) {
+		login := ""
+		if user != nil {
+			login = user.Login
+		}
+		m.bus.Publish(events.AuthExpiredEvent(id, login))
+	})
+
+	miner := drops.NewMiner(client)
+	miner.SetEvents(m.bus, id)
+	m.mu.RLock()
+	miner.SetErrorReporter(m.reporter)
+	m.mu.RUnlock()
+	miner.SetStore(store)
+	miner.SetConfig(drops.NewMinerConfig(cfg))
+
+	validationCtx, stopValidation := context.WithCancel(context.Background())
+	go client.RunTokenValidation(validationCtx)
+
+	account := &Account{ID: id, Client: client, Miner: miner, Store: store, stopValidation: stopValidation}
+
+	m.mu.Lock()
+	m.accounts[id] = account
+	onAdd := m.onAdd
+	m.mu.Unlock()
+
+	if onAdd != nil {
+		onAdd(account)
+	}
+
+	account.startMiner()
+
+	logrus.Infof("Added account %s (%s)", account.Login(), id)
+	return account
+}
+
+// Get returns the account with the given ID, nil if there is none
+func (m *Manager) Get(id string) *Account {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.accounts[id]
+}
+
+// List returns all accounts ordered by ID
+func (m *Manager) List() []*Account {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accounts := make([]*Account, 0, len(m.accounts))
+	for _, account := range m.accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].ID < accounts[j].ID
+	})
+	return accounts
+}
+
+// Stores returns the store of every account by ID, e.g. for a backup
+func (m *Manager) Stores() map[string]storage.Store {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stores := make(map[string]storage.Store, len(m.accounts))
+	for id, account := range m.accounts {
+		stores[id] = account.Store
+	}
+	return stores
+}
+
+// Restore replaces an account's documents and, when given, its token, e.g. from a backup. The miner of an added
+// account is stopped while they are written and reloads them; an account that isn't added yet is added once it
+// has a stored token.
+func (m *Manager) Restore(ctx context.Context, id string, documents map[string][]byte, token *oauth2.Token) error {
+	account := m.Get(id)
+	if account == nil {
+		store, err := m.openStore(id)
+		if err != nil {
+			return err
+		}
+		if err := restoreData(id, store, documents, token); err != nil {
+			return err
+		}
+		if _, err := config.LoadAccountToken(id); err == nil {
+			m.load(id, store)
+		}
+		return nil
+	}
+
+	running, err := account.Miner.StopAndWait(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to stop miner: %w", err)
+	}
+	if err := restoreData(id, account.Store, documents, token); err != nil {
+		return err
+	}
+	if token != nil {
+		account.Client.ReloadToken()
+	}
+	account.Miner.ReloadStore(account.Store)
+	if running {
+		account.startMiner()
+	}
+	return nil
+}
+
+// restoreData writes the documents and the token of Restore
+func restoreData(id string, store storage.Store, documents map[string][]byte, token *oauth2.Token) error {
+	for name, document := range documents {
+		if err := store.WriteRaw(name, document); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	if token != nil {
+		if err := config.SaveAccountToken(id, token); err != nil {
+			return fmt.Errorf("failed to save token: %w", err)
+		}
+	}
+	return nil
+}
+
+// Remove stops the account's miner, revokes its token and forgets it; its data directory is kept
+func (m *Manager) Remove(ctx context.Context, id string) error {
+	m.mu.Lock()
+	account, ok := m.accounts[id]
+	delete(m.accounts, id)
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("account %s not found", id)
+	}
+	account.stopValidation()
+
+	if account.Miner.IsRunning() {
+		if err := account.Miner.Stop(); err != nil {
+			logrus.Warnf("Failed to stop miner for account %s: %v", id, err)
+		}
+	}
+	account.Miner.SetEvents(nil, "")
+	return account.Client.Logout(ctx)
+}
+
+// ApplyConfig pushes changed settings to every account
+func (m *Manager) ApplyConfig() {
+	cfg := m.live.Get()
+	for _, account := range m.List() {
+		account.Client.SetDirectoryOptions(twitch.NewDirectoryOptions(cfg))
+		account.Client.SetStreamQuality(cfg.StreamQuality)
+		account.Client.SetAuthScopes(cfg.AuthScopes)
+		if err := account.Client.SetProxy(cfg.ProxyFor(account.ID)); err != nil {
+			logrus.Errorf("Ignoring proxy for account %s: %v", account.ID, err)
+		}
+		account.Miner.SetConfig(drops.NewMinerConfig(cfg))
+	}
+}
+
+// ShutdownAll shuts every account miner down for good, see drops.Miner.Shutdown
+func (m *Manager) ShutdownAll(ctx context.Context) {
+	for _, account := range m.List() {
+		account.stopValidation()
+		if err := account.Miner.Shutdown(ctx); err != nil {
+			logrus.Warnf("Failed to shut down miner for account %s: %v", account.ID, err)
+		}
+	}
+}
+
+// StopAll stops every running account miner
+func (m *Manager) StopAll() {
+	for _, account := range m.List() {
+		account.stopValidation()
+		if account.Miner.IsRunning() {
+			if err := account.Miner.Stop(); err != nil {
+				logrus.Warnf("Failed to stop miner for account %s: %v", account.ID, err)
+			}
+		}
+	}
+}
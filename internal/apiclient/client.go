@@ -0,0 +1,204 @@
+// Package apiclient talks to a running server over the REST API, for the tdf command line client.
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/twitch"
+)
+
+// Client calls the API of one server, as one account
+type Client struct {
+	baseURL    string
+	apiKey     string
+	accountID  string // "" for the primary account
+	httpClient *http.Client
+}
+
+// Error is an error answered by the API
+type Error struct {
+	Status    int    `json:"-"`
+	Message   string `json:"error"`
+	Code      string `json:"code"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("%s: %s (%s)", e.Message, e.Details, e.Code)
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+}
+
+// New creates a client for the server at baseURL, e.g. http://localhost:8080; apiKey may be empty when the API
+// is open
+func New(baseURL, apiKey string) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q, expected e.g. http://localhost:8080", baseURL)
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}, nil
+}
+
+// ForAccount returns a client acting on the additional account with the given ID, "" for the primary account
+func (c *Client) ForAccount(accountID string) *Client {
+	account := *c
+	account.accountID = accountID
+	return &account
+}
+
+// accountPath prefixes a per-account endpoint with the account
+func (c *Client) accountPath(path string) string {
+	if c.accountID == "" {
+		return "/api" + path
+	}
+	return "/api/accounts/" + url.PathEscape(c.accountID) + path
+}
+
+// do sends a request with body encoded as JSON, when not nil, and decodes the response into out, when not nil
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &Error{Status: resp.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message, apiErr.Code = fmt.Sprintf("server returned status %d", resp.StatusCode), "unknown"
+		}
+		return apiErr
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// Status returns the miner status
+func (c *Client) Status(ctx context.Context) (*drops.MinerStatus, error) {
+	var status drops.MinerStatus
+	if err := c.do(ctx, "GET", c.accountPath("/miner/status"), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Campaigns returns the drop campaigns Twitch lists for the account
+func (c *Client) Campaigns(ctx context.Context) ([]twitch.Campaign, error) {
+	var campaigns []twitch.Campaign
+	if err := c.do(ctx, "GET", c.accountPath("/campaigns/"), nil, &campaigns); err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// ClaimPending claims every completed but unclaimed drop in the inventory
+func (c *Client) ClaimPending(ctx context.Context) (*drops.ClaimSummary, error) {
+	var summary drops.ClaimSummary
+	if err := c.do(ctx, "POST", c.accountPath("/claims/pending"), nil, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// ClaimDrop claims the drop with the given instance ID
+func (c *Client) ClaimDrop(ctx context.Context, instanceID string) error {
+	return c.do(ctx, "POST", c.accountPath("/drops/"+url.PathEscape(instanceID)+"/claim"), nil, nil)
+}
+
+// Pause pauses the miner
+func (c *Client) Pause(ctx context.Context) error {
+	return c.do(ctx, "POST", c.accountPath("/miner/pause"), nil, nil)
+}
+
+// Resume resumes the paused miner
+func (c *Client) Resume(ctx context.Context) error {
+	return c.do(ctx, "POST", c.accountPath("/miner/resume"), nil, nil)
+}
+
+// AddGame adds a game to the priority list by name, the server resolving its slug and ID
+func (c *Client) AddGame(ctx context.Context, name string) error {
+	return c.do(ctx, "POST", "/api/config/game", map[string]string{"game_name": name}, nil)
+}
+
+// DeviceCode is a Twitch device code login in progress
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"` // opens the page with the code filled in
+	ExpiresIn               int    `json:"expires_in"`                // seconds
+	Interval                int    `json:"interval"`                  // seconds
+}
+
+// AuthStatus is whether the primary account is logged in
+type AuthStatus struct {
+	IsLoggedIn bool         `json:"is_logged_in"`
+	User       *twitch.User `json:"user"`
+}
+
+// StartLogin starts a device code login of the primary account
+func (c *Client) StartLogin(ctx context.Context) (*DeviceCode, error) {
+	var code DeviceCode
+	if err := c.do(ctx, "GET", "/api/auth/url", nil, &code); err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// CompleteLogin has the server wait for the code to be entered; AuthStatus tells when it was
+func (c *Client) CompleteLogin(ctx context.Context, deviceCode string) error {
+	return c.do(ctx, "POST", "/api/auth/callback", map[string]string{"device_code": deviceCode}, nil)
+}
+
+// AuthStatus returns whether the primary account is logged in
+func (c *Client) AuthStatus(ctx context.Context) (*AuthStatus, error) {
+	var status AuthStatus
+	if err := c.do(ctx, "GET", "/api/auth/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
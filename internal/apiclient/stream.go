@@ -0,0 +1,83 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"twitchdropsfarmer/internal/drops"
+
+	"github.com/gorilla/websocket"
+)
+
+// Message is a WebSocket message; Data is decoded according to Type, e.g. a drops.MinerStatus for
+// "status_update" or a drops.MinerLogEntry for "log"
+type Message struct {
+	Type      string          `json:"type"`
+	AccountID string          `json:"account_id"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Stream is a WebSocket connection subscribed to some topics
+type Stream struct {
+	conn *websocket.Conn
+}
+
+// Subscribe connects to the WebSocket of the client's account and subscribes to topics, e.g. "status" and "logs"
+func (c *Client) Subscribe(ctx context.Context, topics ...string) (*Stream, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ws"
+	if c.accountID != "" {
+		u.Path += "/accounts/" + url.PathEscape(c.accountID)
+	}
+
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect to the WebSocket: server returned status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to connect to the WebSocket: %w", err)
+	}
+
+	if err := conn.WriteJSON(map[string][]string{"subscribe": topics}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	return &Stream{conn: conn}, nil
+}
+
+// Next waits for the next message; the gorilla client answers the server's pings while it waits
+func (s *Stream) Next() (*Message, error) {
+	var message Message
+	if err := s.conn.ReadJSON(&message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Close closes the connection
+func (s *Stream) Close() error {
+	return s.conn.Close()
+}
+
+// Logs returns the last limit miner log entries, oldest first
+func (c *Client) Logs(ctx context.Context, limit int) ([]drops.MinerLogEntry, error) {
+	var entries []drops.MinerLogEntry
+	path := c.accountPath("/miner/logs") + "?limit=" + strconv.Itoa(limit)
+	if err := c.do(ctx, "GET", path, nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
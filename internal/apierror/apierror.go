@@ -0,0 +1,83 @@
+// Package apierror defines the errors the web API answers with. Every error carries an HTTP status
+// and a stable machine-readable code, so the dashboard and scripts can branch on the code instead of
+// matching messages, which may change.
+package apierror
+
+import (
+	"net/http"
+)
+
+// Error is an API error with its HTTP status and code
+type Error struct {
+	Status  int
+	Code    string
+	Message string // human-readable summary, sent as "error"
+	Details string // optional explanation, e.g. which field was invalid
+
+	cause error
+}
+
+// Errors by code; use WithMessage and WithDetails to describe the failure
+var (
+	ErrInvalidRequest = New(http.StatusBadRequest, "invalid_request", "Invalid request")
+	ErrUnauthorized   = New(http.StatusUnauthorized, "unauthorized", "Valid API key or session required")
+	ErrNotLoggedIn    = New(http.StatusUnauthorized, "not_logged_in", "Not logged in")
+	ErrForbidden      = New(http.StatusForbidden, "forbidden", "Insufficient role")
+	ErrNotFound       = New(http.StatusNotFound, "not_found", "Not found")
+	ErrConflict       = New(http.StatusConflict, "conflict", "Conflict")
+	ErrInternal       = New(http.StatusInternalServerError, "internal_error", "Internal server error")
+	ErrUnavailable    = New(http.StatusServiceUnavailable, "unavailable", "Service unavailable")
+
+	ErrMinerAlreadyRunning = New(http.StatusConflict, "miner_already_running", "Miner is already running")
+	ErrMinerNotRunning     = New(http.StatusConflict, "miner_not_running", "Miner is not running")
+	ErrMinerAlreadyPaused  = New(http.StatusConflict, "miner_already_paused", "Miner is already paused")
+	ErrMinerNotPaused      = New(http.StatusConflict, "miner_not_paused", "Miner is not paused")
+	ErrDropAlreadyClaimed  = New(http.StatusConflict, "drop_already_claimed", "Drop is already claimed")
+
+	ErrTwitchRateLimited = New(http.StatusTooManyRequests, "twitch_rate_limited", "Twitch is rate limiting requests, try again later")
+	ErrTwitchUnavailable = New(http.StatusServiceUnavailable, "twitch_unavailable", "Twitch requests keep failing, try again later")
+)
+
+// New defines an error; codes are part of the API and must not change once released
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Details != "" {
+		return e.Message + ": " + e.Details
+	}
+	return e.Message
+}
+
+// Unwrap returns the error passed to Wrap
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors with the same code, so errors.Is(err, ErrNotFound) holds for every variant of ErrNotFound
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy with another summary
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// WithDetails returns a copy with an explanation
+func (e *Error) WithDetails(details string) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// Wrap returns a copy recording the underlying error for errors.Is and errors.As, it is not sent to the client
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.cause = cause
+	return &c
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+)
+
+// Control actions recorded in the audit log
+const (
+	ActionMinerStart       = "miner.start"
+	ActionMinerStop        = "miner.stop"
+	ActionMinerPause       = "miner.pause"
+	ActionMinerResume      = "miner.resume"
+	ActionSettingsUpdate   = "settings.update"
+	ActionGameAdd          = "game.add"
+	ActionGameRemove       = "game.remove"
+	ActionGameAliases      = "game.aliases"
+	ActionGameChannels     = "game.channels"
+	ActionDropClaim        = "drop.claim"
+	ActionDropClaimPending = "drop.claim_pending"
+	ActionCampaignPin      = "campaign.pin"
+	ActionCampaignUnpin    = "campaign.unpin"
+	ActionCampaignIgnore   = "campaign.ignore"
+	ActionCampaignUnignore = "campaign.unignore"
+	ActionCampaignPriority = "campaign.priority"
+	ActionMinerSwitch      = "miner.switch"
+	ActionMinerUnforce     = "miner.unforce"
+	ActionStateExport      = "state.export"
+	ActionStateImport      = "state.import"
+	ActionTDMImport        = "tdm.import"
+	ActionConfigImport     = "config.import"
+	ActionBackupCreate     = "backup.create"
+	ActionBackupRestore    = "backup.restore"
+	ActionAccountAdd       = "account.add"
+	ActionAccountRemove    = "account.remove"
+	ActionSessionCreate    = "session.create"
+)
+
+// Name of the storage document holding the audit entries
+const auditDocument = "audit"
+
+// Oldest entries are dropped once the log grows past this
+const maxEntries = 5000
+
+// Entry is a single control action taken through the API
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Details   string    `json:"details,omitempty"`
+	SourceIP  string    `json:"source_ip"`
+	Principal string    `json:"principal"`
+}
+
+// Log keeps a persistent, append-only record of control actions
+type Log struct {
+	store   storage.Store
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+// NewLog loads the audit log from storage
+func NewLog(store storage.Store) (*Log, error) {
+	l := &Log{store: store}
+	if err := store.Load(auditDocument, &l.entries); err != nil {
+		return nil, fmt.Errorf("failed to load audit log: %w", err)
+	}
+
+	return l, nil
+}
+
+// Reload re-reads the log from storage, e.g. after a state import
+func (l *Log) Reload() error {
+	var entries []Entry
+	if err := l.store.Load(auditDocument, &entries); err != nil {
+		return fmt.Errorf("failed to load audit log: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = entries
+	return nil
+}
+
+// Record appends an entry and persists the log
+func (l *Log) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > maxEntries {
+		l.entries = append([]Entry(nil), l.entries[len(l.entries)-maxEntries:]...)
+	}
+
+	return l.store.Save(auditDocument, l.entries)
+}
+
+// List returns up to limit entries, newest first, optionally filtered by action
+// A limit of zero or less returns every matching entry
+func (l *Log) List(limit int, action string) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := []Entry{}
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if action != "" && l.entries[i].Action != action {
+			continue
+		}
+		result = append(result, l.entries[i])
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+
+	return result
+}
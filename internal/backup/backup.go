@@ -0,0 +1,260 @@
+// Package backup writes state bundles of the config, every storage document and the Twitch tokens of each account
+// on a cron schedule into the backup directory, keeping the newest few, and reads them back for a restore.
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/bundle"
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Backup file names are backup-<time>.zip
+const (
+	filePrefix = "backup-"
+	fileSuffix = ".zip"
+	timeLayout = "20060102-150405"
+)
+
+// How often the schedule is checked
+const checkInterval = time.Minute
+
+// Backup is a backup file
+type Backup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"` // bytes
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Config holds the backup settings
+type Config struct {
+	Schedule  string // cron spec, e.g. "0 4 * * *" or "@daily", empty to only back up on request
+	Retention int    // backups kept, 0 to keep all
+}
+
+// NewConfig takes the backup settings from the application configuration
+func NewConfig(cfg *config.Config) Config {
+	return Config{
+		Schedule:  cfg.BackupSchedule,
+		Retention: cfg.BackupRetention,
+	}
+}
+
+// ParseSchedule parses a standard five field cron spec or a descriptor like @daily, in local time
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: %w", spec, err)
+	}
+	return schedule, nil
+}
+
+// AccountStores provides the stores of the additional accounts, backed up along with the primary one
+type AccountStores interface {
+	Stores() map[string]storage.Store
+}
+
+// Manager creates, prunes, and opens backups
+type Manager struct {
+	dir   string
+	live  *config.Live
+	store storage.Store
+
+	mu       sync.Mutex // also serializes writing and pruning backup files
+	accounts AccountStores
+	config   Config
+	schedule cron.Schedule // nil without a schedule
+	next     time.Time
+}
+
+// New creates the backup manager writing into dir; call SetConfig, then Run
+func New(dir string, live *config.Live, store storage.Store) *Manager {
+	return &Manager{dir: dir, live: live, store: store}
+}
+
+// SetAccounts includes the stores of the additional accounts in the backups
+func (m *Manager) SetAccounts(accounts AccountStores) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accounts = accounts
+}
+
+// SetConfig applies new settings, keeping the current ones when the schedule doesn't parse
+func (m *Manager) SetConfig(config Config) error {
+	var schedule cron.Schedule
+	if config.Schedule != "" {
+		var err error
+		if schedule, err = ParseSchedule(config.Schedule); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = config
+	m.schedule = schedule
+	if schedule != nil {
+		m.next = schedule.Next(time.Now())
+	}
+	return nil
+}
+
+// Run creates the scheduled backups until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			due := m.schedule != nil && !now.Before(m.next)
+			if due {
+				// A failed backup waits for the next slot rather than retrying every minute
+				m.next = m.schedule.Next(now)
+			}
+			m.mu.Unlock()
+			if !due {
+				continue
+			}
+
+			if backup, err := m.Create(); err != nil {
+				logrus.Errorf("Scheduled backup failed: %v", err)
+			} else {
+				logrus.Infof("Created scheduled backup %s", backup.Name)
+			}
+		}
+	}
+}
+
+// Create writes a backup now, then deletes the oldest ones beyond the retention
+func (m *Manager) Create() (*Backup, error) {
+	source := bundle.Source{Store: m.store}
+	m.mu.Lock()
+	if m.accounts != nil {
+		source.Accounts = m.accounts.Stores()
+	}
+	m.mu.Unlock()
+
+	// Build the bundle before taking the lock, reading every document may take a while
+	var buf bytes.Buffer
+	if err := bundle.Export(&buf, m.live.Get(), source, bundle.Options{Backup: true}); err != nil {
+		return nil, fmt.Errorf("failed to build backup: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// Two backups within a second get a numbered name rather than replacing each other
+	createdAt := time.Now()
+	name := filePrefix + createdAt.Format(timeLayout) + fileSuffix
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(m.dir, name)); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s%s-%d%s", filePrefix, createdAt.Format(timeLayout), i, fileSuffix)
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated backup
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path+".tmp", buf.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	if err := m.pruneLocked(); err != nil {
+		logrus.Errorf("Failed to delete old backups: %v", err)
+	}
+	return &Backup{Name: name, Size: int64(buf.Len()), CreatedAt: createdAt}, nil
+}
+
+// List returns the backups, newest first
+func (m *Manager) List() ([]Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listLocked()
+}
+
+// Open reads a backup for a restore
+func (m *Manager) Open(name string) (*bundle.Contents, error) {
+	if !validName(name) {
+		return nil, fmt.Errorf("invalid backup name %q", name)
+	}
+
+	m.mu.Lock()
+	data, err := os.ReadFile(filepath.Join(m.dir, name))
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return bundle.Read(bytes.NewReader(data), int64(len(data)), "")
+}
+
+func (m *Manager) listLocked() ([]Backup, error) {
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return []Backup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	backups := []Backup{}
+	for _, entry := range entries {
+		if entry.IsDir() || !validName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Name: entry.Name(), Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// pruneLocked deletes the oldest backups beyond the retention
+func (m *Manager) pruneLocked() error {
+	if m.config.Retention <= 0 {
+		return nil
+	}
+	backups, err := m.listLocked()
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups[min(m.config.Retention, len(backups)):] {
+		if err := os.Remove(filepath.Join(m.dir, backup.Name)); err != nil {
+			return err
+		}
+		logrus.Debugf("Deleted old backup %s", backup.Name)
+	}
+	return nil
+}
+
+// validName reports whether name is a backup file name, and not a path leaving the backup directory
+func validName(name string) bool {
+	return filepath.Base(name) == name && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix)
+}
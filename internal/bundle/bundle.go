@@ -0,0 +1,450 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/storage"
+
+	"golang.org/x/oauth2"
+)
+
+// Archive entry names; an additional account's entries are the same under accountsPrefix<id>/
+const (
+	manifestEntry  = "manifest.json"
+	configEntry    = "config.json"
+	configSealed   = "config.enc"
+	tokenEntry     = "token.enc"
+	tokenFileEntry = "token.json"
+	dataPrefix     = "data/"
+	accountsPrefix = "accounts/"
+)
+
+// Bumped whenever the archive layout changes incompatibly
+const bundleVersion = 2
+
+// Documents holding secrets: dashboard sessions, the Web Push keys and subscription endpoints, and webhook
+// failures quoting the webhook URL. Like the config, they are encrypted with the passphrase and left out without one.
+var secretDocuments = map[string]bool{
+	"web_sessions":          true,
+	"webpush_vapid":         true,
+	"webpush_subscriptions": true,
+	"webhook_dead_letters":  true,
+}
+
+// Encryption parameters of the passphrase (PBKDF2-SHA256 + AES-256-GCM)
+const (
+	saltSize         = 16
+	pbkdf2Iterations = 600000
+)
+
+// Limits on the decompressed size of an entry and of the whole bundle, a small upload can expand a lot
+const (
+	maxEntrySize  = 64 << 20
+	maxBundleSize = 256 << 20
+)
+
+// Manifest describes what a bundle contains
+type Manifest struct {
+	Version       int                 `json:"version"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Documents     []string            `json:"documents"`
+	IncludesToken bool                `json:"includes_token"`
+	Encrypted     bool                `json:"encrypted,omitempty"` // the config and secret documents need the passphrase
+	Accounts      map[string][]string `json:"accounts,omitempty"`  // documents of the additional accounts by ID
+}
+
+// Source is what Export reads from: the store of the primary account and those of the additional accounts by ID
+type Source struct {
+	Store    storage.Store
+	Accounts map[string]storage.Store
+}
+
+// Options selects what Export includes besides the config and documents
+type Options struct {
+	// Encrypts the config and the secret documents, and includes the Twitch tokens encrypted with it. Without one
+	// the config's secrets are redacted and the secret documents left out.
+	Passphrase string
+	// Includes the config and every document as they are, and the Twitch tokens the way their token files hold
+	// them, for backups read back by this instance
+	Backup bool
+}
+
+// AccountData is what a bundle holds for one account
+type AccountData struct {
+	Documents map[string][]byte // storage documents by name
+	Token     *oauth2.Token     // nil unless the bundle included a token and it could be read
+}
+
+// Contents is a decoded bundle ready to be applied to an instance
+type Contents struct {
+	Manifest Manifest
+	Config   []byte // raw config.json
+	AccountData
+	Accounts map[string]*AccountData // additional accounts by ID
+}
+
+// Export writes the config and the storage documents of each account as a single zip archive, with the
+// Twitch tokens when the options ask for them
+func Export(w io.Writer, cfg *config.Config, source Source, opts Options) error {
+	documents, err := listDocuments(source.Store, opts)
+	if err != nil {
+		return err
+	}
+
+	manifest := Manifest{
+		Version:   bundleVersion,
+		CreatedAt: time.Now(),
+		Documents: documents,
+		Encrypted: opts.Passphrase != "" && !opts.Backup,
+	}
+	if len(source.Accounts) > 0 {
+		manifest.Accounts = make(map[string][]string, len(source.Accounts))
+		for id, store := range source.Accounts {
+			if manifest.Accounts[id], err = listDocuments(store, opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	archive := zip.NewWriter(w)
+
+	token, err := exportToken(archive, "", config.LoadToken, opts)
+	if err != nil {
+		return err
+	}
+	manifest.IncludesToken = token
+	if err := writeJSON(archive, manifestEntry, manifest); err != nil {
+		return err
+	}
+
+	if err := exportConfig(archive, cfg, opts); err != nil {
+		return err
+	}
+
+	if err := exportDocuments(archive, "", source.Store, documents, opts); err != nil {
+		return err
+	}
+
+	for id, store := range source.Accounts {
+		prefix := accountsPrefix + id + "/"
+		loadToken := func() (*oauth2.Token, error) { return config.LoadAccountToken(id) }
+		if _, err := exportToken(archive, prefix, loadToken, opts); err != nil {
+			return err
+		}
+		if err := exportDocuments(archive, prefix, store, manifest.Accounts[id], opts); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// exportToken writes the token from loadToken under prefix as the options ask, reporting whether it did
+func exportToken(archive *zip.Writer, prefix string, loadToken func() (*oauth2.Token, error), opts Options) (bool, error) {
+	if opts.Passphrase == "" && !opts.Backup {
+		return false, nil
+	}
+	stored, err := loadToken()
+	if err != nil {
+		return false, nil
+	}
+
+	if opts.Backup {
+		data, err := config.MarshalToken(stored)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode token: %w", err)
+		}
+		return true, writeEntry(archive, prefix+tokenFileEntry, data)
+	}
+
+	plain, err := json.Marshal(config.NewStoredToken(stored))
+	if err != nil {
+		return false, fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	token, err := encrypt(plain, opts.Passphrase)
+	if err != nil {
+		return false, err
+	}
+	return true, writeEntry(archive, prefix+tokenEntry, token)
+}
+
+// exportConfig writes the config, encrypted with the passphrase or redacted without one unless it's a backup
+func exportConfig(archive *zip.Writer, cfg *config.Config, opts Options) error {
+	switch {
+	case opts.Backup:
+		return writeJSON(archive, configEntry, cfg)
+	case opts.Passphrase == "":
+		return writeJSON(archive, configEntry, cfg.Redacted())
+	}
+
+	plain, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", configEntry, err)
+	}
+	sealed, err := encrypt(plain, opts.Passphrase)
+	if err != nil {
+		return err
+	}
+	return writeEntry(archive, configSealed, sealed)
+}
+
+// listDocuments returns the documents of store a bundle with the options includes
+func listDocuments(store storage.Store, opts Options) ([]string, error) {
+	all, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	documents := []string{}
+	for _, name := range all {
+		if secretDocuments[name] && opts.Passphrase == "" && !opts.Backup {
+			continue
+		}
+		documents = append(documents, name)
+	}
+	return documents, nil
+}
+
+// exportDocuments writes the named documents of store under prefix, encrypting the secret ones with the
+// passphrase unless it's a backup
+func exportDocuments(archive *zip.Writer, prefix string, store storage.Store, documents []string, opts Options) error {
+	for _, name := range documents {
+		data, err := store.ReadRaw(name)
+		if err != nil {
+			return err
+		}
+		entry := prefix + dataPrefix + name + ".json"
+		if secretDocuments[name] && !opts.Backup {
+			if data, err = encrypt(data, opts.Passphrase); err != nil {
+				return err
+			}
+			entry = prefix + dataPrefix + name + ".enc"
+		}
+		if err := writeEntry(archive, entry, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read decodes a bundle produced by Export
+// Encrypted tokens are only decrypted when a passphrase is given
+func Read(r io.ReaderAt, size int64, passphrase string) (*Contents, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	contents := &Contents{AccountData: AccountData{Documents: make(map[string][]byte)}, Accounts: make(map[string]*AccountData)}
+
+	remaining := int64(maxBundleSize)
+	for _, file := range archive.File {
+		data, err := readEntry(file, &remaining)
+		if err != nil {
+			return nil, err
+		}
+
+		switch file.Name {
+		case manifestEntry:
+			if err := json.Unmarshal(data, &contents.Manifest); err != nil {
+				return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+			}
+			continue
+		case configEntry:
+			contents.Config = data
+			continue
+		case configSealed:
+			if passphrase == "" {
+				return nil, fmt.Errorf("bundle is encrypted, enter its passphrase")
+			}
+			if contents.Config, err = decrypt(data, passphrase); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		account, name := &contents.AccountData, file.Name
+		if rest, ok := strings.CutPrefix(name, accountsPrefix); ok {
+			id, entry, ok := strings.Cut(rest, "/")
+			if !ok || !validName(id) {
+				return nil, fmt.Errorf("invalid account in bundle: %s", file.Name)
+			}
+			if contents.Accounts[id] == nil {
+				contents.Accounts[id] = &AccountData{Documents: make(map[string][]byte)}
+			}
+			account, name = contents.Accounts[id], entry
+		}
+		if err := readAccountEntry(account, name, data, passphrase); err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name, err)
+		}
+	}
+
+	if contents.Manifest.Version == 0 {
+		return nil, fmt.Errorf("bundle has no manifest")
+	}
+	if contents.Manifest.Version > bundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than supported version %d", contents.Manifest.Version, bundleVersion)
+	}
+
+	return contents, nil
+}
+
+// readAccountEntry reads the entry of an account into account, name being relative to the account
+func readAccountEntry(account *AccountData, name string, data []byte, passphrase string) error {
+	switch {
+	case name == tokenFileEntry:
+		token, err := config.UnmarshalToken(data)
+		if err != nil {
+			return fmt.Errorf("invalid token: %w", err)
+		}
+		account.Token = token
+	case name == tokenEntry && passphrase != "":
+		plain, err := decrypt(data, passphrase)
+		if err != nil {
+			return err
+		}
+
+		var stored config.StoredToken
+		if err := json.Unmarshal(plain, &stored); err != nil {
+			return fmt.Errorf("invalid token: %w", err)
+		}
+		account.Token = stored.Token()
+	case strings.HasPrefix(name, dataPrefix) && strings.HasSuffix(name, ".json"):
+		document := strings.TrimSuffix(strings.TrimPrefix(name, dataPrefix), ".json")
+		if !validName(document) {
+			return fmt.Errorf("invalid document name")
+		}
+		account.Documents[document] = data
+	case strings.HasPrefix(name, dataPrefix) && strings.HasSuffix(name, ".enc"):
+		document := strings.TrimSuffix(strings.TrimPrefix(name, dataPrefix), ".enc")
+		if !validName(document) {
+			return fmt.Errorf("invalid document name")
+		}
+		if passphrase == "" {
+			return fmt.Errorf("bundle is encrypted, enter its passphrase")
+		}
+		plain, err := decrypt(data, passphrase)
+		if err != nil {
+			return err
+		}
+		account.Documents[document] = plain
+	}
+	return nil
+}
+
+// validName reports whether name can be used as a document name or account ID, and not as a path
+func validName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && !strings.Contains(name, "..")
+}
+
+func writeJSON(archive *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return writeEntry(archive, name, data)
+}
+
+func writeEntry(archive *zip.Writer, name string, data []byte) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+	}
+	if _, err := entry.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %w", name, err)
+	}
+	return nil
+}
+
+// readEntry decompresses an entry, counting it against what remains of the bundle limit
+func readEntry(file *zip.File, remaining *int64) ([]byte, error) {
+	entry, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in bundle: %w", file.Name, err)
+	}
+	defer entry.Close()
+
+	// The sizes in the archive can't be trusted, count what is actually decompressed
+	limit := min(int64(maxEntrySize), *remaining)
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(entry, limit+1)); err != nil {
+		return nil, fmt.Errorf("failed to read %s in bundle: %w", file.Name, err)
+	}
+	if int64(buf.Len()) > limit {
+		if limit < maxEntrySize {
+			return nil, fmt.Errorf("bundle is larger than %d MB uncompressed", maxBundleSize>>20)
+		}
+		return nil, fmt.Errorf("%s in bundle is larger than %d MB uncompressed", file.Name, maxEntrySize>>20)
+	}
+	*remaining -= int64(buf.Len())
+	return buf.Bytes(), nil
+}
+
+// encrypt seals data as salt || nonce || ciphertext
+func encrypt(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := append(salt, nonce...)
+	return gcm.Seal(out, nonce, data, nil), nil
+}
+
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	if len(data) < saltSize {
+		return nil, fmt.Errorf("encrypted entry is truncated")
+	}
+
+	gcm, err := newGCM(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	rest := data[saltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted entry is truncated")
+	}
+
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt, wrong passphrase?")
+	}
+	return plain, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ExportSchemaVersion is the version of settings exports, bumped whenever a setting is renamed or changes meaning
+const ExportSchemaVersion = 1
+
+// Export is a backup of every setting, with the secrets redacted
+type Export struct {
+	SchemaVersion int             `json:"schema_version"`
+	ExportedAt    time.Time       `json:"exported_at"`
+	Config        json.RawMessage `json:"config"`
+}
+
+// Secrets are replaced by redacted-<hash>, keeping a URL's scheme, so an import onto the same instance can put
+// the current value back in their place
+var redactedPattern = regexp.MustCompile(`redacted-[0-9a-f]{12}`)
+
+// NewExport creates the export of c
+func NewExport(c *Config) (*Export, error) {
+	data, err := json.Marshal(c.Redacted())
+	if err != nil {
+		return nil, err
+	}
+	return &Export{SchemaVersion: ExportSchemaVersion, ExportedAt: time.Now(), Config: data}, nil
+}
+
+// Redacted returns a copy of c with API keys, notification URLs, the webhook and digest URLs, and proxy passwords
+// redacted, safe to show to anyone allowed to read the settings
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.APIKeys = make([]APIKey, len(c.APIKeys))
+	for i, apiKey := range c.APIKeys {
+		apiKey.Key = redactSecret(apiKey.Key)
+		redacted.APIKeys[i] = apiKey
+	}
+	redacted.NotificationURLs = make([]string, len(c.NotificationURLs))
+	for i, notificationURL := range c.NotificationURLs {
+		redacted.NotificationURLs[i] = redactSecret(notificationURL)
+	}
+	redacted.DigestEmail = redactSecret(c.DigestEmail)
+	redacted.WebhookURL = redactSecret(c.WebhookURL)
+	redacted.Proxy = redactCredentials(c.Proxy)
+	redacted.AccountProxies = make(map[string]string, len(c.AccountProxies))
+	for accountID, proxy := range c.AccountProxies {
+		redacted.AccountProxies[accountID] = redactCredentials(proxy)
+	}
+	return &redacted
+}
+
+// Import reads an export over a copy of current, putting current's secrets back for the redacted ones
+// Settings missing from the export keep their current value, unknown settings are an error.
+func Import(data []byte, current *Config) (*Config, error) {
+	var export Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("not a settings export: %w", err)
+	}
+	switch {
+	case export.SchemaVersion == 0:
+		return nil, fmt.Errorf("not a settings export: schema_version is missing")
+	case export.SchemaVersion > ExportSchemaVersion:
+		return nil, fmt.Errorf("schema_version %d is from a newer version, this one reads up to %d", export.SchemaVersion, ExportSchemaVersion)
+	case export.Config == nil:
+		return nil, fmt.Errorf("not a settings export: config is missing")
+	}
+
+	imported, err := decodeOver(export.Config, current, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	if err := imported.restoreSecrets(current); err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
+
+// Decode reads a whole configuration, e.g. of a state bundle, over a copy of current, putting current's secrets
+// back for the redacted ones like Import
+// Settings missing from data keep their current value, unknown settings are ignored.
+func Decode(data []byte, current *Config) (*Config, error) {
+	decoded, err := decodeOver(data, current, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := decoded.restoreSecrets(current); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// decodeOver decodes data over a copy of current, replacing slices and maps rather than merging into them
+func decodeOver(data []byte, current *Config, strict bool) (*Config, error) {
+	// JSON decodes into existing slices and maps, which current shares, so they start out nil
+	decoded := *current
+	fields := reflect.ValueOf(&decoded).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		if kind := fields.Field(i).Kind(); kind == reflect.Slice || kind == reflect.Map {
+			fields.Field(i).SetZero()
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	currentFields := reflect.ValueOf(current).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		if kind := fields.Field(i).Kind(); (kind == reflect.Slice || kind == reflect.Map) && fields.Field(i).IsNil() {
+			fields.Field(i).Set(currentFields.Field(i))
+		}
+	}
+	return &decoded, nil
+}
+
+// restoreSecrets replaces redacted values with the current values they were made from
+func (c *Config) restoreSecrets(current *Config) error {
+	var err error
+	currentKeys := make([]string, len(current.APIKeys))
+	for i, apiKey := range current.APIKeys {
+		currentKeys[i] = apiKey.Key
+	}
+	apiKeys := make([]APIKey, len(c.APIKeys))
+	for i, apiKey := range c.APIKeys {
+		if apiKey.Key, err = restore("api_keys key "+apiKey.Name, apiKey.Key, currentKeys, redactSecret); err != nil {
+			return err
+		}
+		apiKeys[i] = apiKey
+	}
+	c.APIKeys = apiKeys
+
+	notificationURLs := make([]string, len(c.NotificationURLs))
+	for i, notificationURL := range c.NotificationURLs {
+		if notificationURLs[i], err = restore("notification_urls", notificationURL, current.NotificationURLs, redactSecret); err != nil {
+			return err
+		}
+	}
+	c.NotificationURLs = notificationURLs
+
+	if c.DigestEmail, err = restore("digest_email", c.DigestEmail, []string{current.DigestEmail}, redactSecret); err != nil {
+		return err
+	}
+	if c.WebhookURL, err = restore("webhook_url", c.WebhookURL, []string{current.WebhookURL}, redactSecret); err != nil {
+		return err
+	}
+
+	currentProxies := []string{current.Proxy}
+	for _, proxy := range current.AccountProxies {
+		currentProxies = append(currentProxies, proxy)
+	}
+	if c.Proxy, err = restore("proxy", c.Proxy, currentProxies, redactCredentials); err != nil {
+		return err
+	}
+	accountProxies := make(map[string]string, len(c.AccountProxies))
+	for accountID, proxy := range c.AccountProxies {
+		if accountProxies[accountID], err = restore("account_proxies "+accountID, proxy, currentProxies, redactCredentials); err != nil {
+			return err
+		}
+	}
+	c.AccountProxies = accountProxies
+	return nil
+}
+
+// restore returns the candidate value was redacted from, or value itself when it isn't redacted
+func restore(setting, value string, candidates []string, redact func(string) string) (string, error) {
+	if !redactedPattern.MatchString(value) {
+		return value, nil
+	}
+	for _, candidate := range candidates {
+		if candidate != "" && redact(candidate) == value {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%s is redacted and not set to the same value here, enter it again", setting)
+}
+
+// redactSecret hides a whole secret, keeping the scheme of URLs so the export still shows what they are for
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	var scheme string
+	if i := strings.Index(secret, "://"); i > 0 {
+		scheme = secret[:i+3]
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return scheme + "redacted-" + hex.EncodeToString(sum[:6])
+}
+
+// redactCredentials hides only the password of a URL, e.g. of a proxy
+func redactCredentials(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactSecret(rawURL)
+	}
+	password, ok := u.User.Password()
+	if !ok {
+		return rawURL
+	}
+	u.User = url.UserPassword(u.User.Username(), redactSecret(password))
+	return u.String()
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Live holds the configuration in use, shared by the goroutines reading it. A change is made to a Clone and
+// published with Set, so readers never see a half written configuration.
+type Live struct {
+	current atomic.Pointer[Config]
+}
+
+// NewLive starts with cfg in use
+func NewLive(cfg *Config) *Live {
+	l := &Live{}
+	l.current.Store(cfg)
+	return l
+}
+
+// Get returns the configuration in use, which must not be modified
+func (l *Live) Get() *Config {
+	return l.current.Load()
+}
+
+// Set replaces the configuration in use
+func (l *Live) Set(cfg *Config) {
+	l.current.Store(cfg)
+}
+
+// Clone returns a copy of c with slices and maps of its own, so changing an element of one leaves c as it is
+func (c *Config) Clone() *Config {
+	clone := *c
+	fields := reflect.ValueOf(&clone).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		switch {
+		case field.Kind() == reflect.Slice && !field.IsNil():
+			copied := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			reflect.Copy(copied, field)
+			field.Set(copied)
+		case field.Kind() == reflect.Map && !field.IsNil():
+			copied := reflect.MakeMapWithSize(field.Type(), field.Len())
+			for iter := field.MapRange(); iter.Next(); {
+				copied.SetMapIndex(iter.Key(), iter.Value())
+			}
+			field.Set(copied)
+		}
+	}
+	return &clone
+}
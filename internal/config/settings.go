@@ -2,8 +2,10 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -13,26 +15,157 @@ import (
 
 // GameConfig represents a game with name, slug, and ID
 type GameConfig struct {
+	Name    string   `json:"name"`
+	Slug    string   `json:"slug"`
+	ID      string   `json:"id"`
+	Aliases []string `json:"aliases,omitempty"` // other names or slugs campaigns may use for this game
+
+	// Channel logins watched first, in this order, whenever they are live with the game's drops
+	PreferredChannels []string `json:"preferred_channels,omitempty"`
+}
+
+// ScheduleWindow is a time window the miner farms in, e.g. 01:00-08:00 on weekdays
+type ScheduleWindow struct {
+	Days  []string `json:"days,omitempty"` // "mon" to "sun", empty for every day
+	Start string   `json:"start"`          // "HH:MM" in local time
+	End   string   `json:"end"`            // "HH:MM", before Start to end the next day, "24:00" for midnight
+}
+
+// Matches reports whether a campaign game with this name and ID is this priority game
+// The resolved ID wins when both are known, otherwise the name, slug and aliases are compared case-insensitively
+func (g GameConfig) Matches(name, id string) bool {
+	if g.ID != "" && id != "" && g.ID == id {
+		return true
+	}
+	if strings.EqualFold(g.Name, name) || (g.Slug != "" && strings.EqualFold(g.Slug, name)) {
+		return true
+	}
+	for _, alias := range g.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dashboard roles, viewers can only read while admins can also change settings and control the miner
+const (
+	RoleAdmin  = "admin"
+	RoleViewer = "viewer"
+)
+
+// APIKey grants a role to requests presenting the key
+type APIKey struct {
 	Name string `json:"name"`
-	Slug string `json:"slug"`
-	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Role string `json:"role"` // "admin" or "viewer"
+
+	// Twitch account logins this key may see and control, empty for all accounts
+	Accounts []string `json:"accounts,omitempty"`
 }
 
 type Config struct {
 	// Server configuration
-	ServerAddress string `json:"server_address"`
+	ServerAddress  string   `json:"server_address"`
+	DataDir        string   `json:"data_dir"`
+	StorageBackend string   `json:"storage_backend"` // "json" or "postgres", read at startup
+	DatabaseURL    string   `json:"-"`               // Postgres DSN, only from the environment
+	APIKeys        []APIKey `json:"api_keys"`        // when empty the API is open and every caller is admin
+	WebPassword    string   `json:"-"`               // dashboard password for admin sessions, only from the environment
+	TLSCert        string   `json:"tls_cert"`        // certificate file to serve HTTPS with, together with TLSKey
+	TLSKey         string   `json:"tls_key"`
+	AutocertHosts  []string `json:"autocert_hosts"` // hostnames to get Let's Encrypt certificates for, used without TLSCert
+	AutocertEmail  string   `json:"autocert_email"` // contact address for the Let's Encrypt account
+	AutocertHTTP   string   `json:"autocert_http"`  // address answering HTTP-01 challenges and redirecting to HTTPS, empty to disable
+	WSMaxClients   int      `json:"ws_max_clients"` // open WebSocket connections allowed at once, 0 for no limit
 
 	// Twitch API configuration
-	TwitchClientID string `json:"twitch_client_id"`
+	TwitchClientID string            `json:"twitch_client_id"` // replaces the client profile's ID unless it is another profile's
+	ClientProfile  string            `json:"client_profile"`   // identity shown to Twitch: android_app, smartbox, web_player, or mobile_web
+	Proxy          string            `json:"proxy"`            // http://, https://, socks5:// proxy for all Twitch traffic
+	AccountProxies map[string]string `json:"account_proxies"`  // per-account proxy by account ID, overrides Proxy
 
 	// Drop mining configuration
-	PriorityGames   []GameConfig `json:"priority_games"`
-	ClaimDrops      bool         `json:"claim_drops"`
-	WebhookURL      string       `json:"webhook_url"`
-	CheckInterval   int          `json:"check_interval"`   // seconds
-	SwitchThreshold int          `json:"switch_threshold"` // minutes
-	MinimumPoints   int          `json:"minimum_points"`
-	MaximumStreams  int          `json:"maximum_streams"`
+	PriorityGames   []GameConfig     `json:"priority_games"`
+	ExcludeGames    []string         `json:"exclude_games"`  // game names or IDs never farmed
+	WatchUnlisted   bool             `json:"watch_unlisted"` // farm connected campaigns of games in neither list
+	ClaimDrops      bool             `json:"claim_drops"`
+	CheckInterval   int              `json:"check_interval"`   // seconds
+	WatchInterval   int              `json:"watch_interval"`   // seconds between watch requests
+	WatchJitter     int              `json:"watch_jitter"`     // seconds each watch request is randomly sent early or late by
+	SwitchThreshold int              `json:"switch_threshold"` // minutes
+	MinimumPoints   int              `json:"minimum_points"`
+	MinViewers      int              `json:"min_viewers"` // streams with fewer viewers are only picked when no other qualifies, 0 for no minimum
+	MaxViewers      int              `json:"max_viewers"` // streams with more viewers are only picked when no other qualifies, 0 for no maximum
+	MaximumStreams  int              `json:"maximum_streams"`
+	SwitchBonus     int              `json:"switch_bonus"`      // score bonus for the current campaign, 10 equals one priority position
+	ClaimPoints     bool             `json:"claim_points"`      // claim point bonuses on the watched channel
+	PointsChannels  []string         `json:"points_channels"`   // channel logins to claim point bonuses on
+	PointsFallback  bool             `json:"points_fallback"`   // watch points channels when there is nothing to farm
+	AutoFollow      bool             `json:"auto_follow"`       // follow the watched channel when a campaign requires it
+	AutoUnfollow    bool             `json:"auto_unfollow"`     // unfollow channels followed by AutoFollow when switching away
+	ChatPresence    bool             `json:"chat_presence"`     // join the watched channel's chat over IRC
+	AuthScopes      []string         `json:"auth_scopes"`       // extra OAuth scopes requested at login, e.g. user:read:follows, chat:read
+	BandwidthCapMB  int              `json:"bandwidth_cap_mb"`  // daily download cap for watch requests, 0 for none
+	PubSub          bool             `json:"pubsub"`            // real-time drop progress and claims over Twitch PubSub
+	WatchMethod     string           `json:"watch_method"`      // "hls", "spade" (minute-watched events like TDM), or "both"
+	StreamQuality   string           `json:"stream_quality"`    // rendition fetched by HLS watch requests: "lowest", "audio_only", "source", or a name like "480p"
+	PriorityMode    string           `json:"priority_mode"`     // PRIORITY_LIST, ENDING_SOONEST, LOW_AVAILABILITY, or FEWEST_MINUTES_REMAINING
+	DetailsCacheTTL int              `json:"details_cache_ttl"` // minutes campaign details are reused, 0 to always fetch them
+	ClaimInterval   int              `json:"claim_interval"`    // minutes between inventory scans for unclaimed drops, 0 to disable
+	Schedule        []ScheduleWindow `json:"schedule"`          // windows the miner watches in, empty to watch around the clock
+	StallTimeout    int              `json:"stall_timeout"`     // minutes without drop progress while watching before switching streams, 0 to disable
+	CampaignAlerts  string           `json:"campaign_alerts"`   // notify about new campaigns of "priority" games, "all" games, or "off"
+
+	// Stream directory configuration
+	DirectoryFilters []string `json:"directory_filters"` // GameDirectory system filters, e.g. DROPS_ENABLED
+	DirectoryTags    []string `json:"directory_tags"`
+	DirectorySort    string   `json:"directory_sort"`   // "RELEVANCE" or "VIEWER_COUNT"
+	StreamLanguages  []string `json:"stream_languages"` // broadcaster languages to watch, e.g. "en", empty for any
+
+	// Notification configuration
+	WebPushSubject   string   `json:"webpush_subject"`   // contact URI sent with VAPID claims
+	NotificationURLs []string `json:"notification_urls"` // Apprise-style URLs (discord://, tgram://, mailto://, gotify://, ntfy://, pover://)
+	TelegramBotToken string   `json:"-"`                 // bot answering commands, only from the environment
+	TelegramChatIDs  []string `json:"telegram_chat_ids"` // chats the bot takes commands from and notifies
+	DigestEmail      string   `json:"digest_email"`      // mailto:// URL the activity digest is mailed with
+	DigestFrequency  string   `json:"digest_frequency"`  // "off", "daily" or "weekly"
+	DigestHour       int      `json:"digest_hour"`       // local hour digests are sent at, 0 to 23
+
+	// Home Assistant over MQTT, read at startup
+	MQTTURL             string `json:"mqtt_url"` // broker, e.g. tcp://homeassistant.local:1883, empty to disable
+	MQTTUsername        string `json:"mqtt_username"`
+	MQTTPassword        string `json:"-"`                     // only from the environment
+	MQTTTopicPrefix     string `json:"mqtt_topic_prefix"`     // state, event and availability topics go under it
+	MQTTDiscoveryPrefix string `json:"mqtt_discovery_prefix"` // Home Assistant's discovery prefix
+
+	// Generic webhook every event is posted to
+	WebhookURL       string            `json:"webhook_url"`       // empty to disable
+	WebhookSecret    string            `json:"-"`                 // HMAC key deliveries are signed with, only from the environment
+	WebhookEvents    []string          `json:"webhook_events"`    // event types posted, empty for all
+	WebhookTemplates map[string]string `json:"webhook_templates"` // Go template of the JSON payload by event type, or "default"
+
+	// Automatic backups into config/backups
+	BackupSchedule  string `json:"backup_schedule"`  // cron spec, e.g. "0 4 * * *" or "@daily", empty to only back up on request
+	BackupRetention int    `json:"backup_retention"` // backups kept, 0 to keep all
+
+	// Logging configuration
+	LogBufferSize   int               `json:"log_buffer_size"`  // entries kept in memory for /api/logs
+	LogToConsole    bool              `json:"log_to_console"`   // duplicate log lines to stderr/journald
+	LogLevel        string            `json:"log_level"`        // "trace", "debug", "info", "warn" or "error"
+	LogLevels       map[string]string `json:"log_levels"`       // level by package, e.g. {"twitch": "debug", "miner": "info"}
+	LogJSON         bool              `json:"log_json"`         // write log lines as JSON instead of text
+	LogFile         string            `json:"log_file"`         // also write log lines to this file, empty for none
+	LogMaxSizeMB    int               `json:"log_max_size_mb"`  // size the log file is rotated at
+	LogMaxAgeDays   int               `json:"log_max_age_days"` // rotated log files older than this are deleted, 0 to keep them
+	LogMaxBackups   int               `json:"log_max_backups"`  // rotated log files kept, 0 to keep all
+	Pprof           bool              `json:"pprof"`            // serve the Go profiler at /debug/pprof to admins
+	LivenessTimeout int               `json:"liveness_timeout"` // seconds the mining loop may go without a heartbeat before /livez fails
+	OTLPEndpoint    string            `json:"-"`                // collector spans are exported to, only from the environment
+
+	// Error reporting configuration, only from the environment
+	SentryDSN       string `json:"-"` // Sentry project panics and repeated miner errors are sent to
+	ErrorWebhookURL string `json:"-"` // URL the same reports are posted to as JSON
 
 	// UI configuration
 	Theme          string `json:"theme"` // "light" or "dark"
@@ -46,19 +179,92 @@ func Load() (*Config, error) {
 	_ = godotenv.Load()
 
 	cfg := &Config{
-		ServerAddress:   getEnv("SERVER_ADDRESS", ":8080"),
-		TwitchClientID:  getEnv("TWITCH_CLIENT_ID", "kd1unb4b3q4t58fwlpcbzcbnm76a8fp"), // Twitch Android App ID (like TDM)
-		PriorityGames:   []GameConfig{},
-		ClaimDrops:      true,
-		WebhookURL:      getEnv("WEBHOOK_URL", ""),
-		CheckInterval:   60,
-		SwitchThreshold: 5,
-		MinimumPoints:   50,
-		MaximumStreams:  3,
-		Theme:           "dark",
-		Language:        "en",
-		ShowTray:        true,
-		StartMinimized:  false,
+		ServerAddress:    getEnv("SERVER_ADDRESS", ":8080"),
+		DataDir:          getEnv("DATA_DIR", filepath.Join(".", "config", "data")),
+		StorageBackend:   getEnv("STORAGE_BACKEND", "json"),
+		DatabaseURL:      getEnv("DATABASE_URL", ""),
+		APIKeys:          []APIKey{},
+		WebPassword:      getEnv("WEB_PASSWORD", ""),
+		TLSCert:          getEnv("TLS_CERT", ""),
+		TLSKey:           getEnv("TLS_KEY", ""),
+		AutocertHosts:    getEnvList("AUTOCERT_HOSTS"),
+		AutocertEmail:    getEnv("AUTOCERT_EMAIL", ""),
+		AutocertHTTP:     getEnv("AUTOCERT_HTTP", ":80"),
+		WSMaxClients:     100,
+		TwitchClientID:   getEnv("TWITCH_CLIENT_ID", "kd1unb4b3q4t58fwlpcbzcbnm76a8fp"), // Twitch Android App ID (like TDM)
+		ClientProfile:    getEnv("CLIENT_PROFILE", "android_app"),
+		Proxy:            getEnv("PROXY_URL", ""),
+		AccountProxies:   map[string]string{},
+		PriorityGames:    []GameConfig{},
+		ExcludeGames:     []string{},
+		WatchUnlisted:    false,
+		ClaimDrops:       true,
+		CheckInterval:    60,
+		WatchInterval:    20,
+		WatchJitter:      3,
+		SwitchThreshold:  5,
+		MinimumPoints:    50,
+		MaximumStreams:   3,
+		SwitchBonus:      5,
+		ClaimPoints:      true,
+		PointsChannels:   []string{},
+		PointsFallback:   false,
+		AutoFollow:       false,
+		AutoUnfollow:     false,
+		ChatPresence:     false,
+		AuthScopes:       []string{},
+		BandwidthCapMB:   0,
+		PubSub:           true,
+		WatchMethod:      "hls",
+		StreamQuality:    "lowest",
+		PriorityMode:     "PRIORITY_LIST",
+		DetailsCacheTTL:  60,
+		ClaimInterval:    15,
+		Schedule:         []ScheduleWindow{},
+		StallTimeout:     10,
+		CampaignAlerts:   "priority",
+		DirectoryFilters: []string{"DROPS_ENABLED"},
+		DirectoryTags:    []string{},
+		DirectorySort:    "RELEVANCE",
+		StreamLanguages:  []string{},
+		WebPushSubject:   getEnv("WEBPUSH_SUBJECT", "mailto:admin@localhost"),
+		NotificationURLs: []string{},
+		TelegramBotToken: getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramChatIDs:  []string{},
+		DigestFrequency:  "off",
+		DigestHour:       8,
+		LogBufferSize:    500,
+		LogToConsole:     true,
+		LogLevel:         "info",
+		LogLevels:        map[string]string{},
+		LogMaxSizeMB:     10,
+		LogMaxAgeDays:    7,
+		LogMaxBackups:    5,
+		LivenessTimeout:  300,
+		OTLPEndpoint:     getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "")),
+		SentryDSN:        getEnv("SENTRY_DSN", ""),
+		ErrorWebhookURL:  getEnv("ERROR_WEBHOOK_URL", ""),
+		Theme:            "dark",
+		Language:         "en",
+		ShowTray:         true,
+		StartMinimized:   false,
+
+		// Home Assistant over MQTT
+		MQTTURL:             getEnv("MQTT_URL", ""),
+		MQTTUsername:        getEnv("MQTT_USERNAME", ""),
+		MQTTPassword:        getEnv("MQTT_PASSWORD", ""),
+		MQTTTopicPrefix:     "twitchdropsfarmer",
+		MQTTDiscoveryPrefix: "homeassistant",
+
+		// Generic webhook
+		WebhookURL:       getEnv("WEBHOOK_URL", ""),
+		WebhookSecret:    getEnv("WEBHOOK_SECRET", ""),
+		WebhookEvents:    []string{},
+		WebhookTemplates: map[string]string{},
+
+		// Automatic backups
+		BackupSchedule:  "0 4 * * *",
+		BackupRetention: 7,
 	}
 
 	// Load configuration from file if it exists
@@ -85,7 +291,15 @@ func (c *Config) Save() error {
 		return err
 	}
 
-	return os.WriteFile(configPath, data, 0644)
+	// 0600, it holds the web password and notification secrets; WriteFile keeps the mode of an existing file
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Chmod(configPath, 0600); err != nil {
+		return err
+	}
+	rememberFile(data)
+	return nil
 }
 
 func getConfigPath() string {
@@ -93,6 +307,38 @@ func getConfigPath() string {
 	return filepath.Join(".", "config", "config.json")
 }
 
+// BackupDir is the directory backups are written to
+func BackupDir() string {
+	return filepath.Join(".", "config", "backups")
+}
+
+// LogDir is the directory log files may be written to besides the data directory
+func LogDir() string {
+	return filepath.Join(".", "config", "logs")
+}
+
+// CheckLogFile checks that a log file set over the API is a .log file in the data or log directory,
+// so it can't be pointed at the config, the token or a storage document
+func CheckLogFile(dataDir, path string) error {
+	if filepath.Ext(path) != ".log" {
+		return fmt.Errorf("must be a .log file")
+	}
+	file, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{dataDir, LogDir()} {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, file); err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be in the data directory or %s", LogDir())
+}
+
 func getTokenPath() string {
 	// Store auth tokens in ./config directory
 	return filepath.Join(".", "config", "token.json")
@@ -103,6 +349,7 @@ func loadFromFile(cfg *Config, path string) error {
 	if err != nil {
 		return err
 	}
+	rememberFile(data)
 
 	return json.Unmarshal(data, cfg)
 }
@@ -114,60 +361,188 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList splits a comma-separated environment variable, empty entries are dropped
+func getEnvList(key string) []string {
+	values := []string{}
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // Token storage functions
 type StoredToken struct {
-	AccessToken string    `json:"access_token"`
-	TokenType   string    `json:"token_type"`
-	Expiry      time.Time `json:"expiry"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type"`
+	Expiry       time.Time `json:"expiry"`
+	Scopes       []string  `json:"scopes,omitempty"` // granted at login
+}
+
+// NewStoredToken takes what is stored of a token, the scopes from its "scopes" extra
+func NewStoredToken(token *oauth2.Token) StoredToken {
+	storedToken := StoredToken{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+	}
+	if scopes, ok := token.Extra("scopes").([]string); ok {
+		storedToken.Scopes = scopes
+	}
+	return storedToken
+}
+
+// Token returns the stored token with its scopes as the "scopes" extra
+func (t StoredToken) Token() *oauth2.Token {
+	token := &oauth2.Token{
+		AccessToken:  t.AccessToken,
+		RefreshToken: t.RefreshToken,
+		TokenType:    t.TokenType,
+		Expiry:       t.Expiry,
+	}
+	return token.WithExtra(map[string]interface{}{"scopes": t.Scopes})
 }
 
 func SaveToken(token *oauth2.Token) error {
-	tokenPath := getTokenPath()
+	return saveTokenFile(getTokenPath(), token)
+}
+
+func LoadToken() (*oauth2.Token, error) {
+	return loadTokenFile(getTokenPath())
+}
+
+func DeleteToken() error {
+	return os.Remove(getTokenPath())
+}
+
+// Per-account tokens live in ./config/tokens/<user ID>.json
+func getAccountTokenDir() string {
+	return filepath.Join(".", "config", "tokens")
+}
+
+func getAccountTokenPath(accountID string) string {
+	return filepath.Join(getAccountTokenDir(), filepath.Base(accountID)+".json")
+}
+
+// SaveAccountToken stores the token of an additional account
+func SaveAccountToken(accountID string, token *oauth2.Token) error {
+	return saveTokenFile(getAccountTokenPath(accountID), token)
+}
+
+// LoadAccountToken loads the token of an additional account
+func LoadAccountToken(accountID string) (*oauth2.Token, error) {
+	return loadTokenFile(getAccountTokenPath(accountID))
+}
 
+// DeleteAccountToken removes the token of an additional account
+func DeleteAccountToken(accountID string) error {
+	return os.Remove(getAccountTokenPath(accountID))
+}
+
+// ListAccountTokens returns the IDs of all accounts with a stored token
+func ListAccountTokens() ([]string, error) {
+	entries, err := os.ReadDir(getAccountTokenDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	return ids, nil
+}
+
+func saveTokenFile(tokenPath string, token *oauth2.Token) error {
 	// Create config directory if it doesn't exist
 	if err := os.MkdirAll(filepath.Dir(tokenPath), 0755); err != nil {
 		return err
 	}
 
-	storedToken := StoredToken{
-		AccessToken: token.AccessToken,
-		TokenType:   token.TokenType,
-		Expiry:      token.Expiry,
-	}
-
-	data, err := json.MarshalIndent(storedToken, "", "  ")
+	data, err := MarshalToken(token)
 	if err != nil {
 		return err
 	}
-
 	return os.WriteFile(tokenPath, data, 0600) // 0600 for security
 }
 
-func LoadToken() (*oauth2.Token, error) {
-	tokenPath := getTokenPath()
-
+func loadTokenFile(tokenPath string) (*oauth2.Token, error) {
 	data, err := os.ReadFile(tokenPath)
 	if err != nil {
 		return nil, err
 	}
 
-	var storedToken StoredToken
-	if err := json.Unmarshal(data, &storedToken); err != nil {
+	token, encrypted, err := unmarshalToken(data)
+	if err != nil {
 		return nil, err
 	}
 
-	token := &oauth2.Token{
-		AccessToken: storedToken.AccessToken,
-		TokenType:   storedToken.TokenType,
-		Expiry:      storedToken.Expiry,
+	// Encrypt plaintext files left from before encryption was turned on
+	if encrypt, err := tokenKeys.enabled(); err == nil && encrypt && !encrypted {
+		if err := saveTokenFile(tokenPath, token); err != nil {
+			logrus.Errorf("Failed to encrypt %s: %v", tokenPath, err)
+		} else {
+			logrus.Infof("Encrypted plaintext token file %s", tokenPath)
+		}
 	}
 
 	return token, nil
 }
 
-func DeleteToken() error {
-	tokenPath := getTokenPath()
-	return os.Remove(tokenPath)
+// MarshalToken encodes a token the way token files hold it, encrypted when token encryption is on
+func MarshalToken(token *oauth2.Token) ([]byte, error) {
+	data, err := json.MarshalIndent(NewStoredToken(token), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	encrypt, err := tokenKeys.enabled()
+	if err != nil {
+		return nil, err
+	}
+	if encrypt {
+		if data, err = encryptToken(data); err != nil {
+			return nil, fmt.Errorf("failed to encrypt token: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalToken decodes a token encoded by MarshalToken, or left in plaintext
+func UnmarshalToken(data []byte) (*oauth2.Token, error) {
+	token, _, err := unmarshalToken(data)
+	return token, err
+}
+
+// unmarshalToken also reports whether data was encrypted
+func unmarshalToken(data []byte) (*oauth2.Token, bool, error) {
+	data, encrypted, err := decryptToken(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var storedToken StoredToken
+	if err := json.Unmarshal(data, &storedToken); err != nil {
+		return nil, false, err
+	}
+	return storedToken.Token(), encrypted, nil
+}
+
+// ProxyFor returns the proxy for an account, falling back to the global one; use "" for the primary account
+func (c *Config) ProxyFor(accountID string) string {
+	if proxy, ok := c.AccountProxies[accountID]; ok && accountID != "" {
+		return proxy
+	}
+	return c.Proxy
 }
 
 // AddGameToConfig adds a game to the configuration with slug and ID resolution
@@ -195,6 +570,30 @@ func (c *Config) AddGameToConfig(gameName string, gameSlug string, gameID string
 	return c.Save()
 }
 
+// SetGameAliases replaces the aliases of a priority game and saves the config
+func (c *Config) SetGameAliases(gameName string, aliases []string) error {
+	for i, existing := range c.PriorityGames {
+		if existing.Name == gameName {
+			c.PriorityGames[i].Aliases = aliases
+			logrus.Infof("Set %d aliases for priority game '%s'", len(aliases), gameName)
+			return c.Save()
+		}
+	}
+	return fmt.Errorf("game '%s' is not a priority game", gameName)
+}
+
+// SetGamePreferredChannels sets the channels watched first for a priority game and saves the config
+func (c *Config) SetGamePreferredChannels(gameName string, channels []string) error {
+	for i, existing := range c.PriorityGames {
+		if existing.Name == gameName {
+			c.PriorityGames[i].PreferredChannels = channels
+			logrus.Infof("Set %d preferred channels for priority game '%s'", len(channels), gameName)
+			return c.Save()
+		}
+	}
+	return fmt.Errorf("game '%s' is not a priority game", gameName)
+}
+
 // GetGameSlugOrEmpty returns the slug for a game name, or empty string if not found
 func (c *Config) GetGameSlugOrEmpty(gameName string) string {
 	// Check priority games first
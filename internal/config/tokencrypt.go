@@ -0,0 +1,180 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/argon2"
+)
+
+// Where the generated token key is kept when TOKEN_KEYRING is set
+const (
+	keyringService = "TwitchDropsFarmer"
+	keyringUser    = "token-encryption-key"
+)
+
+// Key derivations recorded in encrypted token files
+const (
+	tokenKDFArgon2 = "argon2id" // from the TOKEN_ENCRYPTION_KEY passphrase, with the file's salt
+	tokenKDFNone   = "none"     // the random key from the OS keyring, used as is
+)
+
+// encryptedToken is the content of a token file written with encryption on
+type encryptedToken struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"` // AES-256-GCM sealed StoredToken JSON
+}
+
+// tokenKeySource is the secret token files are encrypted with, read once from the environment or keyring
+type tokenKeySource struct {
+	once       sync.Once
+	passphrase []byte // TOKEN_ENCRYPTION_KEY
+	keyringKey []byte // 32 bytes from the OS keyring
+	err        error
+}
+
+var tokenKeys tokenKeySource
+
+// load reads TOKEN_ENCRYPTION_KEY, or the keyring key when TOKEN_KEYRING is set, generating and storing one
+// the first time; neither set leaves token files in plaintext
+func (s *tokenKeySource) load() error {
+	s.once.Do(func() {
+		if passphrase := os.Getenv("TOKEN_ENCRYPTION_KEY"); passphrase != "" {
+			s.passphrase = []byte(passphrase)
+			return
+		}
+		useKeyring, _ := strconv.ParseBool(os.Getenv("TOKEN_KEYRING"))
+		if !useKeyring {
+			return
+		}
+
+		stored, err := keyring.Get(keyringService, keyringUser)
+		if errors.Is(err, keyring.ErrNotFound) {
+			key := make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				s.err = fmt.Errorf("failed to generate token key: %w", err)
+				return
+			}
+			stored = hex.EncodeToString(key)
+			if err := keyring.Set(keyringService, keyringUser, stored); err != nil {
+				s.err = fmt.Errorf("failed to store token key in the OS keyring: %w", err)
+				return
+			}
+			logrus.Info("Generated a token encryption key and stored it in the OS keyring")
+		} else if err != nil {
+			s.err = fmt.Errorf("failed to read token key from the OS keyring: %w", err)
+			return
+		}
+
+		key, err := hex.DecodeString(stored)
+		if err != nil || len(key) != 32 {
+			s.err = fmt.Errorf("token key in the OS keyring is not 32 hex-encoded bytes")
+			return
+		}
+		s.keyringKey = key
+	})
+	return s.err
+}
+
+// enabled reports whether token files are written encrypted
+func (s *tokenKeySource) enabled() (bool, error) {
+	if err := s.load(); err != nil {
+		return false, err
+	}
+	return s.passphrase != nil || s.keyringKey != nil, nil
+}
+
+// key returns the AES key for a file written with kdf and salt
+func (s *tokenKeySource) key(kdf string, salt []byte) ([]byte, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	switch kdf {
+	case tokenKDFArgon2:
+		if s.passphrase == nil {
+			return nil, fmt.Errorf("token file is encrypted with a passphrase, set TOKEN_ENCRYPTION_KEY")
+		}
+		return argon2.IDKey(s.passphrase, salt, 1, 64*1024, 4, 32), nil
+	case tokenKDFNone:
+		if s.keyringKey == nil {
+			return nil, fmt.Errorf("token file is encrypted with the OS keyring key, set TOKEN_KEYRING")
+		}
+		return s.keyringKey, nil
+	}
+	return nil, fmt.Errorf("unknown token key derivation %q", kdf)
+}
+
+// encryptToken seals the StoredToken JSON, with the passphrase when both a passphrase and keyring are set
+func encryptToken(plaintext []byte) ([]byte, error) {
+	envelope := encryptedToken{Version: 1, KDF: tokenKDFNone}
+	if tokenKeys.passphrase != nil {
+		envelope.KDF = tokenKDFArgon2
+		envelope.Salt = make([]byte, 16)
+		if _, err := rand.Read(envelope.Salt); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := tokenKeys.key(envelope.KDF, envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newTokenGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(envelope.Nonce); err != nil {
+		return nil, err
+	}
+	envelope.Ciphertext = gcm.Seal(nil, envelope.Nonce, plaintext, nil)
+
+	return json.MarshalIndent(envelope, "", "  ")
+}
+
+// decryptToken returns the StoredToken JSON of a token file, and whether it was encrypted
+func decryptToken(data []byte) ([]byte, bool, error) {
+	var envelope encryptedToken
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Ciphertext == nil {
+		// Plaintext token file, or not JSON at all, which StoredToken decoding reports
+		return data, false, nil
+	}
+
+	key, err := tokenKeys.key(envelope.KDF, envelope.Salt)
+	if err != nil {
+		return nil, true, err
+	}
+	gcm, err := newTokenGCM(key)
+	if err != nil {
+		return nil, true, err
+	}
+	if len(envelope.Nonce) != gcm.NonceSize() {
+		return nil, true, fmt.Errorf("invalid token file nonce")
+	}
+	plaintext, err := gcm.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to decrypt token file, wrong key?")
+	}
+	return plaintext, true, nil
+}
+
+func newTokenGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// How long the config file has to stay unchanged before it is reloaded, editors write in several steps
+const watchDebounce = 500 * time.Millisecond
+
+// Hash of config.json as last written by Save or read by Load, so the watcher skips the farmer's own writes
+var (
+	fileHashMu sync.Mutex
+	fileHash   [32]byte
+)
+
+// rememberFile records the content config.json is known to have
+func rememberFile(data []byte) {
+	fileHashMu.Lock()
+	defer fileHashMu.Unlock()
+	fileHash = sha256.Sum256(data)
+}
+
+// fileChanged reports whether data differs from the content last saved or loaded, and remembers it
+func fileChanged(data []byte) bool {
+	hash := sha256.Sum256(data)
+	fileHashMu.Lock()
+	defer fileHashMu.Unlock()
+	if hash == fileHash {
+		return false
+	}
+	fileHash = hash
+	return true
+}
+
+// WatchFile calls reload whenever config.json is changed by something other than Save, until ctx is done
+// The directory is watched rather than the file, so editors that save by replacing the file are picked up too.
+func WatchFile(ctx context.Context, reload func()) error {
+	configPath := getConfigPath()
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(configPath)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		debounce := time.NewTimer(watchDebounce)
+		debounce.Stop()
+		defer debounce.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) == filepath.Base(configPath) && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					debounce.Reset(watchDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logrus.Warnf("Config file watcher error: %v", err)
+			case <-debounce.C:
+				data, err := os.ReadFile(configPath)
+				if err != nil {
+					// Renamed away mid-save, the write that follows fires again
+					logrus.Debugf("Config file not readable yet: %v", err)
+					continue
+				}
+				if fileChanged(data) {
+					reload()
+				}
+			}
+		}
+	}()
+	return nil
+}
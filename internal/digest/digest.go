@@ -0,0 +1,212 @@
+// Package digest mails a daily or weekly summary of the farming activity: drops claimed, time watched,
+// campaigns completed, and the campaigns ending before the next digest.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/notify"
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How often to send digests
+const (
+	FrequencyOff    = "off"
+	FrequencyDaily  = "daily"  // yesterday's activity, every day
+	FrequencyWeekly = "weekly" // last week's activity, every Monday
+)
+
+// Name of the storage document holding when the last digest was sent
+const digestDocument = "digest"
+
+// How often the schedule is checked
+const checkInterval = time.Minute
+
+// How long to wait before trying again when a digest couldn't be sent
+const retryInterval = time.Hour
+
+// How long sending a digest may take
+const sendTimeout = 30 * time.Second
+
+// ValidFrequency reports whether frequency is a supported digest_frequency
+func ValidFrequency(frequency string) bool {
+	switch frequency {
+	case FrequencyOff, FrequencyDaily, FrequencyWeekly:
+		return true
+	}
+	return false
+}
+
+// Config holds the digest settings
+type Config struct {
+	EmailURL  string // mailto:// or mailtos:// URL the digest is sent with
+	Frequency string
+	Hour      int // local hour of the day digests are sent at
+}
+
+// NewConfig takes the digest settings from the application configuration
+func NewConfig(cfg *config.Config) Config {
+	return Config{
+		EmailURL:  cfg.DigestEmail,
+		Frequency: cfg.DigestFrequency,
+		Hour:      cfg.DigestHour,
+	}
+}
+
+// Digest sends the digests of the primary account's miner on schedule
+type Digest struct {
+	miner *drops.Miner
+	store storage.Store
+
+	mu       sync.Mutex
+	config   Config
+	email    *notify.Email // nil without an email URL
+	lastSent time.Time
+	retryAt  time.Time
+}
+
+// digestState is the digest document
+type digestState struct {
+	LastSent time.Time `json:"last_sent"`
+}
+
+// New creates the digest sender; call SetConfig, then Run
+func New(miner *drops.Miner, store storage.Store) *Digest {
+	d := &Digest{miner: miner, store: store}
+
+	var state digestState
+	if err := store.Load(digestDocument, &state); err != nil {
+		logrus.Errorf("Failed to load digest state: %v", err)
+	}
+	d.lastSent = state.LastSent
+	if d.lastSent.IsZero() {
+		// First start, the first digest goes out at the next scheduled time rather than right away
+		d.lastSent = time.Now()
+		d.saveState()
+	}
+	return d
+}
+
+// SetConfig applies new settings, keeping the current ones when the email URL doesn't parse
+func (d *Digest) SetConfig(config Config) error {
+	var email *notify.Email
+	if config.EmailURL != "" {
+		var err error
+		if email, err = notify.ParseEmailURL(config.EmailURL); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.config = config
+	d.email = email
+	d.retryAt = time.Time{}
+	return nil
+}
+
+// Run sends the digests when they are due until ctx is cancelled
+func (d *Digest) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.sendIfDue(ctx, now)
+		}
+	}
+}
+
+func (d *Digest) sendIfDue(ctx context.Context, now time.Time) {
+	d.mu.Lock()
+	config, email := d.config, d.email
+	due := config.Frequency != FrequencyOff && email != nil &&
+		d.lastSent.Before(lastSlot(config, now)) && !now.Before(d.retryAt)
+	d.mu.Unlock()
+	if !due {
+		return
+	}
+
+	err := d.send(ctx, email, config, now)
+
+	d.mu.Lock()
+	if err != nil {
+		logrus.Errorf("Failed to send the %s digest, retrying in %s: %v", config.Frequency, retryInterval, err)
+		d.retryAt = now.Add(retryInterval)
+	} else {
+		logrus.Infof("Sent the %s digest", config.Frequency)
+		d.lastSent = now
+		d.retryAt = time.Time{}
+	}
+	d.mu.Unlock()
+	if err == nil {
+		d.saveState()
+	}
+}
+
+// SendNow sends the digest of the last period right away, e.g. to check the mail settings; the schedule is
+// unchanged. Without a frequency it covers yesterday.
+func (d *Digest) SendNow(ctx context.Context) error {
+	d.mu.Lock()
+	config, email := d.config, d.email
+	d.mu.Unlock()
+
+	if email == nil {
+		return fmt.Errorf("no digest_email configured")
+	}
+	if config.Frequency == FrequencyOff {
+		config.Frequency = FrequencyDaily
+	}
+	return d.send(ctx, email, config, time.Now())
+}
+
+func (d *Digest) send(ctx context.Context, email *notify.Email, config Config, now time.Time) error {
+	report, err := d.build(config, now)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return email.SendMail(ctx, "[TwitchDropsFarmer] "+report.subject(), report.body())
+}
+
+func (d *Digest) saveState() {
+	d.mu.Lock()
+	state := digestState{LastSent: d.lastSent}
+	d.mu.Unlock()
+
+	if err := d.store.Save(digestDocument, state); err != nil {
+		logrus.Errorf("Failed to save digest state: %v", err)
+	}
+}
+
+// lastSlot returns the latest time at or before now a digest is scheduled at
+func lastSlot(config Config, now time.Time) time.Time {
+	slot := time.Date(now.Year(), now.Month(), now.Day(), config.Hour, 0, 0, 0, now.Location())
+	if config.Frequency == FrequencyWeekly {
+		// Back to Monday
+		slot = slot.AddDate(0, 0, -((int(slot.Weekday()) + 6) % 7))
+	}
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -periodDays(config))
+	}
+	return slot
+}
+
+// periodDays is the number of days a digest covers
+func periodDays(config Config) int {
+	if config.Frequency == FrequencyWeekly {
+		return 7
+	}
+	return 1
+}
@@ -0,0 +1,123 @@
+package digest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/drops"
+)
+
+// report is the content of one digest
+type report struct {
+	frequency string
+	stats     *drops.StatsRange
+	ending    []endingCampaign // ending before the next digest, soonest first
+}
+
+// endingCampaign is a campaign with drops still pending that ends before the next digest
+type endingCampaign struct {
+	name             string
+	game             string
+	endsAt           time.Time
+	pendingDrops     int
+	remainingMinutes int  // of the furthest pending drop
+	atRisk           bool // some drop can't be finished before the end
+}
+
+// build gathers the activity of the period before the latest scheduled time, and the campaigns from the
+// miner's forecast ending before the next one
+func (d *Digest) build(config Config, now time.Time) (*report, error) {
+	slot := lastSlot(config, now)
+	stats, err := d.miner.GetStatsEnding(slot.AddDate(0, 0, -1), periodDays(config))
+	if err != nil {
+		return nil, err
+	}
+	next := slot.AddDate(0, 0, periodDays(config))
+
+	campaigns := make(map[string]*endingCampaign)
+	var ending []endingCampaign
+	var order []string
+	for _, forecast := range d.miner.GetStatus().Forecast {
+		if !forecast.CampaignEndsAt.After(now) || !forecast.CampaignEndsAt.Before(next) {
+			continue
+		}
+		campaign := campaigns[forecast.CampaignID]
+		if campaign == nil {
+			campaign = &endingCampaign{name: forecast.CampaignName, game: forecast.GameName, endsAt: forecast.CampaignEndsAt}
+			campaigns[forecast.CampaignID] = campaign
+			order = append(order, forecast.CampaignID)
+		}
+		campaign.pendingDrops++
+		if forecast.RemainingMinutes > campaign.remainingMinutes {
+			campaign.remainingMinutes = forecast.RemainingMinutes
+		}
+		campaign.atRisk = campaign.atRisk || forecast.AtRisk
+	}
+	for _, id := range order {
+		ending = append(ending, *campaigns[id])
+	}
+	sort.SliceStable(ending, func(i, j int) bool { return ending[i].endsAt.Before(ending[j].endsAt) })
+
+	return &report{frequency: config.Frequency, stats: stats, ending: ending}, nil
+}
+
+func (r *report) subject() string {
+	if r.frequency == FrequencyWeekly {
+		return fmt.Sprintf("Weekly digest, %s to %s", formatDate(r.stats.From), formatDate(r.stats.To))
+	}
+	return "Daily digest, " + formatDate(r.stats.To)
+}
+
+// body is the plain text mail
+func (r *report) body() string {
+	totals := r.stats.Totals
+	lines := []string{
+		fmt.Sprintf("Drops claimed: %d", totals.DropsClaimed),
+		fmt.Sprintf("Time watched: %s", formatMinutes(totals.MinutesWatched)),
+		fmt.Sprintf("Campaigns completed: %d", totals.CampaignsCompleted),
+	}
+
+	if len(r.stats.Games) > 0 {
+		lines = append(lines, "", "By game:")
+		for _, game := range r.stats.Games {
+			lines = append(lines, fmt.Sprintf("- %s: %s watched, %d drops claimed, %d campaigns completed",
+				game.GameName, formatMinutes(game.MinutesWatched), game.DropsClaimed, game.CampaignsCompleted))
+		}
+	}
+
+	lines = append(lines, "", "Ending before the next digest:")
+	if len(r.ending) == 0 {
+		lines = append(lines, "- Nothing with drops left to farm")
+	}
+	for _, campaign := range r.ending {
+		line := fmt.Sprintf("- %s (%s), ends %s: %d drops left, %s to go",
+			campaign.name, campaign.game, campaign.endsAt.Local().Format("Mon Jan 2 15:04"),
+			campaign.pendingDrops, formatMinutes(float64(campaign.remainingMinutes)))
+		if campaign.atRisk {
+			line += ", won't finish in time at the current pace"
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\r\n")
+}
+
+// formatMinutes writes minutes as hours and minutes, e.g. 6h 20m
+func formatMinutes(minutes float64) string {
+	total := int(minutes + 0.5)
+	if total < 60 {
+		return fmt.Sprintf("%dm", total)
+	}
+	return fmt.Sprintf("%dh %02dm", total/60, total%60)
+}
+
+// formatDate writes a YYYY-MM-DD date like Mon Jan 2
+func formatDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("Mon Jan 2")
+}
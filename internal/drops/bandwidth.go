@@ -0,0 +1,126 @@
+package drops
+
+import (
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding bytes downloaded per day
+const bandwidthDocument = "bandwidth"
+
+// Watch requests are spaced this far apart once the daily cap is exceeded
+const reducedWatchInterval = 60 * time.Second
+
+// How often the daily totals are written to storage, instead of on every watch request
+const bandwidthSaveInterval = 5 * time.Minute
+
+// Days of history kept in the bandwidth document
+const bandwidthHistoryDays = 30
+
+// BandwidthUsage is the bytes downloaded by watch requests today and on previous days
+type BandwidthUsage struct {
+	Today     int64            `json:"today_bytes"`
+	DailyCap  int64            `json:"daily_cap_bytes"` // 0 when there is no cap
+	Throttled bool             `json:"throttled"`       // watch requests are sent less often until tomorrow
+	History   map[string]int64 `json:"history"`         // bytes by local date, YYYY-MM-DD
+}
+
+// bandwidthMeter accumulates downloaded bytes per local day
+type bandwidthMeter struct {
+	mu        sync.Mutex
+	store     storage.Store
+	days      map[string]int64
+	lastSaved time.Time
+	lastWatch time.Time
+}
+
+// loadBandwidth reads the persisted daily totals from storage
+func (m *Miner) loadBandwidth(store storage.Store) {
+	days := make(map[string]int64)
+	if err := store.Load(bandwidthDocument, &days); err != nil {
+		logrus.Errorf("Failed to load bandwidth usage: %v", err)
+	}
+
+	m.bandwidth.mu.Lock()
+	defer m.bandwidth.mu.Unlock()
+	for day, bytes := range m.bandwidth.days {
+		days[day] += bytes
+	}
+	m.bandwidth.store = store
+	m.bandwidth.days = days
+}
+
+// addBandwidth records bytes downloaded now
+func (m *Miner) addBandwidth(bytes int64) {
+	now := time.Now()
+	today := now.Format("2006-01-02")
+
+	b := &m.bandwidth
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.days == nil {
+		b.days = make(map[string]int64)
+	}
+	if _, ok := b.days[today]; !ok {
+		// New day, drop history past the retention window
+		cutoff := now.AddDate(0, 0, -bandwidthHistoryDays).Format("2006-01-02")
+		for day := range b.days {
+			if day < cutoff {
+				delete(b.days, day)
+			}
+		}
+	}
+	b.days[today] += bytes
+
+	if b.store == nil || now.Sub(b.lastSaved) < bandwidthSaveInterval {
+		return
+	}
+	b.lastSaved = now
+	if err := b.store.Save(bandwidthDocument, b.days); err != nil {
+		logrus.Errorf("Failed to save bandwidth usage: %v", err)
+	}
+}
+
+// GetBandwidthUsage returns today's usage against the configured cap
+func (m *Miner) GetBandwidthUsage() BandwidthUsage {
+	m.mu.RLock()
+	dailyCap := m.config.BandwidthCap
+	m.mu.RUnlock()
+
+	b := &m.bandwidth
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usage := BandwidthUsage{
+		Today:    b.days[time.Now().Format("2006-01-02")],
+		DailyCap: dailyCap,
+		History:  make(map[string]int64, len(b.days)),
+	}
+	for day, bytes := range b.days {
+		usage.History[day] = bytes
+	}
+	usage.Throttled = dailyCap > 0 && usage.Today >= dailyCap
+	return usage
+}
+
+// shouldSkipWatch reports whether a watch request should be skipped to stay near the daily cap
+func (m *Miner) shouldSkipWatch(now time.Time) bool {
+	m.mu.RLock()
+	dailyCap := m.config.BandwidthCap
+	m.mu.RUnlock()
+
+	b := &m.bandwidth
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if dailyCap > 0 && b.days[now.Format("2006-01-02")] >= dailyCap && now.Sub(b.lastWatch) < reducedWatchInterval {
+		return true
+	}
+	b.lastWatch = now
+	return false
+}
@@ -0,0 +1,34 @@
+package drops
+
+import (
+	"context"
+	"sort"
+
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CalendarCampaigns returns the listed campaigns of the priority games, soonest first, with their drops
+// where the details can be fetched, so drop deadlines can be shown next to the campaign dates
+func (m *Miner) CalendarCampaigns(ctx context.Context, campaigns []twitch.Campaign) []twitch.Campaign {
+	var result []twitch.Campaign
+	for _, campaign := range campaigns {
+		if !m.isGamePriority(campaign.Game) || m.isGameExcluded(campaign.Game) {
+			continue
+		}
+
+		details, err := m.getCampaignDetails(ctx, campaign)
+		if err != nil {
+			logrus.Debugf("Failed to get details of %s for the calendar: %v", campaign.Name, err)
+			result = append(result, campaign)
+			continue
+		}
+		result = append(result, *details)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].StartsAt.Before(result[j].StartsAt)
+	})
+	return result
+}
@@ -0,0 +1,215 @@
+package drops
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Channels of a campaign's allow list checked for a live stream per switch, in list order
+const maxAllowedChannelChecks = 25
+
+// findStream picks the stream to watch for a campaign and starts watching it: the live channels of the
+// campaign's allow list when it has one, otherwise the game directory. The game's preferred channels come first
+// when they are live. Streams that don't grant the campaign's drops according to
+// DropsHighlightService_AvailableDrops are passed over
+func (m *Miner) findStream(ctx context.Context, campaign *twitch.Campaign) (*twitch.Stream, *twitch.WatchingSession, error) {
+	preferred := m.preferredStreams(ctx, campaign)
+
+	var streams []twitch.Stream
+	var err error
+	if len(campaign.Allow) > 0 {
+		streams = m.allowedStreams(ctx, campaign)
+		if len(streams) == 0 && len(preferred) == 0 {
+			return nil, nil, fmt.Errorf("none of the %d allowed channels are live for: %s", len(campaign.Allow), campaign.Name)
+		}
+	} else {
+		streams, err = m.directoryStreams(ctx, campaign)
+		if err != nil && len(preferred) == 0 {
+			return nil, nil, fmt.Errorf("failed to get streams for game: %w", err)
+		}
+		if len(streams) == 0 && len(preferred) == 0 {
+			return nil, nil, fmt.Errorf("no streams found for game: %s", campaign.Game.Name)
+		}
+	}
+
+	m.mu.RLock()
+	languages := m.config.StreamLanguages
+	minViewers, maxViewers := m.config.MinViewers, m.config.MaxViewers
+	m.mu.RUnlock()
+
+	// Most viewers first within the viewer bounds, after the preferred channels in the order they are listed
+	sort.SliceStable(streams, func(i, j int) bool {
+		return streams[i].ViewerCount > streams[j].ViewerCount
+	})
+	streams = preferFirst(preferred, withinViewerBounds(streams, minViewers, maxViewers))
+
+	for i := range streams {
+		stream := &streams[i]
+		if m.isOffline(stream.UserLogin) {
+			continue
+		}
+		if !twitch.LanguageAllowed(languages, stream.Language) {
+			// The directory already filters by language, this catches streams looked up by channel
+			logrus.Debugf("Skipping %s: broadcasting in %s", stream.UserLogin, stream.Language)
+			continue
+		}
+		if !m.streamHasCampaign(ctx, stream, campaign) {
+			continue
+		}
+
+		// Start watching session like TDM
+		watchingSession, err := m.twitchClient.StartWatching(ctx, stream.UserLogin)
+		if err != nil {
+			logrus.Debugf("Skipping %s: %v", stream.UserLogin, err)
+			continue
+		}
+		return stream, watchingSession, nil
+	}
+
+	return nil, nil, fmt.Errorf("no suitable stream found for game: %s", campaign.Game.Name)
+}
+
+// preferredStreams returns the live streams of the game's preferred channels that are playing it, in list order;
+// with an allow list only the allowed ones are checked
+func (m *Miner) preferredStreams(ctx context.Context, campaign *twitch.Campaign) []twitch.Stream {
+	index := m.getGamePriorityIndex(campaign.Game)
+	if index < 0 {
+		return nil
+	}
+
+	var allowed map[string]bool
+	if len(campaign.Allow) > 0 {
+		allowed = make(map[string]bool, len(campaign.Allow))
+		for _, login := range campaign.Allow {
+			allowed[strings.ToLower(login)] = true
+		}
+	}
+
+	var streams []twitch.Stream
+	for _, channelLogin := range m.config.PriorityGames[index].PreferredChannels {
+		if (allowed != nil && !allowed[strings.ToLower(channelLogin)]) || m.isOffline(channelLogin) {
+			continue
+		}
+		stream, err := m.twitchClient.GetLiveStream(ctx, channelLogin)
+		if err != nil {
+			logrus.Debugf("Preferred channel %s not available: %v", channelLogin, err)
+			continue
+		}
+		if stream.GameID != "" && campaign.Game.ID != "" && stream.GameID != campaign.Game.ID {
+			logrus.Debugf("Preferred channel %s is playing %s, not %s", channelLogin, stream.GameName, campaign.Game.Name)
+			continue
+		}
+		streams = append(streams, *stream)
+	}
+	return streams
+}
+
+// preferFirst puts the preferred streams ahead of the others, leaving out the others' copies of them
+func preferFirst(preferred, streams []twitch.Stream) []twitch.Stream {
+	if len(preferred) == 0 {
+		return streams
+	}
+	seen := make(map[string]bool, len(preferred))
+	for _, stream := range preferred {
+		seen[strings.ToLower(stream.UserLogin)] = true
+	}
+	ordered := append([]twitch.Stream{}, preferred...)
+	for _, stream := range streams {
+		if !seen[strings.ToLower(stream.UserLogin)] {
+			ordered = append(ordered, stream)
+		}
+	}
+	return ordered
+}
+
+// withinViewerBounds moves streams outside the viewer bounds after the ones inside, so they are only watched when
+// nothing in range qualifies: too big ones first, smallest first, then too small ones, biggest first. streams is
+// sorted by most viewers.
+func withinViewerBounds(streams []twitch.Stream, minViewers, maxViewers int) []twitch.Stream {
+	if minViewers <= 0 && maxViewers <= 0 {
+		return streams
+	}
+	var inRange, tooBig, tooSmall []twitch.Stream
+	for _, stream := range streams {
+		switch {
+		case maxViewers > 0 && stream.ViewerCount > maxViewers:
+			tooBig = append(tooBig, stream)
+		case stream.ViewerCount < minViewers:
+			tooSmall = append(tooSmall, stream)
+		default:
+			inRange = append(inRange, stream)
+		}
+	}
+	if len(inRange) == 0 && len(streams) > 0 {
+		logrus.Debugf("No stream within the viewer bounds, trying the closest ones")
+	}
+	slices.Reverse(tooBig)
+	ordered := append(inRange, tooBig...)
+	return append(ordered, tooSmall...)
+}
+
+// directoryStreams lists live streams of the campaign's game, preferring the slug resolved when
+// the game was added since the campaign may use an alias of its name
+func (m *Miner) directoryStreams(ctx context.Context, campaign *twitch.Campaign) ([]twitch.Stream, error) {
+	if index := m.getGamePriorityIndex(campaign.Game); index >= 0 && m.config.PriorityGames[index].Slug != "" {
+		return m.twitchClient.GetStreamsForGame(ctx, m.config.PriorityGames[index].Slug, m.config.MaximumStreams)
+	}
+	return m.twitchClient.GetStreamsForGameName(ctx, campaign.Game.Name, m.config.MaximumStreams)
+}
+
+// allowedStreams returns the live streams of the campaign's allowed channels that are playing its game
+func (m *Miner) allowedStreams(ctx context.Context, campaign *twitch.Campaign) []twitch.Stream {
+	var streams []twitch.Stream
+	checked := 0
+	for _, channelLogin := range campaign.Allow {
+		if checked >= maxAllowedChannelChecks || len(streams) >= m.config.MaximumStreams {
+			break
+		}
+		if m.isOffline(channelLogin) {
+			continue
+		}
+		checked++
+
+		stream, err := m.twitchClient.GetLiveStream(ctx, channelLogin)
+		if err != nil {
+			logrus.Debugf("Allowed channel %s not available: %v", channelLogin, err)
+			continue
+		}
+		if stream.GameID != "" && campaign.Game.ID != "" && stream.GameID != campaign.Game.ID {
+			logrus.Debugf("Allowed channel %s is playing %s, not %s", channelLogin, stream.GameName, campaign.Game.Name)
+			continue
+		}
+		streams = append(streams, *stream)
+	}
+
+	logrus.Debugf("%d of %d checked allowed channels are live for %s", len(streams), checked, campaign.Name)
+	return streams
+}
+
+// streamHasCampaign checks that the channel grants the campaign's drops; a failed check doesn't rule the stream out
+func (m *Miner) streamHasCampaign(ctx context.Context, stream *twitch.Stream, campaign *twitch.Campaign) bool {
+	if stream.UserID == "" {
+		return true
+	}
+
+	campaignIDs, err := m.twitchClient.GetAvailableDropCampaigns(ctx, stream.UserID)
+	if err != nil {
+		logrus.Debugf("Couldn't check drops available on %s: %v", stream.UserLogin, err)
+		return true
+	}
+	for _, id := range campaignIDs {
+		if id == campaign.ID {
+			return true
+		}
+	}
+
+	logrus.Debugf("Skipping %s - no drops for %s on this channel", stream.UserLogin, campaign.Name)
+	return false
+}
@@ -0,0 +1,24 @@
+package drops
+
+import (
+	"twitchdropsfarmer/internal/twitch"
+)
+
+// updateChat joins the chat of the watched channel when chat presence is enabled,
+// and leaves chat when nothing is watched or the setting is off
+func (m *Miner) updateChat() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.config.ChatPresence || m.currentStream == nil {
+		if m.chat != nil {
+			m.chat.Leave()
+		}
+		return
+	}
+
+	if m.chat == nil {
+		m.chat = twitch.NewChatPresence(m.twitchClient)
+	}
+	m.chat.Join(m.currentStream.UserLogin)
+}
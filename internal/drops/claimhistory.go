@@ -0,0 +1,162 @@
+package drops
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding the claimed drops
+const claimHistoryDocument = "claim_history"
+
+// Oldest claims are dropped once the history grows past this
+const maxClaimHistory = 1000
+
+// How a drop got claimed
+const (
+	ClaimSourceWatch   = "watch"   // reached its minutes on the watched stream
+	ClaimSourcePubSub  = "pubsub"  // claimable event from Twitch PubSub
+	ClaimSourcePending = "pending" // found unclaimed in the inventory
+	ClaimSourceManual  = "manual"  // claimed from the dashboard
+)
+
+// ClaimRecord is a claimed drop kept for /api/claims/history
+type ClaimRecord struct {
+	Time         time.Time `json:"time"`
+	DropID       string    `json:"drop_id"`
+	DropName     string    `json:"drop_name"`
+	Rewards      []string  `json:"rewards,omitempty"` // benefit names
+	ImageURL     string    `json:"image_url,omitempty"`
+	CampaignID   string    `json:"campaign_id,omitempty"`
+	CampaignName string    `json:"campaign_name,omitempty"`
+	GameName     string    `json:"game_name,omitempty"`
+	Source       string    `json:"source"`
+}
+
+// claimHistory keeps the claimed drops in memory and in sync with the storage document
+type claimHistory struct {
+	mu      sync.RWMutex
+	store   storage.Store
+	records []ClaimRecord
+}
+
+// loadClaimHistory reads the persisted claim history from storage
+func (m *Miner) loadClaimHistory(store storage.Store) {
+	records := []ClaimRecord{}
+	if err := store.Load(claimHistoryDocument, &records); err != nil {
+		logrus.Errorf("Failed to load claim history: %v", err)
+	}
+
+	m.claims.mu.Lock()
+	defer m.claims.mu.Unlock()
+	m.claims.store = store
+	m.claims.records = append(records, m.claims.records...)
+	m.trimClaimsLocked()
+}
+
+// GetClaimHistory returns up to limit of the most recent claims, oldest first
+// A limit of zero or less returns the whole history
+func (m *Miner) GetClaimHistory(limit int) []ClaimRecord {
+	m.claims.mu.RLock()
+	defer m.claims.mu.RUnlock()
+
+	records := m.claims.records
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	return append([]ClaimRecord{}, records...)
+}
+
+// announceClaim adds a claimed drop to the history and publishes the claim, and the completion of its campaign
+// when done is set
+func (m *Miner) announceClaim(record ClaimRecord, done bool) {
+	record.Time = time.Now()
+
+	m.claims.mu.Lock()
+	m.claims.records = append(m.claims.records, record)
+	m.trimClaimsLocked()
+	if m.claims.store != nil {
+		if err := m.claims.store.Save(claimHistoryDocument, m.claims.records); err != nil {
+			logrus.Errorf("Failed to save claim history: %v", err)
+		}
+	}
+	m.claims.mu.Unlock()
+
+	message := record.DropName
+	if len(record.Rewards) > 0 && (len(record.Rewards) > 1 || record.Rewards[0] != record.DropName) {
+		message = fmt.Sprintf("%s: %s", record.DropName, strings.Join(record.Rewards, ", "))
+	}
+	campaign := &events.Campaign{ID: record.CampaignID, Name: record.CampaignName, GameName: record.GameName}
+	m.publish(events.Event{
+		Type:     events.DropClaimed,
+		Title:    "Drop claimed",
+		Message:  fmt.Sprintf("%s (%s)", message, record.GameName),
+		ImageURL: record.ImageURL,
+		Campaign: campaign,
+		Drop:     &events.Drop{ID: record.DropID, Name: record.DropName, Rewards: record.Rewards, Source: record.Source},
+	})
+
+	if done && record.CampaignID != "" && m.markCompleted(record.CampaignID) {
+		m.publish(events.Event{
+			Type:     events.CampaignCompleted,
+			Title:    "Campaign completed",
+			Message:  fmt.Sprintf("All drops of %s (%s) claimed", record.CampaignName, record.GameName),
+			Campaign: campaign,
+		})
+	}
+}
+
+func (m *Miner) trimClaimsLocked() {
+	if len(m.claims.records) > maxClaimHistory {
+		m.claims.records = append([]ClaimRecord(nil), m.claims.records[len(m.claims.records)-maxClaimHistory:]...)
+	}
+}
+
+// dropClaimRecord describes a claimed drop of a campaign
+func dropClaimRecord(campaign *twitch.Campaign, drop *twitch.TimeBased, source string) ClaimRecord {
+	record := ClaimRecord{
+		DropID:       drop.ID,
+		DropName:     drop.Name,
+		CampaignID:   campaign.ID,
+		CampaignName: campaign.Name,
+		GameName:     campaign.Game.Name,
+		Source:       source,
+	}
+	for _, edge := range drop.BenefitEdges {
+		if edge.Benefit.Name != "" {
+			record.Rewards = append(record.Rewards, edge.Benefit.Name)
+		}
+		if record.ImageURL == "" {
+			record.ImageURL = edge.Benefit.ImageAssetURL
+		}
+	}
+	return record
+}
+
+// inventoryClaimRecord is dropClaimRecord for a drop of an inventory campaign
+func inventoryClaimRecord(campaign *twitch.DropCampaignGQL, drop *twitch.TimeBasedDropGQL, source string) ClaimRecord {
+	record := ClaimRecord{
+		DropID:       drop.ID,
+		DropName:     drop.Name,
+		CampaignID:   campaign.ID,
+		CampaignName: campaign.Name,
+		GameName:     campaign.GameName(),
+		Source:       source,
+	}
+	for _, edge := range drop.BenefitEdges {
+		if edge.Benefit.Name != "" {
+			record.Rewards = append(record.Rewards, edge.Benefit.Name)
+		}
+		if record.ImageURL == "" {
+			record.ImageURL = edge.Benefit.ImageAssetURL
+		}
+	}
+	return record
+}
@@ -0,0 +1,115 @@
+package drops
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"twitchdropsfarmer/internal/telemetry"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PendingClaim is a completed drop found unclaimed in the inventory
+type PendingClaim struct {
+	DropID       string `json:"drop_id"`
+	DropName     string `json:"drop_name"`
+	InstanceID   string `json:"instance_id"`
+	CampaignID   string `json:"campaign_id"`
+	CampaignName string `json:"campaign_name"`
+	GameName     string `json:"game_name"`
+	Claimed      bool   `json:"claimed"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ClaimSummary is the result of claiming every pending drop
+type ClaimSummary struct {
+	Found     int            `json:"found"`
+	Claimed   int            `json:"claimed"`
+	Failed    int            `json:"failed"`
+	Drops     []PendingClaim `json:"drops"`
+	CheckedAt time.Time      `json:"checked_at"`
+}
+
+// ClaimPendingDrops claims every drop in the inventory that has an instance ID but isn't claimed,
+// including drops completed outside the campaign being mined
+func (m *Miner) ClaimPendingDrops(ctx context.Context) (_ *ClaimSummary, err error) {
+	ctx, span := tracer.Start(ctx, "miner.claim_pending")
+	defer func() { telemetry.End(span, err) }()
+	defer func() { m.trackFailure(operationClaimPending, err) }()
+
+	inventory, err := m.twitchClient.RefreshInventory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory: %w", err)
+	}
+
+	summary := &ClaimSummary{Drops: []PendingClaim{}, CheckedAt: time.Now()}
+	for _, campaign := range inventory.DropCampaignsInProgress {
+		if campaign.TimeBasedDrops == nil {
+			continue
+		}
+		gameName := campaign.GameName()
+
+		claimed := make(map[string]bool)
+		for i := range *campaign.TimeBasedDrops {
+			drop := &(*campaign.TimeBasedDrops)[i]
+			if drop.Self == nil || drop.Self.IsClaimed || drop.Self.DropInstanceID == nil {
+				continue
+			}
+			instanceID, ok := (*drop.Self.DropInstanceID).(string)
+			if !ok || instanceID == "" {
+				continue
+			}
+
+			claim := PendingClaim{
+				DropID:       drop.ID,
+				DropName:     drop.Name,
+				InstanceID:   instanceID,
+				CampaignID:   campaign.ID,
+				CampaignName: campaign.Name,
+				GameName:     gameName,
+			}
+			summary.Found++
+
+			if err := m.twitchClient.ClaimDrop(ctx, instanceID); err != nil {
+				logrus.Errorf("Failed to claim pending drop %s: %v", drop.Name, err)
+				m.logEvent(logrus.ErrorLevel, LogEventClaim, campaign.Name, "", "Failed to claim drop %s: %v", drop.Name, err)
+				claim.Error = err.Error()
+				summary.Failed++
+				summary.Drops = append(summary.Drops, claim)
+				continue
+			}
+
+			claim.Claimed = true
+			summary.Claimed++
+			summary.Drops = append(summary.Drops, claim)
+
+			logrus.Infof("Claimed pending drop: %s", drop.Name)
+			m.logEvent(logrus.InfoLevel, LogEventClaim, campaign.Name, "", "Claimed drop %s (%s)", drop.Name, gameName)
+			claimed[drop.ID] = true
+			m.announceClaim(inventoryClaimRecord(&campaign, drop, ClaimSourcePending), inventoryCampaignDone(&campaign, claimed))
+		}
+	}
+
+	return summary, nil
+}
+
+// reconcileClaims runs ClaimPendingDrops in the background loop while auto-claim is on
+func (m *Miner) reconcileClaims(ctx context.Context) {
+	m.mu.RLock()
+	claimDrops := m.config.ClaimDrops
+	m.mu.RUnlock()
+
+	if !claimDrops || !m.twitchClient.IsLoggedIn() {
+		return
+	}
+
+	summary, err := m.ClaimPendingDrops(ctx)
+	if err != nil {
+		logrus.Debugf("Pending drops check failed: %v", err)
+		return
+	}
+	if summary.Found > 0 {
+		logrus.Infof("Claimed %d of %d pending drops", summary.Claimed, summary.Found)
+	}
+}
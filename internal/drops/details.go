@@ -0,0 +1,141 @@
+package drops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding fetched campaign details
+const detailsDocument = "campaign_details"
+
+// cachedDetails is a CampaignDetails response without the drop progress, and the listing entry it was fetched for
+type cachedDetails struct {
+	Campaign    twitch.Campaign `json:"campaign"`
+	ListingHash string          `json:"listing_hash"` // refetched once the campaign changes in the listing
+	FetchedAt   time.Time       `json:"fetched_at"`
+}
+
+// campaignDetails caches campaign details by campaign ID in memory and in storage
+type campaignDetails struct {
+	mu      sync.Mutex
+	store   storage.Store
+	entries map[string]*cachedDetails
+}
+
+// loadDetails reads the cached campaign details from storage
+func (m *Miner) loadDetails(store storage.Store) {
+	entries := make(map[string]*cachedDetails)
+	if err := store.Load(detailsDocument, &entries); err != nil {
+		logrus.Errorf("Failed to load campaign details cache: %v", err)
+	}
+
+	m.details.mu.Lock()
+	defer m.details.mu.Unlock()
+	m.details.store = store
+	m.details.entries = entries
+}
+
+// hashListing identifies the listing entry of a campaign, so a changed campaign invalidates its details
+func hashListing(campaign twitch.Campaign) string {
+	data, err := json.Marshal(campaign)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// getCampaignDetails returns the details of a listed campaign, from the cache while they are
+// younger than DetailsCacheTTL and the campaign didn't change in the listing; cached details have
+// no drop progress, evaluateCampaigns reads it from the inventory
+func (m *Miner) getCampaignDetails(ctx context.Context, campaign twitch.Campaign) (*twitch.Campaign, error) {
+	m.mu.RLock()
+	ttl := m.config.DetailsCacheTTL
+	m.mu.RUnlock()
+
+	listingHash := hashListing(campaign)
+	d := &m.details
+	if ttl > 0 && listingHash != "" {
+		d.mu.Lock()
+		entry, ok := d.entries[campaign.ID]
+		d.mu.Unlock()
+		if ok && entry.ListingHash == listingHash && time.Since(entry.FetchedAt) < ttl {
+			// Entries written before progress was left out may still carry it
+			details := entry.Campaign
+			applyProgress(&details, nil)
+			return &details, nil
+		}
+	}
+
+	details, err := m.twitchClient.GetCampaignDetails(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 || listingHash == "" {
+		return details, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.entries == nil {
+		d.entries = make(map[string]*cachedDetails)
+	}
+	// Only the campaign and drop metadata is cached, progress and claim state go stale within minutes
+	static := *details
+	applyProgress(&static, nil)
+	d.entries[campaign.ID] = &cachedDetails{Campaign: static, ListingHash: listingHash, FetchedAt: time.Now()}
+	d.save()
+	return details, nil
+}
+
+// applyProgress copies the inventory's progress onto the drops of a campaign, drops missing from it haven't
+// been started
+func applyProgress(campaign *twitch.Campaign, progress map[string]twitch.TimeBasedSelf) {
+	campaign.TimeBasedDrops = slices.Clone(campaign.TimeBasedDrops)
+	for i := range campaign.TimeBasedDrops {
+		campaign.TimeBasedDrops[i].Self = progress[campaign.TimeBasedDrops[i].ID]
+	}
+}
+
+// pruneDetails drops cached details of campaigns that are no longer listed
+func (m *Miner) pruneDetails(campaigns []twitch.Campaign) {
+	listed := make(map[string]bool, len(campaigns))
+	for _, campaign := range campaigns {
+		listed[campaign.ID] = true
+	}
+
+	d := &m.details
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pruned := false
+	for id := range d.entries {
+		if !listed[id] {
+			delete(d.entries, id)
+			pruned = true
+		}
+	}
+	if pruned {
+		d.save()
+	}
+}
+
+// save writes the cache to storage, the caller holds d.mu
+func (d *campaignDetails) save() {
+	if d.store == nil {
+		return
+	}
+	if err := d.store.Save(detailsDocument, d.entries); err != nil {
+		logrus.Errorf("Failed to save campaign details cache: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package drops
+
+import (
+	"sync"
+
+	"twitchdropsfarmer/internal/errreport"
+)
+
+// Failures in a row of one operation after which it is sent to the error reporter
+const reportFailuresAfter = 3
+
+// Operations whose failures are reported
+const (
+	operationMiningCheck  = "mining_check"
+	operationWatch        = "watch_request"
+	operationClaim        = "claim_drop"
+	operationClaimPending = "claim_pending"
+)
+
+// failureTracker counts failures in a row per operation, so only the errors that keep happening get reported
+type failureTracker struct {
+	mu       sync.Mutex
+	reporter *errreport.Reporter
+	counts   map[string]int
+}
+
+// SetErrorReporter sets where repeated failures of the miner's operations are reported, nil to stop
+func (m *Miner) SetErrorReporter(reporter *errreport.Reporter) {
+	m.failures.mu.Lock()
+	defer m.failures.mu.Unlock()
+	m.failures.reporter = reporter
+}
+
+// trackFailure counts a failure of operation, or resets the count when err is nil; the failure reaching
+// reportFailuresAfter is reported with the campaign and channel being watched, later ones wait for a success
+func (m *Miner) trackFailure(operation string, err error) {
+	f := &m.failures
+	f.mu.Lock()
+	if err == nil {
+		delete(f.counts, operation)
+		f.mu.Unlock()
+		return
+	}
+	if f.counts == nil {
+		f.counts = make(map[string]int)
+	}
+	f.counts[operation]++
+	failures := f.counts[operation]
+	reporter := f.reporter
+	f.mu.Unlock()
+
+	if failures != reportFailuresAfter || reporter == nil {
+		return
+	}
+
+	report := errreport.Report{
+		Kind:      errreport.KindMinerError,
+		Message:   err.Error(),
+		Operation: operation,
+		Failures:  failures,
+	}
+	if user := m.twitchClient.GetUser(); user != nil {
+		report.Account = user.Login
+	}
+	m.mu.RLock()
+	if m.currentCampaign != nil {
+		report.Campaign = m.currentCampaign.Name
+	}
+	if m.currentStream != nil {
+		report.Channel = m.currentStream.UserLogin
+	}
+	m.mu.RUnlock()
+	reporter.Capture(report)
+}
@@ -0,0 +1,131 @@
+package drops
+
+import (
+	"sort"
+	"time"
+
+	"twitchdropsfarmer/internal/twitch"
+)
+
+// QueueEstimate is when every farmable drop across the priority campaigns will be done at the current pace
+type QueueEstimate struct {
+	RemainingMinutes    int       `json:"remaining_minutes"`
+	CompletesAt         time.Time `json:"completes_at"`
+	Impossible          bool      `json:"impossible"`           // some campaign ends before its turn finishes
+	ImpossibleCampaigns []string  `json:"impossible_campaigns"` // names of the campaigns that can't be finished
+}
+
+// DropForecast is when a pending drop should complete, given the campaigns queued before it and the schedule
+type DropForecast struct {
+	DropID           string     `json:"drop_id"`
+	DropName         string     `json:"drop_name"`
+	CampaignID       string     `json:"campaign_id"`
+	CampaignName     string     `json:"campaign_name"`
+	GameName         string     `json:"game_name"`
+	QueuePosition    int        `json:"queue_position"` // 1 for the campaign mined first
+	RemainingMinutes int        `json:"remaining_minutes"`
+	CompletesAt      *time.Time `json:"completes_at"` // nil when the schedule never allows finishing it
+	CampaignEndsAt   time.Time  `json:"campaign_ends_at"`
+	AtRisk           bool       `json:"at_risk"` // can't be finished before the campaign ends
+}
+
+// estimateQueue walks the campaigns in scoring order, one stream at a time, and forecasts each pending drop
+// Drops of a campaign progress together, so each campaign takes as long as its furthest drop, or until it ends.
+// Watching only happens inside the schedule windows, so time outside them is skipped.
+func (m *Miner) estimateQueue(campaigns []twitch.Campaign, now time.Time) (*QueueEstimate, []DropForecast) {
+	type queued struct {
+		campaign *twitch.Campaign
+		score    int
+	}
+
+	var queue []queued
+	for i := range campaigns {
+		campaign := &campaigns[i]
+		if m.isCampaignIgnored(campaign.ID) {
+			continue
+		}
+		if score := m.calculateCampaignScore(campaign); score > 0 {
+			queue = append(queue, queued{campaign: campaign, score: score})
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].score > queue[j].score
+	})
+
+	m.mu.RLock()
+	schedule := m.config.Schedule
+	m.mu.RUnlock()
+
+	estimate := &QueueEstimate{ImpossibleCampaigns: []string{}}
+	forecasts := []DropForecast{}
+	turnStarts := now
+	position := 0
+	for _, item := range queue {
+		campaign := item.campaign
+		remaining := campaignRemainingMinutes(campaign)
+		if remaining == 0 {
+			continue
+		}
+		position++
+		estimate.RemainingMinutes += remaining
+
+		impossible := false
+		for _, drop := range campaign.TimeBasedDrops {
+			left := drop.RequiredMinutesWatched - drop.Self.CurrentMinutesWatched
+			if drop.Self.IsClaimed || drop.RequiredMinutesWatched <= 0 || left <= 0 {
+				continue
+			}
+
+			forecast := DropForecast{
+				DropID:           drop.ID,
+				DropName:         drop.Name,
+				CampaignID:       campaign.ID,
+				CampaignName:     campaign.Name,
+				GameName:         campaign.Game.Name,
+				QueuePosition:    position,
+				RemainingMinutes: left,
+				CampaignEndsAt:   campaign.EndsAt,
+			}
+			completesAt := schedule.Elapse(turnStarts, time.Duration(left)*time.Minute)
+			if completesAt.IsZero() {
+				forecast.AtRisk = true
+			} else {
+				forecast.CompletesAt = &completesAt
+				forecast.AtRisk = !campaign.EndsAt.IsZero() && completesAt.After(campaign.EndsAt)
+			}
+			impossible = impossible || forecast.AtRisk
+			forecasts = append(forecasts, forecast)
+		}
+
+		if impossible {
+			estimate.Impossible = true
+			estimate.ImpossibleCampaigns = append(estimate.ImpossibleCampaigns, campaign.Name)
+		}
+
+		// The next campaign's turn starts once this one is done, or has ended
+		turnEnds := schedule.Elapse(turnStarts, time.Duration(remaining)*time.Minute)
+		if turnEnds.IsZero() || (!campaign.EndsAt.IsZero() && turnEnds.After(campaign.EndsAt)) {
+			turnEnds = campaign.EndsAt
+		}
+		if turnEnds.After(turnStarts) {
+			turnStarts = turnEnds
+		}
+	}
+	estimate.CompletesAt = turnStarts
+
+	return estimate, forecasts
+}
+
+// campaignRemainingMinutes is how long the campaign's furthest unclaimed drop still needs
+func campaignRemainingMinutes(campaign *twitch.Campaign) int {
+	remaining := 0
+	for _, drop := range campaign.TimeBasedDrops {
+		if drop.Self.IsClaimed || drop.RequiredMinutesWatched <= 0 {
+			continue
+		}
+		if left := drop.RequiredMinutesWatched - drop.Self.CurrentMinutesWatched; left > remaining {
+			remaining = left
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,118 @@
+package drops
+
+import (
+	"fmt"
+	"sync"
+
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/twitch"
+)
+
+// eventBus is where the miner publishes its events, with the subscription of its stats
+type eventBus struct {
+	mu          sync.RWMutex
+	bus         *events.Bus
+	accountID   string
+	unsubscribe func()
+}
+
+// SetEvents sets the bus the miner publishes on as the account with accountID, "" for the primary account. The
+// miner's stats subscribe to it and count the claims of that account. nil stops publishing, e.g. once the
+// account is removed.
+func (m *Miner) SetEvents(bus *events.Bus, accountID string) {
+	e := &m.bus
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.unsubscribe != nil {
+		e.unsubscribe()
+		e.unsubscribe = nil
+	}
+	e.bus, e.accountID = bus, accountID
+	if bus != nil {
+		e.unsubscribe = bus.Subscribe(events.SinkFunc(m.handleStatsEvent))
+	}
+}
+
+// publish sends an event about the miner's account
+func (m *Miner) publish(event events.Event) {
+	m.bus.mu.RLock()
+	bus, accountID := m.bus.bus, m.bus.accountID
+	m.bus.mu.RUnlock()
+	if bus == nil {
+		return
+	}
+
+	event.AccountID = accountID
+	if user := m.twitchClient.GetUser(); user != nil {
+		event.Login = user.Login
+	}
+	bus.Publish(event)
+}
+
+// handleStatsEvent counts the claims and completed campaigns of the miner's account
+func (m *Miner) handleStatsEvent(event events.Event) {
+	m.bus.mu.RLock()
+	own := event.AccountID == m.bus.accountID
+	m.bus.mu.RUnlock()
+	if !own {
+		return
+	}
+
+	gameName := ""
+	if event.Campaign != nil {
+		gameName = event.Campaign.GameName
+	}
+	switch event.Type {
+	case events.DropClaimed:
+		m.counters.dropsClaimed.Add(1)
+		m.recordClaim(gameName)
+	case events.CampaignCompleted:
+		m.recordCompletion(gameName)
+	}
+}
+
+// watchedLocked returns the ID of the campaign and the login of the channel currently watched; m.mu must be held
+func (m *Miner) watchedLocked() (campaignID, channel string) {
+	if m.currentCampaign != nil {
+		campaignID = m.currentCampaign.ID
+	}
+	if m.currentStream != nil {
+		channel = m.currentStream.UserLogin
+	}
+	return campaignID, channel
+}
+
+// announceSwitch publishes that the miner started on a campaign, nil when farming channel points, and that it
+// switched streams, each only when it changed
+func (m *Miner) announceSwitch(previousCampaign, previousChannel string, campaign *twitch.Campaign, stream *twitch.Stream) {
+	if campaign != nil && campaign.ID != previousCampaign {
+		m.publish(events.Event{
+			Type:     events.CampaignStarted,
+			Title:    "Campaign started",
+			Message:  fmt.Sprintf("Farming %s (%s)", campaign.Name, campaign.Game.Name),
+			ImageURL: campaign.ImageURL,
+			Campaign: eventCampaign(campaign),
+			Channel:  stream.UserLogin,
+		})
+	}
+	if stream.UserLogin != previousChannel {
+		event := events.Event{
+			Type:    events.StreamSwitched,
+			Title:   "Stream switched",
+			Message: "Now watching " + stream.UserName,
+			Channel: stream.UserLogin,
+		}
+		if campaign != nil {
+			event.Message = fmt.Sprintf("Now watching %s for %s", stream.UserName, campaign.Name)
+			event.Campaign = eventCampaign(campaign)
+		}
+		m.publish(event)
+	}
+}
+
+// eventCampaign describes a campaign in events
+func eventCampaign(campaign *twitch.Campaign) *events.Campaign {
+	endsAt := campaign.EndsAt
+	return &events.Campaign{ID: campaign.ID, Name: campaign.Name, GameName: campaign.Game.Name, EndsAt: &endsAt}
+}
@@ -0,0 +1,79 @@
+package drops
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How long a channel that went offline is skipped when picking a stream, the directory lags behind
+const offlineChannelTTL = 10 * time.Minute
+
+// offlineChannels remembers when watched channels were found offline, by lowercase login
+type offlineChannels struct {
+	mu       sync.Mutex
+	channels map[string]time.Time
+}
+
+// markOffline records that a channel's stream ended
+func (m *Miner) markOffline(channelLogin string) {
+	o := &m.offline
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.channels == nil {
+		o.channels = make(map[string]time.Time)
+	}
+	o.channels[strings.ToLower(channelLogin)] = time.Now()
+}
+
+// isOffline reports whether a channel was found offline within offlineChannelTTL
+func (m *Miner) isOffline(channelLogin string) bool {
+	o := &m.offline
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	key := strings.ToLower(channelLogin)
+	at, ok := o.channels[key]
+	if !ok {
+		return false
+	}
+	if time.Since(at) >= offlineChannelTTL {
+		delete(o.channels, key)
+		return false
+	}
+	return true
+}
+
+// failover moves off a watched stream that went offline, to the next best stream of the same campaign
+func (m *Miner) failover(ctx context.Context) {
+	m.mu.RLock()
+	campaign := m.currentCampaign
+	stream := m.currentStream
+	m.mu.RUnlock()
+
+	if stream == nil {
+		return
+	}
+	m.markOffline(stream.UserLogin)
+
+	if campaign == nil {
+		// Points fallback, the next check rotates to another points channel
+		logrus.Infof("%s went offline", stream.UserLogin)
+		m.clearWatching()
+		return
+	}
+
+	logrus.Infof("%s went offline, switching to another stream for %s", stream.UserLogin, campaign.Name)
+	m.logEvent(logrus.InfoLevel, LogEventSwitch, campaign.Name, stream.UserLogin, "%s went offline, switching streams", stream.UserLogin)
+
+	if err := m.switchToCampaign(ctx, campaign); err != nil {
+		logrus.Warnf("No other stream for %s: %v", campaign.Name, err)
+		// Let the next check pick another campaign
+		m.clearWatching()
+		m.invalidateCampaignsCache()
+	}
+}
@@ -0,0 +1,49 @@
+package drops
+
+import (
+	"context"
+	"strings"
+
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// campaignRequiresFollow reports whether a campaign only progresses for followers.
+// Twitch doesn't expose this as a field, so it is detected from the campaign description.
+func campaignRequiresFollow(campaign *twitch.Campaign) bool {
+	description := strings.ToLower(campaign.Description)
+	return strings.Contains(description, "follow")
+}
+
+// updateFollow follows the newly watched channel when the campaign requires it,
+// and unfollows the previously auto-followed channel when AutoUnfollow is set
+func (m *Miner) updateFollow(ctx context.Context, campaign *twitch.Campaign, stream *twitch.Stream) {
+	m.mu.RLock()
+	autoFollow := m.config.AutoFollow
+	autoUnfollow := m.config.AutoUnfollow
+	followedChannelID := m.followedChannelID
+	m.mu.RUnlock()
+
+	if followedChannelID != "" && followedChannelID != stream.UserID && autoUnfollow {
+		if err := m.twitchClient.UnfollowChannel(ctx, followedChannelID); err != nil {
+			logrus.Errorf("Failed to unfollow channel %s: %v", followedChannelID, err)
+		} else {
+			logrus.Infof("Unfollowed previously auto-followed channel %s", followedChannelID)
+			followedChannelID = ""
+		}
+	}
+
+	if autoFollow && stream.UserID != "" && stream.UserID != followedChannelID && campaignRequiresFollow(campaign) {
+		if err := m.twitchClient.FollowChannel(ctx, stream.UserID); err != nil {
+			logrus.Errorf("Failed to follow %s: %v", stream.UserLogin, err)
+		} else {
+			logrus.Infof("Followed %s for follow-gated campaign %s", stream.UserLogin, campaign.Name)
+			followedChannelID = stream.UserID
+		}
+	}
+
+	m.mu.Lock()
+	m.followedChannelID = followedChannelID
+	m.mu.Unlock()
+}
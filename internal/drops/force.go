@@ -0,0 +1,201 @@
+package drops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Score of the forced campaign, above every pin
+const forcedScore = 2 * pinnedScore
+
+// Errors returned by ForceCampaign for campaigns that can't be mined
+var (
+	ErrCampaignNotActive = errors.New("campaign is not active")
+	ErrCampaignNotLinked = errors.New("account is not linked for this campaign")
+	ErrChannelNotAllowed = errors.New("channel doesn't grant this campaign's drops")
+	ErrNotForced         = errors.New("no campaign is forced")
+)
+
+// ForcedCampaign is mined ahead of every other campaign, pinned ones included, until all its drops are done
+type ForcedCampaign struct {
+	CampaignID   string    `json:"campaign_id"`
+	CampaignName string    `json:"campaign_name"`
+	ChannelLogin string    `json:"channel_login,omitempty"` // watched whenever it is live, empty for any stream of the campaign
+	ForcedAt     time.Time `json:"forced_at"`
+}
+
+// ForceCampaign switches to a campaign right away, on channelLogin when given, and keeps mining it whatever the
+// scores say until it finishes or ends. It replaces any campaign forced before.
+func (m *Miner) ForceCampaign(ctx context.Context, campaignID, channelLogin string) (*ForcedCampaign, error) {
+	campaigns, err := m.twitchClient.GetDropCampaigns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaigns: %w", err)
+	}
+
+	var campaign *twitch.Campaign
+	for i := range campaigns {
+		if campaigns[i].ID == campaignID {
+			campaign = &campaigns[i]
+			break
+		}
+	}
+	if campaign == nil || campaign.Status != "ACTIVE" {
+		return nil, ErrCampaignNotActive
+	}
+	if !campaign.Self.IsAccountConnected {
+		return nil, ErrCampaignNotLinked
+	}
+
+	channelLogin = strings.ToLower(strings.TrimSpace(channelLogin))
+	if channelLogin != "" && len(campaign.Allow) > 0 {
+		allowed := false
+		for _, login := range campaign.Allow {
+			if strings.EqualFold(login, channelLogin) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, ErrChannelNotAllowed
+		}
+	}
+
+	forced := &ForcedCampaign{
+		CampaignID:   campaign.ID,
+		CampaignName: campaign.Name,
+		ChannelLogin: channelLogin,
+		ForcedAt:     time.Now(),
+	}
+	err = m.updateOverrides(func(data *CampaignOverrides) {
+		data.Forced = forced
+		// Forcing a campaign overrules ignoring it
+		delete(data.Ignored, campaign.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A channel forced before may have been skipped as offline, give it a fresh look
+	if channelLogin != "" {
+		m.offline.mu.Lock()
+		delete(m.offline.channels, channelLogin)
+		m.offline.mu.Unlock()
+	}
+
+	logrus.Infof("Forced switch to %s", campaign.Name)
+	m.logEvent(logrus.InfoLevel, LogEventSwitch, campaign.Name, channelLogin, "Forced switch to %s", campaign.Name)
+	return forced, nil
+}
+
+// ClearForcedCampaign hands campaign selection back to the scores
+func (m *Miner) ClearForcedCampaign() error {
+	if m.forcedCampaign() == nil {
+		return ErrNotForced
+	}
+	return m.updateOverrides(func(data *CampaignOverrides) {
+		data.Forced = nil
+	})
+}
+
+// forcedCampaign returns the forced campaign, nil when selection follows the scores
+func (m *Miner) forcedCampaign() *ForcedCampaign {
+	m.overrides.mu.RLock()
+	defer m.overrides.mu.RUnlock()
+
+	return m.overrides.data.Forced
+}
+
+// isForced reports whether campaignID is the forced campaign
+func (m *Miner) isForced(campaignID string) bool {
+	forced := m.forcedCampaign()
+	return forced != nil && forced.CampaignID == campaignID
+}
+
+// isOverridden reports whether the campaign is pinned or forced, which mines it even outside the priority games
+func (m *Miner) isOverridden(campaignID string) bool {
+	_, pinned := m.pinPriority(campaignID)
+	return pinned || m.isForced(campaignID)
+}
+
+// expireForcedCampaign drops the forced campaign once it ended or, going by its details, has no minutes left
+func (m *Miner) expireForcedCampaign(campaigns []twitch.Campaign, details []twitch.Campaign) {
+	forced := m.forcedCampaign()
+	if forced == nil {
+		return
+	}
+
+	reason := "it ended"
+	for i := range campaigns {
+		if campaigns[i].ID == forced.CampaignID && campaigns[i].Status == "ACTIVE" {
+			reason = ""
+			break
+		}
+	}
+	for i := range details {
+		if details[i].ID == forced.CampaignID && campaignRemainingMinutes(&details[i]) == 0 {
+			reason = "all its drops are done"
+		}
+	}
+	if reason == "" {
+		return
+	}
+
+	m.overrides.mu.Lock()
+	if m.overrides.data.Forced != nil && m.overrides.data.Forced.CampaignID == forced.CampaignID {
+		m.overrides.data.Forced = nil
+		if m.overrides.store != nil {
+			if err := m.overrides.store.Save(overridesDocument, m.overrides.data); err != nil {
+				logrus.Errorf("Failed to save campaign overrides: %v", err)
+			}
+		}
+	}
+	m.overrides.mu.Unlock()
+
+	logrus.Infof("No longer forcing %s, %s", forced.CampaignName, reason)
+	m.logEvent(logrus.InfoLevel, LogEventSwitch, forced.CampaignName, "", "No longer forcing %s, %s", forced.CampaignName, reason)
+}
+
+// forcedStream starts watching the forced channel when the campaign is the forced one and the channel is live
+// with its game
+func (m *Miner) forcedStream(ctx context.Context, campaign *twitch.Campaign) (*twitch.Stream, *twitch.WatchingSession) {
+	forced := m.forcedCampaign()
+	if forced == nil || forced.CampaignID != campaign.ID || forced.ChannelLogin == "" || m.isOffline(forced.ChannelLogin) {
+		return nil, nil
+	}
+
+	stream, err := m.twitchClient.GetLiveStream(ctx, forced.ChannelLogin)
+	if err == nil && stream.GameID != "" && campaign.Game.ID != "" && stream.GameID != campaign.Game.ID {
+		err = fmt.Errorf("streaming %s", stream.GameName)
+	}
+	var watchingSession *twitch.WatchingSession
+	if err == nil {
+		watchingSession, err = m.twitchClient.StartWatching(ctx, forced.ChannelLogin)
+	}
+	if err != nil {
+		// Looked at again once the offline TTL runs out, another stream of the campaign is watched until then
+		logrus.Infof("Forced channel %s not available, watching another stream for %s: %v", forced.ChannelLogin, campaign.Name, err)
+		m.markOffline(forced.ChannelLogin)
+		return nil, nil
+	}
+	return stream, watchingSession
+}
+
+// wantsForcedChannel reports whether the current campaign is forced onto a channel that isn't being watched but
+// might be live; the caller holds mu
+func (m *Miner) wantsForcedChannel() bool {
+	forced := m.forcedCampaign()
+	if forced == nil || forced.ChannelLogin == "" || m.currentCampaign == nil || m.currentCampaign.ID != forced.CampaignID {
+		return false
+	}
+	if m.currentStream != nil && strings.EqualFold(m.currentStream.UserLogin, forced.ChannelLogin) {
+		return false
+	}
+	return !m.isOffline(forced.ChannelLogin)
+}
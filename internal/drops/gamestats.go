@@ -0,0 +1,80 @@
+package drops
+
+import (
+	"sort"
+	"time"
+)
+
+// Name of the storage document holding the activity per game since the first run, which unlike the daily stats
+// is never pruned
+const gameStatsDocument = "game_stats"
+
+// GameTotals is the activity for one game since it was first farmed
+type GameTotals struct {
+	GameStats
+	LastFarmedAt time.Time `json:"last_farmed_at"`
+}
+
+// GameSummary is a game of /api/stats/games
+type GameSummary struct {
+	GameName string `json:"game_name"`
+	GameTotals
+	MinutesPerDrop float64 `json:"minutes_per_drop"` // 0 until a drop is claimed
+}
+
+// gameTotals returns the totals of a game, marking it farmed at now; the caller holds mu
+func (h *statsHistory) gameTotals(name string, now time.Time) *GameTotals {
+	if h.games == nil {
+		h.games = make(map[string]*GameTotals)
+	}
+	if h.games[name] == nil {
+		h.games[name] = &GameTotals{}
+	}
+	h.games[name].LastFarmedAt = now
+	return h.games[name]
+}
+
+// gameTotalsFromDays adds up the daily stats by game, for installs that kept stats before the totals existed;
+// the last farmed time is the start of the last day with activity
+func gameTotalsFromDays(days map[string]*DayStats) map[string]*GameTotals {
+	games := make(map[string]*GameTotals)
+	for date, day := range days {
+		farmedAt, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			continue
+		}
+		for name, stats := range day.Games {
+			if games[name] == nil {
+				games[name] = &GameTotals{}
+			}
+			games[name].add(*stats)
+			if farmedAt.After(games[name].LastFarmedAt) {
+				games[name].LastFarmedAt = farmedAt
+			}
+		}
+	}
+	return games
+}
+
+// GetGameStats returns the activity of every game ever farmed, most recently farmed first
+func (m *Miner) GetGameStats() []GameSummary {
+	h := &m.stats
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	games := make([]GameSummary, 0, len(h.games))
+	for name, totals := range h.games {
+		summary := GameSummary{GameName: name, GameTotals: *totals}
+		if totals.DropsClaimed > 0 {
+			summary.MinutesPerDrop = totals.MinutesWatched / float64(totals.DropsClaimed)
+		}
+		games = append(games, summary)
+	}
+	sort.Slice(games, func(i, j int) bool {
+		if !games[i].LastFarmedAt.Equal(games[j].LastFarmedAt) {
+			return games[i].LastFarmedAt.After(games[j].LastFarmedAt)
+		}
+		return games[i].GameName < games[j].GameName
+	})
+	return games
+}
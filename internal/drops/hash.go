@@ -0,0 +1,161 @@
+package drops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// campaignsCache remembers the selection made for the last campaign listing
+type campaignsCache struct {
+	mu    sync.Mutex
+	valid bool
+	hash  [sha256.Size]byte
+	best  *twitch.Campaign
+	// evaluated holds the details the selection was made from, for redoing the queue estimate
+	evaluated []twitch.Campaign
+}
+
+// hashCampaigns hashes the campaign listing independent of the order Twitch returned it in
+func hashCampaigns(campaigns []twitch.Campaign) [sha256.Size]byte {
+	sorted := make([]twitch.Campaign, len(campaigns))
+	copy(sorted, campaigns)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	data, err := json.Marshal(sorted)
+	if err != nil {
+		// Can't happen for plain structs, but never treat a failure as unchanged
+		logrus.Debugf("Failed to hash campaigns: %v", err)
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(data)
+}
+
+// cachedBestCampaign returns the previous selection if the listing hash matches
+func (m *Miner) cachedBestCampaign(hash [sha256.Size]byte) (*twitch.Campaign, bool) {
+	m.campaignsCache.mu.Lock()
+	defer m.campaignsCache.mu.Unlock()
+
+	if !m.campaignsCache.valid || m.campaignsCache.hash != hash || hash == [sha256.Size]byte{} {
+		return nil, false
+	}
+	return m.campaignsCache.best, true
+}
+
+// cacheBestCampaign stores the selection made for a listing hash
+func (m *Miner) cacheBestCampaign(hash [sha256.Size]byte, best *twitch.Campaign, evaluated []twitch.Campaign) {
+	m.campaignsCache.mu.Lock()
+	defer m.campaignsCache.mu.Unlock()
+
+	m.campaignsCache.valid = true
+	m.campaignsCache.hash = hash
+	m.campaignsCache.best = best
+	m.campaignsCache.evaluated = evaluated
+}
+
+// invalidateCampaignsCache forces the next check to re-score, e.g. after settings or overrides change
+func (m *Miner) invalidateCampaignsCache() {
+	m.campaignsCache.mu.Lock()
+	defer m.campaignsCache.mu.Unlock()
+
+	m.campaignsCache.valid = false
+	m.campaignsCache.best = nil
+	m.campaignsCache.evaluated = nil
+}
+
+// refreshCampaignProgress reads the mined campaign's progress from the inventory again when the listing didn't
+// change, since neither the listing nor the campaign details carry it; claims, the active drops and the queue
+// estimate then see the minutes watched since the selection was made, without re-scoring
+func (m *Miner) refreshCampaignProgress(ctx context.Context) {
+	m.mu.RLock()
+	current := m.currentCampaign
+	watching := m.currentStream != nil
+	m.mu.RUnlock()
+
+	if current == nil {
+		return
+	}
+
+	inventory, err := m.twitchClient.RefreshInventory(ctx)
+	if err != nil {
+		logrus.Debugf("Failed to refresh progress for %s: %v", current.Name, err)
+		return
+	}
+	fresh := *current
+	applyProgress(&fresh, inventory.DropProgress())
+
+	m.mu.Lock()
+	if m.currentCampaign == nil || m.currentCampaign.ID != fresh.ID {
+		// Switched away while fetching
+		m.mu.Unlock()
+		return
+	}
+	m.currentCampaign = &fresh
+	m.mu.Unlock()
+
+	m.campaignsCache.mu.Lock()
+	if m.campaignsCache.best != nil && m.campaignsCache.best.ID == fresh.ID {
+		m.campaignsCache.best = &fresh
+	}
+	evaluated := make([]twitch.Campaign, len(m.campaignsCache.evaluated))
+	copy(evaluated, m.campaignsCache.evaluated)
+	for i := range evaluated {
+		if evaluated[i].ID == fresh.ID {
+			evaluated[i] = fresh
+		}
+	}
+	m.campaignsCache.evaluated = evaluated
+	m.campaignsCache.mu.Unlock()
+
+	progress := make(map[string]twitch.TimeBasedSelf, len(fresh.TimeBasedDrops))
+	for _, drop := range fresh.TimeBasedDrops {
+		progress[drop.ID] = drop.Self
+	}
+
+	queueEstimate, forecast := m.estimateQueue(evaluated, time.Now())
+	now := time.Now()
+	m.updateStatus(func(s *MinerStatus) {
+		s.CurrentCampaign = &fresh
+		s.QueueEstimate = queueEstimate
+		s.Forecast = forecast
+		for i := range s.ActiveDrops {
+			drop := &s.ActiveDrops[i]
+			self, ok := progress[drop.ID]
+			if !ok {
+				continue
+			}
+			drop.CurrentMinutes = self.CurrentMinutesWatched
+			drop.IsClaimed = self.IsClaimed
+			if drop.RequiredMinutes > 0 {
+				drop.Progress = min(float64(drop.CurrentMinutes)/float64(drop.RequiredMinutes), 1.0)
+			}
+			drop.SetClaimETA(now, watching)
+		}
+	})
+}
+
+// refreshStatusProgress updates the parts of the status that move even when campaigns don't
+func (m *Miner) refreshStatusProgress() {
+	m.mu.RLock()
+	currentSession := m.currentSession
+	m.mu.RUnlock()
+
+	var currentProgress int
+	if currentSession != nil {
+		currentProgress = int(time.Since(currentSession.StartedAt).Minutes())
+	}
+
+	m.updateStatus(func(s *MinerStatus) {
+		s.CurrentProgress = currentProgress
+		s.LastUpdate = time.Now()
+	})
+}
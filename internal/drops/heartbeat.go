@@ -0,0 +1,176 @@
+package drops
+
+import (
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding per-stream heartbeats
+const streamsDocument = "streams"
+
+// StreamRecord is the persisted watch state of a stream
+type StreamRecord struct {
+	ChannelID     string    `json:"channel_id"`
+	ChannelLogin  string    `json:"channel_login"`
+	ChannelName   string    `json:"channel_name"`
+	GameName      string    `json:"game_name"`
+	CampaignID    string    `json:"campaign_id,omitempty"`
+	CampaignName  string    `json:"campaign_name,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	IsWatching    bool      `json:"is_watching"`
+}
+
+// streamHeartbeats keeps stream records in sync with the storage document
+type streamHeartbeats struct {
+	mu      sync.Mutex
+	store   storage.Store
+	records map[string]*StreamRecord // by channel login
+}
+
+// SetStore enables persisting stream heartbeats, campaign overrides, the miner log, bandwidth usage, stats, session history, known campaigns and the miner state to the given storage
+func (m *Miner) SetStore(store storage.Store) {
+	m.loadOverrides(store)
+	m.loadLogs(store)
+	m.loadBandwidth(store)
+	m.loadStats(store)
+	m.loadClaimHistory(store)
+	m.loadSessionHistory(store)
+	m.loadPoints(store)
+	m.loadDetails(store)
+	m.loadState(store)
+	m.loadKnownCampaigns(store)
+
+	records := make(map[string]*StreamRecord)
+	if err := store.Load(streamsDocument, &records); err != nil {
+		logrus.Errorf("Failed to load stream heartbeats: %v", err)
+	}
+
+	// Nothing is being watched until the first heartbeat of this run
+	for _, record := range records {
+		record.IsWatching = false
+	}
+
+	m.heartbeats.mu.Lock()
+	defer m.heartbeats.mu.Unlock()
+	m.heartbeats.store = store
+	m.heartbeats.records = records
+}
+
+// ReloadStore reads everything SetStore loads again after the documents were replaced behind the miner's back,
+// e.g. by restoring a backup, dropping what was kept in memory instead of merging it. The miner must be stopped.
+func (m *Miner) ReloadStore(store storage.Store) {
+	m.logs.mu.Lock()
+	m.logs.entries = nil
+	m.logs.mu.Unlock()
+
+	m.bandwidth.mu.Lock()
+	m.bandwidth.days = nil
+	m.bandwidth.mu.Unlock()
+
+	m.stats.mu.Lock()
+	m.stats.days = nil
+	m.stats.games = nil
+	m.stats.mu.Unlock()
+
+	m.claims.mu.Lock()
+	m.claims.records = nil
+	m.claims.mu.Unlock()
+
+	m.sessions.mu.Lock()
+	m.sessions.sessions = nil
+	m.sessions.mu.Unlock()
+
+	m.SetStore(store)
+}
+
+// recordHeartbeat marks the currently watched stream as alive and every other stream as not watched
+func (m *Miner) recordHeartbeat() {
+	m.mu.RLock()
+	stream := m.currentStream
+	campaign := m.currentCampaign
+	session := m.currentSession
+	m.mu.RUnlock()
+
+	if stream == nil {
+		return
+	}
+
+	h := &m.heartbeats
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.store == nil {
+		return
+	}
+
+	for _, record := range h.records {
+		record.IsWatching = false
+	}
+
+	record, ok := h.records[stream.UserLogin]
+	if !ok {
+		record = &StreamRecord{ChannelLogin: stream.UserLogin}
+		h.records[stream.UserLogin] = record
+	}
+
+	record.ChannelID = stream.UserID
+	record.ChannelName = stream.UserName
+	record.GameName = stream.GameName
+	record.CampaignID = ""
+	record.CampaignName = ""
+	if campaign != nil {
+		record.CampaignID = campaign.ID
+		record.CampaignName = campaign.Name
+	}
+	if session != nil {
+		record.StartedAt = session.StartedAt
+	}
+	record.LastHeartbeat = time.Now()
+	record.IsWatching = true
+
+	if err := h.store.Save(streamsDocument, h.records); err != nil {
+		logrus.Errorf("Failed to save stream heartbeat: %v", err)
+	}
+}
+
+// clearHeartbeats marks every stream as not watched, e.g. when the miner stops
+func (m *Miner) clearHeartbeats() {
+	h := &m.heartbeats
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.store == nil {
+		return
+	}
+
+	for _, record := range h.records {
+		record.IsWatching = false
+	}
+
+	if err := h.store.Save(streamsDocument, h.records); err != nil {
+		logrus.Errorf("Failed to save stream heartbeats: %v", err)
+	}
+}
+
+// LoadCurrentStream reads the stream being watched straight from storage,
+// independent of any in-memory miner state
+// Returns nil when nothing is marked as watching
+func LoadCurrentStream(store storage.Store) (*StreamRecord, error) {
+	records := make(map[string]*StreamRecord)
+	if err := store.Load(streamsDocument, &records); err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.IsWatching {
+			return record, nil
+		}
+	}
+
+	return nil, nil
+}
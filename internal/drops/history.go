@@ -0,0 +1,321 @@
+package drops
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding the daily watch time and claims
+const statsDocument = "stats"
+
+// Days of history kept in the stats document, and the longest range that can be asked for
+const StatsHistoryDays = 365
+
+// How often the watch time is written to storage, claims are written right away
+const statsSaveInterval = 5 * time.Minute
+
+// Longest gap between two watch requests that still counts as watching
+const maxWatchGap = 2 * reducedWatchInterval
+
+// GameStats is the activity for one game
+type GameStats struct {
+	MinutesWatched     float64 `json:"minutes_watched"`
+	DropsClaimed       int     `json:"drops_claimed"`
+	CampaignsCompleted int     `json:"campaigns_completed"`
+}
+
+func (g *GameStats) add(other GameStats) {
+	g.MinutesWatched += other.MinutesWatched
+	g.DropsClaimed += other.DropsClaimed
+	g.CampaignsCompleted += other.CampaignsCompleted
+}
+
+// DayStats is the activity of one local day, in total and by game name
+type DayStats struct {
+	GameStats
+	Games map[string]*GameStats `json:"games,omitempty"`
+}
+
+// game returns the stats of a game for the day, creating them on first use
+func (d *DayStats) game(name string) *GameStats {
+	if d.Games == nil {
+		d.Games = make(map[string]*GameStats)
+	}
+	if d.Games[name] == nil {
+		d.Games[name] = &GameStats{}
+	}
+	return d.Games[name]
+}
+
+// StatsDay is a day of StatsRange
+type StatsDay struct {
+	Date string `json:"date"` // YYYY-MM-DD, local time
+	GameStats
+}
+
+// StatsGame is the activity for a game over a StatsRange
+type StatsGame struct {
+	GameName string `json:"game_name"`
+	GameStats
+}
+
+// StatsRange is the activity over the last days, with every day present so it can be charted directly
+type StatsRange struct {
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Days   []StatsDay  `json:"days"`   // oldest first
+	Totals GameStats   `json:"totals"` // over the whole range
+	Games  []StatsGame `json:"games"`  // most watched first
+}
+
+// statsHistory accumulates the activity per local day
+type statsHistory struct {
+	mu        sync.Mutex
+	store     storage.Store
+	days      map[string]*DayStats
+	games     map[string]*GameTotals // since the first run
+	lastSaved time.Time
+	lastWatch time.Time
+
+	// Campaigns already counted as completed in this run
+	completed map[string]bool
+}
+
+// loadStats reads the persisted daily stats from storage
+func (m *Miner) loadStats(store storage.Store) {
+	days := make(map[string]*DayStats)
+	if err := store.Load(statsDocument, &days); err != nil {
+		logrus.Errorf("Failed to load stats: %v", err)
+	}
+	var games map[string]*GameTotals
+	if err := store.Load(gameStatsDocument, &games); err != nil {
+		logrus.Errorf("Failed to load game stats: %v", err)
+	}
+	if games == nil {
+		games = gameTotalsFromDays(days)
+	}
+
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+	for day, stats := range m.stats.days {
+		if days[day] == nil {
+			days[day] = &DayStats{}
+		}
+		days[day].add(stats.GameStats)
+		for game, gameStats := range stats.Games {
+			days[day].game(game).add(*gameStats)
+		}
+	}
+	for game, totals := range m.stats.games {
+		if games[game] == nil {
+			games[game] = &GameTotals{}
+		}
+		games[game].add(totals.GameStats)
+		games[game].LastFarmedAt = totals.LastFarmedAt
+	}
+	m.stats.store = store
+	m.stats.days = days
+	m.stats.games = games
+}
+
+// today returns today's stats, dropping days past the retention window when a new day starts; the caller holds mu
+func (h *statsHistory) today(now time.Time) *DayStats {
+	if h.days == nil {
+		h.days = make(map[string]*DayStats)
+	}
+	date := now.Format("2006-01-02")
+	if h.days[date] == nil {
+		cutoff := now.AddDate(0, 0, -StatsHistoryDays).Format("2006-01-02")
+		for day := range h.days {
+			if day < cutoff {
+				delete(h.days, day)
+			}
+		}
+		h.days[date] = &DayStats{}
+	}
+	return h.days[date]
+}
+
+// saveLocked writes the stats to storage, the caller holds mu
+func (h *statsHistory) saveLocked(now time.Time) {
+	if h.store == nil {
+		return
+	}
+	h.lastSaved = now
+	if err := h.store.Save(statsDocument, h.days); err != nil {
+		logrus.Errorf("Failed to save stats: %v", err)
+	}
+	if err := h.store.Save(gameStatsDocument, h.games); err != nil {
+		logrus.Errorf("Failed to save game stats: %v", err)
+	}
+}
+
+// recordWatch adds the time since the previous watch request to today's watch time
+func (m *Miner) recordWatch() {
+	m.mu.RLock()
+	gameName := ""
+	if m.currentCampaign != nil {
+		gameName = m.currentCampaign.Game.Name
+	}
+	m.mu.RUnlock()
+
+	now := time.Now()
+	h := &m.stats
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	gap := now.Sub(h.lastWatch)
+	h.lastWatch = now
+	if gap <= 0 || gap > maxWatchGap {
+		// First request after a break, the next one counts from here
+		return
+	}
+
+	minutes := gap.Minutes()
+	day := h.today(now)
+	day.MinutesWatched += minutes
+	if gameName != "" {
+		day.game(gameName).MinutesWatched += minutes
+		h.gameTotals(gameName, now).MinutesWatched += minutes
+	}
+
+	if now.Sub(h.lastSaved) >= statsSaveInterval {
+		h.saveLocked(now)
+	}
+}
+
+// recordClaim counts a claimed drop of a game, "" when the game isn't known
+func (m *Miner) recordClaim(gameName string) {
+	now := time.Now()
+	h := &m.stats
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	day := h.today(now)
+	day.DropsClaimed++
+	if gameName != "" {
+		day.game(gameName).DropsClaimed++
+		h.gameTotals(gameName, now).DropsClaimed++
+	}
+	h.saveLocked(now)
+}
+
+// recordCompletion counts a completed campaign of a game
+func (m *Miner) recordCompletion(gameName string) {
+	now := time.Now()
+	h := &m.stats
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	day := h.today(now)
+	day.CampaignsCompleted++
+	if gameName != "" {
+		day.game(gameName).CampaignsCompleted++
+		h.gameTotals(gameName, now).CampaignsCompleted++
+	}
+	h.saveLocked(now)
+}
+
+// markCompleted reports whether a campaign completes for the first time in this run, so a claim found again
+// doesn't complete it twice
+func (m *Miner) markCompleted(campaignID string) bool {
+	h := &m.stats
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.completed[campaignID] {
+		return false
+	}
+	if h.completed == nil {
+		h.completed = make(map[string]bool)
+	}
+	h.completed[campaignID] = true
+	return true
+}
+
+// campaignDone reports whether every drop of a campaign is claimed, counting the ones in claimed as well
+func campaignDone(campaign *twitch.Campaign, claimed map[string]bool) bool {
+	for _, drop := range campaign.TimeBasedDrops {
+		if !drop.Self.IsClaimed && !claimed[drop.ID] {
+			return false
+		}
+	}
+	return len(campaign.TimeBasedDrops) > 0
+}
+
+// inventoryCampaignDone is campaignDone for an inventory campaign
+func inventoryCampaignDone(campaign *twitch.DropCampaignGQL, claimed map[string]bool) bool {
+	if campaign.TimeBasedDrops == nil || len(*campaign.TimeBasedDrops) == 0 {
+		return false
+	}
+	for _, drop := range *campaign.TimeBasedDrops {
+		if (drop.Self == nil || !drop.Self.IsClaimed) && !claimed[drop.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordManualClaim adds a drop claimed from the dashboard to the claim history and publishes it
+func (m *Miner) RecordManualClaim(campaign *twitch.DropCampaignGQL, drop *twitch.TimeBasedDropGQL) {
+	m.announceClaim(inventoryClaimRecord(campaign, drop, ClaimSourceManual), inventoryCampaignDone(campaign, map[string]bool{drop.ID: true}))
+}
+
+// GetStats returns the activity of the last days, today included
+func (m *Miner) GetStats(days int) (*StatsRange, error) {
+	return m.GetStatsEnding(time.Now(), days)
+}
+
+// GetStatsEnding returns the activity of the days ending with the local day of last, e.g. yesterday's alone
+func (m *Miner) GetStatsEnding(last time.Time, days int) (*StatsRange, error) {
+	if days < 1 || days > StatsHistoryDays {
+		return nil, fmt.Errorf("range must be between 1 and %d days", StatsHistoryDays)
+	}
+
+	h := &m.stats
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := &StatsRange{
+		From:  last.AddDate(0, 0, -(days - 1)).Format("2006-01-02"),
+		To:    last.Format("2006-01-02"),
+		Days:  make([]StatsDay, 0, days),
+		Games: []StatsGame{},
+	}
+
+	games := make(map[string]*GameStats)
+	for i := days - 1; i >= 0; i-- {
+		date := last.AddDate(0, 0, -i).Format("2006-01-02")
+		day := StatsDay{Date: date}
+		if stats := h.days[date]; stats != nil {
+			day.GameStats = stats.GameStats
+			for name, gameStats := range stats.Games {
+				if games[name] == nil {
+					games[name] = &GameStats{}
+				}
+				games[name].add(*gameStats)
+			}
+		}
+		result.Totals.add(day.GameStats)
+		result.Days = append(result.Days, day)
+	}
+
+	for name, gameStats := range games {
+		result.Games = append(result.Games, StatsGame{GameName: name, GameStats: *gameStats})
+	}
+	sort.Slice(result.Games, func(i, j int) bool {
+		if result.Games[i].MinutesWatched != result.Games[j].MinutesWatched {
+			return result.Games[i].MinutesWatched > result.Games[j].MinutesWatched
+		}
+		return result.Games[i].GameName < result.Games[j].GameName
+	})
+
+	return result, nil
+}
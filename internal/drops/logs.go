@@ -0,0 +1,230 @@
+package drops
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding the miner log
+const minerLogsDocument = "miner_logs"
+
+// Oldest miner log entries are dropped once the log grows past this
+const maxMinerLogs = 1000
+
+// Miner log events
+const (
+	LogEventStart       = "start"
+	LogEventStop        = "stop"
+	LogEventSwitch      = "switch"
+	LogEventClaim       = "claim"
+	LogEventPoints      = "points"
+	LogEventError       = "error"
+	LogEventSchedule    = "schedule"
+	LogEventPause       = "pause"
+	LogEventStall       = "stall"
+	LogEventNewCampaign = "new_campaign"
+)
+
+// MinerLogEntry is a single miner event kept for /api/miner/logs
+type MinerLogEntry struct {
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level"`
+	Event    string    `json:"event"`
+	Message  string    `json:"message"`
+	Campaign string    `json:"campaign,omitempty"`
+	Game     string    `json:"game,omitempty"`
+	Channel  string    `json:"channel,omitempty"`
+}
+
+// MinerLogQuery selects miner log entries; zero fields match everything
+type MinerLogQuery struct {
+	Level  string // least severe level included, e.g. "warning" for warnings and errors
+	Event  string // one of the LogEvent constants
+	Game   string // game name, case-insensitive
+	Since  time.Time
+	Until  time.Time
+	Limit  int // entries returned, 0 or less for all of them
+	Offset int // matching entries skipped, counting back from the newest
+}
+
+// Matches reports whether an entry passes the query's filters
+func (q MinerLogQuery) Matches(entry MinerLogEntry) bool {
+	if q.Level != "" {
+		least, err := logrus.ParseLevel(q.Level)
+		level, entryErr := logrus.ParseLevel(entry.Level)
+		if err == nil && entryErr == nil && level > least {
+			return false
+		}
+	}
+	if q.Event != "" && entry.Event != q.Event {
+		return false
+	}
+	if q.Game != "" && !strings.EqualFold(entry.Game, q.Game) {
+		return false
+	}
+	if !q.Since.IsZero() && entry.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && entry.Time.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// minerLogs keeps the miner log in memory and in sync with the storage document
+type minerLogs struct {
+	mu      sync.RWMutex
+	store   storage.Store
+	entries []MinerLogEntry
+
+	// Game of each campaign by name, from the last campaign listing
+	games map[string]string
+
+	// Channels of SubscribeLogs callers
+	subscribers map[chan MinerLogEntry]struct{}
+}
+
+// loadLogs reads the persisted miner log from storage
+func (m *Miner) loadLogs(store storage.Store) {
+	entries := []MinerLogEntry{}
+	if err := store.Load(minerLogsDocument, &entries); err != nil {
+		logrus.Errorf("Failed to load miner logs: %v", err)
+	}
+
+	m.logs.mu.Lock()
+	defer m.logs.mu.Unlock()
+	m.logs.store = store
+	m.logs.entries = append(entries, m.logs.entries...)
+	m.trimLogsLocked()
+}
+
+// GetLogs returns up to limit of the most recent miner log entries, oldest first
+// A limit of zero or less returns the whole log
+func (m *Miner) GetLogs(limit int) []MinerLogEntry {
+	m.logs.mu.RLock()
+	defer m.logs.mu.RUnlock()
+
+	entries := m.logs.entries
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return append([]MinerLogEntry(nil), entries...)
+}
+
+// QueryLogs returns the entries matching a query, oldest first, and how many match in total
+func (m *Miner) QueryLogs(query MinerLogQuery) ([]MinerLogEntry, int) {
+	m.logs.mu.RLock()
+	defer m.logs.mu.RUnlock()
+
+	matching := []MinerLogEntry{}
+	for _, entry := range m.logs.entries {
+		if query.Matches(entry) {
+			matching = append(matching, entry)
+		}
+	}
+	total := len(matching)
+
+	end := total - max(query.Offset, 0)
+	if end < 0 {
+		end = 0
+	}
+	start := 0
+	if query.Limit > 0 && end > query.Limit {
+		start = end - query.Limit
+	}
+	return matching[start:end], total
+}
+
+// SubscribeLogs returns a channel receiving every miner log entry from now on, until cancel is called; entries
+// are dropped while the channel is full
+func (m *Miner) SubscribeLogs() (<-chan MinerLogEntry, func()) {
+	ch := make(chan MinerLogEntry, 100)
+
+	m.logs.mu.Lock()
+	if m.logs.subscribers == nil {
+		m.logs.subscribers = make(map[chan MinerLogEntry]struct{})
+	}
+	m.logs.subscribers[ch] = struct{}{}
+	m.logs.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			m.logs.mu.Lock()
+			delete(m.logs.subscribers, ch)
+			m.logs.mu.Unlock()
+		})
+	}
+}
+
+// rememberCampaignGames keeps the game of each listed campaign, so log entries about them can be filtered by game
+func (m *Miner) rememberCampaignGames(campaigns []twitch.Campaign) {
+	games := make(map[string]string, len(campaigns))
+	for _, campaign := range campaigns {
+		games[campaign.Name] = campaign.Game.Name
+	}
+
+	m.logs.mu.Lock()
+	defer m.logs.mu.Unlock()
+	m.logs.games = games
+}
+
+// GetLogChannel returns miner log entries as they are recorded
+func (m *Miner) GetLogChannel() <-chan MinerLogEntry {
+	return m.logChan
+}
+
+// logEvent records a miner event; it only takes the log lock, so it is safe to call with m.mu held
+func (m *Miner) logEvent(level logrus.Level, event, campaign, channel, format string, args ...interface{}) {
+	entry := MinerLogEntry{
+		Time:     time.Now(),
+		Level:    level.String(),
+		Event:    event,
+		Message:  fmt.Sprintf(format, args...),
+		Campaign: campaign,
+		Channel:  channel,
+	}
+
+	m.logs.mu.Lock()
+	defer m.logs.mu.Unlock()
+
+	if campaign != "" {
+		entry.Game = m.logs.games[campaign]
+	}
+
+	select {
+	case m.logChan <- entry:
+	default:
+		// Nobody is reading, the entry is still in the log
+	}
+	for subscriber := range m.logs.subscribers {
+		select {
+		case subscriber <- entry:
+		default:
+			// Slow reader, it misses this entry
+		}
+	}
+
+	m.logs.entries = append(m.logs.entries, entry)
+	m.trimLogsLocked()
+
+	if m.logs.store == nil {
+		return
+	}
+	if err := m.logs.store.Save(minerLogsDocument, m.logs.entries); err != nil {
+		logrus.Errorf("Failed to save miner logs: %v", err)
+	}
+}
+
+func (m *Miner) trimLogsLocked() {
+	if len(m.logs.entries) > maxMinerLogs {
+		m.logs.entries = append([]MinerLogEntry(nil), m.logs.entries[len(m.logs.entries)-maxMinerLogs:]...)
+	}
+}
@@ -2,14 +2,32 @@ package drops
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/telemetry"
 	"twitchdropsfarmer/internal/twitch"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Status message while the Twitch client pauses requests after repeated failures
+const apiPausedMessage = "Twitch API requests keep failing, mining is paused until they recover"
+
+// Errors returned when starting, stopping, pausing or resuming the miner in the wrong state
+var (
+	ErrAlreadyRunning = errors.New("miner is already running")
+	ErrNotRunning     = errors.New("miner is not running")
+	ErrAlreadyPaused  = errors.New("miner is already paused")
+	ErrNotPaused      = errors.New("miner is not paused")
 )
 
 type Miner struct {
@@ -23,28 +41,175 @@ type Miner struct {
 	currentSession  *MiningSession
 	watchingSession *twitch.WatchingSession
 
+	// When Pause was called, zero while not paused
+	pausedAt time.Time
+
+	// Set by Shutdown, so stop keeps the campaign for the next start; doneChan closes when Start returns
+	shuttingDown bool
+	doneChan     chan struct{}
+
+	// Fires when the next upcoming campaign worth switching to starts
+	campaignStartTimer *time.Timer
+	campaignStartAt    time.Time
+
+	// Index of the next points channel to try in fallback mode
+	pointsFallbackIndex int
+
+	// Channel ID followed by AutoFollow, unfollowed on switch when AutoUnfollow is set
+	followedChannelID string
+
+	// Chat connection for the watched channel, when ChatPresence is enabled
+	chat *twitch.ChatPresence
+
+	// Real-time drop events, when PubSub is enabled
+	pubsub *twitch.PubSub
+
+	// Serializes claims of the mined campaign between the checks and PubSub events
+	claimMu sync.Mutex
+
+	// Drop session found on startup, preferred on the first switch so partial progress isn't lost
+	resumeSession *twitch.CurrentDropProgress
+
 	// Status tracking
 	status   *MinerStatus
 	statusMu sync.RWMutex
+	counters runtimeCounters
+
+	// Persisted stream heartbeats
+	heartbeats streamHeartbeats
+
+	// Persisted miner event log
+	logs minerLogs
+
+	// Bytes downloaded by watch requests per day
+	bandwidth bandwidthMeter
+
+	// Watch time, claims and completed campaigns per day
+	stats statsHistory
+
+	// Failures in a row per operation, for the error reporter
+	failures failureTracker
+
+	// Claimed drops with their reward images
+	claims claimHistory
+
+	// Past mining sessions by stream, for /api/sessions
+	sessions sessionHistory
+
+	// Channel points balances and claimed bonuses per channel
+	points pointsBalances
+
+	// Persisted per-campaign pins and ignores
+	overrides campaignOverrides
+
+	// Selection for the last campaign listing, reused while it doesn't change
+	campaignsCache campaignsCache
+
+	// CampaignDetails responses by campaign ID
+	details campaignDetails
+
+	// Campaigns listed so far, to announce new ones
+	known knownCampaigns
+
+	// Channels recently found offline, skipped when picking a stream
+	offline offlineChannels
+
+	// When the watched drop session last gained minutes
+	watchdog progressWatchdog
+
+	// Status and session persisted across restarts
+	state minerState
 
 	// Configuration
 	config *MinerConfig
 
+	// Events, with the last error published so repeats are left out
+	bus               eventBus
+	lastNotifiedError string
+
 	// Channels for coordination
-	stopChan   chan struct{}
-	statusChan chan *MinerStatus
-	configChan chan struct{}
+	stopChan          chan struct{}
+	statusChan        chan *MinerStatus
+	configChan        chan struct{}
+	campaignStartChan chan struct{}
+	logChan           chan MinerLogEntry
+	campaignsChan     chan []twitch.Campaign
+
+	// Hash of the campaign listing last sent on campaignsChan
+	publishedCampaigns [32]byte
 }
 
 type MinerConfig struct {
 	CheckInterval   time.Duration
 	WatchInterval   time.Duration // How often to send watch requests (like TDM ~20s)
+	WatchJitter     time.Duration // Random amount each watch request is sent early or late by
 	SwitchThreshold time.Duration
 	MinimumPoints   int
 	MaximumStreams  int
 	PriorityGames   []config.GameConfig
 	ClaimDrops      bool
-	WebhookURL      string
+	ClaimPoints     bool          // Claim point bonuses on the watched channel
+	PointsChannels  []string      // Extra channels to claim point bonuses on, without watching them
+	PointsFallback  bool          // Watch PointsChannels in rotation when no campaign can be farmed
+	AutoFollow      bool          // Follow the watched channel when the campaign requires it
+	AutoUnfollow    bool          // Unfollow channels followed by AutoFollow once they are no longer watched
+	ChatPresence    bool          // Join the watched channel's IRC chat
+	SwitchBonus     int           // Score bonus for the campaign being mined, so ties don't cause flapping
+	BandwidthCap    int64         // Bytes per day after which watch requests are sent less often, 0 for no cap
+	PubSub          bool          // Listen for real-time drop progress and claim events
+	WatchMethod     string        // twitch.WatchMethodHLS, WatchMethodSpade, or WatchMethodBoth
+	PriorityMode    string        // How campaigns of priority games are ordered, see PriorityModeList
+	ExcludeGames    []string      // Game names or IDs that are never farmed, even if listed as priority
+	WatchUnlisted   bool          // Farm connected campaigns of games that are in neither list, after the priority games
+	DetailsCacheTTL time.Duration // How long fetched campaign details are reused, 0 to fetch them on every evaluation
+	ClaimInterval   time.Duration // How often the inventory is scanned for unclaimed drops, 0 to disable
+	Schedule        Schedule      // Windows to watch in, empty to watch around the clock
+	StallTimeout    time.Duration // How long drop minutes may stay flat while watching before switching streams, 0 to disable
+	CampaignAlerts  string        // Which new campaigns are announced: CampaignAlertsPriority, CampaignAlertsAll, or CampaignAlertsOff
+	StreamLanguages []string      // Broadcaster languages streams are picked in, empty for any
+	MinViewers      int           // Streams with fewer viewers are tried last, 0 for no minimum
+	MaxViewers      int           // Streams with more viewers are tried after those in range, 0 for no maximum
+}
+
+// NewMinerConfig builds the miner configuration from the application settings
+func NewMinerConfig(cfg *config.Config) *MinerConfig {
+	schedule, err := ParseSchedule(cfg.Schedule)
+	if err != nil {
+		// The settings API validates it, so only a hand-edited config.json gets here
+		logrus.Errorf("Ignoring invalid schedule, watching around the clock: %v", err)
+	}
+
+	return &MinerConfig{
+		CheckInterval:   time.Duration(cfg.CheckInterval) * time.Second,
+		WatchInterval:   time.Duration(cfg.WatchInterval) * time.Second,
+		WatchJitter:     time.Duration(cfg.WatchJitter) * time.Second,
+		SwitchThreshold: time.Duration(cfg.SwitchThreshold) * time.Minute,
+		MinimumPoints:   cfg.MinimumPoints,
+		MaximumStreams:  cfg.MaximumStreams,
+		PriorityGames:   cfg.PriorityGames,
+		ClaimDrops:      cfg.ClaimDrops,
+		ClaimPoints:     cfg.ClaimPoints,
+		PointsChannels:  cfg.PointsChannels,
+		PointsFallback:  cfg.PointsFallback,
+		AutoFollow:      cfg.AutoFollow,
+		AutoUnfollow:    cfg.AutoUnfollow,
+		ChatPresence:    cfg.ChatPresence,
+		SwitchBonus:     cfg.SwitchBonus,
+		BandwidthCap:    int64(cfg.BandwidthCapMB) << 20,
+		PubSub:          cfg.PubSub,
+		WatchMethod:     cfg.WatchMethod,
+		PriorityMode:    cfg.PriorityMode,
+		ExcludeGames:    cfg.ExcludeGames,
+		WatchUnlisted:   cfg.WatchUnlisted,
+		DetailsCacheTTL: time.Duration(cfg.DetailsCacheTTL) * time.Minute,
+		ClaimInterval:   time.Duration(cfg.ClaimInterval) * time.Minute,
+		Schedule:        schedule,
+		StallTimeout:    time.Duration(cfg.StallTimeout) * time.Minute,
+		StreamLanguages: cfg.StreamLanguages,
+		MinViewers:      cfg.MinViewers,
+		MaxViewers:      cfg.MaxViewers,
+		CampaignAlerts:  cfg.CampaignAlerts,
+	}
 }
 
 type MinerStatus struct {
@@ -57,18 +222,45 @@ type MinerStatus struct {
 	LastUpdate      time.Time        `json:"last_update"`
 	NextSwitch      time.Time        `json:"next_switch"`
 	ErrorMessage    string           `json:"error_message"`
+	PointsOnly      bool             `json:"points_only"` // watching a points channel because nothing can be farmed
+	QueueEstimate   *QueueEstimate   `json:"queue_estimate"`
+	Forecast        []DropForecast   `json:"forecast"` // pending drops in queue order
 	ActiveDrops     []ActiveDrop     `json:"active_drops"`
+
+	// Set when the schedule keeps the miner from watching; NextScheduleChange is nil without a schedule
+	OutsideSchedule    bool       `json:"outside_schedule"`
+	NextScheduleChange *time.Time `json:"next_schedule_change"`
+
+	// Set between Pause and Resume
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"paused_at"`
 }
 
 type ActiveDrop struct {
-	ID              string    `json:"id"`
-	Name            string    `json:"name"`
-	GameName        string    `json:"game_name"`
-	RequiredMinutes int       `json:"required_minutes"`
-	CurrentMinutes  int       `json:"current_minutes"`
-	Progress        float64   `json:"progress"`
-	IsClaimed       bool      `json:"is_claimed"`
-	EstimatedTime   time.Time `json:"estimated_time"`
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	GameName         string     `json:"game_name"`
+	RequiredMinutes  int        `json:"required_minutes"`
+	CurrentMinutes   int        `json:"current_minutes"`
+	Progress         float64    `json:"progress"`
+	IsClaimed        bool       `json:"is_claimed"`
+	EstimatedTime    time.Time  `json:"estimated_time"`
+	RemainingMinutes int        `json:"remaining_minutes"`
+	ClaimableAt      *time.Time `json:"claimable_at"` // nil when the drop isn't progressing right now
+}
+
+// SetClaimETA fills in the remaining minutes and, when the drop is being watched, when it becomes claimable
+func (d *ActiveDrop) SetClaimETA(now time.Time, watching bool) {
+	d.RemainingMinutes = d.RequiredMinutes - d.CurrentMinutes
+	if d.RemainingMinutes < 0 || d.IsClaimed {
+		d.RemainingMinutes = 0
+	}
+
+	d.ClaimableAt = nil
+	if watching && !d.IsClaimed {
+		claimableAt := now.Add(time.Duration(d.RemainingMinutes) * time.Minute)
+		d.ClaimableAt = &claimableAt
+	}
 }
 
 type MiningSession struct {
@@ -78,43 +270,63 @@ type MiningSession struct {
 	StreamID   string    `json:"stream_id"`
 	StartedAt  time.Time `json:"started_at"`
 	Status     string    `json:"status"`
+
+	// Set when the process shut down while the session was active, cleared when it is resumed
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+	MinutesWatched int        `json:"minutes_watched,omitempty"`
 }
 
 func NewMiner(twitchClient *twitch.Client) *Miner {
-	return &Miner{
+	m := &Miner{
 		twitchClient: twitchClient,
 		config: &MinerConfig{
 			CheckInterval:   60 * time.Second,
-			WatchInterval:   20 * time.Second, // Like TDM - every ~20 seconds
+			WatchInterval:   defaultWatchInterval,
 			SwitchThreshold: 5 * time.Minute,
 			MinimumPoints:   50,
 			MaximumStreams:  3,
 			PriorityGames:   []config.GameConfig{},
 			ClaimDrops:      true,
+			SwitchBonus:     5,
+			CampaignAlerts:  CampaignAlertsPriority,
 		},
 		status: &MinerStatus{
 			IsRunning:   false,
 			LastUpdate:  time.Now(),
 			ActiveDrops: []ActiveDrop{},
 		},
-		stopChan:   make(chan struct{}),
-		statusChan: make(chan *MinerStatus, 100),
-		configChan: make(chan struct{}, 1), // Buffered channel to avoid blocking
+		counters:          runtimeCounters{processStartedAt: time.Now()},
+		stopChan:          make(chan struct{}),
+		statusChan:        make(chan *MinerStatus, 100),
+		configChan:        make(chan struct{}, 1), // Buffered channel to avoid blocking
+		campaignStartChan: make(chan struct{}, 1),
+		logChan:           make(chan MinerLogEntry, 100),
+		campaignsChan:     make(chan []twitch.Campaign, 10),
 	}
+	// A bus of its own until SetEvents, so the stats count claims without one
+	m.SetEvents(events.NewBus(), "")
+	return m
 }
 
 func (m *Miner) Start(ctx context.Context) error {
 	m.mu.Lock()
 	if m.isRunning {
 		m.mu.Unlock()
-		return fmt.Errorf("miner is already running")
+		return ErrAlreadyRunning
 	}
 	m.isRunning = true
 	// Create fresh stopChan for each start to avoid closed channel issues
 	m.stopChan = make(chan struct{})
+	m.doneChan = make(chan struct{})
+	done := m.doneChan
+	m.updatePubSub()
 	m.mu.Unlock()
+	defer close(done)
 
 	logrus.Info("Starting drop miner...")
+	m.logEvent(logrus.InfoLevel, LogEventStart, "", "", "Miner started")
+	m.counters.minerStartedAt.Store(time.Now().UnixNano())
+	m.counters.loopBeatAt.Store(time.Now().UnixNano())
 
 	// Update status
 	m.updateStatus(func(s *MinerStatus) {
@@ -127,19 +339,44 @@ func (m *Miner) Start(ctx context.Context) error {
 	checkTicker := time.NewTicker(m.config.CheckInterval)
 	defer checkTicker.Stop()
 
-	// Start watch loop (periodic HEAD requests to maintain viewing)
-	watchTicker := time.NewTicker(m.config.WatchInterval)
-	defer watchTicker.Stop()
+	// Start watch loop (periodic HEAD requests to maintain viewing), rescheduled after every request
+	watchFailures := 0
+	watchTimer := time.NewTimer(m.watchDelay(watchFailures))
+	defer watchTimer.Stop()
+
+	// Start points loop (bonus claims on favorite channels)
+	pointsTicker := time.NewTicker(pointsCheckInterval)
+	defer pointsTicker.Stop()
+
+	// Start schedule loop (watching starts and stops on the minute the schedule changes)
+	scheduleTicker := time.NewTicker(time.Minute)
+	defer scheduleTicker.Stop()
+
+	// Start watchdog loop (switches streams when Twitch stops counting the watched one)
+	watchdogTicker := time.NewTicker(watchdogCheckInterval)
+	defer watchdogTicker.Stop()
+
+	// Start claim loop (drops completed outside the mined campaign), off when the interval is 0
+	var claimTick <-chan time.Time
+	if m.config.ClaimInterval > 0 {
+		claimTicker := time.NewTicker(m.config.ClaimInterval)
+		defer claimTicker.Stop()
+		claimTick = claimTicker.C
+	}
+
+	// Pick up the campaign and session of the previous run, and look up the drop session left over from it
+	m.restoreState()
+	m.loadResumeSession(ctx)
 
 	// Initial check
 	if err := m.checkAndUpdate(ctx); err != nil {
 		logrus.Errorf("Initial check failed: %v", err)
-		m.updateStatus(func(s *MinerStatus) {
-			s.ErrorMessage = fmt.Sprintf("Initial check failed: %v", err)
-		})
+		m.reportError(fmt.Sprintf("Initial check failed: %v", err))
 	}
 
 	for {
+		// Every step returns here, and the schedule ticker wakes the loop at least once a minute
+		m.counters.loopBeatAt.Store(time.Now().UnixNano())
 		select {
 		case <-ctx.Done():
 			logrus.Info("Drop miner context cancelled")
@@ -150,23 +387,53 @@ func (m *Miner) Start(ctx context.Context) error {
 		case <-checkTicker.C:
 			if err := m.checkAndUpdate(ctx); err != nil {
 				logrus.Errorf("Mining check failed: %v", err)
-				m.updateStatus(func(s *MinerStatus) {
-					s.ErrorMessage = fmt.Sprintf("Mining check failed: %v", err)
-				})
+				m.reportError(fmt.Sprintf("Mining check failed: %v", err))
 			}
 		case <-m.configChan:
 			// Configuration changed, trigger immediate re-evaluation
 			logrus.Info("Configuration updated, re-evaluating campaigns...")
 			if err := m.checkAndUpdate(ctx); err != nil {
 				logrus.Errorf("Config-triggered mining check failed: %v", err)
-				m.updateStatus(func(s *MinerStatus) {
-					s.ErrorMessage = fmt.Sprintf("Config-triggered mining check failed: %v", err)
-				})
+				m.reportError(fmt.Sprintf("Config-triggered mining check failed: %v", err))
 			}
-		case <-watchTicker.C:
+		case <-watchTimer.C:
 			// Send periodic watch request to maintain viewing (like TDM)
-			if err := m.sendWatchRequest(ctx); err != nil {
+			session := m.getWatchingSession()
+			err := m.sendWatchRequest(ctx)
+			if err != nil {
 				logrus.Debugf("Watch request failed: %v", err)
+				m.counters.failures.Add(1)
+			}
+			watchFailures = nextWatchFailures(watchFailures, err)
+			if m.getWatchingSession() != session {
+				// Failed over to another stream, watch it at the normal pace
+				watchFailures = 0
+			}
+			if watchFailures > 0 {
+				logrus.Debugf("Backing off watch requests after %d failures in a row", watchFailures)
+			}
+			watchTimer.Reset(m.watchDelay(watchFailures))
+		case <-pointsTicker.C:
+			m.claimChannelPoints(ctx)
+		case <-watchdogTicker.C:
+			m.checkProgress(ctx)
+		case <-claimTick:
+			m.reconcileClaims(ctx)
+		case <-m.campaignStartChan:
+			// An upcoming campaign should have started, look at the listing again right away
+			logrus.Info("Upcoming campaign started, re-evaluating campaigns...")
+			m.invalidateCampaignsCache()
+			if err := m.checkAndUpdate(ctx); err != nil {
+				logrus.Errorf("Mining check failed: %v", err)
+				m.reportError(fmt.Sprintf("Mining check failed: %v", err))
+			}
+		case <-scheduleTicker.C:
+			if outside := m.outsideSchedule(); m.applySchedule() && outside {
+				// Back inside the schedule, pick a campaign right away
+				if err := m.checkAndUpdate(ctx); err != nil {
+					logrus.Errorf("Mining check failed: %v", err)
+					m.reportError(fmt.Sprintf("Mining check failed: %v", err))
+				}
 			}
 		}
 	}
@@ -177,47 +444,129 @@ func (m *Miner) Stop() error {
 	defer m.mu.Unlock()
 
 	if !m.isRunning {
-		return fmt.Errorf("miner is not running")
+		return ErrNotRunning
 	}
 
 	close(m.stopChan)
 	return nil
 }
 
+// StopAndWait stops the miner like Stop and returns once the watch loop has ended, or ctx expires; it reports
+// whether the miner was running
+func (m *Miner) StopAndWait(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	running := m.isRunning
+	done := m.doneChan
+	if running {
+		select {
+		case <-m.stopChan:
+			// Stop was already called, the loop is on its way out
+		default:
+			close(m.stopChan)
+		}
+	}
+	m.mu.Unlock()
+
+	if !running || done == nil {
+		return false, nil
+	}
+	select {
+	case <-done:
+		return true, nil
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
 func (m *Miner) stop() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.isRunning = false
+	m.pausedAt = time.Time{}
+	m.stopCampaignStart()
+	m.counters.minerStartedAt.Store(0)
+	m.counters.loopBeatAt.Store(0)
+	m.updatePubSub()
 
 	// End current session if active
 	if m.currentSession != nil {
 		minutesWatched := int(time.Since(m.currentSession.StartedAt).Minutes())
 		logrus.Debugf("Ending mining session: %s, watched %d minutes", m.currentSession.ID, minutesWatched)
+		if m.shuttingDown {
+			m.finalizeSession(m.currentSession, minutesWatched)
+		}
 		m.currentSession = nil
 	}
 
 	// Clear watching session
 	m.watchingSession = nil
+	m.clearHeartbeats()
+	m.invalidateCampaignsCache()
+	if m.chat != nil {
+		m.chat.Leave()
+	}
 
-	// Update status
+	// Undo auto-follow on the way out
+	if m.followedChannelID != "" && m.config.AutoUnfollow {
+		channelID := m.followedChannelID
+		m.followedChannelID = ""
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := m.twitchClient.UnfollowChannel(ctx, channelID); err != nil {
+				logrus.Errorf("Failed to unfollow channel %s: %v", channelID, err)
+			}
+		}()
+	}
+
+	// Update status; on shutdown the campaign stays in the saved status, so the next start resumes it
+	shuttingDown := m.shuttingDown
 	m.updateStatus(func(s *MinerStatus) {
 		s.IsRunning = false
 		s.LastUpdate = time.Now()
 		s.CurrentStream = nil
-		s.CurrentCampaign = nil
+		if !shuttingDown {
+			s.CurrentCampaign = nil
+		}
+		s.Paused = false
+		s.PausedAt = nil
 	})
 
 	logrus.Info("Drop miner stopped")
+	m.logEvent(logrus.InfoLevel, LogEventStop, "", "", "Miner stopped")
 	return nil
 }
 
-func (m *Miner) checkAndUpdate(ctx context.Context) error {
+func (m *Miner) checkAndUpdate(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "miner.check")
+	defer func() { telemetry.End(span, err) }()
+	defer func() { m.trackFailure(operationMiningCheck, err) }()
+
 	// Check if user is logged in
 	if !m.twitchClient.IsLoggedIn() {
 		return fmt.Errorf("user is not logged in")
 	}
 
+	// Twitch keeps failing, wait for the client to let requests through again instead of adding to the errors
+	if until := m.twitchClient.APIPausedUntil(); !until.IsZero() {
+		m.reportError(apiPausedMessage)
+		logrus.Debugf("Skipping mining check, Twitch API requests paused until %s", until.Format("15:04:05"))
+		return nil
+	}
+	m.clearError(apiPausedMessage)
+
+	if !m.applySchedule() {
+		logrus.Debug("Skipping mining check, outside the farming schedule")
+		return nil
+	}
+
+	// Keep the selection as it is until Resume
+	if m.isPaused() {
+		logrus.Debug("Skipping mining check, miner is paused")
+		return nil
+	}
+
 	// Debug: Check user info first
 	user := m.twitchClient.GetUser()
 	if user != nil {
@@ -230,54 +579,58 @@ func (m *Miner) checkAndUpdate(ctx context.Context) error {
 	campaigns, err := m.twitchClient.GetDropCampaigns(ctx)
 	if err != nil {
 		logrus.Debugf("Failed to fetch campaigns (this is expected during development): %v", err)
+		span.RecordError(err)
 		// Return nil to prevent error spam during development
 		return nil
 	}
+	span.SetAttributes(attribute.Int("campaigns", len(campaigns)))
+	m.alertNewCampaigns(ctx, campaigns)
+	m.publishCampaigns(campaigns)
+	m.rememberCampaignGames(campaigns)
+	m.scheduleCampaignStart(campaigns)
 
 	if len(campaigns) == 0 {
 		logrus.Info("No active campaigns found")
+		if err := m.watchPointsFallback(ctx); err != nil {
+			return err
+		}
+		m.updateMinerStatus(campaigns)
 		return nil
 	}
 
-	var campaignsDetails []twitch.Campaign
-	for _, campaign := range campaigns {
-		// Skip expired campaigns first
-		if campaign.Status != "ACTIVE" {
-			logrus.Debugf("Skipping %s - campaign status is %s (not ACTIVE)", campaign.Game.Name, campaign.Status)
-			continue
-		}
-		if !m.isGamePriority(campaign.Game.Name) {
-			logrus.Debugf("Skipping %s - not a priority game", campaign.Game.Name)
-			continue
-		}
-
-		// Skip if not account connected and game is not in priority list
-		if !campaign.Self.IsAccountConnected {
-			logrus.Debugf("Skipping %s - not connected", campaign.Game.Name)
-			continue
-		}
-
-		campaignDetails, err := m.twitchClient.GetCampaignDetails(ctx, campaign.ID)
-		if err != nil {
-			logrus.Debugf("Skipping %s - couldn't fetch campaign details", campaign.Game.Name)
-			continue
-		}
-
-		campaignsDetails = append(campaignsDetails, *campaignDetails)
+	// Re-scoring only matters when the campaign listing changed since the last check
+	hash := hashCampaigns(campaigns)
+	bestCampaign, unchanged := m.cachedBestCampaign(hash)
+	if unchanged {
+		logrus.Debug("Campaigns unchanged since last check, keeping previous selection")
+	} else {
+		m.pruneDetails(campaigns)
+		var evaluated []twitch.Campaign
+		bestCampaign, evaluated = m.evaluateCampaigns(ctx, campaigns)
+		m.cacheBestCampaign(hash, bestCampaign, evaluated)
 	}
 
-	// Find best campaign to watch
-	bestCampaign := m.selectBestCampaign(campaignsDetails)
 	if bestCampaign == nil {
 		logrus.Info("No suitable campaign found")
+		if err := m.watchPointsFallback(ctx); err != nil {
+			return err
+		}
+		m.updateMinerStatus(campaigns)
 		return nil
 	}
 
 	// Check if we need to switch campaigns
+	switched := false
 	if m.shouldSwitchCampaign(bestCampaign) {
 		if err := m.switchToCampaign(ctx, bestCampaign); err != nil {
 			return fmt.Errorf("failed to switch campaign: %w", err)
 		}
+		switched = true
+	}
+
+	// The cached selection predates the minutes watched since, so fetch the mined campaign's progress again
+	if unchanged && !switched {
+		m.refreshCampaignProgress(ctx)
 	}
 
 	// Update progress for current drops
@@ -292,15 +645,92 @@ func (m *Miner) checkAndUpdate(ctx context.Context) error {
 		}
 	}
 
+	// Nothing changed, so only the timestamp and session progress need refreshing
+	if unchanged && !switched {
+		m.refreshStatusProgress()
+		return nil
+	}
+
 	// Update status
 	m.updateMinerStatus(campaigns)
 	return nil
 }
 
+// evaluateCampaigns fetches details for the eligible campaigns and returns the best one to watch
+func (m *Miner) evaluateCampaigns(ctx context.Context, campaigns []twitch.Campaign) (*twitch.Campaign, []twitch.Campaign) {
+	var campaignsDetails []twitch.Campaign
+	for _, campaign := range campaigns {
+		// Skip expired campaigns first
+		if campaign.Status != "ACTIVE" {
+			logrus.Debugf("Skipping %s - campaign status is %s (not ACTIVE)", campaign.Game.Name, campaign.Status)
+			continue
+		}
+		if !m.isOverridden(campaign.ID) && !m.isGameFarmable(campaign.Game) {
+			logrus.Debugf("Skipping %s - not a priority game or excluded", campaign.Game.Name)
+			continue
+		}
+		if !m.isGamePriority(campaign.Game) && !campaign.Self.IsAccountConnected {
+			// Unlisted games are only farmed when connected, don't fetch details for the rest
+			logrus.Debugf("Skipping %s - unlisted and not connected", campaign.Game.Name)
+			continue
+		}
+		if m.isCampaignIgnored(campaign.ID) {
+			logrus.Debugf("Skipping %s - campaign %s is ignored", campaign.Game.Name, campaign.Name)
+			continue
+		}
+
+		// Skip if not account connected and game is not in priority list
+		if !campaign.Self.IsAccountConnected {
+			logrus.Debugf("Skipping %s - not connected", campaign.Game.Name)
+			continue
+		}
+
+		campaignDetails, err := m.getCampaignDetails(ctx, campaign)
+		if err != nil {
+			logrus.Debugf("Skipping %s - couldn't fetch campaign details", campaign.Game.Name)
+			continue
+		}
+
+		// Direct-entitlement campaigns can't be progressed by watching
+		if campaignDetails.Type == twitch.CampaignTypeDirect {
+			logrus.Debugf("Skipping %s - %s is a direct entitlement campaign", campaign.Game.Name, campaign.Name)
+			continue
+		}
+
+		campaignsDetails = append(campaignsDetails, *campaignDetails)
+	}
+
+	// Progress isn't cached with the details, one inventory fetch has it for every campaign
+	if len(campaignsDetails) > 0 {
+		if inventory, err := m.twitchClient.RefreshInventory(ctx); err != nil {
+			logrus.Debugf("Failed to get drop progress: %v", err)
+		} else {
+			progress := inventory.DropProgress()
+			for i := range campaignsDetails {
+				applyProgress(&campaignsDetails[i], progress)
+			}
+		}
+	}
+	m.expireForcedCampaign(campaigns, campaignsDetails)
+
+	queueEstimate, forecast := m.estimateQueue(campaignsDetails, time.Now())
+	m.updateStatus(func(s *MinerStatus) {
+		s.QueueEstimate = queueEstimate
+		s.Forecast = forecast
+	})
+
+	// Find best campaign to watch
+	return m.selectBestCampaign(campaignsDetails), campaignsDetails
+}
+
 func (m *Miner) selectBestCampaign(campaigns []twitch.Campaign) *twitch.Campaign {
 	var bestCampaign *twitch.Campaign
 	var bestScore int
 
+	m.mu.RLock()
+	currentCampaign := m.currentCampaign
+	m.mu.RUnlock()
+
 	logrus.Debugf("Selecting from %d campaigns", len(campaigns))
 	logrus.Debugf("Priority games configured: %v", m.config.PriorityGames)
 
@@ -323,8 +753,13 @@ func (m *Miner) selectBestCampaign(campaigns []twitch.Campaign) *twitch.Campaign
 			continue
 		}
 
-		if !m.isGamePriority(campaign.Game.Name) {
-			logrus.Debugf("Skipping %s - not priority", campaign.Game.Name)
+		if !m.isOverridden(campaign.ID) && !m.isGameFarmable(campaign.Game) {
+			logrus.Debugf("Skipping %s - not priority or excluded", campaign.Game.Name)
+			continue
+		}
+
+		if m.isCampaignIgnored(campaign.ID) {
+			logrus.Debugf("Skipping %s - campaign is ignored", campaign.Name)
 			continue
 		}
 
@@ -335,6 +770,11 @@ func (m *Miner) selectBestCampaign(campaigns []twitch.Campaign) *twitch.Campaign
 
 		// Calculate score
 		score := m.calculateCampaignScore(&campaign)
+
+		// Hysteresis: only leave the current campaign for a meaningfully better one
+		if score > 0 && currentCampaign != nil && campaign.ID == currentCampaign.ID {
+			score += m.config.SwitchBonus
+		}
 		logrus.Debugf("Campaign %s score: %d", campaign.Game.Name, score)
 		if score > bestScore {
 			logrus.Debugf("New best campaign: %s (score %d beats previous %d)", campaign.Game.Name, score, bestScore)
@@ -359,14 +799,33 @@ func (m *Miner) calculateCampaignScore(campaign *twitch.Campaign) int {
 	score := 0
 
 	// Priority games get higher score based on their position in the priority list
-	priorityIndex := m.getGamePriorityIndex(campaign.Game.Name)
+	priorityIndex := m.getGamePriorityIndex(campaign.Game)
+	if m.isGameExcluded(campaign.Game) {
+		priorityIndex = -1
+	}
 	logrus.Debugf("Game '%s' priority index: %d (priority games: %v)", campaign.Game.Name, priorityIndex, m.config.PriorityGames)
-	if priorityIndex >= 0 {
+	if m.isForced(campaign.ID) {
+		// Forced through the API, nothing outranks it
+		score += forcedScore
+		logrus.Debugf("Campaign '%s' is forced", campaign.Name)
+	} else if pinPriority, pinned := m.pinPriority(campaign.ID); pinned {
+		// Pinned campaigns beat every game in the priority list
+		score += pinnedScore + pinPriority
+		logrus.Debugf("Campaign '%s' is pinned with priority %d", campaign.Name, pinPriority)
+	} else if priorityIndex >= 0 {
 		// Higher priority (earlier in list) gets higher score
 		// First game gets 200, second gets 190, third gets 180, etc.
 		priorityScore := 1000 - (priorityIndex * 10)
+		if m.config.PriorityMode != "" && m.config.PriorityMode != PriorityModeList {
+			// Other modes rank by their own measure; the list position only breaks ties
+			priorityScore = 1000 - priorityIndex + m.priorityModeScore(campaign, time.Now())
+		}
 		score += priorityScore
-		logrus.Debugf("Added %d points for priority game '%s' (position %d)", priorityScore, campaign.Game.Name, priorityIndex)
+		logrus.Debugf("Added %d points for priority game '%s' (position %d, mode %s)", priorityScore, campaign.Game.Name, priorityIndex, m.config.PriorityMode)
+	} else if m.config.WatchUnlisted && !m.isGameExcluded(campaign.Game) {
+		// Unlisted games come after every priority game
+		score += unlistedScore
+		logrus.Debugf("Added %d points for unlisted game '%s'", unlistedScore, campaign.Game.Name)
 	} else {
 		// If game is not priority, return 0 immediately
 		logrus.Debugf("Skipping game '%s' (not priority)", campaign.Game.Name)
@@ -407,6 +866,11 @@ func (m *Miner) shouldSwitchCampaign(newCampaign *twitch.Campaign) bool {
 		return true
 	}
 
+	// Switch if the campaign is forced onto a channel that may have come online
+	if m.wantsForcedChannel() {
+		return true
+	}
+
 	// Switch if we've been watching for the threshold time
 	if m.currentSession != nil &&
 		time.Since(m.currentSession.StartedAt) > m.config.SwitchThreshold {
@@ -416,7 +880,10 @@ func (m *Miner) shouldSwitchCampaign(newCampaign *twitch.Campaign) bool {
 	return false
 }
 
-func (m *Miner) switchToCampaign(ctx context.Context, campaign *twitch.Campaign) error {
+func (m *Miner) switchToCampaign(ctx context.Context, campaign *twitch.Campaign) (err error) {
+	ctx, span := tracer.Start(ctx, "miner.switch_campaign", trace.WithAttributes(campaignAttributes(campaign)...))
+	defer func() { telemetry.End(span, err) }()
+
 	logrus.Infof("Switching to campaign: %s", campaign.Name)
 
 	// End current session
@@ -425,22 +892,6 @@ func (m *Miner) switchToCampaign(ctx context.Context, campaign *twitch.Campaign)
 		logrus.Debugf("Ending current session: %s, watched %d minutes", m.currentSession.ID, minutesWatched)
 	}
 
-	// Find best stream for this campaign
-	streams, err := m.twitchClient.GetStreamsForGameName(ctx, campaign.Game.Name, m.config.MaximumStreams)
-	if err != nil {
-		return fmt.Errorf("failed to get streams for game: %w", err)
-	}
-
-	if len(streams) == 0 {
-		return fmt.Errorf("no streams found for game: %s", campaign.Game.Name)
-	}
-
-	// Select best stream
-	bestStream := m.selectBestStream(streams)
-	if bestStream == nil {
-		return fmt.Errorf("no suitable stream found for game: %s", campaign.Game.Name)
-	}
-
 	// Start new session
 	user := m.twitchClient.GetUser()
 	if user == nil {
@@ -449,14 +900,30 @@ func (m *Miner) switchToCampaign(ctx context.Context, campaign *twitch.Campaign)
 
 	sessionID := fmt.Sprintf("session_%d", time.Now().Unix())
 
-	// Start watching session like TDM
-	watchingSession, err := m.twitchClient.StartWatching(ctx, bestStream.UserLogin)
-	if err != nil {
-		return fmt.Errorf("failed to start watching session: %w", err)
+	// Prefer the channel the campaign was forced onto, then the one an in-progress drop session is attached to
+	bestStream, watchingSession := m.forcedStream(ctx, campaign)
+	if bestStream == nil {
+		bestStream, watchingSession = m.resumeStream(ctx, campaign)
 	}
 
+	if bestStream == nil {
+		bestStream, watchingSession, err = m.findStream(ctx, campaign)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Follow-gated campaigns only progress for followers of the watched channel
+	m.updateFollow(ctx, campaign, bestStream)
+
 	// Update current state
 	m.mu.Lock()
+	previousCampaign, previousChannel := m.watchedLocked()
+	startedAt := time.Now()
+	if restored := m.restoredSessionFor(campaign.ID); restored != nil {
+		// Same campaign as before the restart, keep the session going
+		sessionID, startedAt = restored.ID, restored.StartedAt
+	}
 	m.currentCampaign = campaign
 	m.currentStream = bestStream
 	m.currentSession = &MiningSession{
@@ -464,33 +931,22 @@ func (m *Miner) switchToCampaign(ctx context.Context, campaign *twitch.Campaign)
 		UserID:     user.ID,
 		CampaignID: campaign.ID,
 		StreamID:   bestStream.ID,
-		StartedAt:  time.Now(),
+		StartedAt:  startedAt,
 		Status:     "active",
 	}
 	m.watchingSession = watchingSession
+	m.saveSession(m.currentSession)
 	m.mu.Unlock()
 
+	span.SetAttributes(attribute.String("channel", bestStream.UserLogin))
+	m.counters.switches.Add(1)
+	m.updateChat()
 	logrus.Infof("Now watching: %s playing %s", bestStream.UserName, bestStream.GameName)
+	m.logEvent(logrus.InfoLevel, LogEventSwitch, campaign.Name, bestStream.UserLogin, "Now watching %s playing %s", bestStream.UserName, bestStream.GameName)
+	m.announceSwitch(previousCampaign, previousChannel, campaign, bestStream)
 	return nil
 }
 
-func (m *Miner) selectBestStream(streams []twitch.Stream) *twitch.Stream {
-	if len(streams) == 0 {
-		return nil
-	}
-
-	// For now, select the stream with highest viewer count
-	// TODO: Add more sophisticated selection logic
-	bestStream := &streams[0]
-	for _, stream := range streams {
-		if stream.ViewerCount > bestStream.ViewerCount {
-			bestStream = &stream
-		}
-	}
-
-	return bestStream
-}
-
 func (m *Miner) updateDropProgress(ctx context.Context) error {
 	m.mu.RLock()
 	campaign := m.currentCampaign
@@ -509,6 +965,9 @@ func (m *Miner) updateDropProgress(ctx context.Context) error {
 }
 
 func (m *Miner) checkAndClaimDrops(ctx context.Context) error {
+	m.claimMu.Lock()
+	defer m.claimMu.Unlock()
+
 	m.mu.RLock()
 	campaign := m.currentCampaign
 	m.mu.RUnlock()
@@ -517,24 +976,65 @@ func (m *Miner) checkAndClaimDrops(ctx context.Context) error {
 		return nil
 	}
 
-	for _, drop := range campaign.TimeBasedDrops {
+	ctx, span := tracer.Start(ctx, "miner.claim_drops", trace.WithAttributes(campaignAttributes(campaign)...))
+	defer span.End()
+
+	claimed := make(map[string]bool)
+	for i := range campaign.TimeBasedDrops {
+		drop := &campaign.TimeBasedDrops[i]
 		if !drop.Self.IsClaimed &&
 			drop.Self.CurrentMinutesWatched >= drop.RequiredMinutesWatched &&
 			drop.Self.DropInstanceID != "" {
 
 			logrus.Infof("Claiming drop: %s", drop.Name)
 			if err := m.twitchClient.ClaimDrop(ctx, drop.Self.DropInstanceID); err != nil {
+				// The other drops are still claimed, the span only notes the failure
+				span.RecordError(err, trace.WithAttributes(attribute.String("drop.name", drop.Name)))
 				logrus.Errorf("Failed to claim drop %s: %v", drop.Name, err)
+				m.logEvent(logrus.ErrorLevel, LogEventClaim, campaign.Name, "", "Failed to claim drop %s: %v", drop.Name, err)
+				m.counters.failures.Add(1)
+				m.trackFailure(operationClaim, err)
 				continue
 			}
+			m.trackFailure(operationClaim, nil)
 
 			logrus.Infof("Successfully claimed drop: %s", drop.Name)
+			m.logEvent(logrus.InfoLevel, LogEventClaim, campaign.Name, "", "Claimed drop %s (%s)", drop.Name, campaign.Game.Name)
+			claimed[drop.ID] = true
+			m.updateCurrentDrop(drop.ID, func(self *twitch.TimeBasedSelf) { self.IsClaimed = true })
+			m.announceClaim(dropClaimRecord(campaign, drop, ClaimSourceWatch), campaignDone(campaign, claimed))
 		}
 	}
 
 	return nil
 }
 
+// updateCurrentDrop changes the progress of a drop of the mined campaign, on a copy so callers holding the
+// previous campaign don't see it change under them
+func (m *Miner) updateCurrentDrop(dropID string, update func(*twitch.TimeBasedSelf)) {
+	m.mu.Lock()
+	current := m.currentCampaign
+	if current == nil || !slices.ContainsFunc(current.TimeBasedDrops, func(drop twitch.TimeBased) bool { return drop.ID == dropID }) {
+		m.mu.Unlock()
+		return
+	}
+	updated := *current
+	updated.TimeBasedDrops = slices.Clone(current.TimeBasedDrops)
+	for i := range updated.TimeBasedDrops {
+		if updated.TimeBasedDrops[i].ID == dropID {
+			update(&updated.TimeBasedDrops[i].Self)
+		}
+	}
+	m.currentCampaign = &updated
+	m.mu.Unlock()
+
+	m.updateStatus(func(s *MinerStatus) {
+		if s.CurrentCampaign != nil && s.CurrentCampaign.ID == updated.ID {
+			s.CurrentCampaign = &updated
+		}
+	})
+}
+
 func (m *Miner) updateMinerStatus(campaigns []twitch.Campaign) {
 	m.mu.RLock()
 	currentCampaign := m.currentCampaign
@@ -556,6 +1056,9 @@ func (m *Miner) updateMinerStatus(campaigns []twitch.Campaign) {
 		for _, drop := range campaign.TimeBasedDrops {
 			if drop.Self.IsClaimed {
 				claimedDrops++
+			} else if drop.RequiredMinutesWatched <= 0 {
+				// Not a watch-time drop, there is no progress to report
+				continue
 			} else {
 				// Add current session progress for the current campaign's drops
 				var currentMinutes int = drop.Self.CurrentMinutesWatched
@@ -579,7 +1082,7 @@ func (m *Miner) updateMinerStatus(campaigns []twitch.Campaign) {
 				}
 				estimatedTime := time.Now().Add(time.Duration(remainingMinutes) * time.Minute)
 
-				activeDrops = append(activeDrops, ActiveDrop{
+				activeDrop := ActiveDrop{
 					ID:              drop.ID,
 					Name:            drop.Name,
 					GameName:        campaign.Game.Name,
@@ -588,7 +1091,9 @@ func (m *Miner) updateMinerStatus(campaigns []twitch.Campaign) {
 					Progress:        progress,
 					IsClaimed:       drop.Self.IsClaimed,
 					EstimatedTime:   estimatedTime,
-				})
+				}
+				activeDrop.SetClaimETA(time.Now(), currentCampaign != nil && campaign.ID == currentCampaign.ID && currentStream != nil)
+				activeDrops = append(activeDrops, activeDrop)
 			}
 		}
 	}
@@ -617,6 +1122,7 @@ func (m *Miner) updateMinerStatus(campaigns []twitch.Campaign) {
 	m.updateStatus(func(s *MinerStatus) {
 		s.CurrentStream = currentStream
 		s.CurrentCampaign = currentCampaign
+		s.PointsOnly = currentCampaign == nil && currentStream != nil
 		s.CurrentProgress = currentProgress
 		s.TotalCampaigns = len(campaigns)
 		s.ClaimedDrops = claimedDrops
@@ -628,9 +1134,8 @@ func (m *Miner) updateMinerStatus(campaigns []twitch.Campaign) {
 
 func (m *Miner) updateStatus(updateFunc func(*MinerStatus)) {
 	m.statusMu.Lock()
-	defer m.statusMu.Unlock()
-
 	updateFunc(m.status)
+	snapshot := *m.status
 
 	// Send status update to channel (non-blocking)
 	select {
@@ -638,6 +1143,56 @@ func (m *Miner) updateStatus(updateFunc func(*MinerStatus)) {
 	default:
 		// Channel is full, skip this update
 	}
+	m.statusMu.Unlock()
+
+	m.saveStatus(&snapshot)
+}
+
+// reportError records the error in the status and publishes it once per distinct message
+func (m *Miner) reportError(message string) {
+	m.counters.failures.Add(1)
+
+	m.updateStatus(func(s *MinerStatus) {
+		s.ErrorMessage = message
+	})
+
+	m.mu.Lock()
+	if message == m.lastNotifiedError {
+		m.mu.Unlock()
+		return
+	}
+	m.lastNotifiedError = message
+	m.mu.Unlock()
+
+	// Repeats of the same error are left out, like notifications
+	m.logEvent(logrus.ErrorLevel, LogEventError, "", "", "%s", message)
+
+	m.publish(events.Event{
+		Type:    events.MinerError,
+		Title:   "Drop miner error",
+		Message: message,
+		Error:   message,
+	})
+}
+
+// clearError removes message from the status once its cause is gone, so a repeat is reported again
+func (m *Miner) clearError(message string) {
+	m.statusMu.RLock()
+	current := m.status.ErrorMessage
+	m.statusMu.RUnlock()
+	if current != message {
+		return
+	}
+
+	m.updateStatus(func(s *MinerStatus) {
+		s.ErrorMessage = ""
+	})
+
+	m.mu.Lock()
+	if m.lastNotifiedError == message {
+		m.lastNotifiedError = ""
+	}
+	m.mu.Unlock()
 }
 
 func (m *Miner) GetStatus() *MinerStatus {
@@ -653,20 +1208,58 @@ func (m *Miner) GetStatusChannel() <-chan *MinerStatus {
 	return m.statusChan
 }
 
-func (m *Miner) isGamePriority(gameName string) bool {
-	for _, game := range m.config.PriorityGames {
-		if game.Name == gameName {
+// GetCampaignsChannel returns the campaign listings, sent whenever the listing changes
+func (m *Miner) GetCampaignsChannel() <-chan []twitch.Campaign {
+	return m.campaignsChan
+}
+
+// publishCampaigns sends the listing on campaignsChan if it changed since the last one
+func (m *Miner) publishCampaigns(campaigns []twitch.Campaign) {
+	hash := hashCampaigns(campaigns)
+
+	m.mu.Lock()
+	changed := hash != m.publishedCampaigns
+	m.publishedCampaigns = hash
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	select {
+	case m.campaignsChan <- campaigns:
+	default:
+		// Nobody is reading, skip this listing
+	}
+}
+
+func (m *Miner) isGamePriority(game twitch.Game) bool {
+	return m.getGamePriorityIndex(game) >= 0
+}
+
+// isGameExcluded reports whether the game is in ExcludeGames, by ID or case-insensitive name
+func (m *Miner) isGameExcluded(game twitch.Game) bool {
+	for _, excluded := range m.config.ExcludeGames {
+		if excluded == game.ID || strings.EqualFold(excluded, game.Name) {
 			return true
 		}
 	}
 	return false
 }
 
+// isGameFarmable reports whether campaigns for the game may be farmed: excluded games never are,
+// priority games always are, and any other game only with WatchUnlisted
+func (m *Miner) isGameFarmable(game twitch.Game) bool {
+	if m.isGameExcluded(game) {
+		return false
+	}
+	return m.isGamePriority(game) || m.config.WatchUnlisted
+}
+
 // getGamePriorityIndex returns the index of the game in the priority list (0-based)
 // Returns -1 if the game is not in the priority list
-func (m *Miner) getGamePriorityIndex(gameName string) int {
-	for i, game := range m.config.PriorityGames {
-		if game.Name == gameName {
+func (m *Miner) getGamePriorityIndex(game twitch.Game) int {
+	for i, priorityGame := range m.config.PriorityGames {
+		if priorityGame.Matches(game.Name, game.ID) {
 			return i
 		}
 	}
@@ -683,6 +1276,13 @@ func (m *Miner) SetConfig(config *MinerConfig) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.config = config
+	m.invalidateCampaignsCache()
+
+	// Apply the chat toggle right away instead of waiting for the next switch
+	if !config.ChatPresence && m.chat != nil {
+		m.chat.Leave()
+	}
+	m.updatePubSub()
 
 	// Trigger immediate re-evaluation if miner is running
 	if m.isRunning {
@@ -695,14 +1295,61 @@ func (m *Miner) SetConfig(config *MinerConfig) {
 	}
 }
 
-func (m *Miner) sendWatchRequest(ctx context.Context) error {
+func (m *Miner) sendWatchRequest(ctx context.Context) (err error) {
 	m.mu.RLock()
 	watchingSession := m.watchingSession
 	m.mu.RUnlock()
 
-	if watchingSession == nil {
+	if watchingSession == nil || m.outsideSchedule() || m.isPaused() {
 		return nil // No active watching session
 	}
 
-	return m.twitchClient.SendWatchRequest(ctx, watchingSession)
+	// Over the daily bandwidth cap, only send a request every reducedWatchInterval
+	if m.shouldSkipWatch(time.Now()) {
+		return nil
+	}
+
+	m.mu.RLock()
+	watchMethod := m.config.WatchMethod
+	m.mu.RUnlock()
+
+	ctx, span := tracer.Start(ctx, "miner.watch", trace.WithAttributes(
+		attribute.String("channel", watchingSession.ChannelLogin),
+		attribute.String("watch.method", watchMethod),
+	))
+	defer func() { telemetry.End(span, err) }()
+	defer func() { m.trackFailure(operationWatch, err) }()
+
+	if watchMethod == twitch.WatchMethodSpade || watchMethod == twitch.WatchMethodBoth {
+		downloaded, err := m.twitchClient.SendSpadeEvent(ctx, watchingSession)
+		m.addBandwidth(downloaded)
+		if err != nil {
+			if watchMethod == twitch.WatchMethodSpade {
+				// Keep the minutes coming over HLS until Spade works again
+				logrus.Debugf("Spade event failed, falling back to HLS: %v", err)
+				watchMethod = twitch.WatchMethodHLS
+			} else {
+				logrus.Debugf("Spade event failed: %v", err)
+			}
+		}
+	}
+
+	if watchMethod != twitch.WatchMethodSpade {
+		downloaded, err := m.twitchClient.SendWatchRequest(ctx, watchingSession)
+		m.addBandwidth(downloaded)
+		if errors.Is(err, twitch.ErrStreamOffline) {
+			// Don't wait for the switch threshold on a dead playlist
+			m.failover(ctx)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	m.counters.watchRequests.Add(1)
+	m.recordWatch()
+	m.recordSessionWatch()
+	m.recordHeartbeat()
+	return nil
 }
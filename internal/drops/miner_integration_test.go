@@ -0,0 +1,106 @@
+package drops_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+	"twitchdropsfarmer/internal/twitchtest"
+)
+
+// The first drop of the campaign in the twitchtest fixtures, 3 minutes long
+const (
+	testCampaignID = "a1b2c3d4-0000-4000-8000-000000000001"
+	testDropID     = "d0000000-0000-4000-8000-000000000001"
+)
+
+// TestMinerWatchProgressClaim runs the miner against the fake Twitch backend until it has watched the fixture
+// campaign's first drop to completion and claimed it
+func TestMinerWatchProgressClaim(t *testing.T) {
+	server := twitchtest.New()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := server.NewClient(ctx)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	store, err := storage.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+
+	miner := drops.NewMiner(client)
+	miner.SetStore(store)
+	miner.SetConfig(&drops.MinerConfig{
+		CheckInterval:   200 * time.Millisecond,
+		WatchInterval:   50 * time.Millisecond,
+		SwitchThreshold: time.Minute,
+		ClaimDrops:      true,
+		WatchMethod:     twitch.WatchMethodHLS,
+		WatchUnlisted:   true,
+		CampaignAlerts:  drops.CampaignAlertsOff,
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- miner.Start(ctx) }()
+	defer func() {
+		miner.Stop()
+		<-done
+	}()
+
+	// Watching moves the drop along one minute per chunk request
+	waitFor(t, ctx, "drop progress", func() bool { return server.MinutesWatched(testDropID) > 0 })
+
+	status := miner.GetStatus()
+	if status.CurrentCampaign == nil || status.CurrentCampaign.ID != testCampaignID {
+		t.Fatalf("miner isn't farming campaign %s: %+v", testCampaignID, status.CurrentCampaign)
+	}
+	if status.CurrentStream == nil {
+		t.Fatal("miner has no stream while the drop progresses")
+	}
+
+	// The checks refresh the mined campaign's progress and claim the drop once it's complete
+	waitFor(t, ctx, "drop claim", func() bool { return server.Claimed(testDropID) })
+
+	waitFor(t, ctx, "claim record", func() bool {
+		for _, record := range miner.GetClaimHistory(0) {
+			if record.DropID == testDropID {
+				return true
+			}
+		}
+		return false
+	})
+
+	games := miner.GetGameStats()
+	if len(games) == 0 || games[0].GameName != "Rust" || games[0].MinutesWatched <= 0 {
+		t.Fatalf("expected the watch time to be recorded for Rust, got %+v", games)
+	}
+
+	sessions, _ := miner.QuerySessionHistory(drops.SessionHistoryQuery{})
+	if len(sessions) == 0 || sessions[0].CampaignID != testCampaignID {
+		t.Fatalf("expected a mining session for campaign %s, got %+v", testCampaignID, sessions)
+	}
+	if server.Requests("DropsPage_ClaimDropRewards") == 0 {
+		t.Fatal("no claim mutation was sent")
+	}
+}
+
+// waitFor polls condition until it holds, failing the test once ctx expires
+func waitFor(t *testing.T, ctx context.Context, what string, condition func() bool) {
+	t.Helper()
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for !condition() {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %s", what)
+		case <-ticker.C:
+		}
+	}
+}
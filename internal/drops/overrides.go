@@ -0,0 +1,204 @@
+package drops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding per-campaign overrides
+const overridesDocument = "campaign_overrides"
+
+// Base score of a pinned campaign, above anything game-level priority can give
+const pinnedScore = 10000
+
+// CampaignOverrides are per-campaign choices that take precedence over the game list
+type CampaignOverrides struct {
+	Pins    map[string]int  `json:"pins"`    // campaign ID to pin priority, higher is mined first
+	Ignored map[string]bool `json:"ignored"` // campaign IDs never mined, even for priority games
+
+	Forced *ForcedCampaign `json:"forced,omitempty"` // set with POST /api/miner/switch, mined before any pin
+}
+
+// campaignOverrides keeps the overrides in sync with the storage document
+type campaignOverrides struct {
+	mu    sync.RWMutex
+	store storage.Store
+	data  CampaignOverrides
+}
+
+// loadOverrides reads the persisted campaign overrides from storage
+func (m *Miner) loadOverrides(store storage.Store) {
+	data := CampaignOverrides{}
+	if err := store.Load(overridesDocument, &data); err != nil {
+		logrus.Errorf("Failed to load campaign overrides: %v", err)
+	}
+	if data.Pins == nil {
+		data.Pins = make(map[string]int)
+	}
+	if data.Ignored == nil {
+		data.Ignored = make(map[string]bool)
+	}
+
+	m.overrides.mu.Lock()
+	defer m.overrides.mu.Unlock()
+	m.overrides.store = store
+	m.overrides.data = data
+}
+
+// GetCampaignOverrides returns a copy of the current campaign overrides
+func (m *Miner) GetCampaignOverrides() CampaignOverrides {
+	m.overrides.mu.RLock()
+	defer m.overrides.mu.RUnlock()
+
+	pins := make(map[string]int, len(m.overrides.data.Pins))
+	for id, priority := range m.overrides.data.Pins {
+		pins[id] = priority
+	}
+	ignored := make(map[string]bool, len(m.overrides.data.Ignored))
+	for id := range m.overrides.data.Ignored {
+		ignored[id] = true
+	}
+	var forced *ForcedCampaign
+	if m.overrides.data.Forced != nil {
+		copied := *m.overrides.data.Forced
+		forced = &copied
+	}
+	return CampaignOverrides{Pins: pins, Ignored: ignored, Forced: forced}
+}
+
+// PinCampaign makes the campaign win over game-level priority until it is unpinned
+func (m *Miner) PinCampaign(campaignID string, priority int) error {
+	return m.updateOverrides(func(data *CampaignOverrides) {
+		data.Pins[campaignID] = priority
+	})
+}
+
+// PinGame pins the active campaign of a game that ends soonest above every other pin, so it is mined next
+func (m *Miner) PinGame(ctx context.Context, gameName string) (*twitch.Campaign, error) {
+	campaigns, err := m.twitchClient.GetDropCampaigns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaigns: %w", err)
+	}
+
+	var best *twitch.Campaign
+	for i := range campaigns {
+		campaign := &campaigns[i]
+		if campaign.Status != "ACTIVE" || !strings.EqualFold(campaign.Game.Name, gameName) {
+			continue
+		}
+		if best == nil || campaign.EndsAt.Before(best.EndsAt) {
+			best = campaign
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no active campaign for %s", gameName)
+	}
+
+	if err := m.PinCampaignNext(best.ID); err != nil {
+		return nil, err
+	}
+	return best, nil
+}
+
+// PinCampaignNext pins a campaign above every other pin, so it is mined next
+func (m *Miner) PinCampaignNext(campaignID string) error {
+	return m.updateOverrides(func(data *CampaignOverrides) {
+		priority := 0
+		for id, pin := range data.Pins {
+			if id != campaignID && pin >= priority {
+				priority = pin + 1
+			}
+		}
+		data.Pins[campaignID] = priority
+	})
+}
+
+// SetPinOrder replaces the pins with campaignIDs, mined in the order given; an empty list unpins everything
+func (m *Miner) SetPinOrder(campaignIDs []string) error {
+	return m.updateOverrides(func(data *CampaignOverrides) {
+		data.Pins = make(map[string]int, len(campaignIDs))
+		for i, id := range campaignIDs {
+			if _, seen := data.Pins[id]; !seen {
+				data.Pins[id] = len(campaignIDs) - 1 - i
+			}
+		}
+	})
+}
+
+// UnpinCampaign returns the campaign to normal game-level scoring
+func (m *Miner) UnpinCampaign(campaignID string) error {
+	return m.updateOverrides(func(data *CampaignOverrides) {
+		delete(data.Pins, campaignID)
+	})
+}
+
+// IgnoreCampaign stops the campaign from being mined without ignoring the rest of its game
+func (m *Miner) IgnoreCampaign(campaignID string) error {
+	return m.updateOverrides(func(data *CampaignOverrides) {
+		data.Ignored[campaignID] = true
+	})
+}
+
+// UnignoreCampaign lets the campaign be mined again
+func (m *Miner) UnignoreCampaign(campaignID string) error {
+	return m.updateOverrides(func(data *CampaignOverrides) {
+		delete(data.Ignored, campaignID)
+	})
+}
+
+// isCampaignIgnored reports whether the campaign was ignored through the API
+func (m *Miner) isCampaignIgnored(campaignID string) bool {
+	m.overrides.mu.RLock()
+	defer m.overrides.mu.RUnlock()
+
+	return m.overrides.data.Ignored[campaignID]
+}
+
+// pinPriority returns the pin priority of a campaign and whether it is pinned
+func (m *Miner) pinPriority(campaignID string) (int, bool) {
+	m.overrides.mu.RLock()
+	defer m.overrides.mu.RUnlock()
+
+	priority, ok := m.overrides.data.Pins[campaignID]
+	return priority, ok
+}
+
+// updateOverrides applies a change, persists it and re-evaluates campaigns
+func (m *Miner) updateOverrides(change func(data *CampaignOverrides)) error {
+	m.overrides.mu.Lock()
+	if m.overrides.data.Pins == nil {
+		m.overrides.data.Pins = make(map[string]int)
+	}
+	if m.overrides.data.Ignored == nil {
+		m.overrides.data.Ignored = make(map[string]bool)
+	}
+	change(&m.overrides.data)
+
+	var err error
+	if m.overrides.store != nil {
+		err = m.overrides.store.Save(overridesDocument, m.overrides.data)
+	}
+	m.overrides.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	m.invalidateCampaignsCache()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.isRunning {
+		select {
+		case m.configChan <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package drops
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"twitchdropsfarmer/internal/twitch"
+)
+
+// Watch interval used when the settings don't give one, like TDM
+const defaultWatchInterval = 20 * time.Second
+
+// Longest wait between watch requests while backing off
+const maxWatchBackoff = 2 * time.Minute
+
+// watchDelay is how long to wait before the next watch request: the watch interval, plus or minus up to the
+// jitter so requests don't land on a fixed beat, doubled for every failure in a row that calls for backing off
+func (m *Miner) watchDelay(failures int) time.Duration {
+	m.mu.RLock()
+	interval := m.config.WatchInterval
+	jitter := m.config.WatchJitter
+	m.mu.RUnlock()
+
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	if failures > 0 {
+		for i := 0; i < failures && interval < maxWatchBackoff; i++ {
+			interval *= 2
+		}
+		return min(interval, maxWatchBackoff)
+	}
+	if jitter > 0 {
+		interval += rand.N(2*jitter+1) - jitter
+	}
+	return interval
+}
+
+// nextWatchFailures counts the failures in a row that call for backing off: the stream going offline, or Twitch
+// rate limiting requests. Other errors and successes start over at the normal interval.
+func nextWatchFailures(failures int, err error) int {
+	if errors.Is(err, twitch.ErrStreamOffline) || errors.Is(err, twitch.ErrRateLimited) {
+		return failures + 1
+	}
+	return 0
+}
+
+// getWatchingSession returns the session watch requests are sent for, nil when nothing is watched
+func (m *Miner) getWatchingSession() *twitch.WatchingSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.watchingSession
+}
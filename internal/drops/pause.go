@@ -0,0 +1,72 @@
+package drops
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Pause stops sending watch requests and checking campaigns while keeping the campaign,
+// stream and session, so Resume carries on where the miner left off
+func (m *Miner) Pause() error {
+	m.mu.Lock()
+	if !m.isRunning {
+		m.mu.Unlock()
+		return ErrNotRunning
+	}
+	if !m.pausedAt.IsZero() {
+		m.mu.Unlock()
+		return ErrAlreadyPaused
+	}
+	pausedAt := time.Now()
+	m.pausedAt = pausedAt
+	m.mu.Unlock()
+
+	logrus.Info("Drop miner paused")
+	m.logEvent(logrus.InfoLevel, LogEventPause, "", "", "Miner paused")
+	m.updateStatus(func(s *MinerStatus) {
+		s.Paused = true
+		s.PausedAt = &pausedAt
+	})
+	return nil
+}
+
+// Resume picks up watching after Pause; the time spent paused doesn't count towards the switch threshold
+func (m *Miner) Resume() error {
+	m.mu.Lock()
+	if m.pausedAt.IsZero() {
+		m.mu.Unlock()
+		return ErrNotPaused
+	}
+	paused := time.Since(m.pausedAt)
+	m.pausedAt = time.Time{}
+	if m.currentSession != nil {
+		m.currentSession.StartedAt = m.currentSession.StartedAt.Add(paused)
+		m.saveSession(m.currentSession)
+	}
+	m.mu.Unlock()
+
+	logrus.Infof("Drop miner resumed after %s", paused.Round(time.Second))
+	m.logEvent(logrus.InfoLevel, LogEventPause, "", "", "Miner resumed after %s", paused.Round(time.Second))
+	m.updateStatus(func(s *MinerStatus) {
+		s.Paused = false
+		s.PausedAt = nil
+		if !s.NextSwitch.IsZero() {
+			s.NextSwitch = s.NextSwitch.Add(paused)
+		}
+	})
+
+	// Catch up on what changed while paused
+	select {
+	case m.configChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// isPaused reports whether the miner is paused
+func (m *Miner) isPaused() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.pausedAt.IsZero()
+}
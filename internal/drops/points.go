@@ -0,0 +1,313 @@
+package drops
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How often the watched and favorite channels are polled for point bonuses
+// Twitch offers a bonus roughly every 15 minutes of watching
+const pointsCheckInterval = 5 * time.Minute
+
+// Name of the storage document holding channel points balances
+const pointsDocument = "channel_points"
+
+// ChannelPointsBalance is the last known balance on a channel and what the miner earned there
+type ChannelPointsBalance struct {
+	ChannelID    string    `json:"channel_id"`
+	ChannelLogin string    `json:"channel_login"`
+	Balance      int       `json:"balance"`
+	Earned       int       `json:"earned"` // balance increase seen while tracking the channel
+	Claims       int       `json:"claims"` // bonuses claimed
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ChannelPointsSummary is the balances of every tracked channel and their totals
+type ChannelPointsSummary struct {
+	TotalBalance int                     `json:"total_balance"`
+	TotalEarned  int                     `json:"total_earned"`
+	TotalClaims  int                     `json:"total_claims"`
+	Channels     []*ChannelPointsBalance `json:"channels"` // highest balance first
+}
+
+// pointsBalances tracks channel points balances by channel login
+type pointsBalances struct {
+	mu       sync.Mutex
+	store    storage.Store
+	channels map[string]*ChannelPointsBalance
+}
+
+// loadPoints reads the persisted balances from storage
+func (m *Miner) loadPoints(store storage.Store) {
+	channels := make(map[string]*ChannelPointsBalance)
+	if err := store.Load(pointsDocument, &channels); err != nil {
+		logrus.Errorf("Failed to load channel points: %v", err)
+	}
+
+	m.points.mu.Lock()
+	defer m.points.mu.Unlock()
+	m.points.store = store
+	m.points.channels = channels
+}
+
+// recordPoints updates the balance of a channel, counting a claimed bonus when claimed is set
+func (m *Miner) recordPoints(points *twitch.ChannelPoints, claimed bool) {
+	p := &m.points
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.channels == nil {
+		p.channels = make(map[string]*ChannelPointsBalance)
+	}
+	key := strings.ToLower(points.ChannelLogin)
+	balance, ok := p.channels[key]
+	if !ok {
+		balance = &ChannelPointsBalance{ChannelLogin: points.ChannelLogin}
+		p.channels[key] = balance
+	} else if points.Balance > balance.Balance {
+		balance.Earned += points.Balance - balance.Balance
+	}
+	balance.ChannelID = points.ChannelID
+	balance.Balance = points.Balance
+	balance.UpdatedAt = time.Now()
+	if claimed {
+		balance.Claims++
+	}
+
+	if p.store == nil {
+		return
+	}
+	if err := p.store.Save(pointsDocument, p.channels); err != nil {
+		logrus.Errorf("Failed to save channel points: %v", err)
+	}
+}
+
+// GetChannelPoints returns the tracked balances and their totals
+func (m *Miner) GetChannelPoints() ChannelPointsSummary {
+	p := &m.points
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	summary := ChannelPointsSummary{Channels: make([]*ChannelPointsBalance, 0, len(p.channels))}
+	for _, balance := range p.channels {
+		entry := *balance
+		summary.Channels = append(summary.Channels, &entry)
+		summary.TotalBalance += balance.Balance
+		summary.TotalEarned += balance.Earned
+		summary.TotalClaims += balance.Claims
+	}
+	sort.Slice(summary.Channels, func(i, j int) bool {
+		if summary.Channels[i].Balance != summary.Channels[j].Balance {
+			return summary.Channels[i].Balance > summary.Channels[j].Balance
+		}
+		return summary.Channels[i].ChannelLogin < summary.Channels[j].ChannelLogin
+	})
+	return summary
+}
+
+// pointsChannels returns the channels to check for bonuses: the watched channel when
+// ClaimPoints is on, followed by the configured points channels
+func (m *Miner) pointsChannels() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var channels []string
+	seen := make(map[string]bool)
+	if m.config.ClaimPoints && m.currentStream != nil && m.currentStream.UserLogin != "" {
+		channels = append(channels, m.currentStream.UserLogin)
+		seen[strings.ToLower(m.currentStream.UserLogin)] = true
+	}
+	for _, channelLogin := range m.config.PointsChannels {
+		if !seen[strings.ToLower(channelLogin)] {
+			channels = append(channels, channelLogin)
+			seen[strings.ToLower(channelLogin)] = true
+		}
+	}
+	return channels
+}
+
+// claimChannelPoints polls ChannelPointsContext for the watched channel and each configured
+// points channel, claims any available bonus, and records the balances. Only GQL requests are made, no watch traffic.
+func (m *Miner) claimChannelPoints(ctx context.Context) {
+	for _, channelLogin := range m.pointsChannels() {
+		points, err := m.twitchClient.GetChannelPoints(ctx, channelLogin)
+		if err != nil {
+			logrus.Debugf("Failed to get channel points for %s: %v", channelLogin, err)
+			continue
+		}
+
+		if points.ClaimID == "" {
+			logrus.Debugf("No points bonus available on %s (balance: %d)", channelLogin, points.Balance)
+			m.recordPoints(points, false)
+			continue
+		}
+
+		if err := m.twitchClient.ClaimChannelPoints(ctx, points.ChannelID, points.ClaimID); err != nil {
+			logrus.Errorf("Failed to claim points bonus on %s: %v", channelLogin, err)
+			m.recordPoints(points, false)
+			continue
+		}
+
+		m.recordPoints(points, true)
+		logrus.Infof("Claimed points bonus on %s (balance: %d)", channelLogin, points.Balance)
+	}
+}
+
+// handlePointsEvents claims bonuses and records balances pushed over PubSub, so bonuses don't wait for the next poll
+func (m *Miner) handlePointsEvents(events <-chan twitch.PointsEvent) {
+	for event := range events {
+		points, ok := m.trackedPoints(event.ChannelID)
+		if !ok {
+			logrus.Debugf("Ignoring points event for untracked channel %s", event.ChannelID)
+			continue
+		}
+
+		switch event.Type {
+		case twitch.PointsEventClaimAvailable:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			err := m.twitchClient.ClaimChannelPoints(ctx, points.ChannelID, event.ClaimID)
+			cancel()
+			if err != nil {
+				logrus.Errorf("Failed to claim points bonus on %s: %v", points.ChannelLogin, err)
+				continue
+			}
+			m.recordPoints(points, true)
+			logrus.Infof("Claimed points bonus on %s from PubSub event", points.ChannelLogin)
+		case twitch.PointsEventEarned:
+			points.Balance = event.Balance
+			m.recordPoints(points, false)
+		}
+	}
+}
+
+// trackedPoints returns the last known points of a channel by ID, if it is one of the points channels
+func (m *Miner) trackedPoints(channelID string) (*twitch.ChannelPoints, bool) {
+	points := &twitch.ChannelPoints{ChannelID: channelID}
+
+	m.mu.RLock()
+	if m.currentStream != nil && m.currentStream.UserID == channelID {
+		points.ChannelLogin = m.currentStream.UserLogin
+	}
+	m.mu.RUnlock()
+
+	m.points.mu.Lock()
+	for _, balance := range m.points.channels {
+		if balance.ChannelID == channelID {
+			points.ChannelLogin = balance.ChannelLogin
+			points.Balance = balance.Balance
+			break
+		}
+	}
+	m.points.mu.Unlock()
+
+	if points.ChannelLogin == "" {
+		return nil, false
+	}
+	for _, channelLogin := range m.pointsChannels() {
+		if strings.EqualFold(channelLogin, points.ChannelLogin) {
+			return points, true
+		}
+	}
+	return nil, false
+}
+
+// watchPointsFallback rotates through the points channels when no campaign can be farmed,
+// so watch time still earns channel points and watch streaks instead of idling
+func (m *Miner) watchPointsFallback(ctx context.Context) error {
+	m.mu.RLock()
+	enabled := m.config.PointsFallback
+	channels := m.config.PointsChannels
+	switchThreshold := m.config.SwitchThreshold
+	fallbackActive := m.currentCampaign == nil && m.watchingSession != nil
+	session := m.currentSession
+	m.mu.RUnlock()
+
+	if !enabled || len(channels) == 0 {
+		if fallbackActive {
+			logrus.Info("Points fallback disabled, stopping points channel session")
+			m.clearWatching()
+		}
+		return nil
+	}
+
+	// Stay on the current channel until it is time to rotate
+	if fallbackActive && session != nil && time.Since(session.StartedAt) < switchThreshold {
+		return nil
+	}
+
+	user := m.twitchClient.GetUser()
+	if user == nil {
+		return fmt.Errorf("user not available")
+	}
+
+	m.mu.RLock()
+	start := m.pointsFallbackIndex
+	m.mu.RUnlock()
+
+	for i := 0; i < len(channels); i++ {
+		index := (start + i) % len(channels)
+		channelLogin := channels[index]
+
+		// StartWatching fails for offline channels, so it doubles as the live check
+		watchingSession, err := m.twitchClient.StartWatching(ctx, channelLogin)
+		if err != nil {
+			logrus.Debugf("Skipping points channel %s: %v", channelLogin, err)
+			continue
+		}
+
+		m.mu.Lock()
+		previousCampaign, previousChannel := m.watchedLocked()
+		m.pointsFallbackIndex = index + 1
+		stream := &twitch.Stream{
+			UserLogin: channelLogin,
+			UserName:  channelLogin,
+		}
+		m.currentCampaign = nil
+		m.currentStream = stream
+		m.currentSession = &MiningSession{
+			ID:        fmt.Sprintf("session_%d", time.Now().Unix()),
+			UserID:    user.ID,
+			StartedAt: time.Now(),
+			Status:    "points",
+		}
+		m.watchingSession = watchingSession
+		m.saveSession(m.currentSession)
+		m.mu.Unlock()
+
+		m.counters.switches.Add(1)
+		m.updateChat()
+		logrus.Infof("Nothing to farm, watching %s for channel points", channelLogin)
+		m.logEvent(logrus.InfoLevel, LogEventPoints, "", channelLogin, "Nothing to farm, watching %s for channel points", channelLogin)
+		m.announceSwitch(previousCampaign, previousChannel, nil, stream)
+		return nil
+	}
+
+	logrus.Info("Nothing to farm and no points channels are live")
+	m.clearWatching()
+	return nil
+}
+
+// clearWatching drops the current stream and watching session
+func (m *Miner) clearWatching() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.currentCampaign = nil
+	m.currentStream = nil
+	m.currentSession = nil
+	m.saveSession(nil)
+	m.watchingSession = nil
+	m.clearHeartbeats()
+	if m.chat != nil {
+		m.chat.Leave()
+	}
+}
@@ -0,0 +1,61 @@
+package drops
+
+import (
+	"time"
+
+	"twitchdropsfarmer/internal/twitch"
+)
+
+// Priority modes decide how campaigns of the priority games are ordered
+const (
+	PriorityModeList              = "PRIORITY_LIST"            // order of the priority games list
+	PriorityModeEndingSoonest     = "ENDING_SOONEST"           // campaigns that end first
+	PriorityModeLowAvailability   = "LOW_AVAILABILITY"         // campaigns restricted to the fewest channels
+	PriorityModeFewestMinutesLeft = "FEWEST_MINUTES_REMAINING" // campaigns closest to their last drop
+)
+
+// ValidPriorityMode reports whether mode is one of the priority modes
+func ValidPriorityMode(mode string) bool {
+	switch mode {
+	case PriorityModeList, PriorityModeEndingSoonest, PriorityModeLowAvailability, PriorityModeFewestMinutesLeft:
+		return true
+	}
+	return false
+}
+
+// Score of a campaign for an unlisted game with WatchUnlisted, below every priority game
+const unlistedScore = 1
+
+// Upper bound of the mode score, kept below pinnedScore so pins still win
+const maxPriorityModeScore = 8000
+
+// priorityModeScore ranks a priority game's campaign under the configured mode
+// It outweighs the list position, which only breaks ties outside PRIORITY_LIST
+func (m *Miner) priorityModeScore(campaign *twitch.Campaign, now time.Time) int {
+	var penalty int
+	switch m.config.PriorityMode {
+	case PriorityModeEndingSoonest:
+		if campaign.EndsAt.IsZero() {
+			return 0
+		}
+		penalty = int(campaign.EndsAt.Sub(now).Hours()) * 10
+	case PriorityModeLowAvailability:
+		if len(campaign.Allow) == 0 {
+			// Any channel streaming the game counts
+			return 0
+		}
+		penalty = len(campaign.Allow) * 10
+	case PriorityModeFewestMinutesLeft:
+		penalty = campaignRemainingMinutes(campaign)
+	default:
+		return 0
+	}
+
+	if penalty < 0 {
+		penalty = 0
+	}
+	if penalty > maxPriorityModeScore {
+		return 0
+	}
+	return maxPriorityModeScore - penalty
+}
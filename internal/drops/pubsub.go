@@ -0,0 +1,157 @@
+package drops
+
+import (
+	"context"
+	"time"
+
+	"twitchdropsfarmer/internal/telemetry"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// updatePubSub starts listening for drop events while the miner runs with PubSub enabled, and stops otherwise
+// Callers must hold m.mu
+func (m *Miner) updatePubSub() {
+	if !m.config.PubSub || !m.isRunning {
+		if m.pubsub != nil {
+			m.pubsub.Stop()
+		}
+		return
+	}
+
+	if m.pubsub == nil {
+		m.pubsub = twitch.NewPubSub(m.twitchClient)
+		go m.handleDropEvents(m.pubsub.Events())
+		go m.handlePointsEvents(m.pubsub.PointsEvents())
+	}
+	m.pubsub.Start()
+}
+
+// handleDropEvents applies real-time drop events for as long as the process runs
+func (m *Miner) handleDropEvents(events <-chan twitch.DropEvent) {
+	for event := range events {
+		switch event.Type {
+		case twitch.DropEventProgress:
+			m.applyDropProgress(event)
+		case twitch.DropEventClaim:
+			m.claimFromEvent(event)
+		}
+	}
+}
+
+// applyDropProgress updates the drop's minutes in the mined campaign and the active drops without waiting for
+// the next check, and claims it once the required minutes are reached
+func (m *Miner) applyDropProgress(event twitch.DropEvent) {
+	logrus.Debugf("Drop %s progress: %d/%d minutes", event.DropID, event.CurrentMinutes, event.RequiredMinutes)
+
+	m.mu.RLock()
+	watching := m.currentStream != nil
+	claimDrops := m.config.ClaimDrops
+	m.mu.RUnlock()
+
+	m.updateCurrentDrop(event.DropID, func(self *twitch.TimeBasedSelf) {
+		self.CurrentMinutesWatched = event.CurrentMinutes
+	})
+
+	m.updateStatus(func(s *MinerStatus) {
+		for i := range s.ActiveDrops {
+			drop := &s.ActiveDrops[i]
+			if drop.ID != event.DropID {
+				continue
+			}
+
+			drop.CurrentMinutes = event.CurrentMinutes
+			if event.RequiredMinutes > 0 {
+				drop.RequiredMinutes = event.RequiredMinutes
+			}
+			if drop.RequiredMinutes > 0 {
+				drop.Progress = float64(drop.CurrentMinutes) / float64(drop.RequiredMinutes)
+				if drop.Progress > 1.0 {
+					drop.Progress = 1.0
+				}
+			}
+			drop.SetClaimETA(time.Now(), watching)
+		}
+		s.LastUpdate = time.Now()
+	})
+
+	if claimDrops && event.RequiredMinutes > 0 && event.CurrentMinutes >= event.RequiredMinutes {
+		// Progress events carry no instance ID, the inventory has it once the drop is complete
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		m.refreshCampaignProgress(ctx)
+		if err := m.checkAndClaimDrops(ctx); err != nil {
+			logrus.Errorf("Failed to claim completed drop %s: %v", event.DropID, err)
+		}
+	}
+}
+
+// claimFromEvent claims a drop as soon as Twitch reports it claimable
+func (m *Miner) claimFromEvent(event twitch.DropEvent) {
+	m.claimMu.Lock()
+	defer m.claimMu.Unlock()
+
+	m.mu.RLock()
+	claimDrops := m.config.ClaimDrops
+	campaign := m.currentCampaign
+	m.mu.RUnlock()
+
+	if !claimDrops || event.DropInstanceID == "" {
+		return
+	}
+
+	dropName, campaignName, gameName := event.DropID, "", ""
+	record := ClaimRecord{DropID: event.DropID, DropName: event.DropID, Source: ClaimSourcePubSub}
+	if campaign != nil {
+		campaignName, gameName = campaign.Name, campaign.Game.Name
+		record.CampaignID, record.CampaignName, record.GameName = campaign.ID, campaign.Name, campaign.Game.Name
+		for i := range campaign.TimeBasedDrops {
+			if drop := &campaign.TimeBasedDrops[i]; drop.ID == event.DropID {
+				if drop.Self.IsClaimed {
+					logrus.Debugf("Drop %s was already claimed", drop.Name)
+					return
+				}
+				dropName = drop.Name
+				record = dropClaimRecord(campaign, drop, ClaimSourcePubSub)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "miner.claim_event", trace.WithAttributes(
+		attribute.String("drop.name", dropName),
+		attribute.String("campaign.name", campaignName),
+		attribute.String("game.name", gameName),
+	))
+	var err error
+	defer func() { telemetry.End(span, err) }()
+
+	logrus.Infof("Claiming drop from PubSub event: %s", dropName)
+	if err = m.twitchClient.ClaimDrop(ctx, event.DropInstanceID); err != nil {
+		logrus.Errorf("Failed to claim drop %s: %v", dropName, err)
+		m.logEvent(logrus.ErrorLevel, LogEventClaim, campaignName, "", "Failed to claim drop %s: %v", dropName, err)
+		m.counters.failures.Add(1)
+		m.trackFailure(operationClaim, err)
+		return
+	}
+	m.trackFailure(operationClaim, nil)
+
+	logrus.Infof("Successfully claimed drop: %s", dropName)
+	m.logEvent(logrus.InfoLevel, LogEventClaim, campaignName, "", "Claimed drop %s (%s)", dropName, gameName)
+	m.updateCurrentDrop(event.DropID, func(self *twitch.TimeBasedSelf) { self.IsClaimed = true })
+	m.announceClaim(record, campaign != nil && campaignDone(campaign, map[string]bool{event.DropID: true}))
+
+	m.updateStatus(func(s *MinerStatus) {
+		for i := range s.ActiveDrops {
+			if s.ActiveDrops[i].ID == event.DropID {
+				s.ActiveDrops[i].IsClaimed = true
+				s.ActiveDrops[i].SetClaimETA(time.Now(), false)
+			}
+		}
+		s.ClaimedDrops++
+	})
+}
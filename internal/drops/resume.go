@@ -0,0 +1,68 @@
+package drops
+
+import (
+	"context"
+
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loadResumeSession asks Twitch which channel the account's current drop session is attached to
+func (m *Miner) loadResumeSession(ctx context.Context) {
+	if !m.twitchClient.IsLoggedIn() {
+		return
+	}
+
+	// Without a channel ID Twitch reports the session for whatever channel was last watched
+	progress, err := m.twitchClient.GetCurrentDropProgress(ctx, "")
+	if err != nil {
+		logrus.Debugf("No drop session to resume: %v", err)
+		return
+	}
+
+	if progress == nil || progress.ChannelLogin == "" {
+		return
+	}
+
+	logrus.Infof("Found drop session on %s (%d minutes into %s), will resume it if still relevant",
+		progress.ChannelLogin, progress.CurrentMinutesWatched, progress.GameName)
+
+	m.mu.Lock()
+	m.resumeSession = progress
+	m.mu.Unlock()
+}
+
+// resumeStream starts watching the resume session's channel if it still fits the campaign
+// The resume session is consumed either way, it only applies to the first switch after startup
+func (m *Miner) resumeStream(ctx context.Context, campaign *twitch.Campaign) (*twitch.Stream, *twitch.WatchingSession) {
+	m.mu.Lock()
+	resume := m.resumeSession
+	m.resumeSession = nil
+	m.mu.Unlock()
+
+	if resume == nil {
+		return nil, nil
+	}
+
+	if resume.GameName != "" && resume.GameName != campaign.Game.Name {
+		logrus.Debugf("Not resuming %s: session is for %s, not %s", resume.ChannelLogin, resume.GameName, campaign.Game.Name)
+		return nil, nil
+	}
+
+	// StartWatching fails for offline channels, so it doubles as the live check
+	watchingSession, err := m.twitchClient.StartWatching(ctx, resume.ChannelLogin)
+	if err != nil {
+		logrus.Debugf("Not resuming %s: %v", resume.ChannelLogin, err)
+		return nil, nil
+	}
+
+	logrus.Infof("Resuming drop session on %s", resume.ChannelLogin)
+	stream := &twitch.Stream{
+		UserID:    resume.ChannelID,
+		UserLogin: resume.ChannelLogin,
+		UserName:  resume.ChannelLogin,
+		GameName:  campaign.Game.Name,
+	}
+	return stream, watchingSession
+}
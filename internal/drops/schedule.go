@@ -0,0 +1,216 @@
+package drops
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How far ahead the next schedule change is looked for; a schedule repeats every week
+const scheduleLookahead = 8 * 24 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// scheduleWindow is a parsed config.ScheduleWindow, start and end in minutes after midnight
+type scheduleWindow struct {
+	days  [7]bool
+	start int
+	end   int // before start when the window ends the next day
+}
+
+// Schedule is the set of windows the miner watches in; an empty schedule is always active
+type Schedule []scheduleWindow
+
+// parseClock parses "HH:MM" into minutes after midnight, "24:00" included
+func parseClock(clock string) (int, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(clock, "%d:%d", &hours, &minutes); err != nil || len(clock) != 5 {
+		return 0, fmt.Errorf("invalid time %q, must be HH:MM", clock)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("invalid time %q, must be between 00:00 and 24:00", clock)
+	}
+	return hours*60 + minutes, nil
+}
+
+// ParseSchedule validates the schedule windows from the settings
+func ParseSchedule(windows []config.ScheduleWindow) (Schedule, error) {
+	schedule := make(Schedule, 0, len(windows))
+	for i, window := range windows {
+		start, err := parseClock(window.Start)
+		if err != nil {
+			return nil, fmt.Errorf("window %d start: %w", i+1, err)
+		}
+		end, err := parseClock(window.End)
+		if err != nil {
+			return nil, fmt.Errorf("window %d end: %w", i+1, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("window %d is empty, start and end are both %s", i+1, window.Start)
+		}
+
+		parsed := scheduleWindow{start: start, end: end}
+		if len(window.Days) == 0 {
+			parsed.days = [7]bool{true, true, true, true, true, true, true}
+		}
+		for _, day := range window.Days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return nil, fmt.Errorf("window %d: invalid day %q, must be mon, tue, wed, thu, fri, sat or sun", i+1, day)
+			}
+			parsed.days[weekday] = true
+		}
+		schedule = append(schedule, parsed)
+	}
+	return schedule, nil
+}
+
+// Active reports whether now is inside one of the windows
+func (s Schedule) Active(now time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	clock := now.Hour()*60 + now.Minute()
+	today := now.Weekday()
+	yesterday := (today + 6) % 7
+	for _, window := range s {
+		if window.start < window.end {
+			if window.days[today] && clock >= window.start && clock < window.end {
+				return true
+			}
+			continue
+		}
+		// Past midnight, the window belongs to the day it starts on
+		if (window.days[today] && clock >= window.start) || (window.days[yesterday] && clock < window.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextChange returns when the schedule next turns on or off, zero when it never does
+func (s Schedule) NextChange(now time.Time) time.Time {
+	if len(s) == 0 {
+		return time.Time{}
+	}
+
+	active := s.Active(now)
+	// Windows start and end on whole minutes
+	for t := now.Truncate(time.Minute).Add(time.Minute); t.Sub(now) < scheduleLookahead; t = t.Add(time.Minute) {
+		if s.Active(t) != active {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Elapse returns when d of watching time has passed from start, skipping the time outside the windows
+// It is zero when the schedule doesn't turn active again within the lookahead
+func (s Schedule) Elapse(start time.Time, d time.Duration) time.Time {
+	t := start
+	for d > 0 {
+		next := s.NextChange(t)
+		if !s.Active(t) {
+			if next.IsZero() {
+				return time.Time{}
+			}
+			t = next
+			continue
+		}
+		if next.IsZero() || next.Sub(t) >= d {
+			return t.Add(d)
+		}
+		d -= next.Sub(t)
+		t = next
+	}
+	return t
+}
+
+// applySchedule starts or stops watching when the schedule changes and publishes the next change,
+// returning whether the miner may watch now
+func (m *Miner) applySchedule() bool {
+	m.mu.RLock()
+	schedule := m.config.Schedule
+	m.mu.RUnlock()
+
+	now := time.Now()
+	active := schedule.Active(now)
+	var nextChange *time.Time
+	if next := schedule.NextChange(now); !next.IsZero() {
+		nextChange = &next
+	}
+
+	m.statusMu.RLock()
+	wasOutside := m.status.OutsideSchedule
+	previousChange := m.status.NextScheduleChange
+	m.statusMu.RUnlock()
+
+	if active != wasOutside && sameTime(previousChange, nextChange) {
+		return active
+	}
+
+	if active && wasOutside {
+		logrus.Info("Farming schedule started, resuming watching")
+		m.logEvent(logrus.InfoLevel, LogEventSchedule, "", "", "Farming schedule started")
+	}
+	if !active && !wasOutside {
+		logrus.Info("Outside the farming schedule, stopping watching")
+		m.logEvent(logrus.InfoLevel, LogEventSchedule, "", "", "Farming schedule ended")
+		m.stopWatching()
+	}
+
+	m.updateStatus(func(s *MinerStatus) {
+		s.OutsideSchedule = !active
+		s.NextScheduleChange = nextChange
+		if !active {
+			s.CurrentStream = nil
+			s.CurrentCampaign = nil
+			s.ActiveDrops = []ActiveDrop{}
+		}
+	})
+	return active
+}
+
+// sameTime compares optional times
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// stopWatching drops the current campaign, stream and session, so the next check picks them anew
+func (m *Miner) stopWatching() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.currentCampaign = nil
+	m.currentStream = nil
+	m.currentSession = nil
+	m.watchingSession = nil
+	m.clearHeartbeats()
+	m.invalidateCampaignsCache()
+	if m.chat != nil {
+		m.chat.Leave()
+	}
+}
+
+// outsideSchedule reports whether the schedule last kept the miner from watching
+func (m *Miner) outsideSchedule() bool {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+	return m.status.OutsideSchedule
+}
@@ -0,0 +1,153 @@
+package drops
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding the past mining sessions
+const sessionHistoryDocument = "session_history"
+
+// Oldest records are dropped once the history grows past this
+const maxSessionHistory = 1000
+
+// SessionRecord is the part of a mining session spent on one stream, kept for /api/sessions
+type SessionRecord struct {
+	SessionID      string    `json:"session_id"`
+	Channel        string    `json:"channel"`      // login
+	ChannelName    string    `json:"channel_name"` // display name
+	GameName       string    `json:"game_name,omitempty"`
+	CampaignID     string    `json:"campaign_id,omitempty"` // empty while farming channel points
+	CampaignName   string    `json:"campaign_name,omitempty"`
+	MinutesWatched float64   `json:"minutes_watched"`
+	StartedAt      time.Time `json:"started_at"`
+	EndedAt        time.Time `json:"ended_at"` // last watch request, moving on while active
+	Active         bool      `json:"active"`
+}
+
+// SessionHistoryQuery filters and pages the session history
+type SessionHistoryQuery struct {
+	Game   string // game name, case insensitive
+	Limit  int    // 0 for all
+	Offset int    // records skipped, newest first
+}
+
+// sessionHistory keeps the past mining sessions in memory and in sync with the storage document
+type sessionHistory struct {
+	mu        sync.Mutex
+	store     storage.Store
+	sessions  []SessionRecord // oldest first
+	lastSaved time.Time
+}
+
+// loadSessionHistory reads the persisted session history from storage
+func (m *Miner) loadSessionHistory(store storage.Store) {
+	sessions := []SessionRecord{}
+	if err := store.Load(sessionHistoryDocument, &sessions); err != nil {
+		logrus.Errorf("Failed to load session history: %v", err)
+	}
+	// Records active when the process stopped have ended with it
+	for i := range sessions {
+		sessions[i].Active = false
+	}
+
+	h := &m.sessions
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.store = store
+	h.sessions = append(sessions, h.sessions...)
+	h.trimLocked()
+}
+
+// recordSessionWatch extends the active record by the time since its last watch request, or starts a new one
+// when the mining session or stream changed or watching paused
+func (m *Miner) recordSessionWatch() {
+	m.mu.RLock()
+	stream, campaign, current := m.currentStream, m.currentCampaign, m.currentSession
+	m.mu.RUnlock()
+	if stream == nil || current == nil {
+		return
+	}
+
+	session := SessionRecord{SessionID: current.ID, Channel: stream.UserLogin, ChannelName: stream.UserName, GameName: stream.GameName}
+	if campaign != nil {
+		session.CampaignID = campaign.ID
+		session.CampaignName = campaign.Name
+		session.GameName = campaign.Game.Name
+	}
+
+	now := time.Now()
+	h := &m.sessions
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if n := len(h.sessions); n > 0 && h.sessions[n-1].Active {
+		last := &h.sessions[n-1]
+		gap := now.Sub(last.EndedAt)
+		if last.SessionID == session.SessionID && last.Channel == session.Channel && gap > 0 && gap <= maxWatchGap {
+			last.MinutesWatched += gap.Minutes()
+			last.EndedAt = now
+			if now.Sub(h.lastSaved) >= statsSaveInterval {
+				h.saveLocked(now)
+			}
+			return
+		}
+		last.Active = false
+	}
+
+	session.StartedAt = now
+	session.EndedAt = now
+	session.Active = true
+	h.sessions = append(h.sessions, session)
+	h.trimLocked()
+	h.saveLocked(now)
+}
+
+// QuerySessionHistory returns the records matching a query, newest first, and how many match in total
+func (m *Miner) QuerySessionHistory(query SessionHistoryQuery) ([]SessionRecord, int) {
+	now := time.Now()
+	h := &m.sessions
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	matching := []SessionRecord{}
+	for i := len(h.sessions) - 1; i >= 0; i-- {
+		session := h.sessions[i]
+		if query.Game != "" && !strings.EqualFold(session.GameName, query.Game) {
+			continue
+		}
+		// A record stays active until the next watch request, which stops coming once watching stops
+		session.Active = session.Active && now.Sub(session.EndedAt) <= maxWatchGap
+		matching = append(matching, session)
+	}
+	total := len(matching)
+
+	start := min(max(query.Offset, 0), total)
+	end := total
+	if query.Limit > 0 && start+query.Limit < end {
+		end = start + query.Limit
+	}
+	return matching[start:end], total
+}
+
+// saveLocked writes the sessions to storage, the caller holds mu
+func (h *sessionHistory) saveLocked(now time.Time) {
+	if h.store == nil {
+		return
+	}
+	h.lastSaved = now
+	if err := h.store.Save(sessionHistoryDocument, h.sessions); err != nil {
+		logrus.Errorf("Failed to save session history: %v", err)
+	}
+}
+
+func (h *sessionHistory) trimLocked() {
+	if len(h.sessions) > maxSessionHistory {
+		h.sessions = append([]SessionRecord(nil), h.sessions[len(h.sessions)-maxSessionHistory:]...)
+	}
+}
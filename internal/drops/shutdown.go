@@ -0,0 +1,73 @@
+package drops
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Shutdown stops the miner for good: the watch loop ends, the active session is closed with the minutes
+// watched, the final status is saved with the campaign so the next start resumes it, and the documents that
+// are only written every few minutes are flushed. It returns once that is done, or ctx expires.
+func (m *Miner) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.shuttingDown = true
+	running := m.isRunning
+	done := m.doneChan
+	if running {
+		select {
+		case <-m.stopChan:
+			// Stop was already called, the loop is on its way out
+		default:
+			close(m.stopChan)
+		}
+	}
+	m.mu.Unlock()
+
+	if running && done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	m.flush()
+	return nil
+}
+
+// finalizeSession records the end of a session interrupted by Shutdown; callers must hold m.mu
+func (m *Miner) finalizeSession(session *MiningSession, minutesWatched int) {
+	ended := *session
+	now := time.Now()
+	ended.Status = "interrupted"
+	ended.EndedAt = &now
+	ended.MinutesWatched = minutesWatched
+	m.saveSession(&ended)
+	logrus.Infof("Closed mining session %s after %d minutes", session.ID, minutesWatched)
+}
+
+// flush writes the stats, session history and bandwidth documents, which are otherwise only saved every few minutes
+func (m *Miner) flush() {
+	now := time.Now()
+
+	m.stats.mu.Lock()
+	m.stats.saveLocked(now)
+	m.stats.mu.Unlock()
+
+	m.sessions.mu.Lock()
+	m.sessions.saveLocked(now)
+	m.sessions.mu.Unlock()
+
+	b := &m.bandwidth
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.store == nil {
+		return
+	}
+	b.lastSaved = now
+	if err := b.store.Save(bandwidthDocument, b.days); err != nil {
+		logrus.Errorf("Failed to save bandwidth usage: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package drops
+
+import (
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding the last miner state
+const minerStateDocument = "miner_state"
+
+// MinerState is what the miner persists to pick up where it left off after a restart
+type MinerState struct {
+	Status      *MinerStatus   `json:"status"`
+	Session     *MiningSession `json:"session,omitempty"`
+	DropMinutes map[string]int `json:"drop_minutes"` // current minutes by drop ID
+	SavedAt     time.Time      `json:"saved_at"`
+}
+
+// minerState keeps the persisted state in sync with the storage document
+type minerState struct {
+	mu       sync.Mutex
+	store    storage.Store
+	data     MinerState
+	restored *MiningSession // session from the previous run, applied on the next Start
+}
+
+// loadState restores the last status for display and keeps the session for Start
+func (m *Miner) loadState(store storage.Store) {
+	var data MinerState
+	if err := store.Load(minerStateDocument, &data); err != nil {
+		logrus.Errorf("Failed to load miner state: %v", err)
+	}
+
+	m.state.mu.Lock()
+	m.state.store = store
+	m.state.data = data
+	m.state.restored = data.Session
+	m.state.mu.Unlock()
+
+	if data.Status == nil {
+		return
+	}
+
+	// Nothing is running or failing until this run says so
+	status := *data.Status
+	status.IsRunning = false
+	status.ErrorMessage = ""
+
+	m.statusMu.Lock()
+	m.status = &status
+	m.statusMu.Unlock()
+}
+
+// restoreState resumes the previous run's campaign and session so the first check doesn't treat
+// the campaign as new; the stream is picked again, preferring the channel the drop session is on
+func (m *Miner) restoreState() {
+	m.state.mu.Lock()
+	session := m.state.restored
+	m.state.restored = nil
+	m.state.mu.Unlock()
+
+	if session == nil || session.CampaignID == "" {
+		return
+	}
+
+	user := m.twitchClient.GetUser()
+	if user == nil || user.ID != session.UserID {
+		return
+	}
+
+	status := m.GetStatus()
+	if status.CurrentCampaign == nil || status.CurrentCampaign.ID != session.CampaignID {
+		return
+	}
+
+	m.mu.Lock()
+	if m.currentCampaign == nil {
+		session.Status = "active"
+		session.EndedAt = nil
+		session.MinutesWatched = 0
+		m.currentCampaign = status.CurrentCampaign
+		m.currentSession = session
+		logrus.Infof("Restored session %s for campaign %s", session.ID, status.CurrentCampaign.Name)
+	}
+	m.mu.Unlock()
+}
+
+// restoredSessionFor returns the current session if it was restored for campaignID, so a switch back to
+// the same campaign keeps its start time; callers must hold m.mu
+func (m *Miner) restoredSessionFor(campaignID string) *MiningSession {
+	if m.currentSession != nil && m.currentSession.CampaignID == campaignID && m.currentStream == nil {
+		return m.currentSession
+	}
+	return nil
+}
+
+// saveStatus persists a status snapshot along with the per-drop minutes
+func (m *Miner) saveStatus(status *MinerStatus) {
+	dropMinutes := make(map[string]int, len(status.ActiveDrops))
+	for _, drop := range status.ActiveDrops {
+		dropMinutes[drop.ID] = drop.CurrentMinutes
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	m.state.data.Status = status
+	m.state.data.DropMinutes = dropMinutes
+	m.saveStateLocked()
+}
+
+// saveSession persists the active mining session; it only takes the state lock, so it is safe to call with m.mu held
+func (m *Miner) saveSession(session *MiningSession) {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	m.state.data.Session = session
+	m.saveStateLocked()
+}
+
+func (m *Miner) saveStateLocked() {
+	if m.state.store == nil {
+		return
+	}
+
+	m.state.data.SavedAt = time.Now()
+	if err := m.state.store.Save(minerStateDocument, m.state.data); err != nil {
+		logrus.Errorf("Failed to save miner state: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package drops
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// runtimeCounters tracks miner activity since the process started
+type runtimeCounters struct {
+	processStartedAt time.Time
+	minerStartedAt   atomic.Int64 // unix nanoseconds, 0 while stopped
+	loopBeatAt       atomic.Int64 // unix nanoseconds the mining loop last came around, 0 while stopped
+	watchRequests    atomic.Int64
+	switches         atomic.Int64
+	dropsClaimed     atomic.Int64
+	failures         atomic.Int64
+}
+
+// RuntimeStats is a snapshot of the miner's uptime and throughput counters
+type RuntimeStats struct {
+	ProcessStartedAt time.Time      `json:"process_started_at"`
+	ProcessUptime    float64        `json:"process_uptime_seconds"`
+	MinerStartedAt   time.Time      `json:"miner_started_at,omitempty"`
+	MinerUptime      float64        `json:"miner_uptime_seconds"`
+	WatchRequests    int64          `json:"watch_requests"`
+	Switches         int64          `json:"switches"`
+	DropsClaimed     int64          `json:"drops_claimed"`
+	Failures         int64          `json:"failures"`
+	Bandwidth        BandwidthUsage `json:"bandwidth"`
+}
+
+// GetRuntimeStats returns the counters accumulated since the process started
+func (m *Miner) GetRuntimeStats() RuntimeStats {
+	now := time.Now()
+	stats := RuntimeStats{
+		ProcessStartedAt: m.counters.processStartedAt,
+		ProcessUptime:    now.Sub(m.counters.processStartedAt).Seconds(),
+		WatchRequests:    m.counters.watchRequests.Load(),
+		Switches:         m.counters.switches.Load(),
+		DropsClaimed:     m.counters.dropsClaimed.Load(),
+		Failures:         m.counters.failures.Load(),
+		Bandwidth:        m.GetBandwidthUsage(),
+	}
+
+	if startedAt := m.counters.minerStartedAt.Load(); startedAt != 0 {
+		stats.MinerStartedAt = time.Unix(0, startedAt)
+		stats.MinerUptime = now.Sub(stats.MinerStartedAt).Seconds()
+	}
+
+	return stats
+}
+
+// LoopHeartbeat returns when the mining loop last finished a step and went back to waiting, zero while the miner
+// is stopped; a heartbeat that stops moving means a step is stuck
+func (m *Miner) LoopHeartbeat() time.Time {
+	if beat := m.counters.loopBeatAt.Load(); beat != 0 {
+		return time.Unix(0, beat)
+	}
+	return time.Time{}
+}
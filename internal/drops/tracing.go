@@ -0,0 +1,20 @@
+package drops
+
+import (
+	"twitchdropsfarmer/internal/twitch"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Spans around the miner's checks, watch requests and claims, exported when tracing is set up
+var tracer = otel.Tracer("twitchdropsfarmer/internal/drops")
+
+// campaignAttributes describes a campaign on a span
+func campaignAttributes(campaign *twitch.Campaign) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("campaign.id", campaign.ID),
+		attribute.String("campaign.name", campaign.Name),
+		attribute.String("game.name", campaign.Game.Name),
+	}
+}
@@ -0,0 +1,110 @@
+package drops
+
+import (
+	"sort"
+	"time"
+
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How long after its start time an upcoming campaign is checked, Twitch takes a moment to list it as ACTIVE
+const campaignStartDelay = 30 * time.Second
+
+// UpcomingCampaign is a listed campaign that hasn't started yet
+type UpcomingCampaign struct {
+	twitch.Campaign
+	AutoStart bool `json:"auto_start"` // the miner switches to it the moment it starts
+}
+
+// UpcomingCampaigns returns the campaigns starting in the future, soonest first
+func (m *Miner) UpcomingCampaigns(campaigns []twitch.Campaign) []UpcomingCampaign {
+	now := time.Now()
+	upcoming := []UpcomingCampaign{}
+	for _, campaign := range campaigns {
+		if campaign.StartsAt.After(now) {
+			upcoming = append(upcoming, UpcomingCampaign{Campaign: campaign, AutoStart: m.shouldAutoStart(&campaign)})
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].StartsAt.Before(upcoming[j].StartsAt)
+	})
+	return upcoming
+}
+
+// listRank orders campaigns by pin and priority list position only, as upcoming campaigns have no drops to score yet
+func (m *Miner) listRank(campaign *twitch.Campaign) int {
+	if pinPriority, pinned := m.pinPriority(campaign.ID); pinned {
+		return pinnedScore + pinPriority
+	}
+	if m.isGameExcluded(campaign.Game) {
+		return 0
+	}
+	if index := m.getGamePriorityIndex(campaign.Game); index >= 0 {
+		return 1000 - index*10
+	}
+	return 0
+}
+
+// shouldAutoStart reports whether an upcoming campaign ranks above the one being mined
+func (m *Miner) shouldAutoStart(campaign *twitch.Campaign) bool {
+	if !campaign.Self.IsAccountConnected || m.isCampaignIgnored(campaign.ID) {
+		return false
+	}
+	rank := m.listRank(campaign)
+	if rank == 0 {
+		return false
+	}
+
+	m.mu.RLock()
+	current := m.currentCampaign
+	m.mu.RUnlock()
+	return current == nil || rank > m.listRank(current)
+}
+
+// scheduleCampaignStart sets a timer for the first upcoming campaign worth switching to,
+// so it is picked up when it starts instead of at the next check
+func (m *Miner) scheduleCampaignStart(campaigns []twitch.Campaign) {
+	var next *UpcomingCampaign
+	for _, campaign := range m.UpcomingCampaigns(campaigns) {
+		if campaign.AutoStart {
+			next = &campaign
+			break
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var at time.Time
+	if next != nil {
+		at = next.StartsAt.Add(campaignStartDelay)
+	}
+	if at.Equal(m.campaignStartAt) {
+		return
+	}
+
+	m.stopCampaignStart()
+	if next == nil {
+		return
+	}
+	m.campaignStartAt = at
+
+	logrus.Infof("Will switch to %s (%s) when it starts at %s", next.Name, next.Game.Name, next.StartsAt.Local().Format("Jan 2 15:04"))
+	m.campaignStartTimer = time.AfterFunc(time.Until(at), func() {
+		select {
+		case m.campaignStartChan <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// stopCampaignStart cancels the upcoming campaign timer, the caller holds m.mu
+func (m *Miner) stopCampaignStart() {
+	if m.campaignStartTimer != nil {
+		m.campaignStartTimer.Stop()
+		m.campaignStartTimer = nil
+	}
+	m.campaignStartAt = time.Time{}
+}
@@ -0,0 +1,149 @@
+package drops
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How often the watchdog asks Twitch for the drop session's minutes
+const watchdogCheckInterval = time.Minute
+
+// Recoveries in a row without progress after which the stall is reported
+const stallNotifyAfter = 2
+
+// progressWatchdog tracks when the watched drop session last gained minutes
+type progressWatchdog struct {
+	mu          sync.Mutex
+	channel     string // login of the channel being tracked
+	dropID      string
+	minutes     int
+	lastAdvance time.Time
+	recoveries  int  // switches and token refreshes since the last progress
+	notified    bool // the stall was reported, cleared once minutes come in again
+}
+
+// reset stops tracking, e.g. while nothing is watched; the caller holds mu
+func (w *progressWatchdog) reset() {
+	*w = progressWatchdog{}
+}
+
+// checkProgress compares the drop session's minutes with the last check and recovers when they stopped moving
+// for StallTimeout while watching
+func (m *Miner) checkProgress(ctx context.Context) {
+	m.mu.RLock()
+	timeout := m.config.StallTimeout
+	campaign := m.currentCampaign
+	stream := m.currentStream
+	watching := m.watchingSession != nil
+	m.mu.RUnlock()
+
+	w := &m.watchdog
+	if timeout <= 0 || campaign == nil || stream == nil || !watching || m.outsideSchedule() || m.isPaused() {
+		w.mu.Lock()
+		w.reset()
+		w.mu.Unlock()
+		return
+	}
+
+	progress, err := m.twitchClient.GetCurrentDropProgress(ctx, stream.UserID)
+	if err != nil {
+		// Can't tell either way, the minutes are compared again at the next check
+		logrus.Debugf("Watchdog couldn't get drop progress: %v", err)
+		return
+	}
+
+	now := time.Now()
+	w.mu.Lock()
+	if w.channel != stream.UserLogin {
+		// New stream, give it the full timeout; recoveries carry over so a stall across switches is still reported
+		w.channel, w.dropID, w.minutes, w.lastAdvance = stream.UserLogin, progress.DropID, progress.CurrentMinutesWatched, now
+		w.mu.Unlock()
+		return
+	}
+	if progress.DropID != w.dropID || progress.CurrentMinutesWatched > w.minutes {
+		recovered := w.notified
+		w.dropID, w.minutes, w.lastAdvance = progress.DropID, progress.CurrentMinutesWatched, now
+		w.recoveries, w.notified = 0, false
+		w.mu.Unlock()
+		if recovered {
+			logrus.Infof("Drop progress resumed on %s", stream.UserLogin)
+			m.clearError(stalledMessage(campaign))
+		}
+		return
+	}
+	stalledFor := now.Sub(w.lastAdvance)
+	if stalledFor < timeout {
+		w.mu.Unlock()
+		return
+	}
+	failed := w.recoveries
+	report := failed >= stallNotifyAfter && !w.notified
+	if report {
+		w.notified = true
+	}
+	w.recoveries++
+	minutes := w.minutes
+	// The next stream, or the refreshed session, gets the full timeout again
+	w.channel = ""
+	w.mu.Unlock()
+
+	logrus.Warnf("No drop progress on %s for %s (stuck at %d minutes), recovering", stream.UserLogin, stalledFor.Round(time.Minute), minutes)
+	m.logEvent(logrus.WarnLevel, LogEventStall, campaign.Name, stream.UserLogin, "No drop progress for %s, recovering", stalledFor.Round(time.Minute))
+
+	if report {
+		// Shown until minutes come in again, with its own notification instead of a miner error one
+		message := stalledMessage(campaign)
+		m.updateStatus(func(s *MinerStatus) {
+			s.ErrorMessage = message
+		})
+		m.publish(events.Event{
+			Type:     events.MiningStalled,
+			Title:    "Drop progress stalled",
+			Message:  fmt.Sprintf("%s, still no progress after %d stream switches or playback token refreshes", message, failed),
+			Campaign: eventCampaign(campaign),
+			Channel:  stream.UserLogin,
+		})
+	}
+
+	m.recoverStall(ctx, campaign, stream)
+}
+
+// recoverStall moves off a stream Twitch stopped counting, or fetches a new playback token for it when the
+// campaign has no other live stream
+func (m *Miner) recoverStall(ctx context.Context, campaign *twitch.Campaign, stream *twitch.Stream) {
+	// Skipped like an offline channel, so findStream picks another one
+	m.markOffline(stream.UserLogin)
+	err := m.switchToCampaign(ctx, campaign)
+	if err == nil {
+		return
+	}
+	logrus.Debugf("No other stream for %s: %v", campaign.Name, err)
+
+	watchingSession, err := m.twitchClient.StartWatching(ctx, stream.UserLogin)
+	if err != nil {
+		// Most likely offline now, the next check picks another campaign
+		logrus.Warnf("Failed to restart watching %s: %v", stream.UserLogin, err)
+		m.clearWatching()
+		m.invalidateCampaignsCache()
+		return
+	}
+
+	m.mu.Lock()
+	if m.currentStream == stream {
+		m.watchingSession = watchingSession
+	}
+	m.mu.Unlock()
+	logrus.Infof("Refreshed the playback token for %s", stream.UserLogin)
+}
+
+// stalledMessage is the status error shown while a campaign's minutes aren't counted
+func stalledMessage(campaign *twitch.Campaign) string {
+	return fmt.Sprintf("Twitch isn't counting watch time for %s", campaign.Name)
+}
@@ -0,0 +1,169 @@
+package drops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding the campaigns seen so far
+const knownCampaignsDocument = "known_campaigns"
+
+// Which new campaigns are announced, see MinerConfig.CampaignAlerts
+const (
+	CampaignAlertsPriority = "priority" // campaigns of priority games only
+	CampaignAlertsAll      = "all"
+	CampaignAlertsOff      = "off"
+)
+
+// ValidCampaignAlerts reports whether mode is a known campaign alerts setting
+func ValidCampaignAlerts(mode string) bool {
+	switch mode {
+	case CampaignAlertsPriority, CampaignAlertsAll, CampaignAlertsOff:
+		return true
+	}
+	return false
+}
+
+// KnownCampaign is a campaign that was listed at some point
+type KnownCampaign struct {
+	Name      string    `json:"name"`
+	GameName  string    `json:"game_name"`
+	EndsAt    time.Time `json:"ends_at"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// knownCampaigns keeps the campaigns seen so far in sync with the storage document
+type knownCampaigns struct {
+	mu      sync.Mutex
+	store   storage.Store
+	seeded  bool // false until the first listing, which is recorded without alerts
+	entries map[string]*KnownCampaign
+}
+
+// loadKnownCampaigns reads the campaigns seen so far from storage
+func (m *Miner) loadKnownCampaigns(store storage.Store) {
+	var entries map[string]*KnownCampaign
+	if err := store.Load(knownCampaignsDocument, &entries); err != nil {
+		logrus.Errorf("Failed to load known campaigns: %v", err)
+	}
+
+	m.known.mu.Lock()
+	defer m.known.mu.Unlock()
+	m.known.store = store
+	m.known.seeded = entries != nil
+	m.known.entries = entries
+	if entries == nil {
+		m.known.entries = make(map[string]*KnownCampaign)
+	}
+}
+
+// diffCampaigns records the listed campaigns and returns the ones never seen before
+// Ended campaigns are forgotten; the first listing ever is recorded without returning any
+func (m *Miner) diffCampaigns(campaigns []twitch.Campaign) []twitch.Campaign {
+	k := &m.known
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.entries == nil {
+		k.entries = make(map[string]*KnownCampaign)
+	}
+
+	now := time.Now()
+	changed := false
+	var added []twitch.Campaign
+	for _, campaign := range campaigns {
+		if _, ok := k.entries[campaign.ID]; ok {
+			continue
+		}
+		k.entries[campaign.ID] = &KnownCampaign{
+			Name:      campaign.Name,
+			GameName:  campaign.Game.Name,
+			EndsAt:    campaign.EndsAt,
+			FirstSeen: now,
+		}
+		changed = true
+		if k.seeded {
+			added = append(added, campaign)
+		}
+	}
+	for id, entry := range k.entries {
+		if !entry.EndsAt.IsZero() && entry.EndsAt.Before(now) {
+			delete(k.entries, id)
+			changed = true
+		}
+	}
+
+	k.seeded = true
+	if changed && k.store != nil {
+		if err := k.store.Save(knownCampaignsDocument, k.entries); err != nil {
+			logrus.Errorf("Failed to save known campaigns: %v", err)
+		}
+	}
+	return added
+}
+
+// alertNewCampaigns announces campaigns that appeared since the last listing, as configured by CampaignAlerts
+func (m *Miner) alertNewCampaigns(ctx context.Context, campaigns []twitch.Campaign) {
+	added := m.diffCampaigns(campaigns)
+
+	m.mu.RLock()
+	mode := m.config.CampaignAlerts
+	m.mu.RUnlock()
+
+	for _, campaign := range added {
+		if mode == CampaignAlertsOff || (mode != CampaignAlertsAll && !m.isGamePriority(campaign.Game)) {
+			logrus.Debugf("New campaign %s (%s), not announced", campaign.Name, campaign.Game.Name)
+			continue
+		}
+
+		// The listing has no drops, the rewards come from the details
+		rewards := []string{}
+		if details, err := m.getCampaignDetails(ctx, campaign); err != nil {
+			logrus.Debugf("Failed to get rewards of new campaign %s: %v", campaign.Name, err)
+		} else {
+			rewards = CampaignRewards(details)
+		}
+
+		message := fmt.Sprintf("%s: %s, %s to %s", campaign.Game.Name, campaign.Name,
+			campaign.StartsAt.Local().Format("Jan 2 15:04"), campaign.EndsAt.Local().Format("Jan 2 15:04"))
+		if len(rewards) > 0 {
+			message += "\nRewards: " + strings.Join(rewards, ", ")
+		}
+
+		logrus.Infof("New campaign: %s (%s)", campaign.Name, campaign.Game.Name)
+		m.logEvent(logrus.InfoLevel, LogEventNewCampaign, campaign.Name, "", "New campaign for %s, ends %s", campaign.Game.Name, campaign.EndsAt.Local().Format("Jan 2 15:04"))
+		m.publish(events.Event{
+			Type:     events.NewCampaign,
+			Title:    "New drops campaign",
+			Message:  message,
+			ImageURL: campaign.ImageURL,
+			Campaign: eventCampaign(&campaign),
+		})
+	}
+}
+
+// CampaignRewards lists the reward names of a campaign in drop order, without duplicates
+func CampaignRewards(campaign *twitch.Campaign) []string {
+	seen := make(map[string]bool)
+	var rewards []string
+	for _, drop := range campaign.TimeBasedDrops {
+		for _, edge := range drop.BenefitEdges {
+			name := edge.Benefit.Name
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			rewards = append(rewards, name)
+		}
+	}
+	return rewards
+}
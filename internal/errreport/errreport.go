@@ -0,0 +1,126 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"twitchdropsfarmer/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of reports
+const (
+	KindPanic      = "panic"       // recovered from an HTTP handler
+	KindMinerError = "miner_error" // a miner operation that keeps failing
+)
+
+// Report is an error sent to Sentry or the error webhook
+type Report struct {
+	Kind      string    `json:"kind"`
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"`
+	Operation string    `json:"operation,omitempty"` // e.g. "mining_check", or the route of a panicking request
+	Account   string    `json:"account,omitempty"`   // Twitch login of the miner
+	Campaign  string    `json:"campaign,omitempty"`
+	Channel   string    `json:"channel,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Failures  int       `json:"failures,omitempty"` // failures in a row
+	Stack     string    `json:"stack,omitempty"`
+}
+
+// destination receives reports
+type destination interface {
+	Name() string
+	Send(ctx context.Context, report Report) error
+}
+
+// Reporter sends reports to the configured destinations; a nil Reporter drops them, so callers don't check
+// whether reporting is on
+type Reporter struct {
+	destinations []destination
+}
+
+// New creates a Reporter for SENTRY_DSN and ERROR_WEBHOOK_URL, or returns nil when neither is set
+func New(cfg *config.Config) (*Reporter, error) {
+	r := &Reporter{}
+	if cfg.SentryDSN != "" {
+		sentry, err := newSentry(cfg.SentryDSN)
+		if err != nil {
+			return nil, err
+		}
+		r.destinations = append(r.destinations, sentry)
+	}
+	if cfg.ErrorWebhookURL != "" {
+		r.destinations = append(r.destinations, newWebhook(cfg.ErrorWebhookURL))
+	}
+	if len(r.destinations) == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// Capture sends a report in the background
+func (r *Reporter) Capture(report Report) {
+	if r == nil {
+		return
+	}
+	if report.Time.IsZero() {
+		report.Time = time.Now()
+	}
+
+	for _, dest := range r.destinations {
+		go func(dest destination) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := dest.Send(ctx, report); err != nil {
+				logrus.Warnf("Failed to send error report to %s: %v", dest.Name(), err)
+			}
+		}(dest)
+	}
+}
+
+// webhook posts reports as JSON
+type webhook struct {
+	url        string
+	httpClient *http.Client
+}
+
+func newWebhook(url string) *webhook {
+	return &webhook{
+		url:        url,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (w *webhook) Name() string {
+	return "webhook"
+}
+
+func (w *webhook) Send(ctx context.Context, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
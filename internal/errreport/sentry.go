@@ -0,0 +1,127 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// sentry sends reports as events to a Sentry (or Sentry-compatible, like GlitchTip) project
+type sentry struct {
+	endpoint   string // the project's envelope URL
+	dsn        string
+	publicKey  string
+	httpClient *http.Client
+}
+
+// newSentry parses a DSN of the form https://<key>@<host>/<project ID>
+func newSentry(dsn string) (*sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndexByte(path, '/')
+	projectID := path[slash+1:]
+	if projectID == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: no project ID")
+	}
+
+	return &sentry{
+		endpoint:   fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:slash], projectID),
+		dsn:        dsn,
+		publicKey:  u.User.Username(),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (s *sentry) Name() string {
+	return "sentry"
+}
+
+func (s *sentry) Send(ctx context.Context, report Report) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	eventID := hex.EncodeToString(id)
+
+	level := "error"
+	if report.Kind == KindPanic {
+		level = "fatal"
+	}
+	tags := map[string]string{"kind": report.Kind}
+	for key, value := range map[string]string{
+		"operation":  report.Operation,
+		"account":    report.Account,
+		"campaign":   report.Campaign,
+		"channel":    report.Channel,
+		"request_id": report.RequestID,
+	} {
+		if value != "" {
+			tags[key] = value
+		}
+	}
+	extra := map[string]interface{}{}
+	if report.Failures > 0 {
+		extra["failures"] = report.Failures
+	}
+	if report.Stack != "" {
+		extra["stack"] = report.Stack
+	}
+	hostname, _ := os.Hostname()
+
+	event := map[string]interface{}{
+		"event_id":    eventID,
+		"timestamp":   report.Time.UTC().Format(time.RFC3339),
+		"platform":    "go",
+		"level":       level,
+		"logger":      "twitchdropsfarmer",
+		"server_name": hostname,
+		"transaction": report.Operation,
+		"message":     map[string]string{"formatted": report.Message},
+		"tags":        tags,
+		"extra":       extra,
+		// Error messages carry IDs and counts, so the same failing operation is grouped as one issue
+		"fingerprint": []string{report.Kind, report.Operation},
+	}
+
+	// An envelope is newline-separated JSON: its header, then an item header and payload per item
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, part := range []interface{}{
+		map[string]string{"event_id": eventID, "dsn": s.dsn, "sent_at": time.Now().UTC().Format(time.RFC3339)},
+		map[string]string{"type": "event"},
+		event,
+	} {
+		if err := encoder.Encode(part); err != nil {
+			return fmt.Errorf("failed to encode Sentry event: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create Sentry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=twitchdropsfarmer/1.0, sentry_key=%s", s.publicKey))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Sentry event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
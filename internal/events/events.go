@@ -0,0 +1,152 @@
+// Package events is the bus the miners publish what happens to them on, and that notifications, the WebSocket
+// hub, stats and other sinks subscribe to.
+package events
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Type identifies what happened
+type Type string
+
+const (
+	DropClaimed       Type = "drop_claimed"
+	CampaignStarted   Type = "campaign_started"   // the miner switched to a campaign
+	CampaignCompleted Type = "campaign_completed" // the last drop of a campaign was claimed
+	StreamSwitched    Type = "stream_switched"
+	NewCampaign       Type = "new_campaign" // a campaign showed up, see campaign_alerts
+	MiningStalled     Type = "mining_stalled"
+	AuthExpired       Type = "auth_expired"
+	MinerError        Type = "miner_error"
+)
+
+// Types lists every event type
+var Types = []Type{
+	DropClaimed, CampaignStarted, CampaignCompleted, StreamSwitched, NewCampaign, MiningStalled, AuthExpired, MinerError,
+}
+
+// Event is something that happened to the miner of one account
+type Event struct {
+	Type      Type      `json:"type"`
+	Time      time.Time `json:"time"`
+	AccountID string    `json:"account_id"`      // "" for the primary account
+	Login     string    `json:"login,omitempty"` // Twitch login of the account
+
+	// What happened for people, as notifications show it
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	ImageURL string `json:"image_url,omitempty"`
+
+	// Set depending on the type
+	Campaign *Campaign `json:"campaign,omitempty"`
+	Drop     *Drop     `json:"drop,omitempty"`
+	Channel  string    `json:"channel,omitempty"` // login of the channel watched
+	Error    string    `json:"error,omitempty"`
+}
+
+// Campaign is the campaign an event is about; only GameName is set for claims Twitch didn't say the campaign of
+type Campaign struct {
+	ID       string     `json:"id,omitempty"`
+	Name     string     `json:"name,omitempty"`
+	GameName string     `json:"game_name,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// Drop is the drop of a drop_claimed event
+type Drop struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Rewards []string `json:"rewards,omitempty"`
+	Source  string   `json:"source"` // how it got claimed, e.g. "watch" or "pubsub"
+}
+
+// AuthExpiredEvent is published when a Twitch login expired and couldn't be refreshed
+func AuthExpiredEvent(accountID, login string) Event {
+	message := "The Twitch login expired and couldn't be refreshed, log in again to keep farming"
+	if login != "" {
+		message = fmt.Sprintf("The Twitch login of %s expired and couldn't be refreshed, log in again to keep farming", login)
+	}
+	return Event{Type: AuthExpired, AccountID: accountID, Login: login, Title: "Login required", Message: message}
+}
+
+// Sink receives the events published on a bus
+type Sink interface {
+	// Handle is called on the publisher's goroutine, so sinks doing I/O hand the event off and return
+	Handle(event Event)
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(event Event)
+
+// Handle calls f
+func (f SinkFunc) Handle(event Event) {
+	f(event)
+}
+
+// Bus hands every published event to every subscribed sink
+type Bus struct {
+	mu     sync.RWMutex
+	sinks  []subscription
+	nextID int
+}
+
+type subscription struct {
+	id   int
+	sink Sink
+}
+
+// NewBus creates a bus without sinks
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe adds a sink and returns the function removing it
+func (b *Bus) Subscribe(sink Sink) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.sinks = append(b.sinks, subscription{id: id, sink: sink})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.sinks {
+			if s.id == id {
+				b.sinks = append(b.sinks[:i:i], b.sinks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish hands the event to the sinks in the order they subscribed; a sink panicking doesn't keep the event
+// from the others, or bring down the publisher
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	sinks := append([]subscription(nil), b.sinks...)
+	b.mu.RUnlock()
+
+	for _, s := range sinks {
+		deliver(s.sink, event)
+	}
+}
+
+func deliver(sink Sink, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("Event sink %T panicked on %s: %v\n%s", sink, event.Type, r, debug.Stack())
+		}
+	}()
+	sink.Handle(event)
+}
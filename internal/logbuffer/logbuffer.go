@@ -0,0 +1,129 @@
+package logbuffer
+
+import (
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/logging"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSize is the number of entries kept when no size is configured
+const DefaultSize = 500
+
+// Entry is a single captured log line
+type Entry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Buffer is a logrus hook keeping the most recent log entries in a ring
+type Buffer struct {
+	mu      sync.RWMutex
+	entries []Entry
+	next    int  // index the next entry is written to
+	full    bool // whether the ring has wrapped
+}
+
+// New creates a buffer holding up to size entries
+func New(size int) *Buffer {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Buffer{entries: make([]Entry, size)}
+}
+
+// Levels captures every level the logger emits
+func (b *Buffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire stores the entry, overwriting the oldest one when the ring is full; entries below their module's log
+// level are left out
+func (b *Buffer) Fire(entry *logrus.Entry) error {
+	if !logging.Enabled(entry) {
+		return nil
+	}
+	captured := Entry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+	}
+	if len(entry.Data) > 0 {
+		captured.Fields = make(map[string]interface{}, len(entry.Data))
+		for key, value := range entry.Data {
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			captured.Fields[key] = value
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = captured
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	return nil
+}
+
+// Entries returns up to limit of the most recent entries, oldest first
+// A limit of zero or less returns the whole buffer
+func (b *Buffer) Entries(limit int) []Entry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ordered := b.orderedLocked()
+	if limit > 0 && len(ordered) > limit {
+		ordered = ordered[len(ordered)-limit:]
+	}
+	return ordered
+}
+
+// Size returns the capacity of the ring
+func (b *Buffer) Size() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.entries)
+}
+
+// Resize changes the capacity, keeping the most recent entries
+func (b *Buffer) Resize(size int) {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if size == len(b.entries) {
+		return
+	}
+
+	ordered := b.orderedLocked()
+	if len(ordered) > size {
+		ordered = ordered[len(ordered)-size:]
+	}
+
+	b.entries = make([]Entry, size)
+	copy(b.entries, ordered)
+	b.next = len(ordered) % size
+	b.full = len(ordered) == size
+}
+
+func (b *Buffer) orderedLocked() []Entry {
+	if !b.full {
+		return append([]Entry(nil), b.entries[:b.next]...)
+	}
+
+	ordered := make([]Entry, 0, len(b.entries))
+	ordered = append(ordered, b.entries[b.next:]...)
+	ordered = append(ordered, b.entries[:b.next]...)
+	return ordered
+}
@@ -0,0 +1,21 @@
+package logging
+
+import (
+	"context"
+)
+
+// Field naming the API request an entry was logged for, from the context passed to logrus.WithContext
+const RequestIDField = "request_id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying an API request's ID, for the log lines of the calls made with it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
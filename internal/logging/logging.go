@@ -0,0 +1,197 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"twitchdropsfarmer/internal/config"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Field every log entry gets, naming the package that logged it, e.g. "twitch" or "drops"
+const ModuleField = "module"
+
+// Module names accepted in log_levels besides package names, for the packages they stand for
+var moduleAliases = map[string]string{
+	"miner": "drops",
+}
+
+// logger holds what Configure last applied
+type logger struct {
+	mu      sync.RWMutex
+	level   logrus.Level            // for modules without their own level
+	modules map[string]logrus.Level // by package name
+	file    *lumberjack.Logger
+}
+
+var current = logger{level: logrus.InfoLevel}
+
+var hookOnce sync.Once
+
+// ParseLevels checks a default level and the per-module ones, returning them keyed by package name
+func ParseLevels(level string, modules map[string]string) (logrus.Level, map[string]logrus.Level, error) {
+	defaultLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid log level %q", level)
+	}
+	levels := make(map[string]logrus.Level, len(modules))
+	for module, name := range modules {
+		moduleLevel, err := logrus.ParseLevel(name)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid log level %q for %s", name, module)
+		}
+		module = strings.ToLower(module)
+		if alias, ok := moduleAliases[module]; ok {
+			module = alias
+		}
+		levels[module] = moduleLevel
+	}
+	return defaultLevel, levels, nil
+}
+
+// Configure applies the logging settings: levels, text or JSON lines, and the console and rotated file outputs
+func Configure(cfg *config.Config) error {
+	defaultLevel, modules, err := ParseLevels(cfg.LogLevel, cfg.LogLevels)
+	if err != nil {
+		return err
+	}
+
+	hookOnce.Do(func() {
+		// Added before any other hook, so they see the module too
+		logrus.SetReportCaller(true)
+		logrus.AddHook(moduleHook{})
+	})
+
+	// Entries are filtered per module, so logrus lets through the most verbose level any module wants
+	verbosest := defaultLevel
+	for _, level := range modules {
+		if level > verbosest {
+			verbosest = level
+		}
+	}
+
+	var formatter logrus.Formatter = &logrus.TextFormatter{FullTimestamp: true}
+	if cfg.LogJSON {
+		formatter = &logrus.JSONFormatter{}
+	}
+
+	// logrus calls Enabled with its own lock held, so it isn't touched while holding ours
+	current.mu.Lock()
+	current.level = defaultLevel
+	current.modules = modules
+	var replaced *lumberjack.Logger
+	if current.file != nil && (cfg.LogFile == "" || current.file.Filename != cfg.LogFile ||
+		current.file.MaxSize != cfg.LogMaxSizeMB || current.file.MaxAge != cfg.LogMaxAgeDays ||
+		current.file.MaxBackups != cfg.LogMaxBackups) {
+		replaced, current.file = current.file, nil
+	}
+	if current.file == nil && cfg.LogFile != "" {
+		current.file = &lumberjack.Logger{
+			Filename:   cfg.LogFile,
+			MaxSize:    cfg.LogMaxSizeMB,
+			MaxAge:     cfg.LogMaxAgeDays,
+			MaxBackups: cfg.LogMaxBackups,
+		}
+	}
+	file := current.file
+	current.mu.Unlock()
+
+	var outputs []io.Writer
+	if cfg.LogToConsole {
+		outputs = append(outputs, os.Stderr)
+	}
+	if file != nil {
+		outputs = append(outputs, file)
+	}
+
+	logrus.SetLevel(verbosest)
+	logrus.SetFormatter(filteredFormatter{formatter})
+	switch len(outputs) {
+	case 0:
+		// Entries keep reaching the dashboard's log buffer
+		logrus.SetOutput(io.Discard)
+	case 1:
+		logrus.SetOutput(outputs[0])
+	default:
+		logrus.SetOutput(io.MultiWriter(outputs...))
+	}
+	if replaced != nil {
+		replaced.Close()
+	}
+	return nil
+}
+
+// Close closes the log file, if any; later entries reopen it
+func Close() error {
+	current.mu.RLock()
+	file := current.file
+	current.mu.RUnlock()
+	if file == nil {
+		return nil
+	}
+	return file.Close()
+}
+
+// Enabled reports whether an entry passes the level of the module that logged it; hooks keeping entries, like
+// the dashboard's log buffer, skip the others
+func Enabled(entry *logrus.Entry) bool {
+	module, _ := entry.Data[ModuleField].(string)
+
+	current.mu.RLock()
+	defer current.mu.RUnlock()
+	level, ok := current.modules[module]
+	if !ok {
+		level = current.level
+	}
+	return entry.Level <= level
+}
+
+// moduleHook adds the module field from the caller's package, and the request ID of the entry's context
+type moduleHook struct{}
+
+func (moduleHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (moduleHook) Fire(entry *logrus.Entry) error {
+	if _, ok := entry.Data[ModuleField]; !ok && entry.Caller != nil {
+		entry.Data[ModuleField] = packageName(entry.Caller.Function)
+	}
+	// Only needed for the module, file and function fields would clutter every line
+	entry.Caller = nil
+	if entry.Context != nil {
+		if id := RequestID(entry.Context); id != "" {
+			entry.Data[RequestIDField] = id
+		}
+	}
+	return nil
+}
+
+// packageName returns the last element of a function's package path, e.g. "twitch" for
+// "twitchdropsfarmer/internal/twitch.(*Client).Login"
+func packageName(function string) string {
+	if slash := strings.LastIndexByte(function, '/'); slash >= 0 {
+		function = function[slash+1:]
+	}
+	if dot := strings.IndexByte(function, '.'); dot >= 0 {
+		function = function[:dot]
+	}
+	return function
+}
+
+// filteredFormatter writes nothing for entries below their module's level
+type filteredFormatter struct {
+	logrus.Formatter
+}
+
+func (f filteredFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if !Enabled(entry) {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
@@ -0,0 +1,98 @@
+package mqtt
+
+import (
+	"regexp"
+
+	"twitchdropsfarmer/internal/events"
+)
+
+// Characters Home Assistant doesn't accept in a discovery node ID
+var invalidNodeID = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// entity is a Home Assistant entity announced on its discovery topic
+type entity struct {
+	objectID string
+	topic    string // <discovery prefix>/<component>/<node ID>/<object ID>/config
+	config   map[string]interface{}
+}
+
+// nodeID identifies the device to Home Assistant (and the client to the broker), so that farmers with different
+// topic prefixes show up as different devices
+func nodeID(prefix string) string {
+	return invalidNodeID.ReplaceAllString(prefix, "_")
+}
+
+// entities lists the sensors, the running binary sensor, the pause switch and the event entity, all reading
+// <prefix>/state but the events
+func (p *Publisher) entities() []entity {
+	node := nodeID(p.prefix)
+	device := map[string]interface{}{
+		"identifiers":  []string{node},
+		"name":         "Twitch Drops Farmer",
+		"manufacturer": "TwitchDropsFarmer",
+		"model":        "Drops miner",
+	}
+
+	var entities []entity
+	add := func(component, objectID, name string, config map[string]interface{}) {
+		config["name"] = name
+		config["unique_id"] = node + "_" + objectID
+		config["device"] = device
+		config["availability_topic"] = p.topic("availability")
+		if _, ok := config["state_topic"]; !ok {
+			config["state_topic"] = p.topic("state")
+		}
+		entities = append(entities, entity{
+			objectID: objectID,
+			topic:    p.discovery + "/" + component + "/" + node + "/" + objectID + "/config",
+			config:   config,
+		})
+	}
+
+	add("sensor", "progress", "Drop progress", map[string]interface{}{
+		"value_template":      "{{ value_json.progress }}",
+		"unit_of_measurement": "%",
+		"state_class":         "measurement",
+		"icon":                "mdi:progress-clock",
+	})
+	add("sensor", "remaining_minutes", "Drop time remaining", map[string]interface{}{
+		"value_template":      "{{ value_json.remaining_minutes }}",
+		"unit_of_measurement": "min",
+		"device_class":        "duration",
+	})
+	add("sensor", "drop", "Current drop", map[string]interface{}{
+		"value_template": "{{ value_json.drop }}",
+		"icon":           "mdi:gift",
+	})
+	add("sensor", "game", "Current game", map[string]interface{}{
+		"value_template": "{{ value_json.game }}",
+		"icon":           "mdi:gamepad-variant",
+	})
+	add("sensor", "campaign", "Current campaign", map[string]interface{}{
+		"value_template": "{{ value_json.campaign }}",
+		"icon":           "mdi:calendar-star",
+	})
+	add("sensor", "channel", "Current channel", map[string]interface{}{
+		"value_template": "{{ value_json.channel }}",
+		"icon":           "mdi:twitch",
+	})
+	add("sensor", "drops_claimed_today", "Drops claimed today", map[string]interface{}{
+		"value_template": "{{ value_json.drops_claimed_today }}",
+		"state_class":    "total_increasing",
+		"icon":           "mdi:check-decagram",
+	})
+	add("binary_sensor", "running", "Mining", map[string]interface{}{
+		"value_template": "{{ 'ON' if value_json.running and not value_json.paused else 'OFF' }}",
+		"device_class":   "running",
+	})
+	add("switch", "paused", "Paused", map[string]interface{}{
+		"command_topic":  p.topic("pause/set"),
+		"value_template": "{{ 'ON' if value_json.paused else 'OFF' }}",
+		"icon":           "mdi:pause-circle",
+	})
+	add("event", "events", "Events", map[string]interface{}{
+		"state_topic": p.topic("event"),
+		"event_types": events.Types,
+	})
+	return entities
+}
@@ -0,0 +1,257 @@
+// Package mqtt publishes the miner status and events to an MQTT broker and announces them with Home Assistant
+// MQTT discovery, so the farmer shows up there as a device with sensors and a pause switch.
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/events"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sirupsen/logrus"
+)
+
+// How often the miner status is checked for changes to publish
+const statusInterval = 15 * time.Second
+
+// How long a publish may wait for the broker
+const publishTimeout = 10 * time.Second
+
+// Payloads of the availability topic, "offline" being the will the broker sends when the connection drops
+const (
+	payloadOnline  = "online"
+	payloadOffline = "offline"
+)
+
+// Publisher keeps the broker up to date with the primary account's miner and pauses or resumes it from the
+// Home Assistant switch
+type Publisher struct {
+	client    paho.Client
+	prefix    string // topics are <prefix>/state, <prefix>/event, ...
+	discovery string // Home Assistant discovery prefix
+	miner     *drops.Miner
+
+	mu        sync.Mutex
+	lastState []byte // last state published, to only publish changes
+}
+
+// State is the JSON published, retained, to <prefix>/state
+type State struct {
+	Running           bool    `json:"running"`
+	Paused            bool    `json:"paused"`
+	Game              string  `json:"game"`
+	Campaign          string  `json:"campaign"`
+	Channel           string  `json:"channel"`
+	Drop              string  `json:"drop"`     // next drop of the campaign to complete
+	Progress          float64 `json:"progress"` // percent of Drop
+	RemainingMinutes  int     `json:"remaining_minutes"`
+	DropsClaimedToday int     `json:"drops_claimed_today"`
+	Error             string  `json:"error"`
+}
+
+// NewPublisher creates a publisher for cfg's broker; call Run to connect
+func NewPublisher(cfg *config.Config, miner *drops.Miner) *Publisher {
+	p := &Publisher{
+		prefix:    cfg.MQTTTopicPrefix,
+		discovery: cfg.MQTTDiscoveryPrefix,
+		miner:     miner,
+	}
+
+	options := paho.NewClientOptions().
+		AddBroker(cfg.MQTTURL).
+		SetClientID(nodeID(p.prefix)).
+		SetUsername(cfg.MQTTUsername).
+		SetPassword(cfg.MQTTPassword).
+		SetWill(p.topic("availability"), payloadOffline, 1, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOrderMatters(false).
+		SetOnConnectHandler(p.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logrus.Warnf("Lost the MQTT connection, reconnecting: %v", err)
+		})
+	p.client = paho.NewClient(options)
+	return p
+}
+
+// Run connects to the broker and publishes status changes until ctx is cancelled, then marks the device offline
+func (p *Publisher) Run(ctx context.Context) {
+	// Retries in the background until the broker answers, onConnect does the rest
+	p.client.Connect()
+
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if p.client.IsConnectionOpen() {
+				p.publish(p.topic("availability"), payloadOffline, true)
+			}
+			p.client.Disconnect(250)
+			return
+		case <-ticker.C:
+			p.publishState(false)
+		}
+	}
+}
+
+// onConnect runs on every (re)connect: announce the entities, mark the device online and listen to the switch
+func (p *Publisher) onConnect(client paho.Client) {
+	logrus.Info("Connected to the MQTT broker")
+
+	for _, entity := range p.entities() {
+		payload, err := json.Marshal(entity.config)
+		if err != nil {
+			logrus.Errorf("Failed to encode Home Assistant discovery for %s: %v", entity.objectID, err)
+			continue
+		}
+		if err := p.publish(entity.topic, payload, true); err != nil {
+			logrus.Errorf("Failed to publish Home Assistant discovery for %s: %v", entity.objectID, err)
+		}
+	}
+	if err := p.publish(p.topic("availability"), payloadOnline, true); err != nil {
+		logrus.Errorf("Failed to publish MQTT availability: %v", err)
+	}
+
+	client.Subscribe(p.topic("pause/set"), 1, p.handlePause)
+	p.publishState(true)
+}
+
+// handlePause pauses on ON and resumes on OFF
+func (p *Publisher) handlePause(_ paho.Client, message paho.Message) {
+	var err error
+	switch payload := string(message.Payload()); payload {
+	case "ON":
+		err = p.miner.Pause()
+		if errors.Is(err, drops.ErrAlreadyPaused) {
+			err = nil
+		}
+	case "OFF":
+		err = p.miner.Resume()
+		if errors.Is(err, drops.ErrNotPaused) {
+			err = nil
+		}
+	default:
+		err = fmt.Errorf("unknown payload %q, expected ON or OFF", payload)
+	}
+	if err != nil {
+		logrus.Warnf("Ignoring MQTT pause command: %v", err)
+	}
+	// Puts the switch back when the command failed
+	p.publishState(true)
+}
+
+// publishState publishes the miner state when it changed since the last publish, or always with force
+func (p *Publisher) publishState(force bool) {
+	if !p.client.IsConnectionOpen() {
+		return
+	}
+	payload, err := json.Marshal(p.state())
+	if err != nil {
+		logrus.Errorf("Failed to encode MQTT state: %v", err)
+		return
+	}
+
+	p.mu.Lock()
+	unchanged := bytes.Equal(payload, p.lastState)
+	p.mu.Unlock()
+	if unchanged && !force {
+		return
+	}
+
+	if err := p.publish(p.topic("state"), payload, true); err != nil {
+		logrus.Errorf("Failed to publish MQTT state: %v", err)
+		return
+	}
+	p.mu.Lock()
+	p.lastState = payload
+	p.mu.Unlock()
+}
+
+// state summarizes the miner status for the sensors
+func (p *Publisher) state() State {
+	status := p.miner.GetStatus()
+	state := State{
+		Running: status.IsRunning,
+		Paused:  status.Paused,
+		Error:   status.ErrorMessage,
+	}
+	if status.CurrentStream != nil {
+		state.Channel = status.CurrentStream.UserName
+	}
+	if campaign := status.CurrentCampaign; campaign != nil {
+		state.Game, state.Campaign = campaign.Game.Name, campaign.Name
+
+		// The unclaimed drop of the campaign closest to completing
+		var next *drops.ActiveDrop
+		for i := range status.ActiveDrops {
+			drop := &status.ActiveDrops[i]
+			if drop.GameName != campaign.Game.Name || drop.IsClaimed {
+				continue
+			}
+			if next == nil || drop.RemainingMinutes < next.RemainingMinutes {
+				next = drop
+			}
+		}
+		if next != nil {
+			state.Drop = next.Name
+			state.Progress = float64(int(next.Progress*1000)) / 10
+			state.RemainingMinutes = next.RemainingMinutes
+		}
+	}
+	if today, err := p.miner.GetStats(1); err == nil {
+		state.DropsClaimedToday = today.Totals.DropsClaimed
+	}
+	return state
+}
+
+// eventPayload is the JSON published to <prefix>/event, Home Assistant event entities reading event_type
+type eventPayload struct {
+	EventType events.Type `json:"event_type"`
+	events.Event
+}
+
+// Handle publishes the primary account's events to <prefix>/event, which the Home Assistant event entity fires
+// on; events are dropped while the broker is unreachable
+func (p *Publisher) Handle(event events.Event) {
+	if event.AccountID != "" || !p.client.IsConnectionOpen() {
+		return
+	}
+	payload, err := json.Marshal(eventPayload{EventType: event.Type, Event: event})
+	if err != nil {
+		logrus.Errorf("Failed to encode MQTT event: %v", err)
+		return
+	}
+
+	go func() {
+		if err := p.publish(p.topic("event"), payload, false); err != nil {
+			logrus.Errorf("Failed to publish MQTT event %s: %v", event.Type, err)
+			return
+		}
+		// Claims change the drop and today's count, no need to wait for the next check
+		if event.Type == events.DropClaimed {
+			p.publishState(false)
+		}
+	}()
+}
+
+// publish sends a message with QoS 1 and waits for the broker to take it
+func (p *Publisher) publish(topic string, payload interface{}, retained bool) error {
+	token := p.client.Publish(topic, 1, retained, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timed out publishing to %s", topic)
+	}
+	return token.Error()
+}
+
+func (p *Publisher) topic(name string) string {
+	return p.prefix + "/" + name
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Discord posts events to a Discord channel webhook
+type Discord struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// NewDiscord creates a Discord provider for the given webhook
+func NewDiscord(webhookID, webhookToken string) *Discord {
+	return &Discord{
+		webhookURL: fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", webhookID, webhookToken),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (d *Discord) Name() string {
+	return "discord"
+}
+
+// Send posts the event as an embed
+func (d *Discord) Send(ctx context.Context, event Event) error {
+	embed := map[string]interface{}{
+		"title":       event.Title,
+		"description": event.Message,
+		"timestamp":   event.Time.Format(time.RFC3339),
+	}
+	if event.ImageURL != "" {
+		embed["thumbnail"] = map[string]string{"url": event.ImageURL}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"username": "TwitchDropsFarmer",
+		"embeds":   []map[string]interface{}{embed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode discord payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create discord request: %w", redactURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send discord notification: %w", redactURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("discord webhook returned status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
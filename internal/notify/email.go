@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// EmailConfig holds the SMTP settings for the email provider
+type EmailConfig struct {
+	Host        string
+	Port        string
+	Username    string
+	Password    string
+	From        string
+	To          []string
+	ImplicitTLS bool // true for SMTPS (usually port 465), false for STARTTLS
+}
+
+// Email sends events as plain text mails over SMTP
+type Email struct {
+	config EmailConfig
+}
+
+// NewEmail creates an email provider
+func NewEmail(config EmailConfig) *Email {
+	return &Email{config: config}
+}
+
+// Name returns the provider name
+func (e *Email) Name() string {
+	return "email"
+}
+
+// Send mails the event to all recipients
+func (e *Email) Send(ctx context.Context, event Event) error {
+	return e.SendMail(ctx, "[TwitchDropsFarmer] "+event.Title, event.Message)
+}
+
+// SendMail delivers a mail with the given subject and plain text body
+func (e *Email) SendMail(ctx context.Context, subject, body string) error {
+	address := net.JoinHostPort(e.config.Host, e.config.Port)
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if e.config.ImplicitTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: e.config.Host}}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if !e.config.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: e.config.Host}); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+
+	if e.config.Username != "" {
+		auth := smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(e.config.From); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, to := range e.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", to, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+
+	message := strings.Join([]string{
+		"From: " + e.config.From,
+		"To: " + strings.Join(e.config.To, ", "),
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	if _, err := writer.Write([]byte(message)); err != nil {
+		return fmt.Errorf("failed to write mail body: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish mail: %w", err)
+	}
+
+	return client.Quit()
+}
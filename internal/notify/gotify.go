@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Priorities of Gotify messages, urgent events never going out below gotifyUrgentPriority
+const (
+	gotifyDefaultPriority = 5
+	gotifyUrgentPriority  = 8
+)
+
+// Gotify pushes events to a self-hosted Gotify server as messages of one application
+type Gotify struct {
+	messageURL string
+	appToken   string
+	priority   int
+	httpClient *http.Client
+}
+
+// NewGotify creates a Gotify provider for the application token on the server at serverURL
+func NewGotify(serverURL, appToken string, priority int) *Gotify {
+	return &Gotify{
+		messageURL: strings.TrimSuffix(serverURL, "/") + "/message",
+		appToken:   appToken,
+		priority:   priority,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (g *Gotify) Name() string {
+	return "gotify"
+}
+
+// Send posts the event as a message, with its image shown by the Android app
+func (g *Gotify) Send(ctx context.Context, event Event) error {
+	priority := g.priority
+	if event.urgent() && priority < gotifyUrgentPriority {
+		priority = gotifyUrgentPriority
+	}
+
+	message := map[string]interface{}{
+		"title":    event.Title,
+		"message":  event.Message,
+		"priority": priority,
+	}
+	if event.ImageURL != "" {
+		message["extras"] = map[string]interface{}{
+			"client::notification": map[string]string{"bigImageUrl": event.ImageURL},
+		}
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode gotify payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", g.messageURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create gotify request: %w", redactURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.appToken)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send gotify notification: %w", redactURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("gotify server returned status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EventType identifies what happened in the farmer
+type EventType string
+
+const (
+	EventDropClaimed    EventType = "drop_claimed"
+	EventMinerError     EventType = "miner_error"
+	EventNewCampaign    EventType = "new_campaign"
+	EventMiningStalled  EventType = "mining_stalled"
+	EventReauthRequired EventType = "reauth_required"
+	EventTest           EventType = "test"
+)
+
+// Event is a single notification to deliver through every provider
+type Event struct {
+	Type     EventType `json:"type"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	ImageURL string    `json:"image_url,omitempty"` // e.g. the claimed drop's reward, shown by providers that support images
+	Time     time.Time `json:"time"`
+}
+
+// urgent reports whether the event needs someone to step in; providers with priorities send these higher
+func (e Event) urgent() bool {
+	switch e.Type {
+	case EventMinerError, EventMiningStalled, EventReauthRequired:
+		return true
+	}
+	return false
+}
+
+// Provider delivers events to one notification backend
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// Manager fans events out to all configured providers
+type Manager struct {
+	mu           sync.RWMutex
+	webPush      *WebPush
+	urlProviders []Provider // built from Apprise-style notification URLs
+	providers    []Provider // added with AddProvider, e.g. the Telegram bot
+}
+
+// NewManager creates the notification manager and its built-in providers
+func NewManager(cfg *config.Config, store storage.Store) (*Manager, error) {
+	webPush, err := NewWebPush(store, cfg.WebPushSubject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize web push: %w", err)
+	}
+
+	manager := &Manager{webPush: webPush}
+
+	// A bad URL in the config file shouldn't keep the farmer from starting
+	if err := manager.SetURLs(cfg.NotificationURLs); err != nil {
+		logrus.Errorf("Ignoring notification URLs: %v", err)
+	}
+
+	return manager, nil
+}
+
+// Reload re-reads provider state from storage, e.g. after a state import
+func (m *Manager) Reload() error {
+	return m.webPush.Reload()
+}
+
+// redactURL leaves the request URL out of an HTTP client error, for the providers that carry a bot token or
+// webhook secret in it; the errors are logged and shown in the dashboard
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// SetURLs replaces the URL-based providers with ones parsed from urls
+func (m *Manager) SetURLs(urls []string) error {
+	providers, err := ParseURLs(urls)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.urlProviders = providers
+	m.mu.Unlock()
+
+	logrus.Debugf("Configured %d notification URL providers", len(providers))
+	return nil
+}
+
+// AddProvider delivers every later event through provider as well
+func (m *Manager) AddProvider(provider Provider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.providers = append(m.providers, provider)
+}
+
+// WebPush returns the Web Push provider used for browser subscriptions
+func (m *Manager) WebPush() *WebPush {
+	return m.webPush
+}
+
+// notifiedEvents maps the bus events that are notified to their notification type
+var notifiedEvents = map[events.Type]EventType{
+	events.DropClaimed:   EventDropClaimed,
+	events.NewCampaign:   EventNewCampaign,
+	events.MiningStalled: EventMiningStalled,
+	events.AuthExpired:   EventReauthRequired,
+	events.MinerError:    EventMinerError,
+}
+
+// Handle notifies the bus events worth a notification, the others would only be noise
+func (m *Manager) Handle(event events.Event) {
+	eventType, ok := notifiedEvents[event.Type]
+	if !ok {
+		return
+	}
+	m.Notify(Event{
+		Type:     eventType,
+		Title:    event.Title,
+		Message:  event.Message,
+		ImageURL: event.ImageURL,
+		Time:     event.Time,
+	})
+}
+
+// Notify delivers the event to every provider in the background
+func (m *Manager) Notify(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	m.mu.RLock()
+	providers := append([]Provider{m.webPush}, m.urlProviders...)
+	providers = append(providers, m.providers...)
+	m.mu.RUnlock()
+
+	for _, provider := range providers {
+		go func(p Provider) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if err := p.Send(ctx, event); err != nil {
+				logrus.Errorf("Failed to send %s notification via %s: %v", event.Type, p.Name(), err)
+			}
+		}(provider)
+	}
+}
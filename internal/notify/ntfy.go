@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Priorities of ntfy messages (1 to 5), urgent events never going out below ntfyUrgentPriority
+const (
+	ntfyDefaultPriority = 3
+	ntfyUrgentPriority  = 4
+)
+
+// Server used when an ntfy URL names only a topic
+const ntfyPublicServer = "https://ntfy.sh"
+
+// NtfyConfig holds the server, topic, and credentials of the ntfy provider
+type NtfyConfig struct {
+	ServerURL string
+	Topic     string
+	Username  string // for basic auth, when the topic is protected
+	Password  string
+	Token     string // access token, used instead of the username and password
+	Priority  int
+}
+
+// Ntfy publishes events to an ntfy topic, on ntfy.sh or a self-hosted server
+type Ntfy struct {
+	config     NtfyConfig
+	httpClient *http.Client
+}
+
+// NewNtfy creates an ntfy provider
+func NewNtfy(config NtfyConfig) *Ntfy {
+	config.ServerURL = strings.TrimSuffix(config.ServerURL, "/")
+	return &Ntfy{
+		config:     config,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (n *Ntfy) Name() string {
+	return "ntfy"
+}
+
+// Send publishes the event as JSON, which unlike headers keeps non-ASCII titles intact, with its image attached
+func (n *Ntfy) Send(ctx context.Context, event Event) error {
+	priority := n.config.Priority
+	tags := []string{"gift"}
+	if event.urgent() {
+		tags = []string{"warning"}
+		if priority < ntfyUrgentPriority {
+			priority = ntfyUrgentPriority
+		}
+	}
+
+	message := map[string]interface{}{
+		"topic":    n.config.Topic,
+		"title":    event.Title,
+		"message":  event.Message,
+		"priority": priority,
+		"tags":     tags,
+	}
+	if event.ImageURL != "" {
+		message["attach"] = event.ImageURL
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode ntfy payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.config.ServerURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create ntfy request: %w", redactURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.config.Token)
+	} else if n.config.Username != "" {
+		req.SetBasicAuth(n.config.Username, n.config.Password)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send ntfy notification: %w", redactURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("ntfy server returned status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Priorities of Pushover messages (-2 to 1), urgent events never going out below pushoverUrgentPriority
+const (
+	pushoverDefaultPriority = 0
+	pushoverUrgentPriority  = 1
+)
+
+const pushoverMessagesURL = "https://api.pushover.net/1/messages.json"
+
+// Pushover sends events through a Pushover application to a user or group, optionally only to some devices
+type Pushover struct {
+	userKey    string
+	appToken   string
+	devices    []string
+	priority   int
+	httpClient *http.Client
+}
+
+// NewPushover creates a Pushover provider; no devices sends to all of the user's devices
+func NewPushover(userKey, appToken string, devices []string, priority int) *Pushover {
+	return &Pushover{
+		userKey:    userKey,
+		appToken:   appToken,
+		devices:    devices,
+		priority:   priority,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (p *Pushover) Name() string {
+	return "pushover"
+}
+
+// Send posts the event as a message
+func (p *Pushover) Send(ctx context.Context, event Event) error {
+	priority := p.priority
+	if event.urgent() && priority < pushoverUrgentPriority {
+		priority = pushoverUrgentPriority
+	}
+
+	form := url.Values{
+		"token":     {p.appToken},
+		"user":      {p.userKey},
+		"title":     {event.Title},
+		"message":   {event.Message},
+		"priority":  {strconv.Itoa(priority)},
+		"timestamp": {strconv.FormatInt(event.Time.Unix(), 10)},
+	}
+	if len(p.devices) > 0 {
+		form.Set("device", strings.Join(p.devices, ","))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", pushoverMessagesURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create pushover request: %w", redactURL(err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send pushover notification: %w", redactURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("pushover API returned status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
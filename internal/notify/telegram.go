@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Telegram sends events through a Telegram bot to one or more chats
+type Telegram struct {
+	botToken   string
+	chatIDs    []string
+	httpClient *http.Client
+}
+
+// NewTelegram creates a Telegram provider for the given bot and chats
+func NewTelegram(botToken string, chatIDs []string) *Telegram {
+	return &Telegram{
+		botToken:   botToken,
+		chatIDs:    chatIDs,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+// Send delivers the event to every configured chat, as a photo with caption when it has an image
+func (t *Telegram) Send(ctx context.Context, event Event) error {
+	text := fmt.Sprintf("%s\n%s", event.Title, event.Message)
+	var lastErr error
+	for _, chatID := range t.chatIDs {
+		if event.ImageURL != "" {
+			err := t.SendPhoto(ctx, chatID, event.ImageURL, text)
+			if err == nil {
+				continue
+			}
+			// Telegram couldn't fetch the image, the text alone still gets through
+			logrus.Debugf("Failed to send telegram photo, sending text: %v", err)
+		}
+		if err := t.SendMessage(ctx, chatID, text); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// SendMessage sends a plain text message to one chat
+func (t *Telegram) SendMessage(ctx context.Context, chatID, text string) error {
+	return t.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	})
+}
+
+// SendPhoto sends the image at photoURL, which Telegram downloads itself, with a caption to one chat
+func (t *Telegram) SendPhoto(ctx context.Context, chatID, photoURL, caption string) error {
+	return t.call(ctx, "sendPhoto", map[string]interface{}{
+		"chat_id": chatID,
+		"photo":   photoURL,
+		"caption": caption,
+	})
+}
+
+// call posts a Bot API method with a JSON body
+func (t *Telegram) call(ctx context.Context, method string, payload map[string]interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode telegram payload: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/%s", t.botToken, method)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create telegram request: %w", redactURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send telegram notification: %w", redactURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram API returned status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
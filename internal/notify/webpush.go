@@ -0,0 +1,419 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	webPushKeysDocument          = "webpush_vapid"
+	webPushSubscriptionsDocument = "webpush_subscriptions"
+
+	// Record size advertised in the aes128gcm header (RFC 8188)
+	webPushRecordSize = 4096
+)
+
+// PushSubscription is a browser PushSubscription as serialized by the Push API
+type PushSubscription struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys" binding:"required"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// storedVAPIDKeys is the persisted form of the server's VAPID key pair
+type storedVAPIDKeys struct {
+	PrivateKey string `json:"private_key"` // base64 PKCS#8
+	PublicKey  string `json:"public_key"`  // base64url uncompressed P-256 point
+}
+
+// WebPush sends native browser notifications using VAPID (RFC 8292) and
+// aes128gcm payload encryption (RFC 8291) without any third-party service
+type WebPush struct {
+	store      storage.Store
+	subject    string
+	httpClient *http.Client
+
+	privateKey *ecdsa.PrivateKey
+	publicKey  string
+
+	mu            sync.RWMutex
+	subscriptions []PushSubscription
+}
+
+// NewWebPush loads or generates the VAPID keys and loads stored subscriptions
+func NewWebPush(store storage.Store, subject string) (*WebPush, error) {
+	w := &WebPush{
+		store:      store,
+		subject:    subject,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if err := w.loadOrGenerateKeys(); err != nil {
+		return nil, err
+	}
+
+	if err := store.Load(webPushSubscriptionsDocument, &w.subscriptions); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Reload re-reads the VAPID keys and subscriptions from storage, e.g. after a state import
+func (w *WebPush) Reload() error {
+	var subscriptions []PushSubscription
+	if err := w.store.Load(webPushSubscriptionsDocument, &subscriptions); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.loadOrGenerateKeys(); err != nil {
+		return err
+	}
+	w.subscriptions = subscriptions
+	return nil
+}
+
+func (w *WebPush) loadOrGenerateKeys() error {
+	var keys storedVAPIDKeys
+	if err := w.store.Load(webPushKeysDocument, &keys); err != nil {
+		return err
+	}
+
+	if keys.PrivateKey != "" {
+		der, err := base64.StdEncoding.DecodeString(keys.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to decode VAPID private key: %w", err)
+		}
+
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return fmt.Errorf("failed to parse VAPID private key: %w", err)
+		}
+
+		privateKey, ok := parsed.(*ecdsa.PrivateKey)
+		if !ok {
+			return fmt.Errorf("VAPID private key is not an ECDSA key")
+		}
+
+		w.privateKey = privateKey
+		w.publicKey = keys.PublicKey
+		return nil
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate VAPID key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode VAPID private key: %w", err)
+	}
+
+	publicKey, err := privateKey.PublicKey.ECDH()
+	if err != nil {
+		return fmt.Errorf("failed to encode VAPID public key: %w", err)
+	}
+
+	w.privateKey = privateKey
+	w.publicKey = base64.RawURLEncoding.EncodeToString(publicKey.Bytes())
+
+	logrus.Info("Generated new VAPID key pair for Web Push")
+	return w.store.Save(webPushKeysDocument, storedVAPIDKeys{
+		PrivateKey: base64.StdEncoding.EncodeToString(der),
+		PublicKey:  w.publicKey,
+	})
+}
+
+// Name returns the provider name
+func (w *WebPush) Name() string {
+	return "webpush"
+}
+
+// PublicKey returns the VAPID application server key for PushManager.subscribe
+func (w *WebPush) PublicKey() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.publicKey
+}
+
+// Subscribe stores a browser subscription, replacing any with the same endpoint
+func (w *WebPush) Subscribe(sub PushSubscription) error {
+	if _, err := url.ParseRequestURI(sub.Endpoint); err != nil {
+		return fmt.Errorf("invalid subscription endpoint: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sub.CreatedAt = time.Now()
+	for i, existing := range w.subscriptions {
+		if existing.Endpoint == sub.Endpoint {
+			w.subscriptions[i] = sub
+			return w.store.Save(webPushSubscriptionsDocument, w.subscriptions)
+		}
+	}
+
+	w.subscriptions = append(w.subscriptions, sub)
+	return w.store.Save(webPushSubscriptionsDocument, w.subscriptions)
+}
+
+// Unsubscribe removes the subscription with the given endpoint
+func (w *WebPush) Unsubscribe(endpoint string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.removeLocked(endpoint)
+}
+
+func (w *WebPush) removeLocked(endpoint string) error {
+	for i, existing := range w.subscriptions {
+		if existing.Endpoint == endpoint {
+			w.subscriptions = append(w.subscriptions[:i], w.subscriptions[i+1:]...)
+			return w.store.Save(webPushSubscriptionsDocument, w.subscriptions)
+		}
+	}
+	return nil
+}
+
+// SubscriptionCount returns the number of registered browsers
+func (w *WebPush) SubscriptionCount() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return len(w.subscriptions)
+}
+
+// Send pushes the event to every subscribed browser
+// Subscriptions the push service reports as gone are dropped
+func (w *WebPush) Send(ctx context.Context, event Event) error {
+	w.mu.RLock()
+	subscriptions := make([]PushSubscription, len(w.subscriptions))
+	copy(subscriptions, w.subscriptions)
+	w.mu.RUnlock()
+
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode push payload: %w", err)
+	}
+
+	var lastErr error
+	for _, sub := range subscriptions {
+		status, err := w.sendToSubscription(ctx, sub, payload)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if status == http.StatusNotFound || status == http.StatusGone {
+			logrus.Infof("Push subscription expired, removing: %s", sub.Endpoint)
+			w.mu.Lock()
+			if err := w.removeLocked(sub.Endpoint); err != nil {
+				logrus.Errorf("Failed to remove expired push subscription: %v", err)
+			}
+			w.mu.Unlock()
+			continue
+		}
+
+		if status < 200 || status >= 300 {
+			lastErr = fmt.Errorf("push service returned status: %d", status)
+		}
+	}
+
+	return lastErr
+}
+
+func (w *WebPush) sendToSubscription(ctx context.Context, sub PushSubscription, payload []byte) (int, error) {
+	body, err := encryptPushPayload(sub, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil {
+		return 0, fmt.Errorf("invalid subscription endpoint: %w", err)
+	}
+
+	token, err := w.vapidToken(endpoint.Scheme + "://" + endpoint.Host)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create push request: %w", redactURL(err))
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", "86400")
+	req.Header.Set("Urgency", "normal")
+	req.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", token, w.PublicKey()))
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send push: %w", redactURL(err))
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// vapidToken creates the ES256-signed JWT identifying this application server
+func (w *WebPush) vapidToken(audience string) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": audience,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": w.subject,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode VAPID claims: %w", err)
+	}
+
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	w.mu.RLock()
+	privateKey := w.privateKey
+	w.mu.RUnlock()
+
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+
+	// JWS wants the raw fixed-width r || s encoding, not ASN.1
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// encryptPushPayload encrypts payload for a subscription as a single aes128gcm record
+func encryptPushPayload(sub PushSubscription, payload []byte) ([]byte, error) {
+	// Room is needed for the padding delimiter and the 16 byte GCM tag
+	if len(payload)+17 > webPushRecordSize {
+		return nil, fmt.Errorf("push payload too large: %d bytes", len(payload))
+	}
+
+	clientKeyBytes, err := decodeBase64URL(sub.Keys.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	authSecret, err := decodeBase64URL(sub.Keys.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+
+	curve := ecdh.P256()
+	clientKey, err := curve.NewPublicKey(clientKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	// Ephemeral key pair per message
+	serverKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	sharedSecret, err := serverKey.ECDH(clientKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	// RFC 8291 section 3.4: combine the ECDH secret with the auth secret
+	keyInfo := append([]byte("WebPush: info\x00"), clientKeyBytes...)
+	keyInfo = append(keyInfo, serverPublic...)
+
+	prkKey, err := hkdf.Extract(sha256.New, sharedSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	ikm, err := hkdf.Expand(sha256.New, prkKey, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+
+	// RFC 8188 section 2.2: derive the content encryption key and nonce
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 marks the last (and only) record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	header := make([]byte, 0, 16+4+1+len(serverPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+
+	return append(header, ciphertext...), nil
+}
+
+// decodeBase64URL accepts both padded and unpadded base64url, as browsers differ
+func decodeBase64URL(value string) ([]byte, error) {
+	if decoded, err := base64.RawURLEncoding.DecodeString(value); err == nil {
+		return decoded, nil
+	}
+	return base64.URLEncoding.DecodeString(value)
+}
@@ -0,0 +1,8 @@
+-- One row per document, namespaced so additional accounts share the database
+CREATE TABLE documents (
+	namespace  TEXT        NOT NULL,
+	name       TEXT        NOT NULL,
+	data       JSONB       NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (namespace, name)
+);
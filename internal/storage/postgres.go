@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// Schema changes of the Postgres backend, migrations/NNNN_description.sql applied in version order; add a file
+// with the next version for a change, never edit one that has shipped
+//
+//go:embed migrations/*.sql
+var postgresMigrationFiles embed.FS
+
+var postgresMigrations = loadMigrations(postgresMigrationFiles)
+
+// loadMigrations reads the embedded migrations, panicking on a gap in the versions or a misnamed file
+func loadMigrations(files embed.FS) []string {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read migrations: %v", err))
+	}
+
+	// ReadDir sorts by name, and the zero padded versions sort in order
+	migrations := make([]string, 0, len(entries))
+	for i, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version != i+1 {
+			panic(fmt.Sprintf("migration %s should be version %d", entry.Name(), i+1))
+		}
+		data, err := files.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			panic(fmt.Sprintf("failed to read migration %s: %v", entry.Name(), err))
+		}
+		migrations = append(migrations, string(data))
+	}
+	return migrations
+}
+
+// Serializes migrations of concurrent instances, any constant works as long as it stays the same
+const postgresMigrationLock = 7429011
+
+// How long a single query may take
+const postgresQueryTimeout = 10 * time.Second
+
+// PostgresStore keeps documents as JSONB rows of a Postgres database
+type PostgresStore struct {
+	db        *sql.DB
+	namespace string
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// openPostgres connects to dsn and migrates the schema; all namespaces share the database
+func openPostgres(dsn, namespace string) (*PostgresStore, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("the postgres storage backend needs a DSN, set DATABASE_URL")
+	}
+
+	db, err := postgresDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresStore{db: db, namespace: namespace}, nil
+}
+
+// Connections by DSN, so the stores of all accounts share one pool and migrate once
+var (
+	postgresMu  sync.Mutex
+	postgresDBs = make(map[string]*sql.DB)
+)
+
+func postgresDB(dsn string) (*sql.DB, error) {
+	postgresMu.Lock()
+	defer postgresMu.Unlock()
+
+	if db, ok := postgresDBs[dsn]; ok {
+		return db, nil
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres DSN: %w", err)
+	}
+	db.SetMaxOpenConns(5)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if err := migratePostgres(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	postgresDBs[dsn] = db
+	return db, nil
+}
+
+// closePostgres closes every pooled connection; stores opened before can't be used afterwards
+func closePostgres() error {
+	postgresMu.Lock()
+	defer postgresMu.Unlock()
+
+	var firstErr error
+	for dsn, db := range postgresDBs {
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(postgresDBs, dsn)
+	}
+	return firstErr
+}
+
+// migratePostgres applies the migrations newer than the version recorded in schema_migrations
+func migratePostgres(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+		return fmt.Errorf("failed to lock schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER     PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var version int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(postgresMigrations) {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", version, len(postgresMigrations))
+	}
+
+	for i := version; i < len(postgresMigrations); i++ {
+		if _, err := tx.ExecContext(ctx, postgresMigrations[i]); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migrations: %w", err)
+	}
+	return nil
+}
+
+// Load reads the named document into v
+// A missing document is not an error; v is left untouched
+func (s *PostgresStore) Load(name string, v interface{}) error {
+	data, err := s.ReadRaw(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// Save writes v as the named document
+func (s *PostgresStore) Save(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+
+	return s.WriteRaw(name, data)
+}
+
+// List returns the names of all documents in the namespace, sorted
+func (s *PostgresStore) List() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT name FROM documents WHERE namespace = $1 ORDER BY name`, s.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Ping checks that the database answers
+func (s *PostgresStore) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to reach postgres: %w", err)
+	}
+	return nil
+}
+
+// ReadRaw returns the encoded contents of the named document, sql.ErrNoRows when there is none
+func (s *PostgresStore) ReadRaw(name string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM documents WHERE namespace = $1 AND name = $2`, s.namespace, name).Scan(&data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// WriteRaw replaces the named document with already encoded JSON
+func (s *PostgresStore) WriteRaw(name string, data []byte) error {
+	if !json.Valid(data) {
+		return fmt.Errorf("document %s is not valid JSON", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO documents (namespace, name, data, updated_at) VALUES ($1, $2, $3, now())
+		ON CONFLICT (namespace, name) DO UPDATE SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at`,
+		s.namespace, name, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
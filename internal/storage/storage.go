@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Storage is the JSON file Store, keeping every document as <name>.json in a data directory
+type Storage struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// New creates a storage rooted at dir, creating the directory if needed
+func New(dir string) (*Storage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	return &Storage{dir: dir}, nil
+}
+
+// Dir returns the data directory
+func (s *Storage) Dir() string {
+	return s.dir
+}
+
+// Load reads the named document into v
+// A missing document is not an error; v is left untouched
+func (s *Storage) Load(name string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Save writes v as the named document, replacing it atomically
+func (s *Storage) Save(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+
+	return s.WriteRaw(name, data)
+}
+
+// List returns the names of all stored documents, sorted
+func (s *Storage) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data directory: %w", err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Ping checks that the data directory is still writable, e.g. not remounted read-only or full
+func (s *Storage) Ping() error {
+	probe, err := os.CreateTemp(s.dir, ".ping-*")
+	if err != nil {
+		return fmt.Errorf("data directory is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// ReadRaw returns the encoded contents of the named document
+func (s *Storage) ReadRaw(name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return data, nil
+}
+
+// WriteRaw replaces the named document with already encoded JSON, atomically
+func (s *Storage) WriteRaw(name string, data []byte) error {
+	if !json.Valid(data) {
+		return fmt.Errorf("document %s is not valid JSON", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Write to a temp file first so a crash never leaves a truncated document
+	tmpPath := s.path(name) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	if err := os.Rename(tmpPath, s.path(name)); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
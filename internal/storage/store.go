@@ -0,0 +1,57 @@
+package storage
+
+import "fmt"
+
+// Storage backends selectable with the storage_backend setting
+const (
+	BackendJSON     = "json"     // one JSON file per document in the data directory
+	BackendPostgres = "postgres" // one row per document in a Postgres database
+)
+
+// Store persists the named JSON documents of the app: sessions, campaign caches, stats, settings
+// overrides and the rest. Every package goes through it, so a backend only implements these methods
+type Store interface {
+	// Load reads the named document into v; a missing document is not an error and leaves v untouched
+	Load(name string, v interface{}) error
+	// Save writes v as the named document, replacing it atomically
+	Save(name string, v interface{}) error
+	// List returns the names of all stored documents, sorted
+	List() ([]string, error)
+	// ReadRaw returns the encoded contents of the named document
+	ReadRaw(name string) ([]byte, error)
+	// WriteRaw replaces the named document with already encoded JSON, atomically
+	WriteRaw(name string, data []byte) error
+	// Ping checks that documents can still be written, for readiness checks
+	Ping() error
+}
+
+var _ Store = (*Storage)(nil)
+
+// Options selects and configures the backend of a store
+type Options struct {
+	Backend string // an empty backend is BackendJSON
+	Dir     string // data directory of the JSON backend
+
+	// Postgres connection string and the namespace keeping this store's documents apart from the
+	// other accounts', e.g. "accounts/<id>"
+	DSN       string
+	Namespace string
+}
+
+// Close releases the database connections shared by the stores, once nothing writes to them any more;
+// JSON stores hold no open files between calls
+func Close() error {
+	return closePostgres()
+}
+
+// Open creates the store for the selected backend
+func Open(opts Options) (Store, error) {
+	switch opts.Backend {
+	case "", BackendJSON:
+		return New(opts.Dir)
+	case BackendPostgres:
+		return openPostgres(opts.DSN, opts.Namespace)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q, must be %s or %s", opts.Backend, BackendJSON, BackendPostgres)
+	}
+}
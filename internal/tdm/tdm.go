@@ -0,0 +1,211 @@
+// Package tdm reads the files of TwitchDropsMiner (TDM), so users moving over keep their login and game lists.
+package tdm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"twitchdropsfarmer/internal/config"
+
+	"github.com/nlpodyssey/gopickle/pickle"
+	"github.com/nlpodyssey/gopickle/types"
+)
+
+// Cookie TDM keeps its OAuth token in
+const authTokenCookie = "auth-token"
+
+// TDM's PriorityMode values, see settings.json
+const (
+	priorityOnly    = 0 // only the priority games are farmed
+	endingSoonest   = 1 // after them other games, ending soonest first
+	lowAvailFirst   = 2 // after them other games, least available first
+	unknownPriority = -1
+)
+
+// Cookies is the login found in TDM's cookies.jar
+type Cookies struct {
+	AuthToken string // OAuth access token from TDM's Android app client ID, which this app uses too
+}
+
+// Settings are the game lists of TDM's settings.json
+type Settings struct {
+	Priority     []string // game names, highest priority first
+	Exclude      []string // game names never farmed
+	PriorityOnly bool     // farm only the priority games
+}
+
+// ReadCookies extracts the login from cookies.jar, the pickled cookie jar of aiohttp TDM saves
+func ReadCookies(data []byte) (*Cookies, error) {
+	unpickler := pickle.NewUnpickler(bytes.NewReader(data))
+	unpickler.FindClass = findClass
+	root, err := unpickler.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookie jar: %w", err)
+	}
+	jar, ok := root.(*pyDict)
+	if !ok {
+		return nil, errors.New("failed to read cookie jar: not an aiohttp cookie jar")
+	}
+
+	// Cookies are grouped by domain, or (domain, path) in newer aiohttp versions, the token is on twitch.tv
+	cookies := &Cookies{}
+	for _, domain := range *jar.items {
+		simpleCookie, ok := domain.Value.(*pyDict)
+		if !ok {
+			continue
+		}
+		for _, entry := range *simpleCookie.items {
+			morsel, ok := entry.Value.(*pyDict)
+			if !ok {
+				continue
+			}
+			if name, _ := entry.Key.(string); name == authTokenCookie {
+				cookies.AuthToken = morsel.stateString("value")
+			}
+		}
+	}
+	if cookies.AuthToken == "" {
+		return nil, errors.New("no Twitch login in the cookie jar, log in with TDM first")
+	}
+	return cookies, nil
+}
+
+// ReadSettings reads settings.json
+func ReadSettings(data []byte) (*Settings, error) {
+	var raw struct {
+		Priority     json.RawMessage `json:"priority"`
+		Exclude      json.RawMessage `json:"exclude"`
+		PriorityMode json.RawMessage `json:"priority_mode"`
+		PriorityOnly *bool           `json:"priority_only"` // before priority_mode
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	settings := &Settings{PriorityOnly: true}
+	var err error
+	if settings.Priority, err = readGames(raw.Priority); err != nil {
+		return nil, fmt.Errorf("failed to read priority: %w", err)
+	}
+	if settings.Exclude, err = readGames(raw.Exclude); err != nil {
+		return nil, fmt.Errorf("failed to read exclude: %w", err)
+	}
+	if raw.PriorityOnly != nil {
+		settings.PriorityOnly = *raw.PriorityOnly
+	}
+	if raw.PriorityMode != nil {
+		switch readPriorityMode(raw.PriorityMode) {
+		case priorityOnly:
+			settings.PriorityOnly = true
+		case endingSoonest, lowAvailFirst:
+			settings.PriorityOnly = false
+		}
+	}
+	return settings, nil
+}
+
+// Apply replaces the priority and exclude lists of cfg with TDM's
+// TDM farms its priority games in list order before any other, so the priority mode stays PRIORITY_LIST and
+// only whether other games are farmed carries over, as watch_unlisted
+func (s *Settings) Apply(cfg *config.Config) {
+	cfg.PriorityGames = make([]config.GameConfig, 0, len(s.Priority))
+	for _, name := range s.Priority {
+		// The slug and ID are resolved when the game is used
+		cfg.PriorityGames = append(cfg.PriorityGames, config.GameConfig{Name: name})
+	}
+	cfg.ExcludeGames = append([]string{}, s.Exclude...)
+	cfg.PriorityMode = "PRIORITY_LIST"
+	cfg.WatchUnlisted = !s.PriorityOnly
+}
+
+// readGames reads a list of game names, which TDM writes as a plain list or, for sets, as
+// {"__type": "set", "data": [...]}
+func readGames(raw json.RawMessage) ([]string, error) {
+	if raw == nil {
+		return []string{}, nil
+	}
+	var wrapped struct {
+		Type string   `json:"__type"`
+		Data []string `json:"data"`
+	}
+	var games []string
+	if err := json.Unmarshal(raw, &games); err != nil {
+		if wrapErr := json.Unmarshal(raw, &wrapped); wrapErr != nil || wrapped.Type == "" {
+			return nil, err
+		}
+		games = wrapped.Data
+	}
+
+	names := []string{}
+	for _, game := range games {
+		if game = strings.TrimSpace(game); game != "" {
+			names = append(names, game)
+		}
+	}
+	return names, nil
+}
+
+// readPriorityMode reads the PriorityMode enum, written as {"__type": "PriorityMode", "data": 1}
+func readPriorityMode(raw json.RawMessage) int {
+	var mode int
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		return mode
+	}
+	var wrapped struct {
+		Data int `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &wrapped); err == nil {
+		return wrapped.Data
+	}
+	return unknownPriority
+}
+
+// findClass resolves the classes a pickled aiohttp cookie jar is made of, they are all dicts
+func findClass(module, name string) (interface{}, error) {
+	switch module + "." + name {
+	case "collections.defaultdict", "http.cookies.SimpleCookie", "http.cookies.Morsel":
+		return pyDictClass{}, nil
+	}
+	return nil, fmt.Errorf("unexpected class %s.%s in cookie jar", module, name)
+}
+
+// pyDictClass creates pyDicts, whether called (defaultdict) or through __new__ (SimpleCookie, Morsel)
+type pyDictClass struct{}
+
+func (pyDictClass) Call(args ...interface{}) (interface{}, error) {
+	return newPyDict(), nil
+}
+
+func (pyDictClass) PyNew(args ...interface{}) (interface{}, error) {
+	return newPyDict(), nil
+}
+
+// pyDict is a dict subclass, with the state set by __setstate__, e.g. a Morsel's key and value
+type pyDict struct {
+	items *types.Dict
+	state *types.Dict
+}
+
+func newPyDict() *pyDict {
+	return &pyDict{items: types.NewDict(), state: types.NewDict()}
+}
+
+func (d *pyDict) Set(key, value interface{}) {
+	d.items.Set(key, value)
+}
+
+func (d *pyDict) PySetState(state interface{}) error {
+	if dict, ok := state.(*types.Dict); ok {
+		d.state = dict
+	}
+	return nil
+}
+
+func (d *pyDict) stateString(key string) string {
+	value, _ := d.state.Get(key)
+	s, _ := value.(string)
+	return s
+}
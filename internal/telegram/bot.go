@@ -0,0 +1,143 @@
+// Package telegram runs a Telegram bot that reports the miner status and takes a few commands,
+// so the farmer can be watched and controlled from a phone without exposing the dashboard.
+// Only the configured chats are answered; messages from any other chat are ignored.
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/notify"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How long a getUpdates call waits for new messages
+const pollTimeout = 30 * time.Second
+
+// Wait before polling again after getUpdates failed
+const pollRetryDelay = 10 * time.Second
+
+// Bot answers commands from the configured chats and notifies them of miner events
+type Bot struct {
+	*notify.Telegram
+
+	botToken   string
+	chatIDs    map[string]bool
+	miner      *drops.Miner
+	httpClient *http.Client
+	offset     int64 // update ID to poll from
+}
+
+var _ notify.Provider = (*Bot)(nil)
+
+// NewBot creates a bot controlling miner; call Run to start taking commands
+func NewBot(botToken string, chatIDs []string, miner *drops.Miner) *Bot {
+	var ids []string
+	allowed := make(map[string]bool, len(chatIDs))
+	for _, chatID := range chatIDs {
+		if chatID = strings.TrimSpace(chatID); chatID != "" {
+			ids = append(ids, chatID)
+			allowed[chatID] = true
+		}
+	}
+
+	return &Bot{
+		Telegram:   notify.NewTelegram(botToken, ids),
+		botToken:   botToken,
+		chatIDs:    allowed,
+		miner:      miner,
+		httpClient: &http.Client{Timeout: pollTimeout + 10*time.Second},
+	}
+}
+
+// update is the part of a Telegram update the bot reads
+type update struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// Run polls for commands until ctx is cancelled
+func (b *Bot) Run(ctx context.Context) {
+	logrus.Infof("Telegram bot listening for commands from %d chats", len(b.chatIDs))
+	for {
+		updates, err := b.getUpdates(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logrus.Errorf("Failed to get Telegram updates: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollRetryDelay):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			b.offset = u.UpdateID + 1
+			if u.Message == nil || !strings.HasPrefix(u.Message.Text, "/") {
+				continue
+			}
+
+			chatID := strconv.FormatInt(u.Message.Chat.ID, 10)
+			if !b.chatIDs[chatID] {
+				logrus.Warnf("Ignoring Telegram command from unauthorized chat %s", chatID)
+				continue
+			}
+
+			reply := b.handleCommand(ctx, u.Message.Text)
+			if err := b.SendMessage(ctx, chatID, reply); err != nil {
+				logrus.Errorf("Failed to answer Telegram command: %v", err)
+			}
+		}
+	}
+}
+
+func (b *Bot) getUpdates(ctx context.Context) ([]update, error) {
+	query := url.Values{}
+	query.Set("offset", strconv.FormatInt(b.offset, 10))
+	query.Set("timeout", strconv.Itoa(int(pollTimeout.Seconds())))
+	query.Set("allowed_updates", `["message"]`)
+
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates?%s", b.botToken, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("telegram API returned status: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		OK     bool     `json:"ok"`
+		Result []update `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode updates: %w", err)
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("telegram API returned an error")
+	}
+	return result.Result, nil
+}
@@ -0,0 +1,101 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/drops"
+)
+
+const helpText = `Commands:
+/status - what the miner is doing
+/pause - stop watching, keeping the current campaign and stream
+/resume - watch again after /pause
+/claim - claim every completed drop in the inventory
+/switch <game> - farm the active campaign of a game next`
+
+// handleCommand runs a command message and returns the reply
+func (b *Bot) handleCommand(ctx context.Context, text string) string {
+	command, args, _ := strings.Cut(strings.TrimSpace(text), " ")
+	// Group chats address commands to a bot as /status@botname
+	command, _, _ = strings.Cut(command, "@")
+	args = strings.TrimSpace(args)
+
+	switch strings.ToLower(command) {
+	case "/status":
+		return formatStatus(b.miner.GetStatus())
+
+	case "/pause":
+		if err := b.miner.Pause(); err != nil {
+			return fmt.Sprintf("Failed to pause: %v", err)
+		}
+		return "Miner paused, /resume to continue"
+
+	case "/resume":
+		if err := b.miner.Resume(); err != nil {
+			return fmt.Sprintf("Failed to resume: %v", err)
+		}
+		return "Miner resumed"
+
+	case "/claim":
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		summary, err := b.miner.ClaimPendingDrops(ctx)
+		if err != nil {
+			return fmt.Sprintf("Failed to claim drops: %v", err)
+		}
+		if summary.Found == 0 {
+			return "No drops waiting to be claimed"
+		}
+		return fmt.Sprintf("Claimed %d of %d drops", summary.Claimed, summary.Found)
+
+	case "/switch":
+		if args == "" {
+			return "Usage: /switch <game>"
+		}
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		campaign, err := b.miner.PinGame(ctx, args)
+		if err != nil {
+			return fmt.Sprintf("Failed to switch: %v", err)
+		}
+		return fmt.Sprintf("Pinned %s (%s), switching to it now; unpin it from the dashboard to go back to normal priority", campaign.Name, campaign.Game.Name)
+
+	default:
+		return helpText
+	}
+}
+
+// formatStatus describes the miner status in a few lines
+func formatStatus(status *drops.MinerStatus) string {
+	var lines []string
+	switch {
+	case !status.IsRunning:
+		lines = append(lines, "Miner is stopped")
+	case status.Paused:
+		lines = append(lines, "Miner is paused")
+	case status.OutsideSchedule:
+		lines = append(lines, "Outside the farming schedule")
+	default:
+		lines = append(lines, "Miner is running")
+	}
+
+	if status.CurrentCampaign != nil {
+		lines = append(lines, fmt.Sprintf("Campaign: %s (%s)", status.CurrentCampaign.Name, status.CurrentCampaign.Game.Name))
+	}
+	if status.CurrentStream != nil {
+		lines = append(lines, fmt.Sprintf("Watching: %s", status.CurrentStream.UserName))
+	}
+	for _, drop := range status.ActiveDrops {
+		if status.CurrentCampaign == nil || drop.GameName != status.CurrentCampaign.Game.Name || drop.IsClaimed {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %d/%d min", drop.Name, drop.CurrentMinutes, drop.RequiredMinutes))
+	}
+	if status.ErrorMessage != "" {
+		lines = append(lines, fmt.Sprintf("Error: %s", status.ErrorMessage))
+	}
+	return strings.Join(lines, "\n")
+}
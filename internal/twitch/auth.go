@@ -3,10 +3,12 @@ package twitch
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -29,6 +31,10 @@ var (
 type AuthManager struct {
 	clientID   string
 	httpClient *http.Client
+
+	// Extra scopes requested at login on top of RequiredScopes
+	mu     sync.RWMutex
+	scopes []string
 }
 
 type DeviceCodeResponse struct {
@@ -37,6 +43,23 @@ type DeviceCodeResponse struct {
 	Interval        int    `json:"interval"`
 	UserCode        string `json:"user_code"`
 	VerificationURI string `json:"verification_uri"`
+
+	// VerificationURI with the user code filled in, so opening it (e.g. from a QR code) needs no typing
+	VerificationURIComplete string `json:"verification_uri_complete"`
+}
+
+// completeVerificationURI adds the user code to the activation page as device-code, unless Twitch already did
+func completeVerificationURI(verificationURI, userCode string) string {
+	u, err := url.Parse(verificationURI)
+	if err != nil || userCode == "" {
+		return verificationURI
+	}
+	query := u.Query()
+	if query.Get("device-code") == "" {
+		query.Set("device-code", userCode)
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
 }
 
 type DeviceTokenPollResponse struct {
@@ -56,11 +79,25 @@ func NewAuthManager(clientID string) *AuthManager {
 	}
 }
 
+// SetScopes sets the optional scopes requested by the next device flow
+func (a *AuthManager) SetScopes(scopes []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.scopes = append([]string(nil), scopes...)
+}
+
+// Scopes returns every scope requested at login
+func (a *AuthManager) Scopes() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append(append([]string(nil), RequiredScopes...), a.scopes...)
+}
+
 // GenerateDeviceCode initiates the device code flow like TDM
 func (a *AuthManager) GenerateDeviceCode(ctx context.Context) (*DeviceCodeResponse, error) {
 	data := url.Values{}
 	data.Set("client_id", a.clientID)
-	data.Set("scopes", strings.Join(RequiredScopes, " "))
+	data.Set("scopes", strings.Join(a.Scopes(), " "))
 
 	req, err := http.NewRequestWithContext(ctx, "POST", DeviceCodeURL, strings.NewReader(data.Encode()))
 	if err != nil {
@@ -83,6 +120,9 @@ func (a *AuthManager) GenerateDeviceCode(ctx context.Context) (*DeviceCodeRespon
 	if err := json.NewDecoder(resp.Body).Decode(&deviceResp); err != nil {
 		return nil, fmt.Errorf("failed to decode device code response: %w", err)
 	}
+	if deviceResp.VerificationURIComplete == "" {
+		deviceResp.VerificationURIComplete = completeVerificationURI(deviceResp.VerificationURI, deviceResp.UserCode)
+	}
 
 	return &deviceResp, nil
 }
@@ -105,7 +145,7 @@ func (a *AuthManager) PollForToken(ctx context.Context, deviceCode string, inter
 		case <-ticker.C:
 			token, err := a.checkDeviceCodeStatus(ctx, deviceCode)
 			if err != nil {
-				logrus.Debugf("Device code polling error: %v", err)
+				logrus.WithContext(ctx).Debugf("Device code polling error: %v", err)
 				continue
 			}
 			if token != nil {
@@ -167,12 +207,16 @@ func (a *AuthManager) checkDeviceCodeStatus(ctx context.Context, deviceCode stri
 		AccessToken:  tokenResp.AccessToken,
 		RefreshToken: tokenResp.RefreshToken,
 		TokenType:    tokenResp.TokenType,
-		Expiry:       time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+		Expiry:       tokenExpiry(tokenResp.ExpiresIn),
 	}
 
 	return token, nil
 }
 
+// ErrRefreshRejected is returned by RefreshToken when Twitch doesn't accept the refresh token, so only a new
+// login helps; any other error may pass
+var ErrRefreshRejected = errors.New("refresh token rejected by Twitch")
+
 func (a *AuthManager) RefreshToken(ctx context.Context, refreshToken string) (*oauth2.Token, error) {
 	data := url.Values{}
 	data.Set("client_id", a.clientID)
@@ -193,6 +237,10 @@ func (a *AuthManager) RefreshToken(ctx context.Context, refreshToken string) (*o
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		// invalid_grant: the refresh token was revoked or already used
+		return nil, fmt.Errorf("%w: status %d", ErrRefreshRejected, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("token refresh failed with status: %d", resp.StatusCode)
 	}
@@ -206,28 +254,29 @@ func (a *AuthManager) RefreshToken(ctx context.Context, refreshToken string) (*o
 		AccessToken:  tokenResp.AccessToken,
 		RefreshToken: tokenResp.RefreshToken,
 		TokenType:    tokenResp.TokenType,
-		Expiry:       time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+		Expiry:       tokenExpiry(tokenResp.ExpiresIn),
 	}
 
 	return token, nil
 }
 
-func (a *AuthManager) ValidateToken(ctx context.Context, accessToken string) (*User, error) {
+// ValidateToken checks the token and returns its user and the scopes it was granted
+func (a *AuthManager) ValidateToken(ctx context.Context, accessToken string) (*User, []string, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", ValidateURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create validate request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create validate request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
 	resp, err := a.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to validate token: %w", err)
+		return nil, nil, fmt.Errorf("failed to validate token: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("token validation failed with status: %d", resp.StatusCode)
+		return nil, nil, fmt.Errorf("token validation failed with status: %d", resp.StatusCode)
 	}
 
 	var validateResp struct {
@@ -239,11 +288,16 @@ func (a *AuthManager) ValidateToken(ctx context.Context, accessToken string) (*U
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&validateResp); err != nil {
-		return nil, fmt.Errorf("failed to decode validate response: %w", err)
+		return nil, nil, fmt.Errorf("failed to decode validate response: %w", err)
 	}
 
 	// Get full user details
-	return a.getUserDetails(ctx, accessToken, validateResp.UserID)
+	user, err := a.getUserDetails(ctx, accessToken, validateResp.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, validateResp.Scopes, nil
 }
 
 func (a *AuthManager) getUserDetails(ctx context.Context, accessToken, userID string) (*User, error) {
@@ -299,7 +353,7 @@ func (a *AuthManager) RevokeToken(ctx context.Context, accessToken string) error
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		logrus.Warnf("Token revocation returned status: %d", resp.StatusCode)
+		logrus.WithContext(ctx).Warnf("Token revocation returned status: %d", resp.StatusCode)
 	}
 
 	return nil
@@ -0,0 +1,77 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetAvailableDropCampaigns returns the IDs of the campaigns a channel currently grants drops for (like TDM)
+func (g *GraphQLClient) GetAvailableDropCampaigns(ctx context.Context, channelID string) ([]string, error) {
+	resp, err := g.executeOperation(ctx, OpAvailableDrops, map[string]interface{}{
+		"channelID": channelID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var opResp OpAvailableDropsResponse
+	if err := decodeResponse(resp.Data, &opResp); err != nil {
+		return nil, err
+	}
+	if opResp.Channel == nil {
+		return nil, fmt.Errorf("channel %s not found", channelID)
+	}
+
+	campaignIDs := make([]string, 0, len(opResp.Channel.ViewerDropCampaigns))
+	for _, campaign := range opResp.Channel.ViewerDropCampaigns {
+		campaignIDs = append(campaignIDs, campaign.ID)
+	}
+	return campaignIDs, nil
+}
+
+// GetAvailableDropCampaigns returns the IDs of the campaigns a channel currently grants drops for
+func (c *Client) GetAvailableDropCampaigns(ctx context.Context, channelID string) ([]string, error) {
+	gqlClient, err := c.getGQLClient()
+	if err != nil {
+		return nil, err
+	}
+
+	campaignIDs, err := gqlClient.GetAvailableDropCampaigns(ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get available drops: %w", err)
+	}
+	return campaignIDs, nil
+}
+
+// GetLiveStream returns the stream of a channel, or an error when it is offline
+func (c *Client) GetLiveStream(ctx context.Context, channelLogin string) (*Stream, error) {
+	gqlClient, err := c.getGQLClient()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := gqlClient.GetStreamInfo(ctx, channelLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	login := info.Login
+	if login == "" {
+		login = channelLogin
+	}
+	name := info.DisplayName
+	if name == "" {
+		name = login
+	}
+	return &Stream{
+		ID:          info.BroadcastID,
+		UserID:      info.ChannelID,
+		UserLogin:   login,
+		UserName:    name,
+		GameID:      info.Game.ID,
+		GameName:    info.Game.Name,
+		Type:        "live",
+		Title:       info.Title,
+		ViewerCount: info.ViewerCount,
+	}, nil
+}
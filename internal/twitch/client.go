@@ -4,7 +4,10 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
@@ -22,6 +25,7 @@ type Client struct {
 	mu         sync.RWMutex
 	token      *oauth2.Token
 	user       *User
+	scopes     []string // granted to the current token
 	isLoggedIn bool
 
 	// TDM-style session data
@@ -30,8 +34,39 @@ type Client struct {
 
 	// Client configuration
 	clientID string
+	profile  ClientProfile
+
+	// Parsed inventory, reused for inventoryCacheTTL to keep the UI from hammering the Inventory GQL call
+	inventoryMu        sync.Mutex
+	inventoryCache     *InventoryGQL
+	inventoryFetchedAt time.Time
+
+	// Filters for GameDirectory stream lookups
+	directoryOptions DirectoryOptions
+
+	// Rendition fetched by watch requests, see SetStreamQuality
+	streamQuality string
+
+	// Additional accounts keep their token in config/tokens/<accountID>.json instead of config/token.json
+	perAccount bool
+	accountID  string
+
+	// Outbound proxy, applied through the transport shared by the auth, GraphQL and HLS requests
+	transport *http.Transport
+	proxyMu   sync.RWMutex
+	proxy     *url.URL
+
+	// Serializes token refreshes; reauthHandler runs when a refresh fails
+	refreshMu     sync.Mutex
+	reauthHandler func(user *User)
+
+	// Pauses GraphQL requests after repeated failures
+	breaker *circuitBreaker
 }
 
+// How long a fetched inventory is served from cache
+const inventoryCacheTTL = 60 * time.Second
+
 // generateNonce generates a random hex string of specified length
 func generateNonce(length int) string {
 	bytes := make([]byte, length/2)
@@ -39,22 +74,95 @@ func generateNonce(length int) string {
 	return hex.EncodeToString(bytes)
 }
 
-func NewClient(clientID string) *Client {
+func NewClient(profile ClientProfile, proxyURL string) (*Client, error) {
 	client := &Client{
-		authManager: NewAuthManager(clientID),
-		clientID:    clientID,
-		sessionID:   generateNonce(16), // 16 char hex string like TDM
-		deviceID:    generateNonce(32), // 32 char hex string like TDM
+		authManager: NewAuthManager(profile.ClientID),
+		clientID:    profile.ClientID,
+		profile:     profile,
+		sessionID:   generateNonce(16),     // 16 char hex string like TDM
+		deviceID:    profile.newDeviceID(), // 32 chars in the profile's format
+		breaker:     newCircuitBreaker(),
+	}
+	if err := client.initProxy(proxyURL); err != nil {
+		return nil, err
 	}
 
 	// Try to load existing token
 	client.loadStoredToken()
 
-	return client
+	return client, nil
+}
+
+// NewAccountClient creates a client for an additional account; an empty accountID starts logged out
+// and takes the user's ID as its account ID once PollForToken succeeds
+func NewAccountClient(profile ClientProfile, accountID, proxyURL string) (*Client, error) {
+	client := &Client{
+		authManager: NewAuthManager(profile.ClientID),
+		clientID:    profile.ClientID,
+		profile:     profile,
+		sessionID:   generateNonce(16),
+		deviceID:    profile.newDeviceID(),
+		perAccount:  true,
+		accountID:   accountID,
+		breaker:     newCircuitBreaker(),
+	}
+	if err := client.initProxy(proxyURL); err != nil {
+		return nil, err
+	}
+
+	if accountID != "" {
+		client.loadStoredToken()
+	}
+
+	return client, nil
+}
+
+// initProxy sets up the shared transport before the first request, so a stored token is validated through the proxy
+func (c *Client) initProxy(proxyURL string) error {
+	c.transport = c.newTransport()
+	c.authManager.httpClient.Transport = c.transport
+	return c.SetProxy(proxyURL)
+}
+
+// newGraphQLClient creates the GraphQL client for token, sending its requests through the client's proxy
+func (c *Client) newGraphQLClient(accessToken string) *GraphQLClient {
+	gqlClient := NewGraphQLClient(c.profile.ClientInfo, accessToken, c.sessionID, c.deviceID)
+	gqlClient.httpClient.Transport = c.transport
+	gqlClient.breaker = c.breaker
+	return gqlClient
+}
+
+// AccountID returns the ID the client's token is stored under, empty for the primary account
+func (c *Client) AccountID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.accountID
+}
+
+func (c *Client) saveToken(accountID string, token *oauth2.Token) error {
+	if c.perAccount {
+		return config.SaveAccountToken(accountID, token)
+	}
+	return config.SaveToken(token)
+}
+
+func (c *Client) loadToken(accountID string) (*oauth2.Token, error) {
+	if c.perAccount {
+		return config.LoadAccountToken(accountID)
+	}
+	return config.LoadToken()
+}
+
+func (c *Client) deleteToken(accountID string) error {
+	if c.perAccount {
+		return config.DeleteAccountToken(accountID)
+	}
+	return config.DeleteToken()
 }
 
 func (c *Client) loadStoredToken() {
-	token, err := config.LoadToken()
+	accountID := c.AccountID()
+	token, err := c.loadToken(accountID)
 	if err != nil {
 		logrus.Debugf("No stored token found: %v", err)
 		return
@@ -64,33 +172,59 @@ func (c *Client) loadStoredToken() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	user, err := c.authManager.ValidateToken(ctx, token.AccessToken)
+	user, scopes, err := c.authManager.ValidateToken(ctx, token.AccessToken)
+	if err != nil && c.isAuthError(err) && token.RefreshToken != "" {
+		logrus.Debugf("Stored token rejected, refreshing it: %v", err)
+		refreshed, refreshErr := c.authManager.RefreshToken(ctx, token.RefreshToken)
+		if refreshErr != nil && !errors.Is(refreshErr, ErrRefreshRejected) {
+			// Twitch couldn't be reached; keep the token, RunTokenValidation tries again later
+			logrus.Warnf("Could not refresh stored token: %v", refreshErr)
+			return
+		}
+		if refreshErr == nil {
+			if refreshed.RefreshToken == "" {
+				refreshed.RefreshToken = token.RefreshToken
+			}
+			token = refreshed
+			user, scopes, err = c.authManager.ValidateToken(ctx, token.AccessToken)
+		}
+	}
 	if err != nil {
+		if !c.isAuthError(err) {
+			// Twitch couldn't be reached; keep the token, RunTokenValidation tries again later
+			logrus.Warnf("Could not validate stored token: %v", err)
+			return
+		}
 		logrus.Debugf("Stored token invalid: %v", err)
-		// Only delete if actually invalid (not just expired according to our local time)
-		config.DeleteToken()
+		// Only delete if Twitch actually rejected it and it couldn't be refreshed
+		c.deleteToken(accountID)
 		return
 	}
 
-	// Token is valid, set it and extend expiry to 1 year like TDM
-	token.Expiry = time.Now().Add(365 * 24 * time.Hour) // 1 year
+	token = token.WithExtra(map[string]interface{}{"scopes": scopes})
 
 	c.mu.Lock()
 	c.token = token
 	c.user = user
+	c.scopes = scopes
 	c.isLoggedIn = true
 	// Initialize TDM-style GraphQL client with token
-	c.gqlClient = NewGraphQLClient(token.AccessToken, c.sessionID, c.deviceID)
+	c.gqlClient = c.newGraphQLClient(token.AccessToken)
 	c.mu.Unlock()
 
-	// Save the token with extended expiry
-	if err := config.SaveToken(token); err != nil {
-		logrus.Errorf("Failed to save extended token: %v", err)
+	// Save the token, which may have been refreshed
+	if err := c.saveToken(accountID, token); err != nil {
+		logrus.Errorf("Failed to save token: %v", err)
 	}
 
 	logrus.Infof("Loaded stored authentication for %s", user.DisplayName)
 }
 
+// ReloadToken re-reads the stored token, e.g. after a state import replaced it
+func (c *Client) ReloadToken() {
+	c.loadStoredToken()
+}
+
 // Authentication methods - Device Code Flow (like TDM)
 func (c *Client) StartDeviceFlow(ctx context.Context) (*DeviceCodeResponse, error) {
 	return c.authManager.GenerateDeviceCode(ctx)
@@ -101,31 +235,54 @@ func (c *Client) PollForToken(ctx context.Context, deviceCode string, interval i
 	if err != nil {
 		return fmt.Errorf("failed to poll for token: %w", err)
 	}
+	return c.login(ctx, token)
+}
 
-	user, err := c.authManager.ValidateToken(ctx, token.AccessToken)
+// ErrTokenRejected is returned by ImportToken when Twitch doesn't accept the token
+var ErrTokenRejected = errors.New("token rejected by Twitch")
+
+// ImportToken logs in with a token obtained elsewhere, e.g. by TwitchDropsMiner with the same client ID
+func (c *Client) ImportToken(ctx context.Context, token *oauth2.Token) error {
+	if err := c.login(ctx, token); err != nil {
+		if c.isAuthError(err) {
+			return fmt.Errorf("%w: %v", ErrTokenRejected, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// login validates a new token and makes it the client's, saving it
+func (c *Client) login(ctx context.Context, token *oauth2.Token) error {
+	user, scopes, err := c.authManager.ValidateToken(ctx, token.AccessToken)
 	if err != nil {
 		return fmt.Errorf("failed to validate token: %w", err)
 	}
 
-	// Set token expiry to 1 year (like TDM)
-	token.Expiry = time.Now().Add(365 * 24 * time.Hour)
+	// Record the granted scopes with the token
+	token = token.WithExtra(map[string]interface{}{"scopes": scopes})
 
 	c.mu.Lock()
 	c.token = token
 	c.user = user
+	c.scopes = scopes
 	c.isLoggedIn = true
+	if c.perAccount {
+		c.accountID = user.ID
+	}
+	accountID := c.accountID
 	// Initialize TDM-style GraphQL client with token
-	c.gqlClient = NewGraphQLClient(token.AccessToken, c.sessionID, c.deviceID)
+	c.gqlClient = c.newGraphQLClient(token.AccessToken)
 	c.mu.Unlock()
 
 	// Save token to persistent storage
-	if err := config.SaveToken(token); err != nil {
-		logrus.Errorf("Failed to save token: %v", err)
+	if err := c.saveToken(accountID, token); err != nil {
+		logrus.WithContext(ctx).Errorf("Failed to save token: %v", err)
 	} else {
-		logrus.Debug("Token saved to persistent storage")
+		logrus.WithContext(ctx).Debug("Token saved to persistent storage")
 	}
 
-	logrus.Infof("Successfully authenticated as %s", user.DisplayName)
+	logrus.WithContext(ctx).Infof("Successfully authenticated as %s", user.DisplayName)
 	return nil
 }
 
@@ -145,39 +302,76 @@ func (c *Client) GetUser() *User {
 	return &userCopy
 }
 
+// SetAuthScopes sets the optional scopes requested at the next login
+func (c *Client) SetAuthScopes(scopes []string) {
+	c.authManager.SetScopes(scopes)
+}
+
+// GetScopes returns the scopes granted to the current token
+func (c *Client) GetScopes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string{}, c.scopes...)
+}
+
+// MissingScopes returns the configured scopes the current token wasn't granted, which need a new login
+func (c *Client) MissingScopes() []string {
+	granted := make(map[string]bool)
+	for _, scope := range c.GetScopes() {
+		granted[scope] = true
+	}
+
+	missing := []string{}
+	for _, scope := range c.authManager.Scopes() {
+		if !granted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
 func (c *Client) Logout(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.token != nil {
 		if err := c.authManager.RevokeToken(ctx, c.token.AccessToken); err != nil {
-			logrus.Warnf("Failed to revoke token: %v", err)
+			logrus.WithContext(ctx).Warnf("Failed to revoke token: %v", err)
 		}
 	}
 
 	// Delete stored token
-	if err := config.DeleteToken(); err != nil {
-		logrus.Warnf("Failed to delete stored token: %v", err)
+	if err := c.deleteToken(c.accountID); err != nil {
+		logrus.WithContext(ctx).Warnf("Failed to delete stored token: %v", err)
 	}
 
 	c.token = nil
 	c.user = nil
+	c.scopes = nil
 	c.isLoggedIn = false
+	c.InvalidateInventory()
 
-	logrus.Info("Successfully logged out")
+	logrus.WithContext(ctx).Info("Successfully logged out")
 	return nil
 }
 
 func (c *Client) refreshTokenIfNeeded(ctx context.Context) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.mu.RLock()
+	token := c.token
+	c.mu.RUnlock()
 
-	if c.token == nil {
+	if token == nil {
 		return fmt.Errorf("no token available")
 	}
 
-	// Don't check local expiry - let Twitch tell us if the token is invalid
-	// This is more like TDM behavior where tokens work until Twitch says they don't
+	// Refresh shortly before the expiry Twitch reported; tokens without one (like older stored ones)
+	// are used until Twitch rejects them
+	if token.Expiry.IsZero() || time.Until(token.Expiry) > tokenRefreshMargin || token.RefreshToken == "" {
+		return nil
+	}
+	if err := c.refreshToken(ctx); err != nil {
+		logrus.WithContext(ctx).Warnf("Failed to refresh expiring token: %v", err)
+	}
 	return nil
 }
 
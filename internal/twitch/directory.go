@@ -0,0 +1,96 @@
+package twitch
+
+import (
+	"regexp"
+	"strings"
+
+	"twitchdropsfarmer/internal/config"
+)
+
+// Directory sort orders accepted by the GameDirectory operation
+const (
+	DirectorySortRelevance   = "RELEVANCE"
+	DirectorySortViewerCount = "VIEWER_COUNT"
+)
+
+// Twitch's broadcaster languages are ISO 639-1 codes, plus "asl" and "other"
+var streamLanguagePattern = regexp.MustCompile(`^(?i:[a-z]{2}|asl|other)$`)
+
+// ValidStreamLanguage reports whether language is a broadcaster language Twitch filters by
+func ValidStreamLanguage(language string) bool {
+	return streamLanguagePattern.MatchString(language)
+}
+
+// DirectoryOptions are the stream filters sent with GameDirectory
+type DirectoryOptions struct {
+	SystemFilters []string // e.g. DROPS_ENABLED
+	Tags          []string
+	Sort          string
+	Languages     []string // broadcaster languages, e.g. "en", none for any
+}
+
+// NewDirectoryOptions builds the directory options from the application settings
+func NewDirectoryOptions(cfg *config.Config) DirectoryOptions {
+	return DirectoryOptions{
+		SystemFilters: cfg.DirectoryFilters,
+		Tags:          cfg.DirectoryTags,
+		Sort:          cfg.DirectorySort,
+		Languages:     cfg.StreamLanguages,
+	}
+}
+
+// SetDirectoryOptions sets the filters used when looking up streams for a game
+func (c *Client) SetDirectoryOptions(opts DirectoryOptions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.directoryOptions = opts
+}
+
+// LanguageAllowed reports whether a stream in language passes a language allowlist; an empty allowlist, or a
+// stream whose language isn't known, passes
+func LanguageAllowed(languages []string, language string) bool {
+	if len(languages) == 0 || language == "" {
+		return true
+	}
+	for _, allowed := range languages {
+		if strings.EqualFold(allowed, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// variables returns the GameDirectory "options" variable with these filters applied
+func (opts DirectoryOptions) variables() map[string]interface{} {
+	options := make(map[string]interface{})
+	if base, ok := GQLOperations[OpGameDirectory].Variables["options"].(map[string]interface{}); ok {
+		for k, v := range base {
+			options[k] = v
+		}
+	}
+
+	systemFilters := []interface{}{}
+	for _, filter := range opts.SystemFilters {
+		systemFilters = append(systemFilters, filter)
+	}
+	options["systemFilters"] = systemFilters
+
+	tags := []interface{}{}
+	for _, tag := range opts.Tags {
+		tags = append(tags, tag)
+	}
+	options["tags"] = tags
+
+	// Twitch expects upper case codes here, e.g. "EN"
+	languages := []interface{}{}
+	for _, language := range opts.Languages {
+		languages = append(languages, strings.ToUpper(language))
+	}
+	options["broadcasterLanguages"] = languages
+
+	if opts.Sort != "" {
+		options["sort"] = opts.Sort
+	}
+
+	return options
+}
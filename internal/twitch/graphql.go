@@ -5,19 +5,29 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"twitchdropsfarmer/internal/telemetry"
+
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	GraphQLEndpoint = "https://gql.twitch.tv/gql"
 )
 
+// ErrStreamOffline is returned by watch requests once the channel's playlists are gone
+var ErrStreamOffline = errors.New("stream is offline")
+
 // GraphQLClient handles GraphQL requests to Twitch, exactly like TDM
 type GraphQLClient struct {
 	httpClient  *http.Client
@@ -25,27 +35,24 @@ type GraphQLClient struct {
 	accessToken string
 	sessionID   string
 	deviceID    string
+	breaker     *circuitBreaker // shared by the clients of one account, nil for none
+
+	// Client-Integrity token for claim and points operations
+	integrityToken integrityToken
 }
 
-// ClientInfo matches TDM's ClientType.ANDROID_APP
+// ClientInfo matches TDM's ClientType, see ClientProfile
 type ClientInfo struct {
 	ClientURL string
 	ClientID  string
 	UserAgent string
 }
 
-// NewGraphQLClient creates a new GraphQL client with TDM's exact configuration
-func NewGraphQLClient(accessToken, sessionID, deviceID string) *GraphQLClient {
-	// Use TDM's exact Android app client info
-	clientInfo := &ClientInfo{
-		ClientURL: "https://www.twitch.tv",
-		ClientID:  "kd1unb4b3q4t58fwlpcbzcbnm76a8fp",
-		UserAgent: "Dalvik/2.1.0 (Linux; U; Android 7.1.2; SM-G977N Build/LMY48Z) tv.twitch.android.app/16.8.1/1608010",
-	}
-
+// NewGraphQLClient creates a new GraphQL client with TDM's exact configuration, identifying as clientInfo
+func NewGraphQLClient(clientInfo ClientInfo, accessToken, sessionID, deviceID string) *GraphQLClient {
 	return &GraphQLClient{
 		httpClient:  &http.Client{Timeout: 30 * time.Second},
-		clientInfo:  clientInfo,
+		clientInfo:  &clientInfo,
 		accessToken: accessToken,
 		sessionID:   sessionID,
 		deviceID:    deviceID,
@@ -81,9 +88,30 @@ func (g *GraphQLClient) Headers(gql bool) map[string]string {
 	return headers
 }
 
+// GraphQL requests of every client that haven't returned yet, retries and backoff included
+var gqlInFlight atomic.Int64
+
+// Spans around the GraphQL requests, exported when tracing is set up
+var tracer = otel.Tracer("twitchdropsfarmer/internal/twitch")
+
+// GQLInFlight returns how many GraphQL requests are waiting on Twitch across all clients; a number that keeps
+// growing points at requests that never return
+func GQLInFlight() int64 {
+	return gqlInFlight.Load()
+}
+
 // GQLRequest executes GraphQL requests exactly like TDM's gql_request method
-func (g *GraphQLClient) GQLRequest(ctx context.Context, operation *GQLOperation) (*GraphQLResponse, error) {
-	// No GraphQL logging
+// Transient failures are retried with backoff; after repeated failures the client's requests are paused
+func (g *GraphQLClient) GQLRequest(ctx context.Context, operation *GQLOperation) (_ *GraphQLResponse, err error) {
+	ctx, span := tracer.Start(ctx, "gql "+operation.OperationName, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("graphql.operation.name", operation.OperationName)))
+	defer func() { telemetry.End(span, err) }()
+
+	if err := g.breaker.allow(); err != nil {
+		return nil, err
+	}
+	gqlInFlight.Add(1)
+	defer gqlInFlight.Add(-1)
 
 	// Convert operation to JSON
 	jsonBody, err := operation.ToJSON()
@@ -91,6 +119,57 @@ func (g *GraphQLClient) GQLRequest(ctx context.Context, operation *GQLOperation)
 		return nil, fmt.Errorf("failed to marshal operation: %w", err)
 	}
 
+	integrity := g.integrityHeader(ctx, operation.OperationName)
+	integrityRefreshed := false
+	for attempt := 0; ; attempt++ {
+		gqlResp, err := g.doGQLRequest(ctx, jsonBody, integrity)
+		if err == nil {
+			g.breaker.success()
+			return gqlResp, nil
+		}
+
+		if integrity != "" && !integrityRefreshed && failedIntegrity(gqlResp) {
+			// The token was revoked or expired early, fetch a new one and send the operation again
+			logrus.WithContext(ctx).Debugf("%s failed the integrity check, refreshing the integrity token", operation.OperationName)
+			g.invalidateIntegrity()
+			integrity = g.integrityHeader(ctx, operation.OperationName)
+			integrityRefreshed = true
+			attempt--
+			continue
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			// Twitch answered, the request itself is wrong (or the token is)
+			g.breaker.success()
+			return gqlResp, err
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := backoffDelay(attempt)
+		if retryable.retryAfter > delay {
+			delay = retryable.retryAfter
+		}
+		if attempt+1 >= gqlMaxAttempts || delay > gqlMaxRetryAfter {
+			g.breaker.failure()
+			return gqlResp, err
+		}
+
+		logrus.WithContext(ctx).Debugf("Retrying %s in %s: %v", operation.OperationName, delay.Round(time.Millisecond), err)
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt+1), attribute.String("error", err.Error())))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// doGQLRequest sends one GraphQL request, wrapping transient failures in retryableError
+// A non-empty integrity token is sent as the Client-Integrity header
+func (g *GraphQLClient) doGQLRequest(ctx context.Context, jsonBody []byte, integrity string) (*GraphQLResponse, error) {
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "POST", GraphQLEndpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
@@ -102,20 +181,19 @@ func (g *GraphQLClient) GQLRequest(ctx context.Context, operation *GQLOperation)
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
-
-	// No GraphQL headers logging
+	if integrity != "" {
+		req.Header.Set("Client-Integrity", integrity)
+	}
 
 	// Execute request
 	resp, err := g.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, &retryableError{err: fmt.Errorf("failed to execute request: %w", err)}
 	}
 	defer resp.Body.Close()
 
-	// No GraphQL status logging
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GraphQL request failed with status: %d", resp.StatusCode)
+		return nil, statusError(resp)
 	}
 
 	// Handle gzip compression
@@ -123,7 +201,7 @@ func (g *GraphQLClient) GQLRequest(ctx context.Context, operation *GQLOperation)
 	if resp.Header.Get("Content-Encoding") == "gzip" {
 		gzipReader, err := gzip.NewReader(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+			return nil, &retryableError{err: fmt.Errorf("failed to create gzip reader: %w", err)}
 		}
 		defer gzipReader.Close()
 		reader = gzipReader
@@ -132,18 +210,18 @@ func (g *GraphQLClient) GQLRequest(ctx context.Context, operation *GQLOperation)
 	// Parse response
 	var gqlResp GraphQLResponse
 	if err := json.NewDecoder(reader).Decode(&gqlResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, &retryableError{err: fmt.Errorf("failed to decode response: %w", err)}
 	}
 
-	// Handle GraphQL errors like TDM
+	// Handle GraphQL errors like TDM, which retries the transient ones
 	if len(gqlResp.Errors) > 0 {
-		for _, err := range gqlResp.Errors {
-			if err.Message == "service error" || err.Message == "PersistedQueryNotFound" {
-				logrus.Errorf("Retrying a %s for %s", err.Message, operation.OperationName)
-				// TDM would retry here, but for now we'll just log and continue
+		err := fmt.Errorf("GraphQL errors: %v", gqlResp.Errors)
+		for _, gqlErr := range gqlResp.Errors {
+			if retryableGQLErrors[gqlErr.Message] {
+				return &gqlResp, &retryableError{err: err}
 			}
 		}
-		return &gqlResp, fmt.Errorf("GraphQL errors: %v", gqlResp.Errors)
+		return &gqlResp, err
 	}
 
 	return &gqlResp, nil
@@ -196,10 +274,11 @@ func (g *GraphQLClient) GetInventory(ctx context.Context) (*InventoryGQL, error)
 }
 
 // GetStreamsForGame fetches live streams for a specific game using TDM's approach
-func (g *GraphQLClient) GetStreamsForGame(ctx context.Context, gameSlug string, limit int) ([]Stream, error) {
+func (g *GraphQLClient) GetStreamsForGame(ctx context.Context, gameSlug string, limit int, opts DirectoryOptions) ([]Stream, error) {
 	resp, err := g.executeOperation(ctx, OpGameDirectory, map[string]interface{}{
-		"slug":  gameSlug,
-		"limit": limit,
+		"slug":    gameSlug,
+		"limit":   limit,
+		"options": opts.variables(),
 	})
 	if err != nil {
 		return nil, err
@@ -211,7 +290,7 @@ func (g *GraphQLClient) GetStreamsForGame(ctx context.Context, gameSlug string,
 		return nil, fmt.Errorf("failed to parse streams: %w", err)
 	}
 
-	logrus.Debugf("Found %d streams for game slug '%s'", len(streams), gameSlug)
+	logrus.WithContext(ctx).Debugf("Found %d streams for game slug '%s'", len(streams), gameSlug)
 	return streams, nil
 }
 
@@ -227,8 +306,91 @@ func (g *GraphQLClient) ClaimDrop(ctx context.Context, dropInstanceID string) er
 	}
 
 	// Check for successful claim (simplified for now)
-	logrus.Debugf("Drop claim response: %+v", resp.Data)
+	logrus.WithContext(ctx).Debugf("Drop claim response: %+v", resp.Data)
+
+	return nil
+}
+
+// GetChannelPoints fetches the channel points balance and any available bonus claim (like TDM)
+func (g *GraphQLClient) GetChannelPoints(ctx context.Context, channelLogin string) (*ChannelPoints, error) {
+	resp, err := g.executeOperation(ctx, OpChannelPointsContext, map[string]interface{}{
+		"channelLogin": channelLogin,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dataBytes, err := json.Marshal(resp.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal channel points data: %w", err)
+	}
+
+	var opResp OpChannelPointsContextResponse
+	if err := json.Unmarshal(dataBytes, &opResp); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling OpChannelPointsContextResponse: %w", err)
+	}
+
+	if opResp.Community == nil || opResp.Community.Channel == nil {
+		return nil, fmt.Errorf("channel %s not found", channelLogin)
+	}
+
+	channel := opResp.Community.Channel
+	points := &ChannelPoints{
+		ChannelID:    channel.ID,
+		ChannelLogin: channelLogin,
+		Balance:      channel.Self.CommunityPoints.Balance,
+	}
+	if claim := channel.Self.CommunityPoints.AvailableClaim; claim != nil {
+		points.ClaimID = claim.ID
+	}
+
+	return points, nil
+}
+
+// ClaimCommunityPoints claims an available channel points bonus (like TDM)
+func (g *GraphQLClient) ClaimCommunityPoints(ctx context.Context, channelID, claimID string) error {
+	resp, err := g.executeOperation(ctx, OpClaimCommunityPoints, map[string]interface{}{
+		"input": map[string]interface{}{
+			"channelID": channelID,
+			"claimID":   claimID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithContext(ctx).Debugf("Points claim response: %+v", resp.Data)
+	return nil
+}
+
+// FollowChannel follows a channel without enabling live notifications
+func (g *GraphQLClient) FollowChannel(ctx context.Context, channelID string) error {
+	resp, err := g.executeOperation(ctx, OpFollowUser, map[string]interface{}{
+		"input": map[string]interface{}{
+			"disableNotifications": true,
+			"targetID":             channelID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithContext(ctx).Debugf("Follow response: %+v", resp.Data)
+	return nil
+}
+
+// UnfollowChannel unfollows a channel
+func (g *GraphQLClient) UnfollowChannel(ctx context.Context, channelID string) error {
+	resp, err := g.executeOperation(ctx, OpUnfollowUser, map[string]interface{}{
+		"input": map[string]interface{}{
+			"targetID": channelID,
+		},
+	})
+	if err != nil {
+		return err
+	}
 
+	logrus.WithContext(ctx).Debugf("Unfollow response: %+v", resp.Data)
 	return nil
 }
 
@@ -252,52 +414,22 @@ func (g *GraphQLClient) GetGameSlug(ctx context.Context, gameName string) (*Game
 
 // parseSlugRedirectResponse parses the slug redirect response
 func (g *GraphQLClient) parseSlugRedirectResponse(data interface{}) (*GameSlugInfo, error) {
-	// Debug: Log the full response structure
-	responseJSON, _ := json.MarshalIndent(data, "", "  ")
-	logrus.Debugf("SlugRedirect response structure:\n%s", string(responseJSON))
-
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response data format")
+	var opResp OpSlugRedirectResponse
+	if err := decodeResponse(data, &opResp); err != nil {
+		return nil, err
 	}
 
-	// Debug: Log available keys
-	logrus.Debugf("Available keys in response: %+v", getKeys(dataMap))
-
-	gameDirectory, ok := dataMap["gameDirectory"]
-	if !ok || gameDirectory == nil {
-		// Let's try different possible field names
-		if game, ok := dataMap["game"]; ok {
-			gameDirectory = game
-		} else if directoryGame, ok := dataMap["directoryGame"]; ok {
-			gameDirectory = directoryGame
-		} else {
-			return nil, fmt.Errorf("no gameDirectory in response (available keys: %+v)", getKeys(dataMap))
-		}
+	game := opResp.Game
+	if game == nil {
+		return nil, fmt.Errorf("no game in response")
 	}
-
-	gameDirectoryMap, ok := gameDirectory.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid gameDirectory format")
-	}
-
-	// Debug: Log available keys in gameDirectory
-	logrus.Debugf("Available keys in gameDirectory: %+v", getKeys(gameDirectoryMap))
-
-	// Extract slug and ID from the response
-	slug, ok := gameDirectoryMap["slug"].(string)
-	if !ok {
-		return nil, fmt.Errorf("no slug in gameDirectory (available keys: %+v)", getKeys(gameDirectoryMap))
-	}
-
-	id, ok := gameDirectoryMap["id"].(string)
-	if !ok {
-		return nil, fmt.Errorf("no id in gameDirectory (available keys: %+v)", getKeys(gameDirectoryMap))
+	if game.ID == "" || stringValue(game.Slug) == "" {
+		return nil, fmt.Errorf("no id or slug in game")
 	}
 
 	return &GameSlugInfo{
-		ID:   id,
-		Slug: slug,
+		ID:   game.ID,
+		Slug: *game.Slug,
 	}, nil
 }
 
@@ -321,27 +453,20 @@ func (g *GraphQLClient) GetPlaybackAccessToken(ctx context.Context, channelLogin
 
 // parsePlaybackTokenResponse parses the playback access token response
 func (g *GraphQLClient) parsePlaybackTokenResponse(data interface{}) (*PlaybackAccessToken, error) {
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response data format")
+	var opResp OpPlaybackAccessTokenResponse
+	if err := decodeResponse(data, &opResp); err != nil {
+		return nil, err
 	}
 
-	streamPlaybackAccessToken, ok := dataMap["streamPlaybackAccessToken"]
-	if !ok || streamPlaybackAccessToken == nil {
+	token := opResp.StreamPlaybackAccessToken
+	if token == nil {
 		return nil, fmt.Errorf("no streamPlaybackAccessToken in response")
 	}
 
-	tokenMap, ok := streamPlaybackAccessToken.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid streamPlaybackAccessToken format")
-	}
-
-	token := &PlaybackAccessToken{
-		Value:     getString(tokenMap, "value"),
-		Signature: getString(tokenMap, "signature"),
-	}
-
-	return token, nil
+	return &PlaybackAccessToken{
+		Value:     token.Value,
+		Signature: token.Signature,
+	}, nil
 }
 
 // GetStreamURL gets the HLS stream URL for watching (like TDM)
@@ -357,17 +482,19 @@ func (g *GraphQLClient) GetStreamURL(ctx context.Context, channelLogin string, t
 		generateRandomNumber())
 
 	streamURL := baseURL + params
-	logrus.Debugf("Generated stream URL for %s", channelLogin)
+	logrus.WithContext(ctx).Debugf("Generated stream URL for %s", channelLogin)
 
 	return streamURL, nil
 }
 
 // SendWatchRequest sends a HEAD request to simulate watching (exactly like TDM)
-func (g *GraphQLClient) SendWatchRequest(ctx context.Context, streamURL string) error {
+// The rendition is picked from the master playlist by quality, see SetStreamQuality
+// It returns the number of bytes downloaded for the playlists
+func (g *GraphQLClient) SendWatchRequest(ctx context.Context, streamURL, quality string) (int64, error) {
 	// Get the m3u8 playlist first
 	req, err := http.NewRequestWithContext(ctx, "GET", streamURL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create playlist request: %w", err)
+		return 0, fmt.Errorf("failed to create playlist request: %w", err)
 	}
 
 	// Set headers like TDM
@@ -376,120 +503,125 @@ func (g *GraphQLClient) SendWatchRequest(ctx context.Context, streamURL string)
 
 	resp, err := g.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to get playlist: %w", err)
+		return 0, fmt.Errorf("failed to get playlist: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		// Usher has no playlist for channels that aren't live
+		return 0, fmt.Errorf("%w: playlist request failed with status: %d", ErrStreamOffline, resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return 0, fmt.Errorf("%w: playlist request failed with status: %d", ErrRateLimited, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("playlist request failed with status: %d", resp.StatusCode)
+		return 0, fmt.Errorf("playlist request failed with status: %d", resp.StatusCode)
 	}
 
 	// Read playlist content
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read playlist: %w", err)
+		return 0, fmt.Errorf("failed to read playlist: %w", err)
 	}
 
 	// Parse m3u8 to find a stream playlist URL first
 	playlistContent := string(body)
-	logrus.Debugf("M3U8 master playlist received")
+	logrus.WithContext(ctx).Debugf("M3U8 master playlist received")
 
 	// Extract a stream playlist URL (not chunk URL yet)
-	streamPlaylistURL, err := g.extractStreamPlaylistURL(playlistContent)
+	streamPlaylistURL, err := g.extractStreamPlaylistURL(playlistContent, streamURL, quality)
 	if err != nil {
-		return fmt.Errorf("failed to extract stream playlist URL: %w", err)
+		return int64(len(body)), fmt.Errorf("failed to extract stream playlist URL: %w", err)
 	}
 
 	// Now get the actual stream playlist with chunks
-	chunkURL, err := g.getLastChunkFromPlaylist(ctx, streamPlaylistURL)
+	chunkURL, playlistBytes, err := g.getLastChunkFromPlaylist(ctx, streamPlaylistURL)
+	downloaded := int64(len(body)) + playlistBytes
 	if err != nil {
-		return fmt.Errorf("failed to get chunk from stream playlist: %w", err)
+		return downloaded, fmt.Errorf("failed to get chunk from stream playlist: %w", err)
 	}
 
 	// Send HEAD request to the chunk (this is what advances drops)
 	headReq, err := http.NewRequestWithContext(ctx, "HEAD", chunkURL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create watch request: %w", err)
+		return downloaded, fmt.Errorf("failed to create watch request: %w", err)
 	}
 
 	headReq.Header.Set("User-Agent", g.clientInfo.UserAgent)
 
 	headResp, err := g.httpClient.Do(headReq)
 	if err != nil {
-		return fmt.Errorf("failed to send watch request: %w", err)
+		return downloaded, fmt.Errorf("failed to send watch request: %w", err)
 	}
 	defer headResp.Body.Close()
 
-	logrus.Debugf("Watch request sent, status: %d", headResp.StatusCode)
-	return nil
+	logrus.WithContext(ctx).Debugf("Watch request sent, status: %d", headResp.StatusCode)
+	return downloaded, nil
 }
 
-// extractStreamPlaylistURL extracts a stream playlist URL from master playlist
-func (g *GraphQLClient) extractStreamPlaylistURL(masterPlaylist string) (string, error) {
-	lines := strings.Split(masterPlaylist, "\n")
-
-	// Find any stream playlist URL (they end with .m3u8)
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "http") && strings.Contains(line, ".m3u8") {
-			return line, nil
-		}
+// extractStreamPlaylistURL extracts the stream playlist URL of the rendition matching quality from master playlist
+func (g *GraphQLClient) extractStreamPlaylistURL(masterPlaylist, masterURL, quality string) (string, error) {
+	variant, err := selectVariant(parseMasterPlaylist(masterPlaylist, masterURL), quality)
+	if err != nil {
+		return "", err
 	}
 
-	return "", fmt.Errorf("no stream playlist URL found in master playlist")
+	logrus.Debugf("Selected %s rendition (%d bps)", variant.Group, variant.Bandwidth)
+	return variant.URI, nil
 }
 
-// getLastChunkFromPlaylist fetches a stream playlist and extracts the last chunk
-func (g *GraphQLClient) getLastChunkFromPlaylist(ctx context.Context, playlistURL string) (string, error) {
+// getLastChunkFromPlaylist fetches a stream playlist and extracts the last chunk, returning the playlist size too
+func (g *GraphQLClient) getLastChunkFromPlaylist(ctx context.Context, playlistURL string) (string, int64, error) {
 	// Fetch the stream playlist
 	req, err := http.NewRequestWithContext(ctx, "GET", playlistURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create stream playlist request: %w", err)
+		return "", 0, fmt.Errorf("failed to create stream playlist request: %w", err)
 	}
 
 	req.Header.Set("User-Agent", g.clientInfo.UserAgent)
 
 	resp, err := g.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to get stream playlist: %w", err)
+		return "", 0, fmt.Errorf("failed to get stream playlist: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return "", 0, fmt.Errorf("%w: stream playlist request failed with status: %d", ErrStreamOffline, resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", 0, fmt.Errorf("%w: stream playlist request failed with status: %d", ErrRateLimited, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("stream playlist request failed with status: %d", resp.StatusCode)
+		return "", 0, fmt.Errorf("stream playlist request failed with status: %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read stream playlist: %w", err)
+		return "", 0, fmt.Errorf("failed to read stream playlist: %w", err)
 	}
 
 	// Extract the last chunk from this playlist
 	streamPlaylistContent := string(body)
-	logrus.Debugf("Received stream playlist with %d lines", len(strings.Split(streamPlaylistContent, "\n")))
-	return g.extractLastChunk(streamPlaylistContent, playlistURL)
+	logrus.WithContext(ctx).Debugf("Received stream playlist with %d lines", len(strings.Split(streamPlaylistContent, "\n")))
+	chunkURL, err := g.extractLastChunk(streamPlaylistContent, playlistURL)
+	return chunkURL, int64(len(body)), err
 }
 
-// extractLastChunk extracts the last chunk URL from m3u8 playlist (like TDM)
+// extractLastChunk extracts the newest chunk URL from m3u8 playlist (like TDM), skipping ad segments
 func (g *GraphQLClient) extractLastChunk(playlist, baseURL string) (string, error) {
-	lines := strings.Split(playlist, "\n")
-	var lastChunkLine string
-
-	// Find the last .ts file in the playlist (including query parameters)
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// Check if line contains .ts (might have query params after)
-		if strings.Contains(line, ".ts") && strings.HasPrefix(line, "http") {
-			lastChunkLine = line
-		}
+	segment, ok := lastLiveSegment(parseMediaPlaylist(playlist, baseURL))
+	if !ok {
+		// A live playlist always lists its latest chunks
+		return "", fmt.Errorf("%w: no chunk found in playlist", ErrStreamOffline)
 	}
 
-	if lastChunkLine == "" {
-		return "", fmt.Errorf("no chunk found in playlist (looked for .ts URLs)")
+	if segment.Ad {
+		logrus.Debugf("Playlist only lists ad segments, selected chunk URL: %s", segment.URI)
+	} else {
+		logrus.Debugf("Selected chunk URL: %s", segment.URI)
 	}
-
-	logrus.Debugf("Selected chunk URL: %s", lastChunkLine)
-	return lastChunkLine, nil
+	return segment.URI, nil
 }
 
 // generateRandomNumber generates a random number like TDM does
@@ -500,51 +632,19 @@ func generateRandomNumber() int {
 
 // parseCampaignsResponse parses the campaigns GraphQL response
 func (g *GraphQLClient) parseCampaignsResponse(data interface{}) ([]Campaign, error) {
-	logrus.Debugf("Processing campaigns response")
-
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response data format")
+	var opResp OpCampaignsResponse
+	if err := decodeResponse(data, &opResp); err != nil {
+		return nil, err
 	}
 
-	currentUser, ok := dataMap["currentUser"]
-	if !ok || currentUser == nil {
+	if opResp.CurrentUser == nil {
 		logrus.Warning("No currentUser in GraphQL response - authentication may be invalid")
 		return []Campaign{}, nil
 	}
 
-	userMap, ok := currentUser.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid currentUser format")
-	}
-
-	// Look for dropCampaigns in the user data
-	dropCampaigns, ok := userMap["dropCampaigns"]
-	if !ok {
-		logrus.Debug("No dropCampaigns found in currentUser")
-		return []Campaign{}, nil
-	}
-
-	campaignsList, ok := dropCampaigns.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid dropCampaigns format - expected array")
-	}
-
-	var campaigns []Campaign
-	for i, campaignInterface := range campaignsList {
-		campaignMap, ok := campaignInterface.(map[string]interface{})
-		if !ok {
-			logrus.Errorf("Campaign %d: invalid campaign format", i)
-			continue
-		}
-
-		campaign, err := g.parseCampaignNode(campaignMap)
-		if err != nil {
-			logrus.Errorf("Campaign %d: Failed to parse campaign: %v", i, err)
-			continue
-		}
-
-		campaigns = append(campaigns, *campaign)
+	campaigns := make([]Campaign, 0, len(opResp.CurrentUser.DropCampaigns))
+	for i := range opResp.CurrentUser.DropCampaigns {
+		campaigns = append(campaigns, *opResp.CurrentUser.DropCampaigns[i].toCampaign())
 	}
 
 	logrus.Debugf("Parsed %d campaigns from response", len(campaigns))
@@ -572,223 +672,58 @@ func (g *GraphQLClient) GetCampaignDetails(ctx context.Context, campaignID strin
 
 // parseCampaignDetailsResponse parses the campaign details response
 func (g *GraphQLClient) parseCampaignDetailsResponse(data interface{}) (*Campaign, error) {
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response data format")
+	var opResp OpCampaignDetailsResponse
+	if err := decodeResponse(data, &opResp); err != nil {
+		return nil, err
 	}
 
-	user, ok := dataMap["user"]
-	if !ok || user == nil {
+	if opResp.User == nil {
 		logrus.Warning("No user in CampaignDetails response")
 		return nil, fmt.Errorf("no user in response")
 	}
-
-	userMap, ok := user.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid user format")
-	}
-
-	dropCampaign, ok := userMap["dropCampaign"]
-	if !ok || dropCampaign == nil {
+	if opResp.User.DropCampaign == nil {
 		return nil, fmt.Errorf("no dropCampaign in response")
 	}
 
-	campaignMap, ok := dropCampaign.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid dropCampaign format")
+	campaign := opResp.User.DropCampaign.toCampaign()
+	if campaign.Type == "" {
+		// Direct-entitlement campaigns have no time-based drops at all
+		logrus.Debugf("Campaign '%s' (%s): No timeBasedDrops, direct entitlement campaign", campaign.Name, campaign.Game.Name)
+		campaign.Type = CampaignTypeDirect
 	}
 
-	// Parse the detailed campaign
-	return g.parseCampaignNode(campaignMap)
+	return campaign, nil
 }
 
 // parseInventoryResponse parses the inventory GraphQL response
 func (g *GraphQLClient) parseInventoryResponse(data interface{}) (*InventoryGQL, error) {
-	dataBytes, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal inventory data: %w", err)
-	}
-
 	var opResp OpInventoryResponse
-	err = json.Unmarshal(dataBytes, &opResp)
-	if err != nil {
-		return nil, fmt.Errorf("Error unmarshalling OpInventoryResponse: %w", err)
+	if err := decodeResponse(data, &opResp); err != nil {
+		return nil, err
 	}
 
 	return &opResp.CurrentUser.Inventory, nil
 }
 
-// parseCampaignNode parses a single campaign node
-func (g *GraphQLClient) parseCampaignNode(node map[string]interface{}) (*Campaign, error) {
-	campaign := &Campaign{}
-
-	// Basic campaign info
-	campaign.ID = getString(node, "id")
-	campaign.Name = getString(node, "name")
-	campaign.Description = getString(node, "description")
-	campaign.Status = getString(node, "status")
-	campaign.ImageURL = getString(node, "imageURL")
-
-	// Debug: Log campaign details for Don't Starve Together
-	gameName := ""
-	if game, ok := node["game"].(map[string]interface{}); ok {
-		gameName = getString(game, "displayName")
-	}
-
-	// Game info
-	if game, ok := node["game"].(map[string]interface{}); ok {
-		campaign.Game = Game{
-			ID:        getString(game, "id"),
-			Name:      getString(game, "displayName"),
-			BoxArtURL: getString(game, "boxArtURL"),
-		}
-	}
-
-	// Time-based drops
-	if timeBasedDrops, ok := node["timeBasedDrops"].([]interface{}); ok {
-		logrus.Debugf("Campaign '%s' (%s): Found %d timeBasedDrops", campaign.Name, gameName, len(timeBasedDrops))
-		for i, dropInterface := range timeBasedDrops {
-			if dropMap, ok := dropInterface.(map[string]interface{}); ok {
-				drop := TimeBased{
-					ID:   getString(dropMap, "id"),
-					Name: getString(dropMap, "name"),
-				}
-
-				if requiredMinutes, ok := dropMap["requiredMinutesWatched"].(float64); ok {
-					drop.RequiredMinutesWatched = int(requiredMinutes)
-				}
-
-				logrus.Debugf("  Drop %d: '%s' requires %d minutes", i, drop.Name, drop.RequiredMinutesWatched)
-
-				// Note: GetCampaignDetails response doesn't include user progress ("self" field)
-				// User progress comes from DropCurrentSessionContext API call
-				// Initialize with default values - progress will be updated separately
-				drop.Self.IsClaimed = false
-				drop.Self.CurrentMinutesWatched = 0
-				drop.Self.DropInstanceID = ""
-
-				logrus.Debugf("    Drop '%s' initialized with default progress (will be updated from DropCurrentSessionContext)", drop.Name)
-
-				campaign.TimeBasedDrops = append(campaign.TimeBasedDrops, drop)
-			} else {
-				logrus.Errorf("Campaign '%s' (%s): Drop %d is not a valid map", campaign.Name, gameName, i)
-			}
-		}
-	} else {
-		// Check what type timeBasedDrops actually is
-		if timeBasedDropsRaw, exists := node["timeBasedDrops"]; exists {
-			logrus.Errorf("Campaign '%s' (%s): timeBasedDrops exists but wrong type: %T", campaign.Name, gameName, timeBasedDropsRaw)
-		} else {
-			logrus.Debugf("Campaign '%s' (%s): No timeBasedDrops field found", campaign.Name, gameName)
-		}
-	}
-
-	// Campaign self info
-	if self, ok := node["self"].(map[string]interface{}); ok {
-		campaign.Self.IsAccountConnected = getBool(self, "isAccountConnected")
-	}
-
-	return campaign, nil
-}
-
 // parseStreamsResponse parses the streams GraphQL response
 func (g *GraphQLClient) parseStreamsResponse(data interface{}) ([]Stream, error) {
-	// Log the raw streams response to debug stream structure
-	responseJSON, _ := json.MarshalIndent(data, "", "  ")
-	logrus.Debugf("=== RAW STREAMS RESPONSE ===")
-	logrus.Debugf("%s", string(responseJSON))
-	logrus.Debugf("=== END STREAMS RESPONSE ===")
-
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response data format")
+	var opResp OpGameDirectoryResponse
+	if err := decodeResponse(data, &opResp); err != nil {
+		return nil, err
 	}
 
-	game, ok := dataMap["game"]
-	if !ok || game == nil {
+	if opResp.Game == nil || opResp.Game.Streams == nil {
 		logrus.Debug("No game data in streams response")
 		return []Stream{}, nil
 	}
 
-	gameMap, ok := game.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid game format")
-	}
-
-	streams, ok := gameMap["streams"]
-	if !ok {
-		logrus.Debug("No streams found in game data")
-		return []Stream{}, nil
-	}
-
-	streamsMap, ok := streams.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid streams format")
-	}
-
-	edges, ok := streamsMap["edges"].([]interface{})
-	if !ok {
-		logrus.Debug("No stream edges found")
-		return []Stream{}, nil
-	}
-
-	var streamList []Stream
-	for _, edgeInterface := range edges {
-		edgeMap, ok := edgeInterface.(map[string]interface{})
-		if !ok {
+	streams := make([]Stream, 0, len(opResp.Game.Streams.Edges))
+	for _, edge := range opResp.Game.Streams.Edges {
+		if edge.Node == nil {
 			continue
 		}
-
-		node, ok := edgeMap["node"].(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		stream := Stream{
-			ID:              getString(node, "id"),
-			Title:           getString(node, "title"),
-			PreviewImageURL: getString(node, "previewImageURL"),
-		}
-
-		if broadcaster, ok := node["broadcaster"].(map[string]interface{}); ok {
-			stream.UserID = getString(broadcaster, "id")
-			stream.UserLogin = getString(broadcaster, "login")
-			stream.UserName = getString(broadcaster, "displayName")
-		}
-
-		if game, ok := node["game"].(map[string]interface{}); ok {
-			stream.GameName = getString(game, "displayName")
-		}
-
-		if viewersCount, ok := node["viewersCount"].(float64); ok {
-			stream.ViewerCount = int(viewersCount)
-		}
-
-		streamList = append(streamList, stream)
-	}
-
-	return streamList, nil
-}
-
-// Helper functions for safe type assertions
-func getString(m map[string]interface{}, key string) string {
-	if val, ok := m[key].(string); ok {
-		return val
+		streams = append(streams, edge.Node.toStream())
 	}
-	return ""
-}
-
-func getBool(m map[string]interface{}, key string) bool {
-	if val, ok := m[key].(bool); ok {
-		return val
-	}
-	return false
-}
 
-func getKeys(m map[string]interface{}) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	return keys
+	return streams, nil
 }
@@ -4,8 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
-
-	"twitchdropsfarmer/internal/config"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -14,6 +13,9 @@ import (
 
 // getGQLClient safely retrieves the GraphQL client or returns an error if not authenticated
 func (c *Client) getGQLClient() (*GraphQLClient, error) {
+	// Errors only mean there is no token, which the nil check below reports
+	_ = c.refreshTokenIfNeeded(context.Background())
+
 	c.mu.RLock()
 	gqlClient := c.gqlClient
 	c.mu.RUnlock()
@@ -33,13 +35,18 @@ func (c *Client) GetDropCampaigns(ctx context.Context) ([]Campaign, error) {
 	}
 
 	campaigns, err := gqlClient.GetCampaigns(ctx)
-	if err != nil {
-		// Check if this is an authentication error
-		if c.isAuthError(err) {
-			logrus.Info("Token appears invalid, clearing stored token")
-			c.clearToken()
+	if err != nil && c.isAuthError(err) {
+		// Refresh the token and try once more, the token is cleared if Twitch rejects the refresh
+		logrus.WithContext(ctx).Info("Token appears invalid, refreshing it")
+		if !c.recoverFromAuthError(ctx) {
 			return nil, fmt.Errorf("authentication expired, please re-login")
 		}
+		if gqlClient, err = c.getGQLClient(); err != nil {
+			return nil, err
+		}
+		campaigns, err = gqlClient.GetCampaigns(ctx)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to get drop campaigns: %w", err)
 	}
 
@@ -62,13 +69,18 @@ func (c *Client) GetCampaignDetails(ctx context.Context, campaignID string) (*Ca
 	}
 
 	campaign, err := gqlClient.GetCampaignDetails(ctx, campaignID, user.Login)
-	if err != nil {
-		// Check if this is an authentication error
-		if c.isAuthError(err) {
-			logrus.Info("Token appears invalid, clearing stored token")
-			c.clearToken()
+	if err != nil && c.isAuthError(err) {
+		// Refresh the token and try once more, the token is cleared if Twitch rejects the refresh
+		logrus.WithContext(ctx).Info("Token appears invalid, refreshing it")
+		if !c.recoverFromAuthError(ctx) {
 			return nil, fmt.Errorf("authentication expired, please re-login")
 		}
+		if gqlClient, err = c.getGQLClient(); err != nil {
+			return nil, err
+		}
+		campaign, err = gqlClient.GetCampaignDetails(ctx, campaignID, user.Login)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to get campaign details: %w", err)
 	}
 
@@ -102,55 +114,30 @@ func (c *Client) GetCurrentDropProgress(ctx context.Context, channelID string) (
 
 // parseCurrentDropResponse parses the CurrentDrop response using TDM's exact approach
 func (c *Client) parseCurrentDropResponse(data interface{}) (*CurrentDropProgress, error) {
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response data format")
+	var opResp OpCurrentDropResponse
+	if err := decodeResponse(data, &opResp); err != nil {
+		return nil, err
 	}
 
 	// Follow TDM's exact path: context["data"]["currentUser"]["dropCurrentSession"]
-	currentUser, ok := dataMap["currentUser"]
-	if !ok || currentUser == nil {
+	if opResp.CurrentUser == nil {
 		logrus.Debugf("No currentUser in CurrentDrop response")
 		return nil, fmt.Errorf("no currentUser in response")
 	}
 
-	userMap, ok := currentUser.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid currentUser format")
-	}
-
-	dropCurrentSession, ok := userMap["dropCurrentSession"]
-	if !ok || dropCurrentSession == nil {
+	session := opResp.CurrentUser.DropCurrentSession
+	if session == nil {
 		logrus.Debugf("No dropCurrentSession in response - no active drop progress")
 		return nil, nil // This is normal if no drop is being tracked
 	}
 
-	sessionMap, ok := dropCurrentSession.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid dropCurrentSession format")
-	}
-
-	// Extract progress data like TDM does
-	progress := &CurrentDropProgress{}
-
-	if currentMinutes, ok := sessionMap["currentMinutesWatched"].(float64); ok {
-		progress.CurrentMinutesWatched = int(currentMinutes)
-	}
-
-	progress.DropID = getString(sessionMap, "dropID")
-
+	progress := session.toProgress()
 	logrus.Infof("=== SUCCESS: Real Progress from DropCurrentSessionContext ===")
 	logrus.Infof("Drop ID: %s, Current Minutes: %d", progress.DropID, progress.CurrentMinutesWatched)
 
 	return progress, nil
 }
 
-// parseAvailableDropsResponse parses the AvailableDrops response to find progress
-func (c *Client) parseAvailableDropsResponse(data interface{}) (*CurrentDropProgress, error) {
-	// This function is not used anymore since we switched back to DropCurrentSessionContext
-	return nil, fmt.Errorf("AvailableDrops parsing not implemented")
-}
-
 // GetStreamsForGame retrieves streams for a specific game slug
 func (c *Client) GetStreamsForGame(ctx context.Context, gameSlug string, limit int) ([]Stream, error) {
 	gqlClient, err := c.getGQLClient()
@@ -158,7 +145,11 @@ func (c *Client) GetStreamsForGame(ctx context.Context, gameSlug string, limit i
 		return nil, err
 	}
 
-	streams, err := gqlClient.GetStreamsForGame(ctx, gameSlug, limit)
+	c.mu.RLock()
+	opts := c.directoryOptions
+	c.mu.RUnlock()
+
+	streams, err := gqlClient.GetStreamsForGame(ctx, gameSlug, limit, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get streams for game: %w", err)
 	}
@@ -218,17 +209,21 @@ func (c *Client) StartWatching(ctx context.Context, channelLogin string) (*Watch
 		GQLClient:    gqlClient,
 	}
 
-	logrus.Infof("Started watching session for %s", channelLogin)
+	logrus.WithContext(ctx).Infof("Started watching session for %s", channelLogin)
 	return session, nil
 }
 
-// SendWatchRequest sends periodic watch request like TDM
-func (c *Client) SendWatchRequest(ctx context.Context, session *WatchingSession) error {
+// SendWatchRequest sends periodic watch request like TDM, returning the bytes downloaded
+func (c *Client) SendWatchRequest(ctx context.Context, session *WatchingSession) (int64, error) {
 	if session == nil || session.GQLClient == nil {
-		return fmt.Errorf("invalid watching session")
+		return 0, fmt.Errorf("invalid watching session")
 	}
 
-	return session.GQLClient.SendWatchRequest(ctx, session.StreamURL)
+	c.mu.RLock()
+	quality := c.streamQuality
+	c.mu.RUnlock()
+
+	return session.GQLClient.SendWatchRequest(ctx, session.StreamURL, quality)
 }
 
 // ClaimDrop claims a completed drop
@@ -242,11 +237,27 @@ func (c *Client) ClaimDrop(ctx context.Context, dropInstanceID string) error {
 		return fmt.Errorf("failed to claim drop: %w", err)
 	}
 
+	// The claimed drop moves out of the in-progress campaigns
+	c.InvalidateInventory()
 	return nil
 }
 
-// GetInventory retrieves user's drop inventory
+// GetInventory retrieves user's drop inventory, served from cache when fresh
 func (c *Client) GetInventory(ctx context.Context) (*InventoryGQL, error) {
+	c.inventoryMu.Lock()
+	cached := c.inventoryCache
+	fetchedAt := c.inventoryFetchedAt
+	c.inventoryMu.Unlock()
+
+	if cached != nil && time.Since(fetchedAt) < inventoryCacheTTL {
+		return cached, nil
+	}
+
+	return c.RefreshInventory(ctx)
+}
+
+// RefreshInventory fetches the inventory from Twitch, bypassing and updating the cache
+func (c *Client) RefreshInventory(ctx context.Context) (*InventoryGQL, error) {
 	gqlClient, err := c.getGQLClient()
 	if err != nil {
 		return nil, err
@@ -257,9 +268,78 @@ func (c *Client) GetInventory(ctx context.Context) (*InventoryGQL, error) {
 		return nil, fmt.Errorf("failed to get inventory: %w", err)
 	}
 
+	c.inventoryMu.Lock()
+	c.inventoryCache = inventory
+	c.inventoryFetchedAt = time.Now()
+	c.inventoryMu.Unlock()
+
 	return inventory, nil
 }
 
+// InvalidateInventory drops the cached inventory so the next read refetches it
+func (c *Client) InvalidateInventory() {
+	c.inventoryMu.Lock()
+	defer c.inventoryMu.Unlock()
+	c.inventoryCache = nil
+}
+
+// GetChannelPoints retrieves the channel points state for a channel
+func (c *Client) GetChannelPoints(ctx context.Context, channelLogin string) (*ChannelPoints, error) {
+	gqlClient, err := c.getGQLClient()
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := gqlClient.GetChannelPoints(ctx, channelLogin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel points: %w", err)
+	}
+
+	return points, nil
+}
+
+// ClaimChannelPoints claims an available channel points bonus
+func (c *Client) ClaimChannelPoints(ctx context.Context, channelID, claimID string) error {
+	gqlClient, err := c.getGQLClient()
+	if err != nil {
+		return err
+	}
+
+	if err := gqlClient.ClaimCommunityPoints(ctx, channelID, claimID); err != nil {
+		return fmt.Errorf("failed to claim channel points: %w", err)
+	}
+
+	return nil
+}
+
+// FollowChannel follows a channel by ID
+func (c *Client) FollowChannel(ctx context.Context, channelID string) error {
+	gqlClient, err := c.getGQLClient()
+	if err != nil {
+		return err
+	}
+
+	if err := gqlClient.FollowChannel(ctx, channelID); err != nil {
+		return fmt.Errorf("failed to follow channel: %w", err)
+	}
+
+	return nil
+}
+
+// UnfollowChannel unfollows a channel by ID
+func (c *Client) UnfollowChannel(ctx context.Context, channelID string) error {
+	gqlClient, err := c.getGQLClient()
+	if err != nil {
+		return err
+	}
+
+	if err := gqlClient.UnfollowChannel(ctx, channelID); err != nil {
+		return fmt.Errorf("failed to unfollow channel: %w", err)
+	}
+
+	return nil
+}
+
 // Helper methods for GraphQL operations
 
 // isAuthError checks if error indicates authentication issues
@@ -280,5 +360,5 @@ func (c *Client) clearToken() {
 	c.user = nil
 	c.isLoggedIn = false
 	c.gqlClient = nil // Clear TDM GraphQL client
-	config.DeleteToken()
+	c.deleteToken(c.accountID)
 }
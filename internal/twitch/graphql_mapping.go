@@ -0,0 +1,227 @@
+package twitch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// decodeResponse unmarshals GraphQL response data into one of the Op*Response types
+func decodeResponse(data interface{}, v interface{}) error {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response data: %w", err)
+	}
+	if err := json.Unmarshal(dataBytes, v); err != nil {
+		return fmt.Errorf("failed to decode %T: %w", v, err)
+	}
+	return nil
+}
+
+// stringValue dereferences an optional GQL string, empty when it is missing
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (g *GameGQL) toGame() Game {
+	if g == nil {
+		return Game{}
+	}
+
+	// The campaign and directory responses only set displayName, the inventory ones only name
+	name := stringValue(g.DisplayName)
+	if name == "" {
+		name = stringValue(g.Name)
+	}
+	return Game{
+		ID:        g.ID,
+		Name:      name,
+		BoxArtURL: stringValue(g.BoxArtURL),
+	}
+}
+
+// GameName returns the display name of the campaign's game
+func (c *DropCampaignGQL) GameName() string {
+	return c.Game.toGame().Name
+}
+
+// toCampaign maps a campaign to the public type; Type stays empty when the response has no
+// timeBasedDrops field, like the dashboard listing
+func (c *DropCampaignGQL) toCampaign() *Campaign {
+	campaign := &Campaign{
+		ID:             c.ID,
+		Name:           c.Name,
+		Description:    stringValue(c.Description),
+		Game:           c.Game.toGame(),
+		Status:         c.Status,
+		StartsAt:       c.StartAt,
+		EndsAt:         c.EndAt,
+		AccountLinkURL: stringValue(c.AccountLinkURL),
+		ImageURL:       stringValue(c.ImageURL),
+	}
+
+	if c.Self != nil {
+		campaign.Self.IsAccountConnected = c.Self.IsAccountConnected
+	}
+
+	// Channels are only restricted when the allow list is enabled
+	if c.Allow != nil && c.Allow.Channels != nil && (c.Allow.IsEnabled == nil || *c.Allow.IsEnabled) {
+		for _, channel := range *c.Allow.Channels {
+			campaign.Allow = append(campaign.Allow, channel.Name)
+		}
+	}
+
+	if c.TimeBasedDrops != nil {
+		campaign.Type = CampaignTypeTimeBased
+		if len(*c.TimeBasedDrops) == 0 {
+			campaign.Type = CampaignTypeDirect
+		}
+		for i := range *c.TimeBasedDrops {
+			campaign.TimeBasedDrops = append(campaign.TimeBasedDrops, (*c.TimeBasedDrops)[i].toTimeBased())
+		}
+	}
+
+	return campaign
+}
+
+// toTimeBased maps a drop to the public type; the CampaignDetails response has no "self" field,
+// so progress stays zero there and comes from DropCurrentSessionContext instead
+func (d *TimeBasedDropGQL) toTimeBased() TimeBased {
+	drop := TimeBased{
+		ID:                     d.ID,
+		Name:                   d.Name,
+		RequiredMinutesWatched: d.RequiredMinutesWatched,
+		StartsAt:               d.StartAt,
+		EndsAt:                 d.EndAt,
+	}
+
+	for _, edge := range d.BenefitEdges {
+		benefit := Benefit{
+			ID:            edge.Benefit.ID,
+			Name:          edge.Benefit.Name,
+			ImageAssetURL: edge.Benefit.ImageAssetURL,
+			Game:          edge.Benefit.Game.toGame(),
+		}
+		if edge.Benefit.IsIosAvailable != nil {
+			benefit.IsIOS = *edge.Benefit.IsIosAvailable
+		}
+		drop.BenefitEdges = append(drop.BenefitEdges, BenefitEdge{Benefit: benefit})
+	}
+
+	if d.Self != nil {
+		drop.Self.CurrentMinutesWatched = d.Self.CurrentMinutesWatched
+		drop.Self.IsClaimed = d.Self.IsClaimed
+		if d.Self.DropInstanceID != nil {
+			if instanceID, ok := (*d.Self.DropInstanceID).(string); ok {
+				drop.Self.DropInstanceID = instanceID
+			}
+		}
+	}
+
+	return drop
+}
+
+func (s *StreamGQL) toStream() Stream {
+	stream := Stream{
+		ID:              s.ID,
+		UserID:          s.Broadcaster.ID,
+		UserLogin:       s.Broadcaster.Login,
+		UserName:        s.Broadcaster.DisplayName,
+		GameID:          s.Game.ID,
+		GameName:        s.Game.toGame().Name,
+		Type:            s.Type,
+		Title:           s.Title,
+		ViewerCount:     s.ViewersCount,
+		PreviewImageURL: s.PreviewImageURL,
+	}
+	for _, tag := range s.FreeformTags {
+		stream.TagIDs = append(stream.TagIDs, tag.ID)
+	}
+	return stream
+}
+
+func (s *DropCurrentSessionGQL) toProgress() *CurrentDropProgress {
+	return &CurrentDropProgress{
+		CurrentMinutesWatched: s.CurrentMinutesWatched,
+		DropID:                s.DropID,
+		ChannelID:             s.Channel.ID,
+		ChannelLogin:          s.Channel.Name,
+		GameName:              s.Game.toGame().Name,
+	}
+}
+
+// ClaimedReward is a reward in the inventory, with the campaign and drop it came from while the campaign is in progress
+type ClaimedReward struct {
+	BenefitID    string     `json:"benefit_id"`
+	Name         string     `json:"name"`
+	GameName     string     `json:"game_name"`
+	ClaimedAt    *time.Time `json:"claimed_at"` // the last time it was awarded
+	Count        int        `json:"count"`
+	ImageURL     string     `json:"image_url"`
+	CampaignName string     `json:"campaign_name,omitempty"`
+	DropName     string     `json:"drop_name,omitempty"`
+}
+
+// DropProgress returns the progress of the drops of the campaigns in progress, by drop ID
+func (inv *InventoryGQL) DropProgress() map[string]TimeBasedSelf {
+	progress := make(map[string]TimeBasedSelf)
+	for i := range inv.DropCampaignsInProgress {
+		campaign := &inv.DropCampaignsInProgress[i]
+		if campaign.TimeBasedDrops == nil {
+			continue
+		}
+		for j := range *campaign.TimeBasedDrops {
+			drop := &(*campaign.TimeBasedDrops)[j]
+			if drop.Self != nil {
+				progress[drop.ID] = drop.toTimeBased().Self
+			}
+		}
+	}
+	return progress
+}
+
+// ClaimedRewards lists every reward ever claimed, most recently claimed first
+func (inv *InventoryGQL) ClaimedRewards() []ClaimedReward {
+	// Campaigns in progress name the drop of each benefit, older rewards only have the benefit itself
+	type source struct{ campaign, drop string }
+	sources := make(map[string]source)
+	for i := range inv.DropCampaignsInProgress {
+		campaign := &inv.DropCampaignsInProgress[i]
+		if campaign.TimeBasedDrops == nil {
+			continue
+		}
+		for _, drop := range *campaign.TimeBasedDrops {
+			for _, edge := range drop.BenefitEdges {
+				sources[edge.Benefit.ID] = source{campaign: campaign.Name, drop: drop.Name}
+			}
+		}
+	}
+
+	rewards := make([]ClaimedReward, 0, len(inv.GameEventDrops))
+	for _, reward := range inv.GameEventDrops {
+		from := sources[reward.ID]
+		rewards = append(rewards, ClaimedReward{
+			BenefitID:    reward.ID,
+			Name:         reward.Name,
+			GameName:     reward.Game.toGame().Name,
+			ClaimedAt:    reward.LastAwardedAt,
+			Count:        reward.TotalCount,
+			ImageURL:     reward.ImageURL,
+			CampaignName: from.campaign,
+			DropName:     from.drop,
+		})
+	}
+
+	sort.SliceStable(rewards, func(i, j int) bool {
+		a, b := rewards[i].ClaimedAt, rewards[j].ClaimedAt
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.After(*b)
+	})
+	return rewards
+}
@@ -180,38 +180,70 @@ type OpInventoryResponse struct {
 }
 
 type OpCurrentDropResponse struct {
-	CurrentUser struct {
+	CurrentUser *struct {
 		Typename           string                 `json:"__typename"`
 		DropCurrentSession *DropCurrentSessionGQL `json:"dropCurrentSession,omitempty"`
 		ID                 string                 `json:"id"`
-	} `json:"currentUser"`
+	} `json:"currentUser,omitempty"`
 }
 
 type OpCampaignsResponse struct {
-	CurrentUser struct {
+	CurrentUser *struct {
 		Typename      string            `json:"__typename"`
 		DropCampaigns []DropCampaignGQL `json:"dropCampaigns"`
 		ID            string            `json:"id"`
 		Login         *string           `json:"login,omitempty"`
-	} `json:"currentUser"`
+	} `json:"currentUser,omitempty"`
 }
 
 type OpCampaignDetailsResponse struct {
-	User struct {
+	User *struct {
 		Typename     string           `json:"__typename"`
 		DropCampaign *DropCampaignGQL `json:"dropCampaign,omitempty"`
 		ID           string           `json:"id"`
-	} `json:"user"`
+	} `json:"user,omitempty"`
 }
 
 type OpPlaybackAccessTokenResponse struct {
-	StreamPlaybackAccessToken PlaybackAccessTokenGQL `json:"streamPlaybackAccessToken"`
+	StreamPlaybackAccessToken *PlaybackAccessTokenGQL `json:"streamPlaybackAccessToken,omitempty"`
 }
 
 type OpGameDirectoryResponse struct {
-	Game GameGQL `json:"game"`
+	Game *GameGQL `json:"game,omitempty"`
 }
 
 type OpSlugRedirectResponse struct {
-	Game GameGQL `json:"game"`
+	Game *GameGQL `json:"game,omitempty"`
+}
+
+type CommunityPointsGQL struct {
+	Typename       string `json:"__typename"`
+	Balance        int    `json:"balance"`
+	AvailableClaim *struct {
+		Typename string `json:"__typename"`
+		ID       string `json:"id"`
+	} `json:"availableClaim,omitempty"`
+}
+
+type OpAvailableDropsResponse struct {
+	Channel *struct {
+		Typename            string            `json:"__typename"`
+		ID                  string            `json:"id"`
+		ViewerDropCampaigns []DropCampaignGQL `json:"viewerDropCampaigns"`
+	} `json:"channel,omitempty"`
+}
+
+type OpChannelPointsContextResponse struct {
+	Community *struct {
+		Typename string `json:"__typename"`
+		ID       string `json:"id"`
+		Channel  *struct {
+			Typename string `json:"__typename"`
+			ID       string `json:"id"`
+			Self     struct {
+				Typename        string             `json:"__typename"`
+				CommunityPoints CommunityPointsGQL `json:"communityPoints"`
+			} `json:"self"`
+		} `json:"channel,omitempty"`
+	} `json:"community,omitempty"`
 }
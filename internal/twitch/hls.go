@@ -0,0 +1,247 @@
+package twitch
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Stream qualities accepted by SetStreamQuality, besides a rendition name such as "480p" or "720p60"
+const (
+	StreamQualityLowest    = "lowest"     // lowest-bandwidth video rendition
+	StreamQualityAudioOnly = "audio_only" // audio rendition, or the lowest video one when the stream has none
+	StreamQualitySource    = "source"     // highest-bandwidth rendition, what Usher lists first
+)
+
+// Group ID Twitch gives the audio rendition in master playlists
+const audioOnlyGroup = "audio_only"
+
+// Twitch marks stitched ads with this #EXT-X-DATERANGE class, and gives ad segments titles other than "live"
+const (
+	stitchedAdClass  = "twitch-stitched-ad"
+	liveSegmentTitle = "live"
+)
+
+var renditionNamePattern = regexp.MustCompile(`^[0-9]{3,4}p([0-9]{2})?$`)
+
+// ValidStreamQuality reports whether quality is one of the StreamQuality constants or a rendition name
+func ValidStreamQuality(quality string) bool {
+	switch quality {
+	case StreamQualityLowest, StreamQualityAudioOnly, StreamQualitySource:
+		return true
+	}
+	return renditionNamePattern.MatchString(quality)
+}
+
+// SetStreamQuality sets which rendition of the master playlist watch requests fetch
+func (c *Client) SetStreamQuality(quality string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.streamQuality = quality
+}
+
+// streamVariant is a rendition listed by #EXT-X-STREAM-INF in a master playlist
+type streamVariant struct {
+	URI        string
+	Bandwidth  int64
+	Group      string // VIDEO attribute, e.g. "chunked", "720p60" or "audio_only"
+	Name       string // NAME of the group's #EXT-X-MEDIA tag, e.g. "1080p60 (source)"
+	Resolution string
+}
+
+// parseMasterPlaylist returns the renditions of a master playlist in the order they are listed, with their
+// URIs resolved against the playlist's URL
+func parseMasterPlaylist(playlist, playlistURL string) []streamVariant {
+	var variants []streamVariant
+	var pending *streamVariant
+	names := make(map[string]string)
+	for _, line := range strings.Split(playlist, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			attrs := parseAttributes(strings.TrimPrefix(line, "#EXT-X-MEDIA:"))
+			names[attrs["GROUP-ID"]] = attrs["NAME"]
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			attrs := parseAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+			bandwidth, _ := strconv.ParseInt(attrs["BANDWIDTH"], 10, 64)
+			pending = &streamVariant{
+				Bandwidth:  bandwidth,
+				Group:      attrs["VIDEO"],
+				Resolution: attrs["RESOLUTION"],
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+			// Other tags and comments
+		case pending != nil:
+			// The URI line follows its #EXT-X-STREAM-INF tag
+			pending.URI = resolveURI(playlistURL, line)
+			variants = append(variants, *pending)
+			pending = nil
+		}
+	}
+	for i := range variants {
+		variants[i].Name = names[variants[i].Group]
+	}
+	return variants
+}
+
+// parseAttributes splits an m3u8 attribute list, KEY=VALUE pairs where quoted values may contain commas
+func parseAttributes(list string) map[string]string {
+	attrs := make(map[string]string)
+	for list != "" {
+		eq := strings.IndexByte(list, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(list[:eq])
+		list = list[eq+1:]
+
+		var value string
+		if strings.HasPrefix(list, `"`) {
+			end := strings.IndexByte(list[1:], '"')
+			if end < 0 {
+				value, list = list[1:], ""
+			} else {
+				value, list = list[1:end+1], list[end+2:]
+			}
+		} else if comma := strings.IndexByte(list, ','); comma >= 0 {
+			value, list = list[:comma], list[comma:]
+		} else {
+			value, list = list, ""
+		}
+		attrs[key] = value
+		list = strings.TrimPrefix(list, ",")
+	}
+	return attrs
+}
+
+// selectVariant picks the rendition for a quality preference, falling back to the lowest-bandwidth video one
+func selectVariant(variants []streamVariant, quality string) (streamVariant, error) {
+	var video []streamVariant
+	var audio *streamVariant
+	for i := range variants {
+		if variants[i].Group == audioOnlyGroup {
+			audio = &variants[i]
+		} else {
+			video = append(video, variants[i])
+		}
+	}
+	if len(video) == 0 {
+		if audio != nil {
+			return *audio, nil
+		}
+		return streamVariant{}, fmt.Errorf("no stream playlist URL found in master playlist")
+	}
+	sort.SliceStable(video, func(i, j int) bool {
+		return video[i].Bandwidth < video[j].Bandwidth
+	})
+
+	switch quality {
+	case StreamQualityAudioOnly:
+		if audio != nil {
+			return *audio, nil
+		}
+	case StreamQualitySource:
+		return video[len(video)-1], nil
+	case StreamQualityLowest, "":
+	default:
+		// A rendition name matches its group or media name, "720p" matching "720p30" and "720p60"
+		for i := len(video) - 1; i >= 0; i-- {
+			if strings.HasPrefix(video[i].Group, quality) || strings.HasPrefix(video[i].Name, quality) {
+				return video[i], nil
+			}
+		}
+	}
+	return video[0], nil
+}
+
+// mediaSegment is a segment of a media playlist
+type mediaSegment struct {
+	URI      string
+	Title    string // #EXTINF title, "live" for stream content
+	Map      string // initialization section from #EXT-X-MAP, set for fMP4 streams
+	Sequence int64
+	Ad       bool
+}
+
+// parseMediaPlaylist returns the segments of a media playlist, oldest first, with their URIs resolved against
+// the playlist's URL. Segments titled like the "Amazon" ad segments, or not titled "live" inside a stitched ad
+// break, are marked Ad.
+func parseMediaPlaylist(playlist, playlistURL string) []mediaSegment {
+	var segments []mediaSegment
+	var sequence int64
+	var initSection string
+	var pending *mediaSegment
+	var adBreakEnds float64 // seconds into the playlist the current stitched ad break runs to
+	var elapsed float64
+	for _, line := range strings.Split(playlist, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			sequence, _ = strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			attrs := parseAttributes(strings.TrimPrefix(line, "#EXT-X-MAP:"))
+			if attrs["URI"] != "" {
+				initSection = resolveURI(playlistURL, attrs["URI"])
+			}
+		case strings.HasPrefix(line, "#EXT-X-DATERANGE:"):
+			attrs := parseAttributes(strings.TrimPrefix(line, "#EXT-X-DATERANGE:"))
+			if attrs["CLASS"] == stitchedAdClass || strings.HasPrefix(attrs["ID"], "stitched-ad-") {
+				duration, _ := strconv.ParseFloat(attrs["DURATION"], 64)
+				adBreakEnds = elapsed + duration
+			}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			duration, title, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			seconds, _ := strconv.ParseFloat(duration, 64)
+			pending = &mediaSegment{
+				Title: title,
+				Ad:    isAdTitle(title) || (elapsed < adBreakEnds && title != liveSegmentTitle),
+			}
+			elapsed += seconds
+		case line == "" || strings.HasPrefix(line, "#"):
+			// Other tags, including Twitch's #EXT-X-TWITCH-PREFETCH hints, and comments
+		case pending != nil:
+			// The URI line follows its #EXTINF tag
+			pending.URI = resolveURI(playlistURL, line)
+			pending.Map = initSection
+			pending.Sequence = sequence
+			segments = append(segments, *pending)
+			sequence++
+			pending = nil
+		}
+	}
+	return segments
+}
+
+// isAdTitle reports whether an #EXTINF title belongs to an ad segment
+func isAdTitle(title string) bool {
+	return title != liveSegmentTitle && strings.Contains(strings.ToLower(title), "amazon")
+}
+
+// lastLiveSegment returns the newest segment that isn't an ad, or the newest segment when the playlist is all ads
+func lastLiveSegment(segments []mediaSegment) (mediaSegment, bool) {
+	for i := len(segments) - 1; i >= 0; i-- {
+		if !segments[i].Ad {
+			return segments[i], true
+		}
+	}
+	if len(segments) > 0 {
+		return segments[len(segments)-1], true
+	}
+	return mediaSegment{}, false
+}
+
+// resolveURI resolves a playlist URI against the URL of the playlist it appears in
+func resolveURI(playlistURL, uri string) string {
+	base, err := url.Parse(playlistURL)
+	if err != nil {
+		return uri
+	}
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	return base.ResolveReference(ref).String()
+}
@@ -0,0 +1,123 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	IntegrityEndpoint = "https://gql.twitch.tv/integrity"
+
+	// Tokens are fetched again this long before Twitch says they expire
+	integrityExpiryMargin = time.Minute
+
+	// GraphQL error Twitch answers with when an operation needs a (fresh) integrity token
+	integrityErrorMessage = "failed integrity check"
+)
+
+// Operations sent with a Client-Integrity header; Twitch rejects these from clients that can't pass its integrity check
+var integrityOperations = map[string]bool{
+	GQLOperations[OpClaimDrop].OperationName:            true,
+	GQLOperations[OpClaimCommunityPoints].OperationName: true,
+}
+
+// integrityToken caches the Client-Integrity token of one access token
+type integrityToken struct {
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// integrityResponse is the body of IntegrityEndpoint
+type integrityResponse struct {
+	Token      string `json:"token"`
+	Expiration int64  `json:"expiration"` // Unix milliseconds
+	RequestID  string `json:"request_id"`
+}
+
+// integrity returns the cached integrity token, fetching a new one when there is none or it is about to expire
+func (g *GraphQLClient) integrity(ctx context.Context) (string, error) {
+	g.integrityToken.mu.Lock()
+	defer g.integrityToken.mu.Unlock()
+
+	if g.integrityToken.token != "" && time.Until(g.integrityToken.expiresAt) > integrityExpiryMargin {
+		return g.integrityToken.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", IntegrityEndpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create integrity request: %w", err)
+	}
+	for key, value := range g.Headers(true) {
+		req.Header.Set(key, value)
+	}
+	// The body isn't gzipped unless asked for, and there is no need to
+	req.Header.Del("Accept-Encoding")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get integrity token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", fmt.Errorf("%w: integrity request failed with status: %d", ErrRateLimited, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("integrity request failed with status: %d", resp.StatusCode)
+	}
+
+	var body integrityResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode integrity response: %w", err)
+	}
+	if body.Token == "" {
+		return "", fmt.Errorf("no integrity token in response")
+	}
+
+	g.integrityToken.token = body.Token
+	g.integrityToken.expiresAt = time.UnixMilli(body.Expiration)
+	logrus.WithContext(ctx).Debugf("Got integrity token, expires %s", g.integrityToken.expiresAt.Format(time.RFC3339))
+	return body.Token, nil
+}
+
+// invalidateIntegrity drops the cached integrity token, so the next operation that needs one fetches it again
+func (g *GraphQLClient) invalidateIntegrity() {
+	g.integrityToken.mu.Lock()
+	defer g.integrityToken.mu.Unlock()
+	g.integrityToken.token = ""
+	g.integrityToken.expiresAt = time.Time{}
+}
+
+// integrityHeader returns the Client-Integrity header value for an operation, empty when it doesn't need one
+// Without a token the operation is still sent, Twitch doesn't enforce the check everywhere
+func (g *GraphQLClient) integrityHeader(ctx context.Context, operationName string) string {
+	if !integrityOperations[operationName] {
+		return ""
+	}
+	token, err := g.integrity(ctx)
+	if err != nil {
+		logrus.WithContext(ctx).Warnf("Sending %s without an integrity token: %v", operationName, err)
+		return ""
+	}
+	return token
+}
+
+// failedIntegrity reports whether Twitch rejected a response for its integrity token
+func failedIntegrity(resp *GraphQLResponse) bool {
+	if resp == nil {
+		return false
+	}
+	for _, gqlErr := range resp.Errors {
+		if gqlErr.Message == integrityErrorMessage {
+			return true
+		}
+	}
+	return false
+}
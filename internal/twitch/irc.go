@@ -0,0 +1,161 @@
+package twitch
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ircAddress        = "irc.chat.twitch.tv:6697"
+	ircReconnectDelay = 30 * time.Second
+	ircReadTimeout    = 6 * time.Minute // Twitch sends PING roughly every 5 minutes
+)
+
+// ChatPresence keeps the account joined to the chat of the channel being watched
+// It authenticates with the user's token and falls back to an anonymous login when
+// the token lacks chat scopes
+type ChatPresence struct {
+	client *Client
+
+	mu      sync.Mutex
+	channel string
+	cancel  context.CancelFunc
+}
+
+// NewChatPresence creates an idle chat presence for the client's account
+func NewChatPresence(client *Client) *ChatPresence {
+	return &ChatPresence{client: client}
+}
+
+// Join leaves the current chat, if any, and joins the given channel's chat
+func (p *ChatPresence) Join(channelLogin string) {
+	channelLogin = strings.ToLower(channelLogin)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.channel == channelLogin && p.cancel != nil {
+		return
+	}
+
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.channel = channelLogin
+	p.cancel = cancel
+
+	go p.run(ctx, channelLogin)
+}
+
+// Leave disconnects from chat
+func (p *ChatPresence) Leave() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+	p.channel = ""
+}
+
+// Channel returns the channel whose chat is currently joined
+func (p *ChatPresence) Channel() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.channel
+}
+
+// run keeps a connection to the channel's chat open until ctx is cancelled
+func (p *ChatPresence) run(ctx context.Context, channelLogin string) {
+	anonymous := false
+	for {
+		err := p.session(ctx, channelLogin, anonymous)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == errChatAuthFailed && !anonymous {
+			logrus.WithContext(ctx).Infof("Chat login rejected, joining %s chat anonymously", channelLogin)
+			anonymous = true
+			continue
+		}
+
+		logrus.WithContext(ctx).Debugf("Chat connection to %s lost: %v", channelLogin, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ircReconnectDelay):
+		}
+	}
+}
+
+var errChatAuthFailed = fmt.Errorf("chat authentication failed")
+
+// session runs one IRC connection, returning when it drops or ctx is cancelled
+func (p *ChatPresence) session(ctx context.Context, channelLogin string, anonymous bool) error {
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 10 * time.Second}}
+	conn, err := dialer.DialContext(ctx, "tcp", ircAddress)
+	if err != nil {
+		return fmt.Errorf("failed to connect to chat: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock the reader when the presence is moved or stopped
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	nick, pass := fmt.Sprintf("justinfan%d", 10000+rand.Intn(90000)), "SCHMOOPIIE"
+	if !anonymous {
+		token, err := p.client.getAccessToken(ctx)
+		user := p.client.GetUser()
+		if err == nil && user != nil {
+			nick, pass = strings.ToLower(user.Login), "oauth:"+token
+		}
+	}
+
+	for _, line := range []string{
+		"PASS " + pass,
+		"NICK " + nick,
+		"JOIN #" + channelLogin,
+	} {
+		if _, err := fmt.Fprintf(conn, "%s\r\n", line); err != nil {
+			return fmt.Errorf("failed to send to chat: %w", err)
+		}
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(ircReadTimeout))
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			if _, err := fmt.Fprintf(conn, "PONG%s\r\n", strings.TrimPrefix(line, "PING")); err != nil {
+				return err
+			}
+		case strings.Contains(line, "NOTICE * :Login authentication failed"),
+			strings.Contains(line, "NOTICE * :Improperly formatted auth"):
+			return errChatAuthFailed
+		case strings.Contains(line, " JOIN #"+channelLogin):
+			logrus.WithContext(ctx).Debugf("Joined %s chat as %s", channelLogin, nick)
+		case strings.Contains(line, " RECONNECT"):
+			return fmt.Errorf("server requested reconnect")
+		}
+	}
+}
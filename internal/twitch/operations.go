@@ -87,6 +87,8 @@ const (
 	OpNotificationsView
 	OpNotificationsList
 	OpNotificationsDelete
+	OpFollowUser
+	OpUnfollowUser
 )
 
 // String returns the operation name for the given operation type
@@ -122,6 +124,10 @@ func (op OperationType) String() string {
 		return "NotificationsList"
 	case OpNotificationsDelete:
 		return "NotificationsDelete"
+	case OpFollowUser:
+		return "FollowUser"
+	case OpUnfollowUser:
+		return "UnfollowUser"
 	default:
 		return "Unknown"
 	}
@@ -208,7 +214,7 @@ var GQLOperations = map[OperationType]*GQLOperation{
 		},
 	),
 
-	// returns drops available for a particular channel (unused)
+	// returns drops available for a particular channel
 	OpAvailableDrops: NewGQLOperation(
 		"DropsHighlightService_AvailableDrops",
 		"9a62a09bce5b53e26e64a671e530bc599cb6aab1e5ba3cbd5d85966d3940716f",
@@ -245,8 +251,8 @@ var GQLOperations = map[OperationType]*GQLOperation{
 				"freeformTags":           nil,
 				"includeRestricted":      []string{"SUB_ONLY_LIVE"},
 				"recommendationsContext": map[string]interface{}{"platform": "web"},
-				"sort":                   "RELEVANCE", // also accepted: "VIEWER_COUNT"
-				"systemFilters":          []interface{}{},
+				"sort":                   "RELEVANCE",     // also accepted: "VIEWER_COUNT"
+				"systemFilters":          []interface{}{}, // filled from DirectoryOptions, DROPS_ENABLED by default
 				"tags":                   []interface{}{},
 				"requestID":              "JIRA-VXP-2397",
 			},
@@ -294,6 +300,29 @@ var GQLOperations = map[OperationType]*GQLOperation{
 			},
 		},
 	),
+
+	// follows a channel, some campaigns only progress for followers
+	OpFollowUser: NewGQLOperation(
+		"FollowButton_FollowUser",
+		"800e7346bdf7e5278a3c1d3f21b2b56e2639928f86815677a7126b093b2fdd08",
+		map[string]interface{}{
+			"input": map[string]interface{}{
+				"disableNotifications": true,
+				"targetID":             nil, // channel ID as a str - to be filled in
+			},
+		},
+	),
+
+	// unfollows a channel
+	OpUnfollowUser: NewGQLOperation(
+		"FollowButton_UnfollowUser",
+		"f7dae976ebf41c755ae2d758546bfd176b4eeb856656098bb40e0a672ca0d880",
+		map[string]interface{}{
+			"input": map[string]interface{}{
+				"targetID": nil, // channel ID as a str - to be filled in
+			},
+		},
+	),
 }
 
 // Helper function to get an operation and fill in variables
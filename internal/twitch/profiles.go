@@ -0,0 +1,118 @@
+package twitch
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"twitchdropsfarmer/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Client profiles accepted in the client_profile setting, like TDM's ClientType
+const (
+	ProfileAndroidApp = "android_app"
+	ProfileSmartBox   = "smartbox"
+	ProfileWebPlayer  = "web_player"
+	ProfileMobileWeb  = "mobile_web"
+)
+
+// Characters of the device IDs each kind of client generates
+const (
+	hexAlphabet          = "0123456789abcdef"
+	alphanumericAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// ClientProfile is the identity the client presents to Twitch: its client ID, user agent and device ID format
+// Tokens are issued to a client ID, so switching profiles means logging in again
+type ClientProfile struct {
+	Name string
+	ClientInfo
+	deviceIDAlphabet string
+}
+
+var clientProfiles = map[string]ClientProfile{
+	ProfileAndroidApp: {
+		Name: ProfileAndroidApp,
+		ClientInfo: ClientInfo{
+			ClientURL: "https://www.twitch.tv",
+			ClientID:  "kd1unb4b3q4t58fwlpcbzcbnm76a8fp",
+			UserAgent: "Dalvik/2.1.0 (Linux; U; Android 7.1.2; SM-G977N Build/LMY48Z) tv.twitch.android.app/16.8.1/1608010",
+		},
+		deviceIDAlphabet: hexAlphabet,
+	},
+	ProfileSmartBox: {
+		Name: ProfileSmartBox,
+		ClientInfo: ClientInfo{
+			ClientURL: "https://android.tv.twitch.tv",
+			ClientID:  "ue6666qo983tsx6so1t0vnawi233wa",
+			UserAgent: "Mozilla/5.0 (Linux; Android 7.1; Smart Box C1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36",
+		},
+		deviceIDAlphabet: hexAlphabet,
+	},
+	ProfileWebPlayer: {
+		Name: ProfileWebPlayer,
+		ClientInfo: ClientInfo{
+			ClientURL: "https://www.twitch.tv",
+			ClientID:  "kimne78kx3ncx6brgo4mv6wki5h1ko",
+			UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		},
+		deviceIDAlphabet: alphanumericAlphabet,
+	},
+	ProfileMobileWeb: {
+		Name: ProfileMobileWeb,
+		ClientInfo: ClientInfo{
+			ClientURL: "https://m.twitch.tv",
+			ClientID:  "r8s4dac0uhzifbpu9sjdiwzctle17ff",
+			UserAgent: "Mozilla/5.0 (Linux; Android 13; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+		},
+		deviceIDAlphabet: alphanumericAlphabet,
+	},
+}
+
+// ValidClientProfile reports whether name is one of the Profile constants
+func ValidClientProfile(name string) bool {
+	_, ok := clientProfiles[name]
+	return ok
+}
+
+// LookupClientProfile returns a built-in profile by name
+func LookupClientProfile(name string) (ClientProfile, bool) {
+	profile, ok := clientProfiles[name]
+	return profile, ok
+}
+
+// NewClientProfile builds the client profile from the application settings
+// A custom twitch_client_id replaces the profile's; the built-in IDs only work with their own user agent, so
+// one of those always selects its profile's ID instead
+func NewClientProfile(cfg *config.Config) ClientProfile {
+	profile, ok := clientProfiles[cfg.ClientProfile]
+	if !ok {
+		logrus.Errorf("Unknown client profile %q, using %s", cfg.ClientProfile, ProfileAndroidApp)
+		profile = clientProfiles[ProfileAndroidApp]
+	}
+
+	if cfg.TwitchClientID != "" && cfg.TwitchClientID != profile.ClientID {
+		for _, builtin := range clientProfiles {
+			if builtin.ClientID == cfg.TwitchClientID {
+				return profile
+			}
+		}
+		profile.ClientID = cfg.TwitchClientID
+	}
+	return profile
+}
+
+// newDeviceID generates a 32 character device ID in the format of the profile's client
+func (p ClientProfile) newDeviceID() string {
+	alphabet := p.deviceIDAlphabet
+	if alphabet == "" {
+		alphabet = hexAlphabet
+	}
+	id := make([]byte, 32)
+	for i := range id {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		id[i] = alphabet[n.Int64()]
+	}
+	return string(id)
+}
@@ -0,0 +1,79 @@
+package twitch
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ParseProxyURL validates an http://, https://, socks5:// or socks5h:// proxy URL; empty means no proxy
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, must be http, https, socks5 or socks5h", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", raw)
+	}
+
+	return proxyURL, nil
+}
+
+// SetProxy routes the client's GraphQL, auth, Helix, HLS and PubSub traffic through proxyURL
+// An empty URL goes back to the HTTP_PROXY/HTTPS_PROXY environment variables
+func (c *Client) SetProxy(proxyURL string) error {
+	parsed, err := ParseProxyURL(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	c.proxyMu.Lock()
+	c.proxy = parsed
+	c.proxyMu.Unlock()
+
+	// Don't reuse connections made through the previous proxy
+	c.transport.CloseIdleConnections()
+	return nil
+}
+
+// SetTransport replaces the transport shared by the client's HTTP requests, e.g. to send them to
+// a local test server; the proxy settings don't apply to it
+func (c *Client) SetTransport(transport *http.Transport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.transport = transport
+	c.authManager.httpClient.Transport = transport
+	if c.gqlClient != nil {
+		c.gqlClient.httpClient.Transport = transport
+	}
+}
+
+// proxyFor is the Proxy function of the client's transport and PubSub dialer
+func (c *Client) proxyFor(req *http.Request) (*url.URL, error) {
+	c.proxyMu.RLock()
+	proxy := c.proxy
+	c.proxyMu.RUnlock()
+
+	if proxy == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	return proxy, nil
+}
+
+// newTransport creates the transport shared by every HTTP client of c
+func (c *Client) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = c.proxyFor
+	return transport
+}
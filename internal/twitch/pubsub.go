@@ -0,0 +1,319 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pubSubURL            = "wss://pubsub-edge.twitch.tv/v1"
+	pubSubPingInterval   = 4 * time.Minute // Twitch drops connections without a PING every 5 minutes
+	pubSubPongTimeout    = 10 * time.Second
+	pubSubMinBackoff     = 5 * time.Second
+	pubSubMaxBackoff     = 5 * time.Minute
+	pubSubDropEventTopic = "user-drop-events"
+	pubSubPointsTopic    = "community-points-user-v1"
+)
+
+// Drop event types pushed on the user-drop-events topic
+const (
+	DropEventProgress = "drop-progress"
+	DropEventClaim    = "drop-claim"
+)
+
+// Points event types pushed on the community-points-user-v1 topic
+const (
+	PointsEventClaimAvailable = "claim-available"
+	PointsEventEarned         = "points-earned"
+)
+
+// DropEvent is a real-time drop update for the logged in user
+type DropEvent struct {
+	Type            string
+	DropID          string
+	DropInstanceID  string // only set for claim events
+	CurrentMinutes  int
+	RequiredMinutes int
+}
+
+// PointsEvent is a real-time channel points update for the logged in user
+type PointsEvent struct {
+	Type      string
+	ChannelID string
+	ClaimID   string // only set for claim-available events
+	Balance   int    // only set for points-earned events
+}
+
+// PubSub listens to the user's drop and channel points events over Twitch PubSub, reconnecting with backoff
+type PubSub struct {
+	client *Client
+	events chan DropEvent
+	points chan PointsEvent
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// NewPubSub creates an idle PubSub listener for the client's account
+func NewPubSub(client *Client) *PubSub {
+	return &PubSub{
+		client: client,
+		events: make(chan DropEvent, 32),
+		points: make(chan PointsEvent, 32),
+	}
+}
+
+// Events returns the channel drop events are delivered on
+func (p *PubSub) Events() <-chan DropEvent {
+	return p.events
+}
+
+// PointsEvents returns the channel points events are delivered on
+func (p *PubSub) PointsEvents() <-chan PointsEvent {
+	return p.points
+}
+
+// Start connects in the background, it does nothing if already started
+func (p *PubSub) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.run(ctx)
+}
+
+// Stop disconnects from PubSub
+func (p *PubSub) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+}
+
+// IsRunning reports whether the listener is started
+func (p *PubSub) IsRunning() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cancel != nil
+}
+
+// run keeps a PubSub connection open until ctx is cancelled
+func (p *PubSub) run(ctx context.Context) {
+	backoff := pubSubMinBackoff
+	for {
+		connectedAt := time.Now()
+		err := p.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A connection that lasted a while was healthy, start the backoff over
+		if time.Since(connectedAt) > pubSubPingInterval {
+			backoff = pubSubMinBackoff
+		}
+
+		logrus.WithContext(ctx).Debugf("PubSub connection lost, retrying in %s: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > pubSubMaxBackoff {
+			backoff = pubSubMaxBackoff
+		}
+	}
+}
+
+// pubSubMessage is the envelope of every PubSub frame
+type pubSubMessage struct {
+	Type  string `json:"type"`
+	Nonce string `json:"nonce,omitempty"`
+	Error string `json:"error,omitempty"`
+	Data  struct {
+		Topic   string `json:"topic"`
+		Message string `json:"message"`
+	} `json:"data"`
+}
+
+// session runs one PubSub connection, returning when it drops or ctx is cancelled
+func (p *PubSub) session(ctx context.Context) error {
+	user := p.client.GetUser()
+	if user == nil {
+		return fmt.Errorf("not logged in")
+	}
+	token, err := p.client.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second, Proxy: p.client.proxyFor}
+	conn, _, err := dialer.DialContext(ctx, pubSubURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PubSub: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock the reader when the listener is stopped
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// gorilla/websocket allows one concurrent writer
+	var writeMu sync.Mutex
+	send := func(v interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(v)
+	}
+
+	nonce := generateNonce(30)
+	if err := send(map[string]interface{}{
+		"type":  "LISTEN",
+		"nonce": nonce,
+		"data": map[string]interface{}{
+			"topics":     []string{pubSubDropEventTopic + "." + user.ID, pubSubPointsTopic + "." + user.ID},
+			"auth_token": token,
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	// Ping in the background; a missing PONG shows up as a read deadline error
+	pingCtx, cancelPing := context.WithCancel(ctx)
+	defer cancelPing()
+	go func() {
+		ticker := time.NewTicker(pubSubPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pingCtx.Done():
+				return
+			case <-ticker.C:
+				if err := send(map[string]string{"type": "PING"}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(pubSubPingInterval + pubSubPongTimeout))
+
+		var msg pubSubMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+
+		switch msg.Type {
+		case "RESPONSE":
+			if msg.Nonce == nonce && msg.Error != "" {
+				return fmt.Errorf("LISTEN rejected: %s", msg.Error)
+			}
+			logrus.WithContext(ctx).Debugf("Listening for drop and points events for %s", user.Login)
+		case "RECONNECT":
+			return fmt.Errorf("server requested reconnect")
+		case "MESSAGE":
+			if strings.HasPrefix(msg.Data.Topic, pubSubPointsTopic+".") {
+				if event, ok := parsePointsEvent(msg.Data.Message); ok {
+					select {
+					case p.points <- event:
+					default:
+						logrus.WithContext(ctx).Debug("Dropping PubSub points event, consumer is behind")
+					}
+				}
+				continue
+			}
+			if event, ok := parseDropEvent(msg.Data.Message); ok {
+				select {
+				case p.events <- event:
+				default:
+					logrus.WithContext(ctx).Debug("Dropping PubSub event, consumer is behind")
+				}
+			}
+		}
+	}
+}
+
+// parseDropEvent decodes a user-drop-events message
+func parseDropEvent(message string) (DropEvent, bool) {
+	var payload struct {
+		Type string `json:"type"`
+		Data struct {
+			DropID              string `json:"drop_id"`
+			DropInstanceID      string `json:"drop_instance_id"`
+			CurrentProgressMin  int    `json:"current_progress_min"`
+			RequiredProgressMin int    `json:"required_progress_min"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(message), &payload); err != nil {
+		logrus.Debugf("Ignoring malformed PubSub message: %v", err)
+		return DropEvent{}, false
+	}
+
+	switch payload.Type {
+	case DropEventProgress, DropEventClaim:
+	default:
+		return DropEvent{}, false
+	}
+
+	return DropEvent{
+		Type:            payload.Type,
+		DropID:          payload.Data.DropID,
+		DropInstanceID:  payload.Data.DropInstanceID,
+		CurrentMinutes:  payload.Data.CurrentProgressMin,
+		RequiredMinutes: payload.Data.RequiredProgressMin,
+	}, true
+}
+
+// parsePointsEvent decodes a community-points-user-v1 message
+func parsePointsEvent(message string) (PointsEvent, bool) {
+	var payload struct {
+		Type string `json:"type"`
+		Data struct {
+			ChannelID string `json:"channel_id"`
+			Claim     struct {
+				ID        string `json:"id"`
+				ChannelID string `json:"channel_id"`
+			} `json:"claim"`
+			Balance struct {
+				Balance int `json:"balance"`
+			} `json:"balance"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(message), &payload); err != nil {
+		logrus.Debugf("Ignoring malformed PubSub message: %v", err)
+		return PointsEvent{}, false
+	}
+
+	switch payload.Type {
+	case PointsEventClaimAvailable:
+		return PointsEvent{
+			Type:      payload.Type,
+			ChannelID: payload.Data.Claim.ChannelID,
+			ClaimID:   payload.Data.Claim.ID,
+		}, payload.Data.Claim.ID != ""
+	case PointsEventEarned:
+		return PointsEvent{
+			Type:      payload.Type,
+			ChannelID: payload.Data.ChannelID,
+			Balance:   payload.Data.Balance.Balance,
+		}, true
+	}
+	return PointsEvent{}, false
+}
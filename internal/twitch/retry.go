@@ -0,0 +1,183 @@
+package twitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Attempts per GQL request for transient failures, with exponential backoff between them
+	gqlMaxAttempts = 4
+	gqlBaseBackoff = time.Second
+	gqlMaxBackoff  = 30 * time.Second
+	// Longer Retry-After waits fail the request instead of blocking the miner
+	gqlMaxRetryAfter = 2 * time.Minute
+
+	// Requests failing in a row before GQL requests are paused, and for how long
+	circuitFailureThreshold = 5
+	circuitCooldown         = 5 * time.Minute
+)
+
+// ErrRateLimited is wrapped by errors for requests Twitch answered with 429 Too Many Requests
+var ErrRateLimited = errors.New("rate limited by Twitch")
+
+// ErrCircuitOpen is returned without sending a request while GQL requests are paused after repeated failures
+var ErrCircuitOpen = errors.New("twitch GraphQL requests paused after repeated failures")
+
+// GraphQL error messages Twitch returns for transient failures, retried like TDM
+var retryableGQLErrors = map[string]bool{
+	"service error":          true,
+	"service timeout":        true,
+	"service unavailable":    true,
+	"PersistedQueryNotFound": true,
+}
+
+// retryableError is a failure worth retrying, after at least retryAfter when Twitch asked for it
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// backoffDelay returns the wait before retry attempt+1: exponential, capped, with jitter
+func backoffDelay(attempt int) time.Duration {
+	delay := gqlBaseBackoff << attempt
+	if delay > gqlMaxBackoff {
+		delay = gqlMaxBackoff
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date, zero when it is missing
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// statusError wraps a failed HTTP status, retryable for rate limits and server errors
+func statusError(resp *http.Response) error {
+	err := fmt.Errorf("GraphQL request failed with status: %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		err = fmt.Errorf("%w: GraphQL request failed with status: %d", ErrRateLimited, resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return &retryableError{err: err, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	return err
+}
+
+// circuitBreaker pauses GQL requests of a client after circuitFailureThreshold requests failed in a row
+// A nil breaker never trips
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{}
+}
+
+// allow fails while the breaker is open; once the cooldown passes requests are let through
+// again, and the first one to fail reopens it
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// success closes the breaker; Twitch answered, even if with an error that isn't worth retrying
+func (b *circuitBreaker) success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures >= circuitFailureThreshold {
+		logrus.Info("Twitch GraphQL requests are working again")
+	}
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+// failure counts a request that failed after all retries
+func (b *circuitBreaker) failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.failures >= circuitFailureThreshold {
+		b.openUntil = time.Now().Add(circuitCooldown)
+		logrus.Warnf("%d GraphQL requests failed in a row, pausing requests until %s", b.failures, b.openUntil.Format("15:04:05"))
+	}
+}
+
+// pausedUntil returns when requests resume, zero when the breaker is closed
+func (b *circuitBreaker) pausedUntil() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Now().Before(b.openUntil) {
+		return b.openUntil
+	}
+	return time.Time{}
+}
+
+// APIPausedUntil returns when GQL requests resume after repeated failures, zero when they aren't paused
+func (c *Client) APIPausedUntil() time.Time {
+	return c.breaker.pausedUntil()
+}
+
+// Ping checks that Twitch's GraphQL endpoint is reachable through the client's proxy, for readiness checks;
+// any HTTP answer counts, but requests paused after repeated failures don't
+func (c *Client) Ping(ctx context.Context) error {
+	if until := c.APIPausedUntil(); !until.IsZero() {
+		return fmt.Errorf("requests paused after repeated failures until %s", until.Format("15:04:05"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, GraphQLEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	c.mu.RLock()
+	httpClient := &http.Client{Transport: c.transport, Timeout: 10 * time.Second}
+	c.mu.RUnlock()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("twitch is unreachable: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
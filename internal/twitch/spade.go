@@ -0,0 +1,232 @@
+package twitch
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Watch methods: HLS playlist requests (default), Spade minute-watched events, or both
+const (
+	WatchMethodHLS   = "hls"
+	WatchMethodSpade = "spade"
+	WatchMethodBoth  = "both"
+)
+
+// Spade counts one minute per minute-watched event, so sending more often than this doesn't help
+const spadeEventInterval = time.Minute
+
+// Browser user agent for the channel page; the Android one gets served a page without the Spade URL
+const spadeBrowserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+var (
+	// Like TDM: the Spade URL is in the channel page, or in the settings script it links to
+	spadeURLPattern      = regexp.MustCompile(`"spade_?url":\s*"(https://[^"]+)"`)
+	spadeSettingsPattern = regexp.MustCompile(`https://(?:static\.twitchcdn\.net|assets\.twitch\.tv)/config/settings\.[0-9a-f]+\.js`)
+)
+
+// StreamInfo identifies a live broadcast for Spade events, and what is being streamed
+type StreamInfo struct {
+	ChannelID   string
+	BroadcastID string
+	Login       string
+	DisplayName string
+	ViewerCount int
+	Title       string
+	Game        Game
+}
+
+// GetStreamInfo looks up the channel and broadcast ID of a live channel (like TDM)
+func (g *GraphQLClient) GetStreamInfo(ctx context.Context, channelLogin string) (*StreamInfo, error) {
+	resp, err := g.executeOperation(ctx, OpGetStreamInfo, map[string]interface{}{
+		"channel": channelLogin,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dataBytes, err := json.Marshal(resp.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stream info data: %w", err)
+	}
+
+	var data struct {
+		User *struct {
+			ID          string `json:"id"`
+			Login       string `json:"login"`
+			DisplayName string `json:"displayName"`
+			Stream      *struct {
+				ID           string `json:"id"`
+				ViewersCount int    `json:"viewersCount"`
+			} `json:"stream"`
+			BroadcastSettings *struct {
+				Title string   `json:"title"`
+				Game  *GameGQL `json:"game"`
+			} `json:"broadcastSettings"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(dataBytes, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode stream info: %w", err)
+	}
+
+	if data.User == nil {
+		return nil, fmt.Errorf("channel %s not found", channelLogin)
+	}
+	if data.User.Stream == nil {
+		return nil, fmt.Errorf("channel %s is offline", channelLogin)
+	}
+
+	info := &StreamInfo{
+		ChannelID:   data.User.ID,
+		BroadcastID: data.User.Stream.ID,
+		Login:       data.User.Login,
+		DisplayName: data.User.DisplayName,
+		ViewerCount: data.User.Stream.ViewersCount,
+	}
+	if settings := data.User.BroadcastSettings; settings != nil {
+		info.Title = settings.Title
+		info.Game = settings.Game.toGame()
+	}
+	return info, nil
+}
+
+// GetSpadeURL extracts the Spade endpoint from the channel page
+func (g *GraphQLClient) GetSpadeURL(ctx context.Context, channelLogin string) (string, int64, error) {
+	page, err := g.fetchPage(ctx, "https://www.twitch.tv/"+url.PathEscape(channelLogin))
+	downloaded := int64(len(page))
+	if err != nil {
+		return "", downloaded, fmt.Errorf("failed to get channel page: %w", err)
+	}
+
+	if match := spadeURLPattern.FindStringSubmatch(page); match != nil {
+		return match[1], downloaded, nil
+	}
+
+	settingsURL := spadeSettingsPattern.FindString(page)
+	if settingsURL == "" {
+		return "", downloaded, fmt.Errorf("no Spade URL or settings script in channel page")
+	}
+
+	settings, err := g.fetchPage(ctx, settingsURL)
+	downloaded += int64(len(settings))
+	if err != nil {
+		return "", downloaded, fmt.Errorf("failed to get settings script: %w", err)
+	}
+
+	if match := spadeURLPattern.FindStringSubmatch(settings); match != nil {
+		return match[1], downloaded, nil
+	}
+	return "", downloaded, fmt.Errorf("no Spade URL in settings script")
+}
+
+func (g *GraphQLClient) fetchPage(ctx context.Context, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", spadeBrowserUserAgent)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return string(body), fmt.Errorf("request failed with status: %d", resp.StatusCode)
+	}
+	return string(body), nil
+}
+
+// SendMinuteWatched posts a minute-watched event to Spade (like TDM)
+func (g *GraphQLClient) SendMinuteWatched(ctx context.Context, spadeURL, userID string, info *StreamInfo) error {
+	payload, err := json.Marshal([]map[string]interface{}{{
+		"event": "minute-watched",
+		"properties": map[string]interface{}{
+			"channel_id":   info.ChannelID,
+			"broadcast_id": info.BroadcastID,
+			"player":       "site",
+			"user_id":      userID,
+		},
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to encode minute-watched event: %w", err)
+	}
+
+	form := url.Values{"data": {base64.StdEncoding.EncodeToString(payload)}}
+	req, err := http.NewRequestWithContext(ctx, "POST", spadeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create Spade request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", g.clientInfo.UserAgent)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send minute-watched event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("minute-watched event failed with status: %d", resp.StatusCode)
+	}
+
+	logrus.WithContext(ctx).Debugf("Minute-watched event sent for broadcast %s", info.BroadcastID)
+	return nil
+}
+
+// SendSpadeEvent sends a minute-watched event for the session at most once per minute, returning the bytes
+// downloaded; the Spade URL and broadcast are looked up on the first call and kept in the session
+func (c *Client) SendSpadeEvent(ctx context.Context, session *WatchingSession) (int64, error) {
+	if session == nil || session.GQLClient == nil {
+		return 0, fmt.Errorf("invalid watching session")
+	}
+	if time.Since(session.lastSpadeEvent) < spadeEventInterval {
+		return 0, nil
+	}
+
+	user := c.GetUser()
+	if user == nil {
+		return 0, fmt.Errorf("not logged in")
+	}
+
+	var downloaded int64
+	if session.spadeURL == "" {
+		spadeURL, pageBytes, err := session.GQLClient.GetSpadeURL(ctx, session.ChannelLogin)
+		downloaded += pageBytes
+		if err != nil {
+			return downloaded, err
+		}
+		session.spadeURL = spadeURL
+	}
+
+	if session.streamInfo == nil {
+		info, err := session.GQLClient.GetStreamInfo(ctx, session.ChannelLogin)
+		if err != nil {
+			return downloaded, err
+		}
+		session.streamInfo = info
+	}
+
+	if err := session.GQLClient.SendMinuteWatched(ctx, session.spadeURL, user.ID, session.streamInfo); err != nil {
+		// The broadcast may have restarted, look it up again next time
+		session.streamInfo = nil
+		return downloaded, err
+	}
+
+	session.lastSpadeEvent = time.Now()
+	return downloaded, nil
+}
@@ -0,0 +1,161 @@
+package twitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// Twitch asks apps to validate their tokens hourly
+	tokenValidateInterval = time.Hour
+	// Tokens are refreshed this long before they expire
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// tokenExpiry converts expires_in to an expiry, zero when Twitch doesn't report one
+func tokenExpiry(expiresIn int) time.Time {
+	if expiresIn <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(expiresIn) * time.Second)
+}
+
+// SetReauthHandler sets the callback run when the token can't be refreshed and the user has to log in again
+func (c *Client) SetReauthHandler(handler func(user *User)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reauthHandler = handler
+}
+
+// LoginWithToken validates an access token obtained elsewhere and logs in with it, without storing it
+func (c *Client) LoginWithToken(ctx context.Context, token *oauth2.Token) error {
+	user, scopes, err := c.authManager.ValidateToken(ctx, token.AccessToken)
+	if err != nil {
+		return fmt.Errorf("failed to validate token: %w", err)
+	}
+	token = token.WithExtra(map[string]interface{}{"scopes": scopes})
+
+	c.mu.Lock()
+	c.token = token
+	c.user = user
+	c.scopes = scopes
+	c.isLoggedIn = true
+	if c.perAccount && c.accountID == "" {
+		c.accountID = user.ID
+	}
+	c.gqlClient = c.newGraphQLClient(token.AccessToken)
+	c.mu.Unlock()
+
+	c.InvalidateInventory()
+	return nil
+}
+
+// RunTokenValidation validates the token every hour until ctx is cancelled, refreshing it when Twitch rejects it
+// It also retries loading a stored token that couldn't be validated at startup, e.g. because the network was down
+func (c *Client) RunTokenValidation(ctx context.Context) {
+	ticker := time.NewTicker(tokenValidateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.validateToken(ctx)
+		}
+	}
+}
+
+func (c *Client) validateToken(ctx context.Context) {
+	if !c.IsLoggedIn() {
+		c.loadStoredToken()
+		return
+	}
+
+	c.mu.RLock()
+	token := c.token
+	c.mu.RUnlock()
+	if token == nil {
+		return
+	}
+
+	if _, _, err := c.authManager.ValidateToken(ctx, token.AccessToken); err != nil {
+		if !c.isAuthError(err) {
+			logrus.WithContext(ctx).Debugf("Token validation skipped: %v", err)
+			return
+		}
+		logrus.WithContext(ctx).Infof("Token rejected by Twitch, refreshing it")
+		c.recoverFromAuthError(ctx)
+	}
+}
+
+// refreshToken exchanges the refresh token for a new access token and stores it
+func (c *Client) refreshToken(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	current := c.token
+	c.mu.RUnlock()
+
+	if current == nil {
+		return fmt.Errorf("no token available")
+	}
+	if current.RefreshToken == "" {
+		return fmt.Errorf("%w: no refresh token stored, log in again", ErrRefreshRejected)
+	}
+
+	token, err := c.authManager.RefreshToken(ctx, current.RefreshToken)
+	if err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = current.RefreshToken
+	}
+	token = token.WithExtra(map[string]interface{}{"scopes": c.GetScopes()})
+
+	c.mu.Lock()
+	c.token = token
+	c.gqlClient = c.newGraphQLClient(token.AccessToken)
+	accountID := c.accountID
+	c.mu.Unlock()
+
+	if err := c.saveToken(accountID, token); err != nil {
+		logrus.WithContext(ctx).Errorf("Failed to save refreshed token: %v", err)
+	}
+
+	logrus.WithContext(ctx).Info("Refreshed Twitch access token")
+	return nil
+}
+
+// recoverFromAuthError refreshes a rejected token; when Twitch rejects the refresh too the token is dropped and
+// the re-auth handler runs, any other failure keeps it for the next attempt. It reports whether the token was
+// refreshed
+func (c *Client) recoverFromAuthError(ctx context.Context) bool {
+	err := c.refreshToken(ctx)
+	if err == nil {
+		return true
+	}
+	if !errors.Is(err, ErrRefreshRejected) {
+		logrus.WithContext(ctx).Warnf("Failed to refresh token, trying again later: %v", err)
+		return false
+	}
+
+	logrus.WithContext(ctx).Warnf("Failed to refresh token, a new login is required: %v", err)
+
+	c.mu.RLock()
+	handler := c.reauthHandler
+	c.mu.RUnlock()
+	user := c.GetUser()
+
+	c.clearToken()
+	if handler != nil {
+		handler(user)
+	}
+	return false
+}
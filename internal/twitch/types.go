@@ -35,6 +35,12 @@ type Stream struct {
 	TagIDs          []string  `json:"tag_ids"`
 }
 
+// Campaign types, the dashboard listing omits drops so its campaigns have no type until details are fetched
+const (
+	CampaignTypeTimeBased = "time_based"         // progresses by watching
+	CampaignTypeDirect    = "direct_entitlement" // granted outside of watch time, nothing to farm
+)
+
 // Campaign represents a Twitch drop campaign
 type Campaign struct {
 	ID             string       `json:"id"`
@@ -42,6 +48,7 @@ type Campaign struct {
 	Description    string       `json:"description"`
 	Game           Game         `json:"game"`
 	Status         string       `json:"status"`
+	Type           string       `json:"type,omitempty"`
 	StartsAt       time.Time    `json:"starts_at"`
 	EndsAt         time.Time    `json:"ends_at"`
 	AccountLinkURL string       `json:"account_link_url"`
@@ -63,6 +70,8 @@ type TimeBased struct {
 	Name                   string        `json:"name"`
 	BenefitEdges           []BenefitEdge `json:"benefit_edges"`
 	RequiredMinutesWatched int           `json:"required_minutes_watched"`
+	StartsAt               time.Time     `json:"starts_at"` // a drop may be available for less time than its campaign
+	EndsAt                 time.Time     `json:"ends_at"`
 	Self                   TimeBasedSelf `json:"self"`
 }
 
@@ -99,12 +108,20 @@ type WatchingSession struct {
 	ChannelLogin string
 	StreamURL    string
 	GQLClient    *GraphQLClient
+
+	// Spade state, filled in by SendSpadeEvent
+	spadeURL       string
+	streamInfo     *StreamInfo
+	lastSpadeEvent time.Time
 }
 
 // CurrentDropProgress represents current drop progress from TDM's CurrentDrop operation
 type CurrentDropProgress struct {
 	CurrentMinutesWatched int
 	DropID                string
+	ChannelID             string // channel the drop session is attached to
+	ChannelLogin          string
+	GameName              string
 }
 
 // GameSlugInfo represents the response from SlugRedirect/DirectoryGameRedirect
@@ -181,3 +198,11 @@ type MinerStatus struct {
 	NextSwitch      time.Time `json:"next_switch"`
 	ErrorMessage    string    `json:"error_message"`
 }
+
+// ChannelPoints represents the user's channel points state for a channel
+type ChannelPoints struct {
+	ChannelID    string `json:"channel_id"`
+	ChannelLogin string `json:"channel_login"`
+	Balance      int    `json:"balance"`
+	ClaimID      string `json:"claim_id"` // empty when no bonus is available
+}
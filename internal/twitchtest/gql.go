@@ -0,0 +1,325 @@
+package twitchtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"twitchdropsfarmer/internal/twitch"
+)
+
+// Channel points balance reported for every channel
+const pointsBalance = 1250
+
+// gqlRequest is the body of a persisted GQL query
+type gqlRequest struct {
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Operations answered with "failed integrity check" unless sent with the token from /integrity
+var integrityOperations = map[string]bool{
+	"DropsPage_ClaimDropRewards": true,
+	"ClaimCommunityPoints":       true,
+}
+
+// gqlHandler builds the data of an operation's response, or returns a GraphQL error message
+type gqlHandler func(s *Server, variables map[string]interface{}) (interface{}, error)
+
+// Responses by operation name; the ones served unchanged come straight from fixtures/<name>.json
+var gqlHandlers = map[string]gqlHandler{
+	"ViewerDropsDashboard":                 staticFixture("ViewerDropsDashboard.json"),
+	"DropCampaignDetails":                  (*Server).campaignDetails,
+	"Inventory":                            (*Server).inventory,
+	"DropCurrentSessionContext":            (*Server).currentSession,
+	"DirectoryPage_Game":                   staticFixture("DirectoryPage_Game.json"),
+	"DirectoryGameRedirect":                staticFixture("DirectoryGameRedirect.json"),
+	"PlaybackAccessToken":                  staticFixture("PlaybackAccessToken.json"),
+	"DropsHighlightService_AvailableDrops": (*Server).availableDrops,
+	"VideoPlayerStreamInfoOverlayChannel":  (*Server).streamInfo,
+	"DropsPage_ClaimDropRewards":           (*Server).claimDrop,
+	"ChannelPointsContext":                 (*Server).channelPoints,
+	"ClaimCommunityPoints":                 emptyResponse("claimCommunityPoints"),
+	"FollowButton_FollowUser":              emptyResponse("followUser"),
+	"FollowButton_UnfollowUser":            emptyResponse("unfollowUser"),
+}
+
+func staticFixture(name string) gqlHandler {
+	return func(s *Server, variables map[string]interface{}) (interface{}, error) {
+		return json.RawMessage(fixture(name)), nil
+	}
+}
+
+func emptyResponse(field string) gqlHandler {
+	return func(s *Server, variables map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{field: map[string]interface{}{}}, nil
+	}
+}
+
+func (s *Server) handleGQL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized", "status": 401, "message": "The \"Authorization\" token is invalid."})
+		return
+	}
+
+	var req gqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid GQL request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests[req.OperationName]++
+	s.mu.Unlock()
+
+	handler, ok := gqlHandlers[req.OperationName]
+	if !ok {
+		writeJSON(w, http.StatusOK, twitch.GraphQLResponse{
+			Errors: []twitch.GraphQLError{{Message: fmt.Sprintf("twitchtest: no fixture for operation %s", req.OperationName)}},
+		})
+		return
+	}
+
+	if integrityOperations[req.OperationName] && r.Header.Get("Client-Integrity") != IntegrityToken {
+		writeJSON(w, http.StatusOK, twitch.GraphQLResponse{
+			Errors: []twitch.GraphQLError{{Message: "failed integrity check", Path: []interface{}{req.OperationName}}},
+		})
+		return
+	}
+
+	data, err := handler(s, req.Variables)
+	if err != nil {
+		writeJSON(w, http.StatusOK, twitch.GraphQLResponse{Errors: []twitch.GraphQLError{{Message: err.Error()}}})
+		return
+	}
+	writeJSON(w, http.StatusOK, twitch.GraphQLResponse{
+		Data:       data,
+		Extensions: map[string]interface{}{"operationName": req.OperationName, "durationMilliseconds": 12},
+	})
+}
+
+// stringVariable reads a string variable, also from the "input" object of mutations
+func stringVariable(variables map[string]interface{}, name string) string {
+	if value, ok := variables[name].(string); ok {
+		return value
+	}
+	if input, ok := variables["input"].(map[string]interface{}); ok {
+		if value, ok := input[name].(string); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// instanceID is the drop instance ID Twitch assigns to a completed drop
+func instanceID(campaignID, dropID string) string {
+	return fmt.Sprintf("%s#%s#%s", UserID, campaignID, dropID)
+}
+
+func (s *Server) campaignDetails(variables map[string]interface{}) (interface{}, error) {
+	var resp twitch.OpCampaignDetailsResponse
+	decodeFixture("DropCampaignDetails.json", &resp)
+	if resp.User == nil || resp.User.DropCampaign == nil || resp.User.DropCampaign.ID != stringVariable(variables, "dropID") {
+		// Twitch returns a null campaign for unknown IDs
+		return map[string]interface{}{"user": map[string]interface{}{"__typename": "User", "id": UserID, "dropCampaign": nil}}, nil
+	}
+	return resp, nil
+}
+
+// inventory returns the inventory fixture with the simulated progress of its drops
+func (s *Server) inventory(variables map[string]interface{}) (interface{}, error) {
+	var resp twitch.OpInventoryResponse
+	decodeFixture("Inventory.json", &resp)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, campaign := range resp.CurrentUser.Inventory.DropCampaignsInProgress {
+		if campaign.TimeBasedDrops == nil {
+			continue
+		}
+		for i := range *campaign.TimeBasedDrops {
+			drop := &(*campaign.TimeBasedDrops)[i]
+			if drop.Self == nil {
+				continue
+			}
+			drop.Self.CurrentMinutesWatched = s.watched[drop.ID]
+			drop.Self.IsClaimed = s.claimed[drop.ID]
+			if drop.Self.CurrentMinutesWatched >= drop.RequiredMinutesWatched {
+				var id interface{} = instanceID(campaign.ID, drop.ID)
+				drop.Self.DropInstanceID = &id
+			}
+		}
+	}
+	return resp, nil
+}
+
+// currentSession reports the first drop of the inventory that is still in progress
+func (s *Server) currentSession(variables map[string]interface{}) (interface{}, error) {
+	var resp twitch.OpInventoryResponse
+	decodeFixture("Inventory.json", &resp)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var session interface{}
+	for _, campaign := range resp.CurrentUser.Inventory.DropCampaignsInProgress {
+		if session != nil || campaign.TimeBasedDrops == nil {
+			continue
+		}
+		for _, drop := range *campaign.TimeBasedDrops {
+			if s.watched[drop.ID] == 0 || s.watched[drop.ID] >= drop.RequiredMinutesWatched {
+				continue
+			}
+			session = map[string]interface{}{
+				"__typename":             "DropCurrentSession",
+				"channel":                map[string]interface{}{"__typename": "Channel", "id": stringVariable(variables, "channelID")},
+				"currentMinutesWatched":  s.watched[drop.ID],
+				"dropID":                 drop.ID,
+				"game":                   campaign.Game,
+				"requiredMinutesWatched": drop.RequiredMinutesWatched,
+			}
+			break
+		}
+	}
+
+	return map[string]interface{}{
+		"currentUser": map[string]interface{}{"__typename": "User", "id": UserID, "dropCurrentSession": session},
+	}, nil
+}
+
+// directoryStream looks a channel up in the directory fixture
+func directoryStream(channelLogin string) *twitch.StreamGQL {
+	var resp twitch.OpGameDirectoryResponse
+	decodeFixture("DirectoryPage_Game.json", &resp)
+	if resp.Game == nil || resp.Game.Streams == nil {
+		return nil
+	}
+	for _, edge := range resp.Game.Streams.Edges {
+		if edge.Node != nil && strings.EqualFold(edge.Node.Broadcaster.Login, channelLogin) {
+			return edge.Node
+		}
+	}
+	return nil
+}
+
+// availableDrops lists the active dashboard campaigns on every channel
+func (s *Server) availableDrops(variables map[string]interface{}) (interface{}, error) {
+	var dashboard twitch.OpCampaignsResponse
+	decodeFixture("ViewerDropsDashboard.json", &dashboard)
+
+	campaigns := []map[string]interface{}{}
+	if dashboard.CurrentUser != nil {
+		for _, campaign := range dashboard.CurrentUser.DropCampaigns {
+			if campaign.Status == "ACTIVE" {
+				campaigns = append(campaigns, map[string]interface{}{"__typename": "DropCampaign", "id": campaign.ID, "name": campaign.Name})
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"channel": map[string]interface{}{
+			"__typename":          "Channel",
+			"id":                  stringVariable(variables, "channelID"),
+			"viewerDropCampaigns": campaigns,
+		},
+	}, nil
+}
+
+func (s *Server) streamInfo(variables map[string]interface{}) (interface{}, error) {
+	channelLogin := stringVariable(variables, "channel")
+	stream := directoryStream(channelLogin)
+	if stream == nil {
+		return map[string]interface{}{"user": nil}, nil
+	}
+
+	s.mu.Lock()
+	offline := s.offline[strings.ToLower(channelLogin)]
+	s.mu.Unlock()
+
+	var live interface{}
+	if !offline {
+		live = map[string]interface{}{"__typename": "Stream", "id": stream.ID, "viewersCount": stream.ViewersCount}
+	}
+	return map[string]interface{}{
+		"user": map[string]interface{}{
+			"__typename":  "User",
+			"id":          stream.Broadcaster.ID,
+			"login":       stream.Broadcaster.Login,
+			"displayName": stream.Broadcaster.DisplayName,
+			"stream":      live,
+			"broadcastSettings": map[string]interface{}{
+				"__typename": "BroadcastSettings",
+				"title":      stream.Title,
+				"game":       stream.Game,
+			},
+		},
+	}, nil
+}
+
+// claimDrop claims a completed drop by its instance ID
+func (s *Server) claimDrop(variables map[string]interface{}) (interface{}, error) {
+	id := stringVariable(variables, "dropInstanceID")
+
+	var resp twitch.OpInventoryResponse
+	decodeFixture("Inventory.json", &resp)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, campaign := range resp.CurrentUser.Inventory.DropCampaignsInProgress {
+		if campaign.TimeBasedDrops == nil {
+			continue
+		}
+		for _, drop := range *campaign.TimeBasedDrops {
+			if instanceID(campaign.ID, drop.ID) != id || s.watched[drop.ID] < drop.RequiredMinutesWatched {
+				continue
+			}
+
+			status := "ELIGIBLE_FOR_ALL"
+			if s.claimed[drop.ID] {
+				status = "DROP_INSTANCE_ALREADY_CLAIMED"
+			}
+			s.claimed[drop.ID] = true
+			return map[string]interface{}{
+				"claimDropRewards": map[string]interface{}{
+					"__typename":     "ClaimDropRewardsPayload",
+					"dropInstanceID": id,
+					"status":         status,
+				},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("drop instance %q not found", id)
+}
+
+func (s *Server) channelPoints(variables map[string]interface{}) (interface{}, error) {
+	stream := directoryStream(stringVariable(variables, "channelLogin"))
+	if stream == nil {
+		return map[string]interface{}{"community": nil}, nil
+	}
+
+	return map[string]interface{}{
+		"community": map[string]interface{}{
+			"__typename": "User",
+			"id":         stream.Broadcaster.ID,
+			"channel": map[string]interface{}{
+				"__typename": "Channel",
+				"id":         stream.Broadcaster.ID,
+				"self": map[string]interface{}{
+					"__typename": "ChannelSelfEdge",
+					"communityPoints": map[string]interface{}{
+						"__typename":     "CommunityPointsProperties",
+						"balance":        pointsBalance,
+						"availableClaim": nil,
+					},
+				},
+			},
+		},
+	}, nil
+}
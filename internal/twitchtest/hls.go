@@ -0,0 +1,102 @@
+package twitchtest
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"twitchdropsfarmer/internal/twitch"
+)
+
+// playlistLogin returns the channel login of a playlist or segment path
+func playlistLogin(p, suffix string) string {
+	return strings.ToLower(strings.TrimSuffix(path.Base(p), suffix))
+}
+
+// isLive reports whether a channel from the directory fixture is live
+func (s *Server) isLive(channelLogin string) bool {
+	if directoryStream(channelLogin) == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.offline[channelLogin]
+}
+
+func writePlaylist(w http.ResponseWriter, playlist string) {
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Write([]byte(playlist))
+}
+
+// handleMasterPlaylist serves usher's /api/channel/hls/<login>.m3u8
+func (s *Server) handleMasterPlaylist(w http.ResponseWriter, r *http.Request) {
+	login := playlistLogin(r.URL.Path, ".m3u8")
+	if r.URL.Query().Get("token") == "" || r.URL.Query().Get("sig") == "" {
+		http.Error(w, "missing playback token", http.StatusForbidden)
+		return
+	}
+	if !s.isLive(login) {
+		http.Error(w, "[]", http.StatusNotFound)
+		return
+	}
+	writePlaylist(w, strings.ReplaceAll(string(fixture("master.m3u8")), "{login}", login))
+}
+
+// handleMediaPlaylist serves /v1/playlist/<login>-<quality>.m3u8, its live edge moving on every request
+func (s *Server) handleMediaPlaylist(w http.ResponseWriter, r *http.Request) {
+	login := playlistLogin(r.URL.Path, ".m3u8")
+	if i := strings.LastIndex(login, "-"); i >= 0 {
+		login = login[:i]
+	}
+	if !s.isLive(login) {
+		http.Error(w, "", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	s.sequence++
+	sequence := s.sequence
+	s.mu.Unlock()
+
+	playlist := strings.NewReplacer(
+		"{login}", login,
+		"{sequence}", strconv.Itoa(sequence),
+		"{next}", strconv.Itoa(sequence+1),
+	).Replace(string(fixture("media.m3u8")))
+	writePlaylist(w, playlist)
+}
+
+// handleSegment answers the HEAD request for a chunk, which is what advances drops
+func (s *Server) handleSegment(w http.ResponseWriter, r *http.Request) {
+	login := path.Base(path.Dir(r.URL.Path))
+	if !s.isLive(login) {
+		http.Error(w, "", http.StatusNotFound)
+		return
+	}
+
+	s.addWatchMinutes()
+	w.Header().Set("Content-Type", "video/MP2T")
+	w.WriteHeader(http.StatusOK)
+}
+
+// addWatchMinutes adds MinutesPerWatch to every unclaimed drop of the inventory, up to its required minutes
+func (s *Server) addWatchMinutes() {
+	var resp twitch.OpInventoryResponse
+	decodeFixture("Inventory.json", &resp)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, campaign := range resp.CurrentUser.Inventory.DropCampaignsInProgress {
+		if campaign.TimeBasedDrops == nil {
+			continue
+		}
+		for _, drop := range *campaign.TimeBasedDrops {
+			if s.claimed[drop.ID] {
+				continue
+			}
+			s.watched[drop.ID] = min(s.watched[drop.ID]+s.minutesPerWatch, drop.RequiredMinutesWatched)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package twitchtest serves recorded Twitch responses over httptest, so the miner can be driven
+// through a full watch, progress and claim cycle without talking to Twitch.
+//
+// The Server answers the GQL operations the client sends (campaigns, campaign details, inventory,
+// directory, playback token, ...) from the JSON fixtures in fixtures/, along with the OAuth,
+// Helix, integrity and HLS endpoints; claim and points mutations fail the integrity check without
+// the token from /integrity. It redirects every host to itself through Transport, so the client
+// keeps using its real URLs. Drop progress is simulated: every chunk HEAD request counts as
+// MinutesPerWatch minutes for the unclaimed drops in the inventory fixture, and a drop gets its
+// instance ID once it has enough minutes, ready to be claimed.
+//
+//	server := twitchtest.New()
+//	defer server.Close()
+//	client, err := server.NewClient(ctx)
+//	miner := drops.NewMiner(client)
+package twitchtest
+
+import (
+	"context"
+	"crypto/tls"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/twitch"
+
+	"golang.org/x/oauth2"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// The account the fixtures are recorded for
+const (
+	ClientID    = "kimne78kx3ncx6brgo4mv6wki5h1ko"
+	AccessToken = "twitchtest-access-token"
+	// Client-Integrity token handed out by /integrity
+	IntegrityToken = "twitchtest-integrity-token"
+	UserID         = "100000001"
+	UserLogin      = "farmer"
+)
+
+// Server is a fake Twitch backend for one mock account
+type Server struct {
+	srv *httptest.Server
+
+	mu              sync.Mutex
+	minutesPerWatch int
+	watched         map[string]int  // minutes watched by drop ID
+	claimed         map[string]bool // claimed drops by drop ID
+	offline         map[string]bool // channel logins whose playlists 404
+	requests        map[string]int  // GQL requests by operation name
+	sequence        int             // media sequence of the HLS playlists
+}
+
+// New starts a server; close it with Close
+func New() *Server {
+	s := &Server{
+		minutesPerWatch: 1,
+		watched:         make(map[string]int),
+		claimed:         make(map[string]bool),
+		offline:         make(map[string]bool),
+		requests:        make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gql", s.handleGQL)
+	mux.HandleFunc("/integrity", s.handleIntegrity)
+	mux.HandleFunc("/oauth2/validate", s.handleValidate)
+	mux.HandleFunc("/oauth2/token", s.handleToken)
+	mux.HandleFunc("/oauth2/revoke", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/helix/users", s.handleUsers)
+	mux.HandleFunc("/api/channel/hls/", s.handleMasterPlaylist)
+	mux.HandleFunc("/v1/playlist/", s.handleMediaPlaylist)
+	mux.HandleFunc("/v1/segment/", s.handleSegment)
+
+	s.srv = httptest.NewTLSServer(mux)
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Transport returns a transport that sends requests for any host to the server
+func (s *Server) Transport() *http.Transport {
+	addr := s.srv.Listener.Addr().String()
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+}
+
+// NewClient returns a client logged in to the mock account, sending its requests to the server
+// Its token isn't stored, the Twitch client config on disk is left alone
+func (s *Server) NewClient(ctx context.Context) (*twitch.Client, error) {
+	profile, _ := twitch.LookupClientProfile(twitch.ProfileWebPlayer)
+	client, err := twitch.NewAccountClient(profile, "", "")
+	if err != nil {
+		return nil, err
+	}
+	client.SetTransport(s.Transport())
+
+	token := &oauth2.Token{AccessToken: AccessToken, TokenType: "bearer", Expiry: time.Now().Add(4 * time.Hour)}
+	if err := client.LoginWithToken(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to log in to test server: %w", err)
+	}
+	return client, nil
+}
+
+// SetMinutesPerWatch sets the minutes every watch request adds to the drops in progress
+func (s *Server) SetMinutesPerWatch(minutes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minutesPerWatch = minutes
+}
+
+// SetOffline takes a channel offline, or back online; the playlists of offline channels 404
+func (s *Server) SetOffline(channelLogin string, offline bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offline[strings.ToLower(channelLogin)] = offline
+}
+
+// MinutesWatched returns the minutes watched for a drop
+func (s *Server) MinutesWatched(dropID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.watched[dropID]
+}
+
+// Claimed reports whether a drop was claimed
+func (s *Server) Claimed(dropID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.claimed[dropID]
+}
+
+// Requests returns how many GQL requests were made for an operation, e.g. "ViewerDropsDashboard"
+func (s *Server) Requests(operationName string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[operationName]
+}
+
+// authorized checks the OAuth or Bearer token of a request
+func authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	return auth == "OAuth "+AccessToken || auth == "Bearer "+AccessToken
+}
+
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"status": 401, "message": "invalid access token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"client_id":  ClientID,
+		"login":      UserLogin,
+		"scopes":     []string{},
+		"user_id":    UserID,
+		"expires_in": 14400,
+	})
+}
+
+func (s *Server) handleIntegrity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized", "status": 401})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token":      IntegrityToken,
+		"expiration": time.Now().Add(16 * time.Hour).UnixMilli(),
+		"request_id": "twitchtest",
+	})
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  AccessToken,
+		"refresh_token": "twitchtest-refresh-token",
+		"expires_in":    14400,
+		"token_type":    "bearer",
+	})
+}
+
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized", "status": 401})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": []twitch.User{{ID: UserID, Login: UserLogin, DisplayName: "Farmer"}},
+	})
+}
+
+// fixture reads a file from fixtures/
+func fixture(name string) []byte {
+	data, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		panic(fmt.Sprintf("twitchtest: missing fixture %s: %v", name, err))
+	}
+	return data
+}
+
+// decodeFixture unmarshals a JSON fixture into v
+func decodeFixture(name string, v interface{}) {
+	if err := json.Unmarshal(fixture(name), v); err != nil {
+		panic(fmt.Sprintf("twitchtest: invalid fixture %s: %v", name, err))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
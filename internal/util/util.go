@@ -2,6 +2,7 @@ package util
 
 import (
 	"context"
+	"time"
 
 	"twitchdropsfarmer/internal/drops"
 	"twitchdropsfarmer/internal/twitch"
@@ -92,6 +93,8 @@ func GenerateActiveDrops(ctx context.Context, twitchClient *twitch.Client, campa
 			Progress:        progress,
 			IsClaimed:       isClaimed,
 		}
+		// The campaign is the one being watched, so every unclaimed drop is progressing
+		activeDrop.SetClaimETA(time.Now(), true)
 		activeDrops = append(activeDrops, activeDrop)
 	}
 
@@ -0,0 +1,183 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"twitchdropsfarmer/internal/accounts"
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/audit"
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Context key holding the account resolved from /api/accounts/:accountID
+const accountContextKey = "account"
+
+// SetAccounts sets the manager for additional accounts and forwards their miner updates over WebSocket
+func (s *Server) SetAccounts(manager *accounts.Manager) {
+	s.accounts = manager
+	manager.OnAdd(func(account *accounts.Account) {
+		s.forwardMinerEvents(account.ID, account.Client, account.Miner)
+	})
+}
+
+// accountFromContext returns the additional account the request is for, nil for the primary account
+func accountFromContext(c *gin.Context) *accounts.Account {
+	if value, ok := c.Get(accountContextKey); ok {
+		return value.(*accounts.Account)
+	}
+	return nil
+}
+
+// clientFor returns the Twitch client of the account the request is for
+func (s *Server) clientFor(c *gin.Context) *twitch.Client {
+	if account := accountFromContext(c); account != nil {
+		return account.Client
+	}
+	return s.twitchClient
+}
+
+// minerFor returns the miner of the account the request is for
+func (s *Server) minerFor(c *gin.Context) *drops.Miner {
+	if account := accountFromContext(c); account != nil {
+		return account.Miner
+	}
+	return s.miner
+}
+
+// storeFor returns the storage of the account the request is for
+func (s *Server) storeFor(c *gin.Context) storage.Store {
+	if account := accountFromContext(c); account != nil {
+		return account.Store
+	}
+	return s.store
+}
+
+// applyAccountsConfig pushes changed settings to the additional accounts
+func (s *Server) applyAccountsConfig() {
+	if s.accounts != nil {
+		s.accounts.ApplyConfig()
+	}
+}
+
+// Account middleware resolves :accountID to one of the additional accounts
+func (s *Server) AccountMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var account *accounts.Account
+		if s.accounts != nil {
+			account = s.accounts.Get(c.Param("accountID"))
+		}
+		if account == nil {
+			respondError(c, apierror.ErrNotFound.WithMessage("Account not found"))
+			return
+		}
+
+		c.Set(accountContextKey, account)
+		c.Next()
+	}
+}
+
+// Account handlers
+func (s *Server) listAccounts(c *gin.Context) {
+	result := []gin.H{}
+	if s.accounts != nil {
+		for _, account := range s.accounts.List() {
+			user := account.Client.GetUser()
+			if isScoped(c) && (user == nil || !canAccessAccount(c, user.Login)) {
+				continue
+			}
+
+			result = append(result, gin.H{
+				"id":           account.ID,
+				"user":         user,
+				"is_logged_in": account.Client.IsLoggedIn(),
+				"is_running":   account.Miner.IsRunning(),
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accounts": result})
+}
+
+// addAccount starts a device code login for a new account; finish it with /api/accounts/callback
+func (s *Server) addAccount(c *gin.Context) {
+	if s.accounts == nil {
+		respondError(c, apierror.ErrUnavailable.WithMessage("Multiple accounts are not enabled"))
+		return
+	}
+
+	deviceResp, err := s.accounts.BeginLogin(c.Request.Context())
+	if err != nil {
+		requestLog(c).Errorf("Failed to start device flow for new account: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to start device flow"))
+		return
+	}
+
+	// Kept for GET /api/auth/qr
+	s.storeDeviceCode(deviceResp, true)
+
+	c.JSON(http.StatusOK, deviceCodeJSON(deviceResp))
+}
+
+func (s *Server) handleAccountCallback(c *gin.Context) {
+	if s.accounts == nil {
+		respondError(c, apierror.ErrUnavailable.WithMessage("Multiple accounts are not enabled"))
+		return
+	}
+
+	var req struct {
+		DeviceCode string `json:"device_code" binding:"required"`
+		Interval   int    `json:"interval"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+	if !s.accounts.IsPendingLogin(req.DeviceCode) {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid device code"))
+		return
+	}
+	if req.Interval <= 0 {
+		req.Interval = 5
+	}
+
+	// Poll in the background like the primary login; the copy keeps the caller for the audit entry
+	auditCtx := c.Copy()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+		defer cancel()
+
+		account, err := s.accounts.CompleteLogin(ctx, req.DeviceCode, req.Interval)
+		if err != nil {
+			logrus.Errorf("Failed to add account: %v", err)
+			return
+		}
+		s.recordAudit(auditCtx, audit.ActionAccountAdd, fmt.Sprintf("%s (%s)", account.Login(), account.ID))
+	}()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Polling for authorization...",
+	})
+}
+
+func (s *Server) removeAccount(c *gin.Context) {
+	account := accountFromContext(c)
+	login := account.Login()
+
+	if err := s.accounts.Remove(c.Request.Context(), account.ID); err != nil {
+		requestLog(c).Errorf("Failed to remove account %s: %v", account.ID, err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to remove account"))
+		return
+	}
+
+	s.recordAudit(c, audit.ActionAccountRemove, fmt.Sprintf("%s (%s)", login, account.ID))
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Backup handlers
+func (s *Server) listBackups(c *gin.Context) {
+	backups, err := s.backups.List()
+	if err != nil {
+		requestLog(c).Errorf("Failed to list backups: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to list backups"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+func (s *Server) createBackup(c *gin.Context) {
+	backup, err := s.backups.Create()
+	if err != nil {
+		requestLog(c).Errorf("Failed to create backup: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to create backup"))
+		return
+	}
+
+	s.recordAudit(c, audit.ActionBackupCreate, backup.Name)
+	c.JSON(http.StatusOK, backup)
+}
+
+// restoreBackup restores a backup like an imported state bundle, after backing up the current state so the
+// restore can be undone
+func (s *Server) restoreBackup(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	contents, err := s.backups.Open(req.Name)
+	if errors.Is(err, os.ErrNotExist) {
+		respondError(c, apierror.ErrNotFound.WithMessage("Backup not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid backup").WithDetails(err.Error()))
+		return
+	}
+
+	previous, err := s.backups.Create()
+	if err != nil {
+		requestLog(c).Errorf("Failed to back up the current state before a restore: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to back up the current state"))
+		return
+	}
+	if !s.restoreBundle(c, contents) {
+		return
+	}
+
+	s.recordAudit(c, audit.ActionBackupRestore, req.Name)
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"documents": len(contents.Documents),
+		"accounts":  len(contents.Accounts),
+		"previous":  previous.Name,
+	})
+}
@@ -0,0 +1,121 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timestamps in iCalendar files are UTC
+const icsTimeFormat = "20060102T150405Z"
+
+// Longest line allowed by RFC 5545, longer ones are folded
+const icsLineLength = 75
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+// getCampaignCalendar serves the start and end of the priority games' campaigns, and drop deadlines, as an iCalendar feed
+func (s *Server) getCampaignCalendar(c *gin.Context) {
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
+		return
+	}
+
+	campaigns, err := s.clientFor(c).GetDropCampaigns(c.Request.Context())
+	if err != nil {
+		requestLog(c).Errorf("Failed to get campaigns: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to get campaigns")))
+		return
+	}
+
+	calendar := campaignCalendar(s.minerFor(c).CalendarCampaigns(c.Request.Context(), campaigns), time.Now())
+	c.Header("Content-Disposition", `inline; filename="twitch-drops.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar)
+}
+
+// icsWriter builds an iCalendar file with CRLF line endings and folded long lines
+type icsWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *icsWriter) line(name, value string) {
+	line := name + ":" + value
+	limit := icsLineLength
+	for len(line) > limit {
+		// Fold on a rune boundary, continuation lines start with a space that counts towards their length
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.buf.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = icsLineLength - 1
+	}
+	w.buf.WriteString(line + "\r\n")
+}
+
+func (w *icsWriter) event(uid string, at time.Time, stamp time.Time, summary, description, url string) {
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", uid+"@twitchdropsfarmer")
+	w.line("DTSTAMP", stamp.UTC().Format(icsTimeFormat))
+	w.line("DTSTART", at.UTC().Format(icsTimeFormat))
+	w.line("SUMMARY", icsEscaper.Replace(summary))
+	if description != "" {
+		w.line("DESCRIPTION", icsEscaper.Replace(description))
+	}
+	if url != "" {
+		w.line("URL", url)
+	}
+	w.line("END", "VEVENT")
+}
+
+// campaignCalendar lists an event when each campaign starts and ends, and when drops end before their campaign
+func campaignCalendar(campaigns []twitch.Campaign, now time.Time) []byte {
+	w := &icsWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//TwitchDropsFarmer//Drop Campaigns//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.line("X-WR-CALNAME", "Twitch Drops")
+
+	for _, campaign := range campaigns {
+		var description []string
+		if rewards := drops.CampaignRewards(&campaign); len(rewards) > 0 {
+			description = append(description, "Rewards: "+strings.Join(rewards, ", "))
+		}
+		if !campaign.Self.IsAccountConnected && campaign.AccountLinkURL != "" {
+			description = append(description, "Link your account: "+campaign.AccountLinkURL)
+		}
+		details := strings.Join(description, "\n")
+
+		if !campaign.StartsAt.IsZero() {
+			w.event(campaign.ID+"-start", campaign.StartsAt, now,
+				fmt.Sprintf("%s drops start: %s", campaign.Game.Name, campaign.Name), details, campaign.AccountLinkURL)
+		}
+		if !campaign.EndsAt.IsZero() {
+			w.event(campaign.ID+"-end", campaign.EndsAt, now,
+				fmt.Sprintf("%s drops end: %s", campaign.Game.Name, campaign.Name), details, campaign.AccountLinkURL)
+		}
+
+		for _, drop := range campaign.TimeBasedDrops {
+			if drop.EndsAt.IsZero() || !drop.EndsAt.Before(campaign.EndsAt) {
+				continue
+			}
+			w.event(campaign.ID+"-"+drop.ID+"-end", drop.EndsAt, now,
+				fmt.Sprintf("%s drop deadline: %s", campaign.Game.Name, drop.Name),
+				fmt.Sprintf("Needs %d minutes watched, part of %s", drop.RequiredMinutesWatched, campaign.Name), "")
+		}
+	}
+
+	w.line("END", "VCALENDAR")
+	return w.buf.Bytes()
+}
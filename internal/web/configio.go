@@ -0,0 +1,196 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/audit"
+	"twitchdropsfarmer/internal/backup"
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/digest"
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/logging"
+	"twitchdropsfarmer/internal/notify"
+	"twitchdropsfarmer/internal/twitch"
+	"twitchdropsfarmer/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Upper bound for an uploaded settings export
+const maxConfigImportSize = 1 << 20
+
+// exportConfig downloads every setting as one JSON document, with API keys, notification URLs, webhook and
+// digest URLs, and proxy passwords redacted
+func (s *Server) exportConfig(c *gin.Context) {
+	export, err := config.NewExport(s.config())
+	if err != nil {
+		requestLog(c).Errorf("Failed to export settings: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to export settings"))
+		return
+	}
+
+	filename := fmt.Sprintf("twitchdropsfarmer-config-%s.json", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// importConfig replaces the settings with an export, once it is fully validated
+func (s *Server) importConfig(c *gin.Context) {
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxConfigImportSize))
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Failed to read settings export").WithDetails(err.Error()))
+		return
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	imported, err := config.Import(data, s.config())
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid settings export").WithDetails(err.Error()))
+		return
+	}
+	if apiErr := validateConfig(imported); apiErr != nil {
+		respondError(c, apiErr)
+		return
+	}
+
+	if err := imported.Save(); err != nil {
+		requestLog(c).Errorf("Failed to save imported configuration: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to save configuration"))
+		return
+	}
+	before := s.config()
+	s.live.Set(imported)
+	s.applyConfig()
+
+	changed := changedSettings(before, imported)
+	s.recordAudit(c, audit.ActionConfigImport, strings.Join(changed, ", "))
+	if len(changed) > 0 {
+		s.broadcastSettingsChanged(SettingsSourceAPI, changed)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "changed": changed})
+}
+
+// validateConfig checks a whole configuration with the rules PUT /api/config applies to each setting
+func validateConfig(cfg *config.Config) *apierror.Error {
+	invalid := func(message, details string) *apierror.Error {
+		return apierror.ErrInvalidRequest.WithMessage(message).WithDetails(details)
+	}
+
+	for _, apiKey := range cfg.APIKeys {
+		if apiKey.Key == "" || (apiKey.Role != config.RoleAdmin && apiKey.Role != config.RoleViewer) {
+			return invalid("Invalid api_keys", "every key needs a key and the admin or viewer role")
+		}
+	}
+	if cfg.WebhookURL != "" {
+		if u, err := url.Parse(cfg.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalid("Invalid webhook_url", "must be an http:// or https:// URL")
+		}
+	}
+	for _, eventType := range cfg.WebhookEvents {
+		if !webhook.ValidEventType(eventType) {
+			return invalid("Invalid webhook_events", "unknown event type "+eventType)
+		}
+	}
+	if err := webhook.ValidateTemplates(cfg.WebhookTemplates); err != nil {
+		return invalid("Invalid webhook_templates", err.Error())
+	}
+	if _, err := notify.ParseURLs(cfg.NotificationURLs); err != nil {
+		return invalid("Invalid notification URL", err.Error())
+	}
+	if cfg.DigestEmail != "" {
+		if _, err := notify.ParseEmailURL(cfg.DigestEmail); err != nil {
+			return invalid("Invalid digest_email", err.Error())
+		}
+	}
+	if cfg.BackupSchedule != "" {
+		if _, err := backup.ParseSchedule(cfg.BackupSchedule); err != nil {
+			return invalid("Invalid backup_schedule", err.Error())
+		}
+	}
+	if cfg.BackupRetention < 0 {
+		return invalid("Invalid backup_retention", "must be 0 to keep all backups, or the number kept")
+	}
+	if !digest.ValidFrequency(cfg.DigestFrequency) {
+		return invalid("Invalid digest_frequency", "must be off, daily, or weekly")
+	}
+	if cfg.DigestHour < 0 || cfg.DigestHour > 23 {
+		return invalid("Invalid digest_hour", "must be an hour from 0 to 23")
+	}
+
+	if cfg.WatchInterval < 10 || cfg.WatchInterval > 60 {
+		return invalid("Invalid watch_interval", "must be between 10 and 60 seconds")
+	}
+	if cfg.WatchJitter < 0 || cfg.WatchJitter > cfg.WatchInterval/2 {
+		return invalid("Invalid watch_jitter", "must be between 0 seconds and half the watch interval")
+	}
+	if cfg.MinViewers < 0 || cfg.MaxViewers < 0 || (cfg.MaxViewers > 0 && cfg.MinViewers > cfg.MaxViewers) {
+		return invalid("Invalid viewer bounds", "min_viewers and max_viewers must be 0 or more, with min_viewers not above max_viewers")
+	}
+	if cfg.DetailsCacheTTL < 0 {
+		return invalid("Invalid details_cache_ttl", "must be 0 or more minutes")
+	}
+	if cfg.ClaimInterval < 0 {
+		return invalid("Invalid claim_interval", "must be 0 or more minutes")
+	}
+	if !drops.ValidPriorityMode(cfg.PriorityMode) {
+		return invalid("Invalid priority mode", "must be PRIORITY_LIST, ENDING_SOONEST, LOW_AVAILABILITY, or FEWEST_MINUTES_REMAINING")
+	}
+	if !drops.ValidCampaignAlerts(cfg.CampaignAlerts) {
+		return invalid("Invalid campaign alerts", "must be priority, all, or off")
+	}
+	switch cfg.WatchMethod {
+	case twitch.WatchMethodHLS, twitch.WatchMethodSpade, twitch.WatchMethodBoth:
+	default:
+		return invalid("Invalid watch method", "must be hls, spade, or both")
+	}
+	if !twitch.ValidClientProfile(cfg.ClientProfile) {
+		return invalid("Invalid client profile", "must be android_app, smartbox, web_player, or mobile_web")
+	}
+	if !twitch.ValidStreamQuality(cfg.StreamQuality) {
+		return invalid("Invalid stream quality", "must be lowest, audio_only, source, or a rendition such as 480p")
+	}
+	if _, err := twitch.ParseProxyURL(cfg.Proxy); err != nil {
+		return invalid("Invalid proxy", err.Error())
+	}
+	for accountID, proxy := range cfg.AccountProxies {
+		if _, err := twitch.ParseProxyURL(proxy); err != nil {
+			return invalid("Invalid proxy for account "+accountID, err.Error())
+		}
+	}
+	for _, language := range cfg.StreamLanguages {
+		if !twitch.ValidStreamLanguage(language) {
+			return invalid("Invalid stream language", "must be a language code such as en, de or asl, or other")
+		}
+	}
+	if cfg.DirectorySort != twitch.DirectorySortRelevance && cfg.DirectorySort != twitch.DirectorySortViewerCount {
+		return invalid("Invalid directory sort", "must be RELEVANCE or VIEWER_COUNT")
+	}
+	if _, err := drops.ParseSchedule(cfg.Schedule); err != nil {
+		return invalid("Invalid schedule", err.Error())
+	}
+	if cfg.StallTimeout != 0 && (cfg.StallTimeout < 3 || cfg.StallTimeout > 120) {
+		return invalid("Invalid stall_timeout", "must be 0 to disable, or between 3 and 120 minutes")
+	}
+
+	if _, _, err := logging.ParseLevels(cfg.LogLevel, cfg.LogLevels); err != nil {
+		return invalid("Invalid log_levels", err.Error())
+	}
+	if cfg.LogMaxSizeMB < 0 || cfg.LogMaxAgeDays < 0 || cfg.LogMaxBackups < 0 {
+		return invalid("Invalid log rotation", "log_max_size_mb, log_max_age_days and log_max_backups must be 0 or more")
+	}
+	if cfg.WSMaxClients < 0 {
+		return invalid("Invalid ws_max_clients", "must be 0 for no limit, or the number of connections allowed")
+	}
+	if cfg.LivenessTimeout < 120 {
+		return invalid("Invalid liveness_timeout", "must be at least 120 seconds")
+	}
+	return nil
+}
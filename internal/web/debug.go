@@ -0,0 +1,81 @@
+package web
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DebugRuntime is what /api/debug/runtime reports, for spotting goroutine and connection leaks
+type DebugRuntime struct {
+	Goroutines    int        `json:"goroutines"`
+	GOMAXPROCS    int        `json:"gomaxprocs"`
+	GoVersion     string     `json:"go_version"`
+	WSConnections int64      `json:"ws_connections"`
+	GQLInFlight   int64      `json:"gql_in_flight"` // GraphQL requests waiting on Twitch, retries included
+	HeapAlloc     uint64     `json:"heap_alloc_bytes"`
+	HeapInuse     uint64     `json:"heap_inuse_bytes"`
+	HeapObjects   uint64     `json:"heap_objects"`
+	Sys           uint64     `json:"sys_bytes"` // memory obtained from the OS
+	NumGC         uint32     `json:"num_gc"`
+	LastGC        *time.Time `json:"last_gc,omitempty"`
+	LastGCPause   float64    `json:"last_gc_pause_ms"`
+	GCPauseTotal  float64    `json:"gc_pause_total_ms"`
+	GCCPUFraction float64    `json:"gc_cpu_fraction"`
+}
+
+// getDebugRuntime reports goroutines, memory, GC, and the open WebSocket and GraphQL requests
+func (s *Server) getDebugRuntime(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := DebugRuntime{
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		GoVersion:     runtime.Version(),
+		WSConnections: s.wsOpen.Load(),
+		GQLInFlight:   twitch.GQLInFlight(),
+		HeapAlloc:     mem.HeapAlloc,
+		HeapInuse:     mem.HeapInuse,
+		HeapObjects:   mem.HeapObjects,
+		Sys:           mem.Sys,
+		NumGC:         mem.NumGC,
+		GCPauseTotal:  float64(mem.PauseTotalNs) / 1e6,
+		GCCPUFraction: mem.GCCPUFraction,
+	}
+	if mem.NumGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC))
+		stats.LastGC = &lastGC
+		stats.LastGCPause = float64(mem.PauseNs[(mem.NumGC+255)%256]) / 1e6
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// servePprof serves net/http/pprof's index, named profiles, and CPU profile and trace
+func (s *Server) servePprof(c *gin.Context) {
+	if !s.config().Pprof {
+		respondError(c, apierror.ErrNotFound.WithMessage("Profiling is off").WithDetails("turn on the pprof setting"))
+		return
+	}
+
+	switch strings.TrimPrefix(c.Param("profile"), "/") {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		// The index, and heap, goroutine, allocs and the other named profiles
+		pprof.Index(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"errors"
+
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// errorResponse is the body of every failed API request
+type errorResponse struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// respondError answers with the status of err and stops the handler chain
+func respondError(c *gin.Context, err *apierror.Error) {
+	c.AbortWithStatusJSON(err.Status, errorResponse{
+		Error:     err.Message,
+		Code:      err.Code,
+		Details:   err.Details,
+		RequestID: c.GetString(requestIDContextKey),
+	})
+}
+
+// requestLog returns a logger tagging lines with the request's ID
+func requestLog(c *gin.Context) *logrus.Entry {
+	return logrus.WithContext(c.Request.Context())
+}
+
+// classifyError returns the API error for Twitch and miner failures, and fallback for anything else
+func classifyError(err error, fallback *apierror.Error) *apierror.Error {
+	var apiErr *apierror.Error
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, twitch.ErrRateLimited):
+		return apierror.ErrTwitchRateLimited.Wrap(err)
+	case errors.Is(err, twitch.ErrCircuitOpen):
+		return apierror.ErrTwitchUnavailable.Wrap(err)
+	case errors.Is(err, drops.ErrAlreadyRunning):
+		return apierror.ErrMinerAlreadyRunning.Wrap(err)
+	case errors.Is(err, drops.ErrNotRunning):
+		return apierror.ErrMinerNotRunning.Wrap(err)
+	case errors.Is(err, drops.ErrAlreadyPaused):
+		return apierror.ErrMinerAlreadyPaused.Wrap(err)
+	case errors.Is(err, drops.ErrNotPaused):
+		return apierror.ErrMinerNotPaused.Wrap(err)
+	case errors.Is(err, drops.ErrCampaignNotActive):
+		return apierror.ErrNotFound.WithMessage("Campaign not found or not active").Wrap(err)
+	case errors.Is(err, drops.ErrCampaignNotLinked):
+		return apierror.ErrConflict.WithMessage("Account is not linked for this campaign").Wrap(err)
+	case errors.Is(err, drops.ErrChannelNotAllowed):
+		return apierror.ErrInvalidRequest.WithMessage("Channel doesn't grant this campaign's drops").Wrap(err)
+	case errors.Is(err, drops.ErrNotForced):
+		return apierror.ErrConflict.WithMessage("No campaign is forced").Wrap(err)
+	}
+	return fallback.Wrap(err)
+}
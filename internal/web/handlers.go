@@ -1,15 +1,32 @@
 package web
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/audit"
+	"twitchdropsfarmer/internal/backup"
+	"twitchdropsfarmer/internal/bundle"
 	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/digest"
 	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/logbuffer"
+	"twitchdropsfarmer/internal/logging"
+	"twitchdropsfarmer/internal/notify"
 	"twitchdropsfarmer/internal/twitch"
 	"twitchdropsfarmer/internal/util"
+	"twitchdropsfarmer/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -19,21 +36,15 @@ import (
 func (s *Server) getAuthURL(c *gin.Context) {
 	deviceResp, err := s.twitchClient.StartDeviceFlow(c.Request.Context())
 	if err != nil {
-		logrus.Errorf("Failed to start device flow: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start device flow"})
+		requestLog(c).Errorf("Failed to start device flow: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to start device flow"))
 		return
 	}
 
 	// Store device code in memory (in production, use Redis or database)
-	s.storeDeviceCode(deviceResp.DeviceCode, deviceResp)
+	s.storeDeviceCode(deviceResp, false)
 
-	c.JSON(http.StatusOK, gin.H{
-		"device_code":      deviceResp.DeviceCode,
-		"user_code":        deviceResp.UserCode,
-		"verification_uri": deviceResp.VerificationURI,
-		"expires_in":       deviceResp.ExpiresIn,
-		"interval":         deviceResp.Interval,
-	})
+	c.JSON(http.StatusOK, deviceCodeJSON(deviceResp))
 }
 
 func (s *Server) handleAuthCallback(c *gin.Context) {
@@ -42,16 +53,16 @@ func (s *Server) handleAuthCallback(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logrus.Errorf("Auth callback binding error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		requestLog(c).Errorf("Auth callback binding error: %v", err)
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
 		return
 	}
 
-	logrus.Infof("Received device code: %s", req.DeviceCode)
+	requestLog(c).Infof("Received device code: %s", req.DeviceCode)
 
-	deviceResp := s.getDeviceCode(req.DeviceCode)
-	if deviceResp == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device code"})
+	login, ok := s.getDeviceCode(req.DeviceCode)
+	if !ok || login.account {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid device code"))
 		return
 	}
 
@@ -60,7 +71,7 @@ func (s *Server) handleAuthCallback(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
 		defer cancel()
 
-		if err := s.twitchClient.PollForToken(ctx, req.DeviceCode, deviceResp.Interval); err != nil {
+		if err := s.twitchClient.PollForToken(ctx, req.DeviceCode, login.response.Interval); err != nil {
 			logrus.Errorf("Failed to poll for token: %v", err)
 		}
 	}()
@@ -73,8 +84,8 @@ func (s *Server) handleAuthCallback(c *gin.Context) {
 
 func (s *Server) handleLogout(c *gin.Context) {
 	if err := s.twitchClient.Logout(c.Request.Context()); err != nil {
-		logrus.Errorf("Failed to logout: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		requestLog(c).Errorf("Failed to logout: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to logout"))
 		return
 	}
 
@@ -84,38 +95,102 @@ func (s *Server) handleLogout(c *gin.Context) {
 func (s *Server) getAuthStatus(c *gin.Context) {
 	isLoggedIn := s.twitchClient.IsLoggedIn()
 	var user interface{} = nil
+	scopes := []string{}
+	missingScopes := []string{}
 
 	if isLoggedIn {
 		user = s.twitchClient.GetUser()
+		scopes = s.twitchClient.GetScopes()
+		missingScopes = s.twitchClient.MissingScopes()
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"is_logged_in": isLoggedIn,
-		"user":         user,
+		"is_logged_in":   isLoggedIn,
+		"user":           user,
+		"scopes":         scopes,
+		"missing_scopes": missingScopes, // configured but not granted, log in again to get them
 	})
 }
 
 // User handlers
 func (s *Server) getUserProfile(c *gin.Context) {
-	if !s.twitchClient.IsLoggedIn() {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
 		return
 	}
 
-	user := s.twitchClient.GetUser()
+	user := s.clientFor(c).GetUser()
 	c.JSON(http.StatusOK, user)
 }
 
 func (s *Server) getUserInventory(c *gin.Context) {
-	if !s.twitchClient.IsLoggedIn() {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
+		return
+	}
+
+	inventory, err := s.clientFor(c).GetInventory(c.Request.Context())
+	if err != nil {
+		requestLog(c).Errorf("Failed to get inventory: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to get inventory")))
+		return
+	}
+
+	c.JSON(http.StatusOK, inventory)
+}
+
+// exportInventory downloads every claimed reward as ?format=json (default) or csv
+func (s *Server) exportInventory(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid format").WithDetails("must be json or csv"))
+		return
+	}
+
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
+		return
+	}
+
+	inventory, err := s.clientFor(c).RefreshInventory(c.Request.Context())
+	if err != nil {
+		requestLog(c).Errorf("Failed to get inventory: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to get inventory")))
+		return
+	}
+	rewards := inventory.ClaimedRewards()
+
+	filename := fmt.Sprintf("twitch-drops-%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == "json" {
+		c.JSON(http.StatusOK, rewards)
 		return
 	}
 
-	inventory, err := s.twitchClient.GetInventory(c.Request.Context())
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"game", "reward", "claimed_at", "count", "campaign", "drop", "image_url", "benefit_id"})
+	for _, reward := range rewards {
+		claimedAt := ""
+		if reward.ClaimedAt != nil {
+			claimedAt = reward.ClaimedAt.Format(time.RFC3339)
+		}
+		w.Write([]string{reward.GameName, reward.Name, claimedAt, strconv.Itoa(reward.Count), reward.CampaignName, reward.DropName, reward.ImageURL, reward.BenefitID})
+	}
+	w.Flush()
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+func (s *Server) refreshUserInventory(c *gin.Context) {
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
+		return
+	}
+
+	inventory, err := s.clientFor(c).RefreshInventory(c.Request.Context())
 	if err != nil {
-		logrus.Errorf("Failed to get inventory: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inventory"})
+		requestLog(c).Errorf("Failed to refresh inventory: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to refresh inventory")))
 		return
 	}
 
@@ -124,64 +199,86 @@ func (s *Server) getUserInventory(c *gin.Context) {
 
 // Campaign handlers
 func (s *Server) getCampaigns(c *gin.Context) {
-	if !s.twitchClient.IsLoggedIn() {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
 		return
 	}
 
-	campaigns, err := s.twitchClient.GetDropCampaigns(c.Request.Context())
+	campaigns, err := s.clientFor(c).GetDropCampaigns(c.Request.Context())
 	if err != nil {
-		logrus.Errorf("Failed to get campaigns: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get campaigns"})
+		requestLog(c).Errorf("Failed to get campaigns: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to get campaigns")))
 		return
 	}
 
 	c.JSON(http.StatusOK, campaigns)
 }
 
+// getUpcomingCampaigns lists the campaigns that haven't started yet, flagging the ones the miner will switch to
+func (s *Server) getUpcomingCampaigns(c *gin.Context) {
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
+		return
+	}
+
+	campaigns, err := s.clientFor(c).GetDropCampaigns(c.Request.Context())
+	if err != nil {
+		requestLog(c).Errorf("Failed to get campaigns: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to get campaigns")))
+		return
+	}
+
+	c.JSON(http.StatusOK, s.minerFor(c).UpcomingCampaigns(campaigns))
+}
+
 func (s *Server) getCampaign(c *gin.Context) {
-	if !s.twitchClient.IsLoggedIn() {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
 		return
 	}
 
 	campaignID := c.Param("id")
 	if campaignID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign ID is required"})
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Campaign ID is required"))
 		return
 	}
 
-	campaigns, err := s.twitchClient.GetDropCampaigns(c.Request.Context())
+	campaigns, err := s.clientFor(c).GetDropCampaigns(c.Request.Context())
 	if err != nil {
-		logrus.Errorf("Failed to get campaigns: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get campaigns"})
+		requestLog(c).Errorf("Failed to get campaigns: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to get campaigns")))
 		return
 	}
 
 	for _, campaign := range campaigns {
 		if campaign.ID == campaignID {
+			// Details include the drops, so the campaign type is known
+			if details, err := s.clientFor(c).GetCampaignDetails(c.Request.Context(), campaignID); err == nil {
+				c.JSON(http.StatusOK, details)
+				return
+			}
 			c.JSON(http.StatusOK, campaign)
 			return
 		}
 	}
 
-	c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+	respondError(c, apierror.ErrNotFound.WithMessage("Campaign not found"))
 }
 
 func (s *Server) getCampaignDrops(c *gin.Context) {
-	if !s.twitchClient.IsLoggedIn() {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
 		return
 	}
 
 	campaignID := c.Param("id")
 	if campaignID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign ID is required"})
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Campaign ID is required"))
 		return
 	}
 
 	// Get drops from current miner status
-	status := s.miner.GetStatus()
+	status := s.minerFor(c).GetStatus()
 	var drops []drops.ActiveDrop
 
 	// Find drops for the requested campaign
@@ -194,14 +291,232 @@ func (s *Server) getCampaignDrops(c *gin.Context) {
 	c.JSON(http.StatusOK, drops)
 }
 
+func (s *Server) pinCampaign(c *gin.Context) {
+	campaignID := c.Param("id")
+	if campaignID == "" {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Campaign ID is required"))
+		return
+	}
+
+	req := struct {
+		Pinned   bool `json:"pinned"`
+		Priority int  `json:"priority"` // orders pinned campaigns, higher is mined first
+	}{Pinned: true}
+	// An empty body pins with priority 0
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	var err error
+	if req.Pinned {
+		err = s.minerFor(c).PinCampaign(campaignID, req.Priority)
+	} else {
+		err = s.minerFor(c).UnpinCampaign(campaignID)
+	}
+	if err != nil {
+		requestLog(c).Errorf("Failed to update pin for campaign %s: %v", campaignID, err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to update campaign pin"))
+		return
+	}
+
+	if req.Pinned {
+		s.recordAudit(c, audit.ActionCampaignPin, fmt.Sprintf("%s (priority %d)", campaignID, req.Priority))
+	} else {
+		s.recordAudit(c, audit.ActionCampaignUnpin, campaignID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"campaign_id": campaignID,
+		"pinned":      req.Pinned,
+		"pins":        s.minerFor(c).GetCampaignOverrides().Pins,
+	})
+}
+
+// setCampaignPriority reorders the pinned campaigns, or pins one campaign above the others so it is mined next
+func (s *Server) setCampaignPriority(c *gin.Context) {
+	var req struct {
+		CampaignIDs *[]string `json:"campaign_ids"` // every pin, mined first to last
+		CampaignID  string    `json:"campaign_id"`  // pinned above the existing pins
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+	if (req.CampaignIDs == nil) == (req.CampaignID == "") {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails("send either campaign_ids or campaign_id"))
+		return
+	}
+
+	var err error
+	var details string
+	if req.CampaignIDs != nil {
+		err = s.minerFor(c).SetPinOrder(*req.CampaignIDs)
+		details = strings.Join(*req.CampaignIDs, ", ")
+	} else {
+		err = s.minerFor(c).PinCampaignNext(req.CampaignID)
+		details = req.CampaignID + " (next)"
+	}
+	if err != nil {
+		requestLog(c).Errorf("Failed to update campaign priority: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to update campaign priority"))
+		return
+	}
+
+	s.recordAudit(c, audit.ActionCampaignPriority, details)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"pins":    s.minerFor(c).GetCampaignOverrides().Pins,
+	})
+}
+
+func (s *Server) ignoreCampaign(c *gin.Context) {
+	campaignID := c.Param("id")
+	if campaignID == "" {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Campaign ID is required"))
+		return
+	}
+
+	req := struct {
+		Ignored bool `json:"ignored"`
+	}{Ignored: true}
+	// An empty body ignores the campaign
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	var err error
+	if req.Ignored {
+		err = s.minerFor(c).IgnoreCampaign(campaignID)
+	} else {
+		err = s.minerFor(c).UnignoreCampaign(campaignID)
+	}
+	if err != nil {
+		requestLog(c).Errorf("Failed to update ignore for campaign %s: %v", campaignID, err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to update campaign ignore"))
+		return
+	}
+
+	if req.Ignored {
+		s.recordAudit(c, audit.ActionCampaignIgnore, campaignID)
+	} else {
+		s.recordAudit(c, audit.ActionCampaignUnignore, campaignID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"campaign_id": campaignID,
+		"ignored":     req.Ignored,
+	})
+}
+
+// Drop handlers
+func (s *Server) claimDrop(c *gin.Context) {
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
+		return
+	}
+
+	instanceID := c.Param("instanceID")
+	if instanceID == "" {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Drop instance ID is required"))
+		return
+	}
+
+	// Only claim instances that are actually in the inventory and not yet claimed
+	inventory, err := s.clientFor(c).RefreshInventory(c.Request.Context())
+	if err != nil {
+		requestLog(c).Errorf("Failed to get inventory: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to get inventory")))
+		return
+	}
+
+	drop, campaign := findInventoryDrop(inventory, instanceID)
+	if drop == nil {
+		respondError(c, apierror.ErrNotFound.WithMessage("Drop instance not found in inventory"))
+		return
+	}
+
+	if drop.Self.IsClaimed {
+		respondError(c, apierror.ErrDropAlreadyClaimed)
+		return
+	}
+
+	if err := s.clientFor(c).ClaimDrop(c.Request.Context(), instanceID); err != nil {
+		requestLog(c).Errorf("Failed to claim drop %s: %v", drop.Name, err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to claim drop")))
+		return
+	}
+
+	gameName := ""
+	if campaign.Game != nil && campaign.Game.DisplayName != nil {
+		gameName = *campaign.Game.DisplayName
+	}
+
+	requestLog(c).Infof("Manually claimed drop: %s", drop.Name)
+	s.minerFor(c).RecordManualClaim(campaign, drop)
+	s.recordAudit(c, audit.ActionDropClaim, fmt.Sprintf("%s (%s)", drop.Name, gameName))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"drop_id":     drop.ID,
+		"drop_name":   drop.Name,
+		"campaign_id": campaign.ID,
+	})
+}
+
+// claimPendingDrops claims every completed but unclaimed drop in the inventory
+func (s *Server) claimPendingDrops(c *gin.Context) {
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
+		return
+	}
+
+	summary, err := s.minerFor(c).ClaimPendingDrops(c.Request.Context())
+	if err != nil {
+		requestLog(c).Errorf("Failed to claim pending drops: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to claim pending drops")))
+		return
+	}
+
+	if summary.Found > 0 {
+		s.recordAudit(c, audit.ActionDropClaimPending, fmt.Sprintf("%d of %d pending drops claimed", summary.Claimed, summary.Found))
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// findInventoryDrop looks up the in-progress drop with the given instance ID
+func findInventoryDrop(inventory *twitch.InventoryGQL, instanceID string) (*twitch.TimeBasedDropGQL, *twitch.DropCampaignGQL) {
+	for i := range inventory.DropCampaignsInProgress {
+		campaign := &inventory.DropCampaignsInProgress[i]
+		if campaign.TimeBasedDrops == nil {
+			continue
+		}
+
+		for j := range *campaign.TimeBasedDrops {
+			drop := &(*campaign.TimeBasedDrops)[j]
+			if drop.Self == nil || drop.Self.DropInstanceID == nil {
+				continue
+			}
+			if id, ok := (*drop.Self.DropInstanceID).(string); ok && id == instanceID {
+				return drop, campaign
+			}
+		}
+	}
+
+	return nil, nil
+}
+
 // Miner handlers
 func (s *Server) getMinerStatus(c *gin.Context) {
-	status := s.miner.GetStatus()
+	status := s.minerFor(c).GetStatus()
 	c.JSON(http.StatusOK, status)
 }
 
 func (s *Server) getCurrentDrop(c *gin.Context) {
-	status := s.miner.GetStatus()
+	status := s.minerFor(c).GetStatus()
 
 	if !status.IsRunning {
 		c.JSON(http.StatusOK, gin.H{
@@ -237,6 +552,7 @@ func (s *Server) getCurrentDrop(c *gin.Context) {
 							Progress:        float64(drop.Self.CurrentMinutesWatched) / float64(drop.RequiredMinutesWatched),
 							IsClaimed:       drop.Self.IsClaimed,
 						}
+						activeDrop.SetClaimETA(time.Now(), status.CurrentStream != nil)
 						currentDrop = &activeDrop
 						break
 					}
@@ -258,8 +574,8 @@ func (s *Server) getCurrentDrop(c *gin.Context) {
 }
 
 func (s *Server) getDropProgress(c *gin.Context) {
-	logrus.Infof("=== getDropProgress HANDLER CALLED ===")
-	status := s.miner.GetStatus()
+	requestLog(c).Infof("=== getDropProgress HANDLER CALLED ===")
+	status := s.minerFor(c).GetStatus()
 
 	if !status.IsRunning {
 		c.JSON(http.StatusOK, gin.H{
@@ -277,22 +593,22 @@ func (s *Server) getDropProgress(c *gin.Context) {
 	// Use utility functions for consistent drop progress calculation
 	var activeDrops []drops.ActiveDrop
 
-	logrus.Infof("=== Progress Handler Debug ===")
-	logrus.Infof("CurrentCampaign is nil: %v", status.CurrentCampaign == nil)
-	logrus.Infof("CurrentStream is nil: %v", status.CurrentStream == nil)
+	requestLog(c).Infof("=== Progress Handler Debug ===")
+	requestLog(c).Infof("CurrentCampaign is nil: %v", status.CurrentCampaign == nil)
+	requestLog(c).Infof("CurrentStream is nil: %v", status.CurrentStream == nil)
 
 	if status.CurrentCampaign != nil && status.CurrentStream != nil {
-		logrus.Infof("=== Using utility functions for Real Progress ===")
-		logrus.Infof("Channel ID (UserID): %s, Stream ID: %s", status.CurrentStream.UserID, status.CurrentStream.ID)
+		requestLog(c).Infof("=== Using utility functions for Real Progress ===")
+		requestLog(c).Infof("Channel ID (UserID): %s, Stream ID: %s", status.CurrentStream.UserID, status.CurrentStream.ID)
 
 		// Generate active drops with real-time progress using utility function
 		var err error
-		activeDrops, err = util.GenerateActiveDrops(c.Request.Context(), s.twitchClient, status.CurrentCampaign, status.CurrentStream)
+		activeDrops, err = util.GenerateActiveDrops(c.Request.Context(), s.clientFor(c), status.CurrentCampaign, status.CurrentStream)
 		if err != nil {
-			logrus.Errorf("Failed to generate active drops: %v", err)
+			requestLog(c).Errorf("Failed to generate active drops: %v", err)
 			// Keep empty activeDrops array as fallback
 		} else {
-			logrus.Infof("Successfully generated active drops using utility function!")
+			requestLog(c).Infof("Successfully generated active drops using utility function!")
 		}
 	}
 
@@ -306,61 +622,211 @@ func (s *Server) getDropProgress(c *gin.Context) {
 }
 
 func (s *Server) startMiner(c *gin.Context) {
-	if !s.twitchClient.IsLoggedIn() {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
 		return
 	}
 
-	if s.miner.IsRunning() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Miner is already running"})
+	if s.minerFor(c).IsRunning() {
+		respondError(c, apierror.ErrMinerAlreadyRunning)
 		return
 	}
 
-	// Create new context for miner
-	s.minerCtx, s.minerCancel = context.WithCancel(context.Background())
-
-	// Start miner in background with managed context
-	go func() {
-		if err := s.miner.Start(s.minerCtx); err != nil {
-			logrus.Errorf("Miner start error: %v", err)
+	if account := accountFromContext(c); account != nil {
+		if err := account.StartMiner(); err != nil {
+			respondError(c, classifyError(err, apierror.ErrInvalidRequest.WithMessage(err.Error())))
+			return
 		}
-	}()
+		s.recordAudit(c, audit.ActionMinerStart, account.ID)
+		c.JSON(http.StatusOK, gin.H{"success": true})
+		return
+	}
 
+	s.runMiner()
+	s.recordAudit(c, audit.ActionMinerStart, "")
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// runMiner starts the primary miner in the background with a context of its own
+func (s *Server) runMiner() {
+	s.minerCtx, s.minerCancel = context.WithCancel(context.Background())
+	go func(ctx context.Context) {
+		if err := s.miner.Start(ctx); err != nil {
+			logrus.Errorf("Miner start error: %v", err)
+		}
+	}(s.minerCtx)
+}
+
 func (s *Server) stopMiner(c *gin.Context) {
-	if !s.miner.IsRunning() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Miner is not running"})
+	if !s.minerFor(c).IsRunning() {
+		respondError(c, apierror.ErrMinerNotRunning)
 		return
 	}
 
-	// Cancel the miner context first
-	if s.minerCancel != nil {
+	// Cancel the miner context first; additional accounts are only stopped through Stop
+	account := accountFromContext(c)
+	if account == nil && s.minerCancel != nil {
 		s.minerCancel()
 	}
 
-	if err := s.miner.Stop(); err != nil {
-		logrus.Errorf("Failed to stop miner: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop miner"})
+	if err := s.minerFor(c).Stop(); err != nil {
+		requestLog(c).Errorf("Failed to stop miner: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to stop miner"))
+		return
+	}
+
+	details := ""
+	if account != nil {
+		details = account.ID
+	}
+	s.recordAudit(c, audit.ActionMinerStop, details)
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// pauseMiner stops watching without giving up the current campaign, stream and session
+func (s *Server) pauseMiner(c *gin.Context) {
+	if err := s.minerFor(c).Pause(); err != nil {
+		respondError(c, classifyError(err, apierror.ErrInvalidRequest.WithMessage("Failed to pause miner")))
+		return
+	}
+
+	details := ""
+	if account := accountFromContext(c); account != nil {
+		details = account.ID
+	}
+	s.recordAudit(c, audit.ActionMinerPause, details)
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// switchCampaign switches to a campaign right away and keeps mining it, overriding the scores until it is done
+func (s *Server) switchCampaign(c *gin.Context) {
+	var req struct {
+		CampaignID   string `json:"campaign_id" binding:"required"`
+		ChannelLogin string `json:"channel_login"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("campaign_id is required").WithDetails(err.Error()))
+		return
+	}
+
+	forced, err := s.minerFor(c).ForceCampaign(c.Request.Context(), req.CampaignID, req.ChannelLogin)
+	if err != nil {
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to switch campaign")))
+		return
+	}
+
+	details := forced.CampaignName
+	if forced.ChannelLogin != "" {
+		details += " on " + forced.ChannelLogin
+	}
+	s.recordAudit(c, audit.ActionMinerSwitch, details)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"forced":  forced,
+		"running": s.minerFor(c).IsRunning(),
+	})
+}
+
+// clearForcedCampaign hands campaign selection back to the scores
+func (s *Server) clearForcedCampaign(c *gin.Context) {
+	if err := s.minerFor(c).ClearForcedCampaign(); err != nil {
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to clear forced campaign")))
+		return
+	}
+
+	s.recordAudit(c, audit.ActionMinerUnforce, "")
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (s *Server) resumeMiner(c *gin.Context) {
+	if err := s.minerFor(c).Resume(); err != nil {
+		respondError(c, classifyError(err, apierror.ErrInvalidRequest.WithMessage("Failed to resume miner")))
 		return
 	}
 
+	details := ""
+	if account := accountFromContext(c); account != nil {
+		details = account.ID
+	}
+	s.recordAudit(c, audit.ActionMinerResume, details)
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// Stats handlers
+func (s *Server) getRuntimeStats(c *gin.Context) {
+	c.JSON(http.StatusOK, s.minerFor(c).GetRuntimeStats())
+}
+
+// getStats returns the daily watch time, claims and completed campaigns, ?range=30d by default
+func (s *Server) getStats(c *gin.Context) {
+	days, err := parseStatsRange(c.DefaultQuery("range", "30d"))
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid range").WithDetails(err.Error()))
+		return
+	}
+
+	stats, err := s.minerFor(c).GetStats(days)
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid range").WithDetails(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// parseStatsRange parses a range of days like "30d", or "all" for the whole history
+func parseStatsRange(value string) (int, error) {
+	if value == "all" {
+		return drops.StatsHistoryDays, nil
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+	if err != nil || !strings.HasSuffix(value, "d") {
+		return 0, fmt.Errorf("must be a number of days like 30d, or all")
+	}
+	return days, nil
+}
+
+// getGameStats returns the minutes watched, drops claimed and campaigns completed of every game ever farmed
+func (s *Server) getGameStats(c *gin.Context) {
+	c.JSON(http.StatusOK, s.minerFor(c).GetGameStats())
+}
+
+func (s *Server) getChannelPoints(c *gin.Context) {
+	c.JSON(http.StatusOK, s.minerFor(c).GetChannelPoints())
+}
+
 // Settings handlers
 func (s *Server) getSettings(c *gin.Context) {
-	c.JSON(http.StatusOK, s.config)
+	// Viewers only get the secrets redacted, admins need them to edit the settings
+	cfg := s.config()
+	settings := *cfg
+	if c.GetString(roleContextKey) != config.RoleAdmin {
+		settings = *cfg.Redacted()
+	}
+
+	// Never echo API keys back, only their names and roles
+	settings.APIKeys = make([]config.APIKey, len(cfg.APIKeys))
+	for i, apiKey := range cfg.APIKeys {
+		settings.APIKeys[i] = config.APIKey{Name: apiKey.Name, Role: apiKey.Role, Accounts: apiKey.Accounts}
+	}
+
+	c.JSON(http.StatusOK, settings)
 }
 
 func (s *Server) updateSettings(c *gin.Context) {
 	var updates map[string]interface{}
 	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		respondError(c, apierror.ErrInvalidRequest)
 		return
 	}
 
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	// Changes go to a copy, put in use once every setting checks out and it is saved
+	before := s.config()
+	previousGames := before.PriorityGames
+	cfg := before.Clone()
+
 	// Update configuration
 	if priorityGames, ok := updates["priority_games"].([]interface{}); ok {
 		var games []config.GameConfig
@@ -371,6 +837,12 @@ func (s *Server) updateSettings(c *gin.Context) {
 					Slug: getString(gameMap, "slug"),
 					ID:   getString(gameMap, "id"),
 				}
+				if aliases, ok := getStringSlice(gameMap, "aliases"); ok {
+					gameConfig.Aliases = aliases
+				}
+				if channels, ok := getStringSlice(gameMap, "preferred_channels"); ok {
+					gameConfig.PreferredChannels = normalizeChannels(channels)
+				}
 				games = append(games, gameConfig)
 			} else if gameStr, ok := game.(string); ok {
 				// Handle legacy string format - convert to GameConfig
@@ -382,75 +854,537 @@ func (s *Server) updateSettings(c *gin.Context) {
 				games = append(games, gameConfig)
 			}
 		}
-		s.config.PriorityGames = games
+		cfg.PriorityGames = games
 	}
 
 	if claimDrops, ok := updates["claim_drops"].(bool); ok {
-		s.config.ClaimDrops = claimDrops
+		cfg.ClaimDrops = claimDrops
 	}
 
 	if webhookURL, ok := updates["webhook_url"].(string); ok {
-		s.config.WebhookURL = webhookURL
+		if webhookURL != "" {
+			if u, err := url.Parse(webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid webhook_url").WithDetails("must be an http:// or https:// URL"))
+				return
+			}
+		}
+		cfg.WebhookURL = webhookURL
 	}
 
-	if checkInterval, ok := updates["check_interval"].(float64); ok {
-		s.config.CheckInterval = int(checkInterval)
+	if webhookEvents, ok := getStringSlice(updates, "webhook_events"); ok {
+		for _, eventType := range webhookEvents {
+			if !webhook.ValidEventType(eventType) {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid webhook_events").WithDetails("unknown event type "+eventType))
+				return
+			}
+		}
+		cfg.WebhookEvents = webhookEvents
 	}
 
-	if switchThreshold, ok := updates["switch_threshold"].(float64); ok {
-		s.config.SwitchThreshold = int(switchThreshold)
+	if webhookTemplates, ok := updates["webhook_templates"].(map[string]interface{}); ok {
+		templates := make(map[string]string, len(webhookTemplates))
+		for eventType, value := range webhookTemplates {
+			source, _ := value.(string)
+			templates[eventType] = source
+		}
+		if err := webhook.ValidateTemplates(templates); err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid webhook_templates").WithDetails(err.Error()))
+			return
+		}
+		cfg.WebhookTemplates = templates
 	}
 
-	if minimumPoints, ok := updates["minimum_points"].(float64); ok {
-		s.config.MinimumPoints = int(minimumPoints)
+	if notificationURLs, ok := getStringSlice(updates, "notification_urls"); ok {
+		if _, err := notify.ParseURLs(notificationURLs); err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid notification URL").WithDetails(err.Error()))
+			return
+		}
+		cfg.NotificationURLs = notificationURLs
 	}
 
-	if maximumStreams, ok := updates["maximum_streams"].(float64); ok {
-		s.config.MaximumStreams = int(maximumStreams)
+	if digestEmail, ok := updates["digest_email"].(string); ok {
+		if digestEmail != "" {
+			if _, err := notify.ParseEmailURL(digestEmail); err != nil {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid digest_email").WithDetails(err.Error()))
+				return
+			}
+		}
+		cfg.DigestEmail = digestEmail
 	}
 
-	if theme, ok := updates["theme"].(string); ok {
-		s.config.Theme = theme
+	if backupSchedule, ok := updates["backup_schedule"].(string); ok {
+		if backupSchedule != "" {
+			if _, err := backup.ParseSchedule(backupSchedule); err != nil {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid backup_schedule").WithDetails(err.Error()))
+				return
+			}
+		}
+		cfg.BackupSchedule = backupSchedule
 	}
 
-	if language, ok := updates["language"].(string); ok {
-		s.config.Language = language
+	if backupRetention, ok := updates["backup_retention"].(float64); ok {
+		if backupRetention < 0 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid backup_retention").WithDetails("must be 0 to keep all backups, or the number kept"))
+			return
+		}
+		cfg.BackupRetention = int(backupRetention)
 	}
 
-	if showTray, ok := updates["show_tray"].(bool); ok {
-		s.config.ShowTray = showTray
+	if digestFrequency, ok := updates["digest_frequency"].(string); ok {
+		if !digest.ValidFrequency(digestFrequency) {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid digest_frequency").WithDetails("must be off, daily, or weekly"))
+			return
+		}
+		cfg.DigestFrequency = digestFrequency
 	}
 
-	if startMinimized, ok := updates["start_minimized"].(bool); ok {
-		s.config.StartMinimized = startMinimized
+	if digestHour, ok := updates["digest_hour"].(float64); ok {
+		if digestHour < 0 || digestHour > 23 || digestHour != float64(int(digestHour)) {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid digest_hour").WithDetails("must be an hour from 0 to 23"))
+			return
+		}
+		cfg.DigestHour = int(digestHour)
 	}
 
-	// Update miner configuration
-	minerConfig := &drops.MinerConfig{
-		CheckInterval:   time.Duration(s.config.CheckInterval) * time.Second,
-		SwitchThreshold: time.Duration(s.config.SwitchThreshold) * time.Minute,
-		MinimumPoints:   s.config.MinimumPoints,
-		MaximumStreams:  s.config.MaximumStreams,
-		PriorityGames:   s.config.PriorityGames,
-		ClaimDrops:      s.config.ClaimDrops,
-		WebhookURL:      s.config.WebhookURL,
+	if checkInterval, ok := updates["check_interval"].(float64); ok {
+		if checkInterval < 10 || checkInterval > 3600 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid check_interval").WithDetails("must be between 10 and 3600 seconds"))
+			return
+		}
+		cfg.CheckInterval = int(checkInterval)
 	}
-	s.miner.SetConfig(minerConfig)
 
-	// Save configuration
-	if err := s.config.Save(); err != nil {
-		logrus.Errorf("Failed to save configuration: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save configuration"})
+	if watchInterval, ok := updates["watch_interval"].(float64); ok {
+		// Twitch stops counting minutes when a stream isn't requested for about a minute
+		if watchInterval < 10 || watchInterval > 60 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid watch_interval").WithDetails("must be between 10 and 60 seconds"))
+			return
+		}
+		cfg.WatchInterval = int(watchInterval)
+	}
+
+	if watchJitter, ok := updates["watch_jitter"].(float64); ok {
+		if watchJitter < 0 || int(watchJitter) > cfg.WatchInterval/2 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid watch_jitter").WithDetails("must be between 0 seconds and half the watch interval"))
+			return
+		}
+		cfg.WatchJitter = int(watchJitter)
+	}
+
+	if switchThreshold, ok := updates["switch_threshold"].(float64); ok {
+		if switchThreshold < 1 || switchThreshold > 1440 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid switch_threshold").WithDetails("must be between 1 and 1440 minutes"))
+			return
+		}
+		cfg.SwitchThreshold = int(switchThreshold)
+	}
+
+	if minimumPoints, ok := updates["minimum_points"].(float64); ok {
+		if minimumPoints < 0 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid minimum_points").WithDetails("must be 0 or more"))
+			return
+		}
+		cfg.MinimumPoints = int(minimumPoints)
+	}
+
+	minViewers, minSet := updates["min_viewers"].(float64)
+	maxViewers, maxSet := updates["max_viewers"].(float64)
+	if minSet || maxSet {
+		if !minSet {
+			minViewers = float64(cfg.MinViewers)
+		}
+		if !maxSet {
+			maxViewers = float64(cfg.MaxViewers)
+		}
+		if minViewers < 0 || maxViewers < 0 || (maxViewers > 0 && minViewers > maxViewers) {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid viewer bounds").WithDetails("min_viewers and max_viewers must be 0 or more, with min_viewers not above max_viewers"))
+			return
+		}
+		cfg.MinViewers = int(minViewers)
+		cfg.MaxViewers = int(maxViewers)
+	}
+
+	if maximumStreams, ok := updates["maximum_streams"].(float64); ok {
+		if maximumStreams < 1 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid maximum_streams").WithDetails("must be at least 1"))
+			return
+		}
+		cfg.MaximumStreams = int(maximumStreams)
+	}
+
+	if switchBonus, ok := updates["switch_bonus"].(float64); ok {
+		cfg.SwitchBonus = int(switchBonus)
+	}
+
+	if detailsCacheTTL, ok := updates["details_cache_ttl"].(float64); ok {
+		if detailsCacheTTL < 0 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid details_cache_ttl").WithDetails("must be 0 or more minutes"))
+			return
+		}
+		cfg.DetailsCacheTTL = int(detailsCacheTTL)
+	}
+
+	if claimInterval, ok := updates["claim_interval"].(float64); ok {
+		if claimInterval < 0 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid claim_interval").WithDetails("must be 0 or more minutes"))
+			return
+		}
+		cfg.ClaimInterval = int(claimInterval)
+	}
+
+	if bandwidthCap, ok := updates["bandwidth_cap_mb"].(float64); ok {
+		if bandwidthCap < 0 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid bandwidth_cap_mb").WithDetails("must be 0 for no cap, or the daily cap in MB"))
+			return
+		}
+		cfg.BandwidthCapMB = int(bandwidthCap)
+	}
+
+	if claimPoints, ok := updates["claim_points"].(bool); ok {
+		cfg.ClaimPoints = claimPoints
+	}
+
+	if pointsChannels, ok := getStringSlice(updates, "points_channels"); ok {
+		cfg.PointsChannels = pointsChannels
+	}
+
+	if pointsFallback, ok := updates["points_fallback"].(bool); ok {
+		cfg.PointsFallback = pointsFallback
+	}
+
+	if autoFollow, ok := updates["auto_follow"].(bool); ok {
+		cfg.AutoFollow = autoFollow
+	}
+
+	if autoUnfollow, ok := updates["auto_unfollow"].(bool); ok {
+		cfg.AutoUnfollow = autoUnfollow
+	}
+
+	if pubSub, ok := updates["pubsub"].(bool); ok {
+		cfg.PubSub = pubSub
+	}
+
+	if excludeGames, ok := getStringSlice(updates, "exclude_games"); ok {
+		cfg.ExcludeGames = excludeGames
+	}
+
+	if watchUnlisted, ok := updates["watch_unlisted"].(bool); ok {
+		cfg.WatchUnlisted = watchUnlisted
+	}
+
+	if priorityMode, ok := updates["priority_mode"].(string); ok {
+		if !drops.ValidPriorityMode(priorityMode) {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid priority mode").WithDetails("must be PRIORITY_LIST, ENDING_SOONEST, LOW_AVAILABILITY, or FEWEST_MINUTES_REMAINING"))
+			return
+		}
+		cfg.PriorityMode = priorityMode
+	}
+
+	if campaignAlerts, ok := updates["campaign_alerts"].(string); ok {
+		if !drops.ValidCampaignAlerts(campaignAlerts) {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid campaign alerts").WithDetails("must be priority, all, or off"))
+			return
+		}
+		cfg.CampaignAlerts = campaignAlerts
+	}
+
+	if watchMethod, ok := updates["watch_method"].(string); ok {
+		switch watchMethod {
+		case twitch.WatchMethodHLS, twitch.WatchMethodSpade, twitch.WatchMethodBoth:
+		default:
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid watch method").WithDetails("must be hls, spade, or both"))
+			return
+		}
+		cfg.WatchMethod = watchMethod
+	}
+
+	if clientProfile, ok := updates["client_profile"].(string); ok {
+		// Applied at the next start, the logged in clients keep the identity their tokens were issued to
+		if !twitch.ValidClientProfile(clientProfile) {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid client profile").WithDetails("must be android_app, smartbox, web_player, or mobile_web"))
+			return
+		}
+		cfg.ClientProfile = clientProfile
+	}
+
+	if streamQuality, ok := updates["stream_quality"].(string); ok {
+		if !twitch.ValidStreamQuality(streamQuality) {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid stream quality").WithDetails("must be lowest, audio_only, source, or a rendition such as 480p"))
+			return
+		}
+		cfg.StreamQuality = streamQuality
+	}
+
+	if chatPresence, ok := updates["chat_presence"].(bool); ok {
+		cfg.ChatPresence = chatPresence
+	}
+
+	if proxy, ok := updates["proxy"].(string); ok {
+		if _, err := twitch.ParseProxyURL(proxy); err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid proxy").WithDetails(err.Error()))
+			return
+		}
+		cfg.Proxy = proxy
+	}
+
+	if accountProxies, ok := updates["account_proxies"].(map[string]interface{}); ok {
+		proxies := make(map[string]string, len(accountProxies))
+		for accountID, value := range accountProxies {
+			proxy, _ := value.(string)
+			if _, err := twitch.ParseProxyURL(proxy); err != nil {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid proxy for account "+accountID).WithDetails(err.Error()))
+				return
+			}
+			proxies[accountID] = proxy
+		}
+		cfg.AccountProxies = proxies
+	}
+
+	if authScopes, ok := getStringSlice(updates, "auth_scopes"); ok {
+		cfg.AuthScopes = authScopes
+	}
+
+	if directoryFilters, ok := getStringSlice(updates, "directory_filters"); ok {
+		cfg.DirectoryFilters = directoryFilters
+	}
+
+	if directoryTags, ok := getStringSlice(updates, "directory_tags"); ok {
+		cfg.DirectoryTags = directoryTags
+	}
+
+	if streamLanguages, ok := getStringSlice(updates, "stream_languages"); ok {
+		for i, language := range streamLanguages {
+			if !twitch.ValidStreamLanguage(language) {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid stream language").WithDetails("must be a language code such as en, de or asl, or other"))
+				return
+			}
+			streamLanguages[i] = strings.ToLower(language)
+		}
+		cfg.StreamLanguages = streamLanguages
+	}
+
+	if directorySort, ok := updates["directory_sort"].(string); ok {
+		if directorySort != twitch.DirectorySortRelevance && directorySort != twitch.DirectorySortViewerCount {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid directory sort").WithDetails("must be RELEVANCE or VIEWER_COUNT"))
+			return
+		}
+		cfg.DirectorySort = directorySort
+	}
+
+	if logBufferSize, ok := updates["log_buffer_size"].(float64); ok {
+		if logBufferSize < 0 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid log_buffer_size").WithDetails("must be 0 for the default, or the number of entries kept"))
+			return
+		}
+		cfg.LogBufferSize = int(logBufferSize)
+	}
+
+	if logToConsole, ok := updates["log_to_console"].(bool); ok {
+		cfg.LogToConsole = logToConsole
+	}
+
+	if logLevel, ok := updates["log_level"].(string); ok {
+		if _, _, err := logging.ParseLevels(logLevel, nil); err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid log_level").WithDetails("must be trace, debug, info, warn or error"))
+			return
+		}
+		cfg.LogLevel = logLevel
+	}
+
+	if logLevels, ok := updates["log_levels"].(map[string]interface{}); ok {
+		levels := make(map[string]string, len(logLevels))
+		for module, value := range logLevels {
+			level, _ := value.(string)
+			levels[module] = level
+		}
+		if _, _, err := logging.ParseLevels(cfg.LogLevel, levels); err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid log_levels").WithDetails(err.Error()))
+			return
+		}
+		cfg.LogLevels = levels
+	}
+
+	if pprof, ok := updates["pprof"].(bool); ok {
+		cfg.Pprof = pprof
+	}
+
+	if logJSON, ok := updates["log_json"].(bool); ok {
+		cfg.LogJSON = logJSON
+	}
+
+	if logFile, ok := updates["log_file"].(string); ok {
+		if logFile != "" {
+			if err := config.CheckLogFile(cfg.DataDir, logFile); err != nil {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid log_file").WithDetails(err.Error()))
+				return
+			}
+		}
+		cfg.LogFile = logFile
+	}
+
+	for key, value := range map[string]*int{
+		"log_max_size_mb":  &cfg.LogMaxSizeMB,
+		"log_max_age_days": &cfg.LogMaxAgeDays,
+		"log_max_backups":  &cfg.LogMaxBackups,
+	} {
+		if limit, ok := updates[key].(float64); ok {
+			if limit < 0 {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid "+key).WithDetails("must be 0 or more"))
+				return
+			}
+			*value = int(limit)
+		}
+	}
+
+	if theme, ok := updates["theme"].(string); ok {
+		cfg.Theme = theme
+	}
+
+	if language, ok := updates["language"].(string); ok {
+		cfg.Language = language
+	}
+
+	if showTray, ok := updates["show_tray"].(bool); ok {
+		cfg.ShowTray = showTray
+	}
+
+	if wsMaxClients, ok := updates["ws_max_clients"].(float64); ok {
+		if wsMaxClients < 0 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid ws_max_clients").WithDetails("must be 0 for no limit, or the number of connections allowed"))
+			return
+		}
+		cfg.WSMaxClients = int(wsMaxClients)
+	}
+
+	if livenessTimeout, ok := updates["liveness_timeout"].(float64); ok {
+		// The loop always wakes within a minute, a step taking a little longer isn't stuck yet
+		if livenessTimeout < 120 {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid liveness_timeout").WithDetails("must be at least 120 seconds"))
+			return
+		}
+		cfg.LivenessTimeout = int(livenessTimeout)
+	}
+
+	if stallTimeout, ok := updates["stall_timeout"].(float64); ok {
+		// Drop minutes only move every minute or so, shorter timeouts would switch away from working streams
+		if stallTimeout != 0 && (stallTimeout < 3 || stallTimeout > 120) {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid stall_timeout").WithDetails("must be 0 to disable, or between 3 and 120 minutes"))
+			return
+		}
+		cfg.StallTimeout = int(stallTimeout)
+	}
+
+	if schedule, ok := updates["schedule"].([]interface{}); ok {
+		windows := []config.ScheduleWindow{}
+		for _, item := range schedule {
+			windowMap, ok := item.(map[string]interface{})
+			if !ok {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid schedule").WithDetails("windows must be objects with start, end and days"))
+				return
+			}
+			window := config.ScheduleWindow{
+				Start: getString(windowMap, "start"),
+				End:   getString(windowMap, "end"),
+			}
+			if days, ok := getStringSlice(windowMap, "days"); ok && len(days) > 0 {
+				window.Days = days
+			}
+			windows = append(windows, window)
+		}
+		if _, err := drops.ParseSchedule(windows); err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid schedule").WithDetails(err.Error()))
+			return
+		}
+		cfg.Schedule = windows
+	}
+
+	if startMinimized, ok := updates["start_minimized"].(bool); ok {
+		cfg.StartMinimized = startMinimized
+	}
+
+	// Save configuration
+	if err := cfg.Save(); err != nil {
+		requestLog(c).Errorf("Failed to save configuration: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to save configuration"))
 		return
 	}
+	s.live.Set(cfg)
+
+	// The settings below were checked above, and only take effect now that they are saved
+	if _, ok := getStringSlice(updates, "notification_urls"); ok {
+		if err := s.notifier.SetURLs(cfg.NotificationURLs); err != nil {
+			requestLog(c).Errorf("Failed to apply notification URLs: %v", err)
+		}
+	}
+	if _, ok := updates["proxy"].(string); ok {
+		if err := s.twitchClient.SetProxy(cfg.Proxy); err != nil {
+			requestLog(c).Errorf("Failed to apply proxy: %v", err)
+		}
+	}
+	if _, ok := getStringSlice(updates, "auth_scopes"); ok {
+		s.twitchClient.SetAuthScopes(cfg.AuthScopes)
+	}
+	if _, ok := updates["log_buffer_size"].(float64); ok && s.logBuffer != nil {
+		s.logBuffer.Resize(cfg.LogBufferSize)
+	}
+	if err := logging.Configure(cfg); err != nil {
+		requestLog(c).Errorf("Failed to apply log settings: %v", err)
+	}
 
+	// Update miner configuration
+	s.miner.SetConfig(drops.NewMinerConfig(cfg))
+	s.twitchClient.SetDirectoryOptions(twitch.NewDirectoryOptions(cfg))
+	if s.digest != nil {
+		// digest_email was parsed above
+		s.digest.SetConfig(digest.NewConfig(cfg))
+	}
+	if s.webhook != nil {
+		// webhook_events and webhook_templates were checked above
+		s.webhook.SetConfig(webhook.NewConfig(cfg))
+	}
+	if s.backups != nil {
+		// backup_schedule was parsed above
+		s.backups.SetConfig(backup.NewConfig(cfg))
+	}
+	s.twitchClient.SetStreamQuality(cfg.StreamQuality)
+	s.applyAccountsConfig()
+
+	s.auditSettingsUpdate(c, updates, previousGames)
+	if changed := changedSettings(before, cfg); len(changed) > 0 {
+		s.broadcastSettingsChanged(SettingsSourceAPI, changed)
+	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
-// Game management handlers
+// auditSettingsUpdate records the changed setting keys and any priority games added or removed
+func (s *Server) auditSettingsUpdate(c *gin.Context, updates map[string]interface{}, previousGames []config.GameConfig) {
+	keys := make([]string, 0, len(updates))
+	for key := range updates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	s.recordAudit(c, audit.ActionSettingsUpdate, strings.Join(keys, ", "))
+
+	previous := make(map[string]bool)
+	for _, game := range previousGames {
+		previous[game.Name] = true
+	}
+	current := make(map[string]bool)
+	for _, game := range s.config().PriorityGames {
+		current[game.Name] = true
+		if !previous[game.Name] {
+			s.recordAudit(c, audit.ActionGameAdd, game.Name)
+		}
+	}
+	for _, game := range previousGames {
+		if !current[game.Name] {
+			s.recordAudit(c, audit.ActionGameRemove, game.Name)
+		}
+	}
+}
 func (s *Server) addGameWithSlug(c *gin.Context) {
 	if !s.twitchClient.IsLoggedIn() {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+		respondError(c, apierror.ErrNotLoggedIn)
 		return
 	}
 
@@ -459,41 +1393,36 @@ func (s *Server) addGameWithSlug(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
 		return
 	}
 
 	// Get the slug and ID from Twitch
 	slugInfo, err := s.twitchClient.GetGameSlug(c.Request.Context(), req.GameName)
 	if err != nil {
-		logrus.Errorf("Failed to get slug for game '%s': %v", req.GameName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve game slug"})
+		requestLog(c).Errorf("Failed to get slug for game '%s': %v", req.GameName, err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to resolve game slug")))
 		return
 	}
 
 	// Add the game to config with the resolved slug and ID
-	logrus.Infof("Adding game '%s' with slug '%s' and ID '%s' to config", req.GameName, slugInfo.Slug, slugInfo.ID)
-	err = s.config.AddGameToConfig(req.GameName, slugInfo.Slug, slugInfo.ID)
+	requestLog(c).Infof("Adding game '%s' with slug '%s' and ID '%s' to config", req.GameName, slugInfo.Slug, slugInfo.ID)
+	err = s.changeConfig(func(cfg *config.Config) error {
+		return cfg.AddGameToConfig(req.GameName, slugInfo.Slug, slugInfo.ID)
+	})
 	if err != nil {
-		logrus.Errorf("Failed to add game to config: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add game to config"})
+		requestLog(c).Errorf("Failed to add game to config: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to add game to config"))
 		return
 	}
 
-	logrus.Infof("Successfully added game '%s' with slug '%s' and ID '%s' to config", req.GameName, slugInfo.Slug, slugInfo.ID)
+	requestLog(c).Infof("Successfully added game '%s' with slug '%s' and ID '%s' to config", req.GameName, slugInfo.Slug, slugInfo.ID)
 
 	// Update miner configuration with the new game list
-	minerConfig := &drops.MinerConfig{
-		CheckInterval:   time.Duration(s.config.CheckInterval) * time.Second,
-		SwitchThreshold: time.Duration(s.config.SwitchThreshold) * time.Minute,
-		MinimumPoints:   s.config.MinimumPoints,
-		MaximumStreams:  s.config.MaximumStreams,
-		PriorityGames:   s.config.PriorityGames,
-		ClaimDrops:      s.config.ClaimDrops,
-		WebhookURL:      s.config.WebhookURL,
-	}
-	s.miner.SetConfig(minerConfig)
+	s.miner.SetConfig(drops.NewMinerConfig(s.config()))
+	s.applyAccountsConfig()
 
+	s.recordAudit(c, audit.ActionGameAdd, req.GameName)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"game": config.GameConfig{
@@ -504,16 +1433,122 @@ func (s *Server) addGameWithSlug(c *gin.Context) {
 	})
 }
 
+func (s *Server) setGameAliases(c *gin.Context) {
+	var req struct {
+		GameName string   `json:"game_name" binding:"required"`
+		Aliases  []string `json:"aliases"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	aliases := []string{}
+	for _, alias := range req.Aliases {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	found := false
+	for _, game := range s.config().PriorityGames {
+		if game.Name == req.GameName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		respondError(c, apierror.ErrNotFound.WithMessage("Game is not a priority game"))
+		return
+	}
+
+	if err := s.changeConfig(func(cfg *config.Config) error { return cfg.SetGameAliases(req.GameName, aliases) }); err != nil {
+		requestLog(c).Errorf("Failed to set aliases for game '%s': %v", req.GameName, err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to save configuration"))
+		return
+	}
+
+	// Update miner configuration so campaigns under the aliases match right away
+	s.miner.SetConfig(drops.NewMinerConfig(s.config()))
+	s.applyAccountsConfig()
+
+	s.recordAudit(c, audit.ActionGameAliases, fmt.Sprintf("%s: %s", req.GameName, strings.Join(aliases, ", ")))
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"game_name": req.GameName,
+		"aliases":   aliases,
+	})
+}
+
+// setGameChannels sets the channels watched first for a priority game whenever they are live with its drops
+func (s *Server) setGameChannels(c *gin.Context) {
+	var req struct {
+		GameName string   `json:"game_name" binding:"required"`
+		Channels []string `json:"channels"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+	channels := normalizeChannels(req.Channels)
+
+	found := false
+	for _, game := range s.config().PriorityGames {
+		if game.Name == req.GameName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		respondError(c, apierror.ErrNotFound.WithMessage("Game is not a priority game"))
+		return
+	}
+
+	err := s.changeConfig(func(cfg *config.Config) error { return cfg.SetGamePreferredChannels(req.GameName, channels) })
+	if err != nil {
+		requestLog(c).Errorf("Failed to set preferred channels for game '%s': %v", req.GameName, err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to save configuration"))
+		return
+	}
+
+	// Picked up at the next stream switch
+	s.miner.SetConfig(drops.NewMinerConfig(s.config()))
+	s.applyAccountsConfig()
+
+	s.recordAudit(c, audit.ActionGameChannels, fmt.Sprintf("%s: %s", req.GameName, strings.Join(channels, ", ")))
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"game_name": req.GameName,
+		"channels":  channels,
+	})
+}
+
+// normalizeChannels lowercases channel logins, dropping empty entries and repeats
+func normalizeChannels(channels []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, channel := range channels {
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		if channel != "" && !seen[channel] {
+			seen[channel] = true
+			normalized = append(normalized, channel)
+		}
+	}
+	return normalized
+}
+
 // Stream handlers
 func (s *Server) getStreamsForGame(c *gin.Context) {
-	if !s.twitchClient.IsLoggedIn() {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+	if !s.clientFor(c).IsLoggedIn() {
+		respondError(c, apierror.ErrNotLoggedIn)
 		return
 	}
 
 	gameID := c.Param("gameId")
 	if gameID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Game ID is required"})
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Game ID is required"))
 		return
 	}
 
@@ -523,10 +1558,10 @@ func (s *Server) getStreamsForGame(c *gin.Context) {
 		limit = 10
 	}
 
-	streams, err := s.twitchClient.GetStreamsForGameName(c.Request.Context(), gameID, limit)
+	streams, err := s.clientFor(c).GetStreamsForGameName(c.Request.Context(), gameID, limit)
 	if err != nil {
-		logrus.Errorf("Failed to get streams for game: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get streams"})
+		requestLog(c).Errorf("Failed to get streams for game: %v", err)
+		respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to get streams")))
 		return
 	}
 
@@ -534,28 +1569,402 @@ func (s *Server) getStreamsForGame(c *gin.Context) {
 }
 
 func (s *Server) getCurrentStream(c *gin.Context) {
-	status := s.miner.GetStatus()
-	if status.CurrentStream == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No current stream"})
+	// Served from storage so it reflects the last heartbeat even if the miner state is unavailable
+	record, err := drops.LoadCurrentStream(s.storeFor(c))
+	if err != nil {
+		requestLog(c).Errorf("Failed to load current stream: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to load current stream"))
+		return
+	}
+
+	if record == nil {
+		respondError(c, apierror.ErrNotFound.WithMessage("No current stream"))
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// Notification handlers
+func (s *Server) sendTestNotification(c *gin.Context) {
+	s.notifier.Notify(notify.Event{
+		Type:    notify.EventTest,
+		Title:   "Test notification",
+		Message: "Notifications from TwitchDropsFarmer are working",
+	})
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// sendDigest mails the digest of the last period now, to check the digest settings
+func (s *Server) sendDigest(c *gin.Context) {
+	if s.digest == nil {
+		respondError(c, apierror.ErrUnavailable.WithMessage("Digests are not available"))
+		return
+	}
+	if err := s.digest.SendNow(c.Request.Context()); err != nil {
+		requestLog(c).Errorf("Failed to send digest: %v", err)
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Failed to send digest").WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// testWebhook posts a sample event to the webhook now, once, to check the webhook settings
+func (s *Server) testWebhook(c *gin.Context) {
+	if s.webhook == nil {
+		respondError(c, apierror.ErrUnavailable.WithMessage("Webhooks are not available"))
+		return
+	}
+
+	var req struct {
+		Type string `json:"type"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+	if req.Type == "" {
+		req.Type = string(events.DropClaimed)
+	}
+	if !webhook.ValidEventType(req.Type) {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid event type").WithDetails("unknown event type "+req.Type))
+		return
+	}
+
+	if err := s.webhook.Test(c.Request.Context(), events.Type(req.Type)); err != nil {
+		requestLog(c).Errorf("Failed to send test webhook: %v", err)
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Failed to send test webhook").WithDetails(err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, status.CurrentStream)
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// getWebhookDeadLetters lists the webhook deliveries that failed every attempt
+func (s *Server) getWebhookDeadLetters(c *gin.Context) {
+	if s.webhook == nil {
+		respondError(c, apierror.ErrUnavailable.WithMessage("Webhooks are not available"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dead_letters": s.webhook.DeadLetters()})
+}
+
+func (s *Server) getWebPushKey(c *gin.Context) {
+	webPush := s.notifier.WebPush()
+	c.JSON(http.StatusOK, gin.H{
+		"public_key":    webPush.PublicKey(),
+		"subscriptions": webPush.SubscriptionCount(),
+	})
+}
+
+func (s *Server) subscribeWebPush(c *gin.Context) {
+	var sub notify.PushSubscription
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	if err := s.notifier.WebPush().Subscribe(sub); err != nil {
+		requestLog(c).Errorf("Failed to store push subscription: %v", err)
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Failed to store push subscription").WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (s *Server) unsubscribeWebPush(c *gin.Context) {
+	var req struct {
+		Endpoint string `json:"endpoint" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	if err := s.notifier.WebPush().Unsubscribe(req.Endpoint); err != nil {
+		requestLog(c).Errorf("Failed to remove push subscription: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to remove push subscription"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// Log handlers
+func (s *Server) getLogs(c *gin.Context) {
+	if s.logBuffer == nil {
+		c.JSON(http.StatusOK, []logbuffer.Entry{})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		limit = 0
+	}
+
+	c.JSON(http.StatusOK, s.logBuffer.Entries(limit))
+}
+
+// getClaimHistory lists the most recent claimed drops with their reward images
+func (s *Server) getClaimHistory(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		limit = 100
+	}
+
+	c.JSON(http.StatusOK, s.minerFor(c).GetClaimHistory(limit))
+}
+
+// getSessionHistory lists the streams watched by past and active mining sessions, newest first, with the number
+// matching in X-Total-Count
+func (s *Server) getSessionHistory(c *gin.Context) {
+	query := drops.SessionHistoryQuery{Game: c.Query("game"), Limit: 50}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		query.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		query.Offset = offset
+	}
+
+	sessions, total := s.minerFor(c).QuerySessionHistory(query)
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, sessions)
+}
+
+// Audit handlers
+func (s *Server) getAuditLog(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		limit = 100
+	}
+
+	c.JSON(http.StatusOK, s.auditLog.List(limit, c.Query("action")))
+}
+
+// recordAudit stores a control action with the caller's IP and principal
+func (s *Server) recordAudit(c *gin.Context, action, details string) {
+	principal := c.GetString(principalContextKey)
+	if principal == "" {
+		principal = "anonymous"
+	}
+
+	entry := audit.Entry{
+		Action:    action,
+		Details:   details,
+		SourceIP:  c.ClientIP(),
+		Principal: principal,
+	}
+	if err := s.auditLog.Record(entry); err != nil {
+		requestLog(c).Errorf("Failed to record audit entry %s: %v", action, err)
+	}
+}
+
+// Upper bound for uploaded state bundles
+const maxBundleSize = 32 << 20
+
+// State bundle handlers
+func (s *Server) exportState(c *gin.Context) {
+	var req struct {
+		Passphrase string `json:"passphrase"` // encrypts the secrets and includes the Twitch tokens
+	}
+	// An empty body is fine, it just exports with the secrets redacted and without the tokens
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.Export(&buf, s.config(), s.bundleSource(), bundle.Options{Passphrase: req.Passphrase}); err != nil {
+		requestLog(c).Errorf("Failed to export state: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to export state"))
+		return
+	}
+
+	s.recordAudit(c, audit.ActionStateExport, fmt.Sprintf("include token: %t", req.Passphrase != ""))
+
+	filename := fmt.Sprintf("twitchdropsfarmer-%s.zip", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+func (s *Server) importState(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleSize)
+
+	file, _, err := c.Request.FormFile("bundle")
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Bundle file is required").WithDetails(err.Error()))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Failed to read bundle").WithDetails(err.Error()))
+		return
+	}
+
+	contents, err := bundle.Read(bytes.NewReader(data), int64(len(data)), c.PostForm("passphrase"))
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid bundle").WithDetails(err.Error()))
+		return
+	}
+	if !s.restoreBundle(c, contents) {
+		return
+	}
+
+	s.recordAudit(c, audit.ActionStateImport, fmt.Sprintf("%d documents, %d accounts, token: %t", len(contents.Documents), len(contents.Accounts), contents.Token != nil))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"documents":      len(contents.Documents),
+		"accounts":       len(contents.Accounts),
+		"token_imported": contents.Token != nil,
+	})
+}
+
+// bundleSource is every store a state bundle is made of
+func (s *Server) bundleSource() bundle.Source {
+	source := bundle.Source{Store: s.store}
+	if s.accounts != nil {
+		source.Accounts = s.accounts.Stores()
+	}
+	return source
+}
+
+// restoreBundle applies the config, documents, and tokens of a bundle, responding with the error and returning
+// false when one can't be applied
+// The miners are stopped while their documents are replaced and reload them, the ones running are started again.
+func (s *Server) restoreBundle(c *gin.Context, contents *bundle.Contents) bool {
+	if contents.Config != nil {
+		s.reloadMu.Lock()
+		before := s.config()
+		cfg, err := config.Decode(contents.Config, before)
+		if err != nil {
+			s.reloadMu.Unlock()
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid config in bundle").WithDetails(err.Error()))
+			return false
+		}
+		if err := cfg.Save(); err != nil {
+			s.reloadMu.Unlock()
+			requestLog(c).Errorf("Failed to save imported configuration: %v", err)
+			respondError(c, apierror.ErrInternal.WithMessage("Failed to save configuration"))
+			return false
+		}
+		s.live.Set(cfg)
+		s.reloadMu.Unlock()
+
+		s.applyConfig()
+		if changed := changedSettings(before, cfg); len(changed) > 0 {
+			s.broadcastSettingsChanged(SettingsSourceAPI, changed)
+		}
+	}
+
+	if !s.restorePrimary(c, &contents.AccountData) {
+		return false
+	}
+
+	for id, account := range contents.Accounts {
+		if s.accounts == nil {
+			break
+		}
+		if err := s.accounts.Restore(c.Request.Context(), id, account.Documents, account.Token); err != nil {
+			requestLog(c).Errorf("Failed to import account %s: %v", id, err)
+			respondError(c, apierror.ErrInternal.WithMessage("Failed to import data").WithDetails(err.Error()))
+			return false
+		}
+	}
+	return true
+}
+
+// restorePrimary replaces the documents and the token of the primary account, restarting its miner afterwards
+// when it was running
+func (s *Server) restorePrimary(c *gin.Context, data *bundle.AccountData) bool {
+	if s.minerCancel != nil {
+		s.minerCancel()
+	}
+	running, err := s.miner.StopAndWait(c.Request.Context())
+	if err != nil {
+		requestLog(c).Errorf("Failed to stop miner: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to stop miner"))
+		return false
+	}
+	defer func() {
+		s.miner.ReloadStore(s.store)
+		if running {
+			s.runMiner()
+		}
+	}()
+
+	for name, document := range data.Documents {
+		if err := s.store.WriteRaw(name, document); err != nil {
+			requestLog(c).Errorf("Failed to import %s: %v", name, err)
+			respondError(c, apierror.ErrInternal.WithMessage("Failed to import data").WithDetails(err.Error()))
+			return false
+		}
+	}
+
+	if err := s.auditLog.Reload(); err != nil {
+		requestLog(c).Errorf("Failed to reload audit log: %v", err)
+	}
+	if err := s.notifier.Reload(); err != nil {
+		requestLog(c).Errorf("Failed to reload notifications: %v", err)
+	}
+	s.sessions.reload()
+
+	if data.Token != nil {
+		if err := config.SaveToken(data.Token); err != nil {
+			requestLog(c).Errorf("Failed to save imported token: %v", err)
+			respondError(c, apierror.ErrInternal.WithMessage("Failed to save token"))
+			return false
+		}
+		s.twitchClient.ReloadToken()
+	}
+	return true
+}
+
+// deviceLogin is a device code login started through the API
+type deviceLogin struct {
+	response *twitch.DeviceCodeResponse
+	account  bool // for a new additional account, completed with POST /api/accounts/callback
 }
 
 // Device code storage methods (in production, use Redis or database)
-func (s *Server) storeDeviceCode(deviceCode string, response *twitch.DeviceCodeResponse) {
-	s.deviceCodes[deviceCode] = response
+func (s *Server) storeDeviceCode(response *twitch.DeviceCodeResponse, account bool) {
+	s.deviceCodesMu.Lock()
+	s.deviceCodes[response.DeviceCode] = deviceLogin{response: response, account: account}
+	s.deviceCodesMu.Unlock()
 
 	// Clean up expired codes after their expiry time
 	go func() {
 		time.Sleep(time.Duration(response.ExpiresIn) * time.Second)
-		delete(s.deviceCodes, deviceCode)
+		s.deviceCodesMu.Lock()
+		delete(s.deviceCodes, response.DeviceCode)
+		s.deviceCodesMu.Unlock()
 	}()
 }
 
-func (s *Server) getDeviceCode(deviceCode string) *twitch.DeviceCodeResponse {
-	return s.deviceCodes[deviceCode]
+func (s *Server) getDeviceCode(deviceCode string) (deviceLogin, bool) {
+	s.deviceCodesMu.Lock()
+	defer s.deviceCodesMu.Unlock()
+	login, ok := s.deviceCodes[deviceCode]
+	return login, ok
+}
+
+// deviceCodeJSON is the answer to a started device code login
+func deviceCodeJSON(deviceResp *twitch.DeviceCodeResponse) gin.H {
+	return gin.H{
+		"device_code":               deviceResp.DeviceCode,
+		"user_code":                 deviceResp.UserCode,
+		"verification_uri":          deviceResp.VerificationURI,
+		"verification_uri_complete": deviceResp.VerificationURIComplete,
+		"qr_url":                    "/api/auth/qr?device_code=" + url.QueryEscape(deviceResp.DeviceCode),
+		"expires_in":                deviceResp.ExpiresIn,
+		"interval":                  deviceResp.Interval,
+	}
 }
 
 // Helper function for safe string extraction
@@ -565,3 +1974,19 @@ func getString(m map[string]interface{}, key string) string {
 	}
 	return ""
 }
+
+// Helper function for extracting a list of strings from a JSON array
+func getStringSlice(m map[string]interface{}, key string) ([]string, bool) {
+	list, ok := m[key].([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	values := []string{}
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			values = append(values, str)
+		}
+	}
+	return values, true
+}
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/drops"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How long a Twitch reachability check is reused, so frequent probes don't each send a request to Twitch
+const readyzTwitchInterval = 30 * time.Second
+
+// Result of a passing check
+const checkOK = "ok"
+
+// HealthReport is what /readyz and /livez answer, with the result of each check
+type HealthReport struct {
+	Status string            `json:"status"` // "ok", or "unavailable" when a check failed
+	Checks map[string]string `json:"checks"` // "ok" or why the check failed
+}
+
+// readinessCache keeps the last Twitch reachability check
+type readinessCache struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// getHealth answers as soon as the server serves requests
+func (s *Server) getHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": checkOK})
+}
+
+// getReadyz checks what the farmer needs to work: storage accepting writes, a config.json that loads, and
+// Twitch answering. Failing Kubernetes readiness probes takes the instance out of service without restarting it.
+func (s *Server) getReadyz(c *gin.Context) {
+	checks := map[string]string{}
+
+	if s.store != nil {
+		checks["storage"] = checkResult(s.store.Ping())
+	}
+
+	s.reloadMu.Lock()
+	configErr := s.configErr
+	s.reloadMu.Unlock()
+	checks["config"] = checkResult(configErr)
+
+	checks["twitch"] = checkResult(s.twitchReachable(c.Request.Context()))
+
+	respondHealth(c, checks)
+}
+
+// getLivez checks that the mining loops of running miners are still coming around; unlike readiness, a failure
+// means the process is wedged and should be restarted
+func (s *Server) getLivez(c *gin.Context) {
+	timeout := time.Duration(s.config().LivenessTimeout) * time.Second
+	checks := map[string]string{
+		"miner": checkResult(loopAlive(s.miner, timeout)),
+	}
+	if s.accounts != nil {
+		for _, account := range s.accounts.List() {
+			checks["miner:"+account.ID] = checkResult(loopAlive(account.Miner, timeout))
+		}
+	}
+
+	respondHealth(c, checks)
+}
+
+// twitchReachable pings Twitch, or returns the previous result while it is recent
+func (s *Server) twitchReachable(ctx context.Context) error {
+	cache := &s.twitchReady
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if time.Since(cache.checkedAt) < readyzTwitchInterval {
+		return cache.err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	cache.err = s.twitchClient.Ping(ctx)
+	cache.checkedAt = time.Now()
+	return cache.err
+}
+
+// loopAlive fails when a running miner's loop heartbeat is older than timeout; stopped miners are alive
+func loopAlive(miner *drops.Miner, timeout time.Duration) error {
+	beat := miner.LoopHeartbeat()
+	if beat.IsZero() {
+		return nil
+	}
+	if since := time.Since(beat); since > timeout {
+		return fmt.Errorf("mining loop stuck for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+func checkResult(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return checkOK
+}
+
+// respondHealth answers 200 when every check passed, 503 otherwise
+func respondHealth(c *gin.Context, checks map[string]string) {
+	report := HealthReport{Status: checkOK, Checks: checks}
+	status := http.StatusOK
+	for _, result := range checks {
+		if result != checkOK {
+			report.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,115 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/audit"
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/tdm"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+// Upper bound for the uploaded TwitchDropsMiner files together
+const maxTDMImportSize = 4 << 20
+
+// importTDM takes over the login (cookies.jar) and the game lists (settings.json) of TwitchDropsMiner, either
+// file may be left out
+func (s *Server) importTDM(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTDMImportSize)
+
+	cookiesData, err := readFormFile(c, "cookies")
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Failed to read cookies").WithDetails(err.Error()))
+		return
+	}
+	settingsData, err := readFormFile(c, "settings")
+	if err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Failed to read settings").WithDetails(err.Error()))
+		return
+	}
+	if cookiesData == nil && settingsData == nil {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Nothing to import").WithDetails("upload cookies.jar as cookies, settings.json as settings, or both"))
+		return
+	}
+
+	// Parse both files before changing anything
+	var cookies *tdm.Cookies
+	if cookiesData != nil {
+		if cookies, err = tdm.ReadCookies(cookiesData); err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid cookies.jar").WithDetails(err.Error()))
+			return
+		}
+	}
+	var settings *tdm.Settings
+	if settingsData != nil {
+		if settings, err = tdm.ReadSettings(settingsData); err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid settings.json").WithDetails(err.Error()))
+			return
+		}
+	}
+
+	response := gin.H{"success": true, "token_imported": cookies != nil}
+
+	if cookies != nil {
+		token := &oauth2.Token{AccessToken: cookies.AuthToken, TokenType: "bearer"}
+		if err := s.twitchClient.ImportToken(c.Request.Context(), token); err != nil {
+			requestLog(c).Warnf("Failed to import TDM login: %v", err)
+			if errors.Is(err, twitch.ErrTokenRejected) {
+				respondError(c, apierror.ErrInvalidRequest.WithMessage("Twitch rejected the TDM login").WithDetails("log in with TDM again and export a fresh cookies.jar"))
+				return
+			}
+			respondError(c, classifyError(err, apierror.ErrInternal.WithMessage("Failed to verify the TDM login")))
+			return
+		}
+		if user := s.twitchClient.GetUser(); user != nil {
+			response["user"] = user.Login
+		}
+	}
+
+	if settings != nil {
+		before := s.config()
+		err := s.changeConfig(func(cfg *config.Config) error {
+			settings.Apply(cfg)
+			return cfg.Save()
+		})
+		if err != nil {
+			requestLog(c).Errorf("Failed to save imported configuration: %v", err)
+			respondError(c, apierror.ErrInternal.WithMessage("Failed to save configuration"))
+			return
+		}
+		s.applyConfig()
+		if changed := changedSettings(before, s.config()); len(changed) > 0 {
+			s.broadcastSettingsChanged(SettingsSourceAPI, changed)
+		}
+		response["priority_games"] = len(settings.Priority)
+		response["exclude_games"] = len(settings.Exclude)
+	}
+
+	details := fmt.Sprintf("token: %t", cookies != nil)
+	if settings != nil {
+		details += fmt.Sprintf(", %d priority games, %d excluded games", len(settings.Priority), len(settings.Exclude))
+	}
+	s.recordAudit(c, audit.ActionTDMImport, details)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// readFormFile reads an uploaded file, nil when the form has no such file
+func readFormFile(c *gin.Context, field string) ([]byte, error) {
+	file, _, err := c.Request.FormFile(field)
+	if err == http.ErrMissingFile || err == http.ErrNotMultipart {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
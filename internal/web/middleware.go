@@ -1,12 +1,29 @@
 package web
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
 	"net/http"
+	"runtime/debug"
 	"strings"
 	"time"
 
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/errreport"
+	"twitchdropsfarmer/internal/logging"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // CORS middleware
@@ -14,8 +31,8 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		c.Header("Access-Control-Expose-Headers", "Content-Length")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-API-Key, X-Request-ID, traceparent, tracestate")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, X-Request-ID")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -27,6 +44,67 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
+// Context key and header holding the ID of the current request
+const (
+	requestIDContextKey = "request_id"
+	requestIDHeader     = "X-Request-ID"
+)
+
+// Request ID middleware tags every request with an ID, returned in the X-Request-ID header and error responses
+// A caller's own X-Request-ID is kept when it looks sane, so IDs can be followed from a reverse proxy
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > 64 || strings.ContainsAny(id, " \t\r\n") {
+			buf := make([]byte, 8)
+			rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+
+		c.Set(requestIDContextKey, id)
+		c.Header(requestIDHeader, id)
+		// Twitch calls made for the request log it with their lines
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// Spans around the HTTP handlers, exported when tracing is set up
+var tracer = otel.Tracer("twitchdropsfarmer/internal/web")
+
+// Tracing middleware starts a span for every request, continuing a trace passed in the traceparent header
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		// Named after the route rather than the path, so /api/campaigns/:id is one operation
+		route := c.FullPath()
+		name := c.Request.Method + " " + route
+		if route == "" {
+			name = c.Request.Method
+		}
+		ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", c.Request.URL.Path),
+			attribute.String(logging.RequestIDField, c.GetString(requestIDContextKey)),
+		))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
+
 // Logging middleware
 func LoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -65,6 +143,182 @@ func LoggingMiddleware() gin.HandlerFunc {
 }
 
 
+// Context keys holding the authenticated dashboard principal and role
+const (
+	principalContextKey = "principal"
+	roleContextKey      = "role"
+	accountsContextKey  = "accounts"
+)
+
+// API key middleware resolves the caller's role from a password session or an API key
+// Keys are read from "Authorization: Bearer", "X-API-Key", or the api_key query parameter (for WebSockets)
+func (s *Server) APIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Without configured keys or password the API stays open, like before roles existed
+		if !s.authRequired() {
+			c.Set(roleContextKey, config.RoleAdmin)
+			c.Next()
+			return
+		}
+
+		// Sessions come from the password, which grants full access
+		if s.hasSession(c) {
+			c.Set(principalContextKey, sessionPrincipal)
+			c.Set(roleContextKey, config.RoleAdmin)
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			key = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if key == "" {
+			key = c.Query("api_key")
+		}
+
+		for _, apiKey := range s.config().APIKeys {
+			if apiKey.Key != "" && subtle.ConstantTimeCompare([]byte(apiKey.Key), []byte(key)) == 1 {
+				c.Set(principalContextKey, apiKey.Name)
+				c.Set(roleContextKey, apiKey.Role)
+				c.Set(accountsContextKey, apiKey.Accounts)
+				c.Next()
+				return
+			}
+		}
+
+		respondError(c, apierror.ErrUnauthorized)
+	}
+}
+
+// isScoped reports whether the caller's API key is limited to some accounts
+func isScoped(c *gin.Context) bool {
+	return len(c.GetStringSlice(accountsContextKey)) > 0
+}
+
+// canAccessAccount reports whether the caller's API key is scoped to the given account login
+func canAccessAccount(c *gin.Context, accountLogin string) bool {
+	if !isScoped(c) {
+		return true
+	}
+
+	for _, account := range c.GetStringSlice(accountsContextKey) {
+		if strings.EqualFold(account, accountLogin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Account scope middleware rejects keys that aren't scoped to the logged in Twitch account
+func (s *Server) AccountScopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Scoped keys fail closed while the account is logged out, there is no login to check them against
+		user := s.clientFor(c).GetUser()
+		if !isScoped(c) || (user != nil && canAccessAccount(c, user.Login)) {
+			c.Next()
+			return
+		}
+
+		respondError(c, apierror.ErrForbidden.WithMessage("API key is not scoped to this account"))
+	}
+}
+
+// Unscoped middleware rejects keys scoped to accounts on endpoints acting on the whole app, like settings, logs and
+// backups, which reach every account's data
+func UnscopedMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isScoped(c) {
+			respondError(c, apierror.ErrForbidden.WithMessage("API key is scoped to accounts and can't use app-wide endpoints"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Role middleware lets viewers through for reads and requires admin for mutating requests
+func RoleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString(roleContextKey)
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if role == config.RoleAdmin || role == config.RoleViewer {
+				c.Next()
+				return
+			}
+		default:
+			if role == config.RoleAdmin {
+				c.Next()
+				return
+			}
+		}
+
+		respondError(c, apierror.ErrForbidden)
+	}
+}
+
+// Admin middleware requires the admin role whatever the method, for reads that expose the process' internals
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(roleContextKey) != config.RoleAdmin {
+			respondError(c, apierror.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// etagWriter holds the response body back so an ETag can be computed over it
+type etagWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// ETag middleware answers polling clients with 304 Not Modified when the response body hasn't changed
+func ETagMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		writer := &etagWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if c.Writer.Status() != http.StatusOK {
+			c.Writer.Write(writer.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(writer.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", "no-cache")
+
+		for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				c.Writer.WriteHeader(http.StatusNotModified)
+				c.Writer.WriteHeaderNow()
+				return
+			}
+		}
+
+		c.Writer.Write(writer.body.Bytes())
+	}
+}
+
 // Authentication middleware
 func (s *Server) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -86,10 +340,7 @@ func (s *Server) AuthMiddleware() gin.HandlerFunc {
 
 		// Check if user is authenticated
 		if !s.twitchClient.IsLoggedIn() {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authentication required",
-			})
-			c.Abort()
+			respondError(c, apierror.ErrNotLoggedIn.WithMessage("Authentication required"))
 			return
 		}
 
@@ -98,15 +349,20 @@ func (s *Server) AuthMiddleware() gin.HandlerFunc {
 }
 
 // Error handling middleware
-func ErrorHandlingMiddleware() gin.HandlerFunc {
+// Panics are sent to the error reporter with the route and request ID
+func (s *Server) ErrorHandlingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				logrus.Errorf("Panic recovered: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "Internal server error",
+				requestLog(c).Errorf("Panic recovered: %v", err)
+				s.reporter.Capture(errreport.Report{
+					Kind:      errreport.KindPanic,
+					Message:   fmt.Sprint(err),
+					Operation: c.Request.Method + " " + c.FullPath(),
+					RequestID: c.GetString(requestIDContextKey),
+					Stack:     string(debug.Stack()),
 				})
-				c.Abort()
+				respondError(c, apierror.ErrInternal)
 			}
 		}()
 
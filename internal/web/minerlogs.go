@@ -0,0 +1,104 @@
+package web
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/drops"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// How often an idle log stream sends a comment, so proxies don't close it
+const logStreamKeepAlive = 30 * time.Second
+
+// getMinerLogs lists miner log entries, oldest first, with the number of matching entries in X-Total-Count
+func (s *Server) getMinerLogs(c *gin.Context) {
+	query, ok := minerLogQuery(c)
+	if !ok {
+		return
+	}
+	query.Limit = 100
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		query.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		query.Offset = offset
+	}
+
+	entries, total := s.minerFor(c).QueryLogs(query)
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, entries)
+}
+
+// streamMinerLogs sends miner log entries matching the query as server-sent "log" events as they are recorded
+func (s *Server) streamMinerLogs(c *gin.Context) {
+	query, ok := minerLogQuery(c)
+	if !ok {
+		return
+	}
+
+	entries, cancel := s.minerFor(c).SubscribeLogs()
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(logStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case entry := <-entries:
+			if query.Matches(entry) {
+				c.SSEvent("log", entry)
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				requestLog(c).Debugf("Log stream closed: %v", err)
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// minerLogQuery reads the level, event, game, since and until filters, responding with an error when one is invalid
+func minerLogQuery(c *gin.Context) (drops.MinerLogQuery, bool) {
+	query := drops.MinerLogQuery{
+		Level: c.Query("level"),
+		Event: c.Query("event"),
+		Game:  c.Query("game"),
+	}
+	if query.Level != "" {
+		if _, err := logrus.ParseLevel(query.Level); err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid level").WithDetails("must be trace, debug, info, warning or error"))
+			return query, false
+		}
+	}
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid "+param.name).WithDetails("must be an RFC 3339 time, e.g. 2024-05-01T18:00:00Z"))
+			return query, false
+		}
+		*param.value = parsed
+	}
+	return query, true
+}
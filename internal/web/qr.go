@@ -0,0 +1,52 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"twitchdropsfarmer/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/skip2/go-qrcode"
+)
+
+// Size of the QR code PNG in pixels, set with ?size=
+const (
+	defaultQRSize = 256
+	minQRSize     = 128
+	maxQRSize     = 1024
+)
+
+// getAuthQR renders verification_uri_complete of a device code login in progress as a QR code PNG, so the
+// login can be done by scanning it with a phone
+func (s *Server) getAuthQR(c *gin.Context) {
+	deviceCode := c.Query("device_code")
+	if deviceCode == "" {
+		respondError(c, apierror.ErrInvalidRequest.WithMessage("Missing device_code").WithDetails("device_code is required"))
+		return
+	}
+	size := defaultQRSize
+	if raw := c.Query("size"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < minQRSize || parsed > maxQRSize {
+			respondError(c, apierror.ErrInvalidRequest.WithMessage("Invalid size").WithDetails("size must be between 128 and 1024"))
+			return
+		}
+		size = parsed
+	}
+
+	login, ok := s.getDeviceCode(deviceCode)
+	if !ok {
+		respondError(c, apierror.ErrNotFound.WithMessage("Device code not found").WithDetails("the code is unknown or has expired"))
+		return
+	}
+	png, err := qrcode.Encode(login.response.VerificationURIComplete, qrcode.Medium, size)
+	if err != nil {
+		logrus.Errorf("Failed to render QR code: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to render QR code"))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
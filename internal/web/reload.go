@@ -0,0 +1,137 @@
+package web
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"twitchdropsfarmer/internal/backup"
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/digest"
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/logging"
+	"twitchdropsfarmer/internal/twitch"
+	"twitchdropsfarmer/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Where a settings change came from, sent with settings_changed
+const (
+	SettingsSourceAPI    = "api"    // PUT /api/settings or a bundle import
+	SettingsSourceFile   = "file"   // config.json edited on disk
+	SettingsSourceSIGHUP = "sighup" // the process received SIGHUP
+)
+
+// ReloadConfig re-reads config.json and applies it like a settings update, then tells every dashboard which
+// settings changed. A file that doesn't parse leaves the current settings in place.
+func (s *Server) ReloadConfig(source string) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	fresh, err := config.Load()
+	s.configErr = err
+	if err != nil {
+		logrus.Errorf("Failed to reload configuration, keeping the current one: %v", err)
+		return
+	}
+
+	changed := changedSettings(s.config(), fresh)
+	if len(changed) == 0 {
+		logrus.Infof("Configuration reloaded (%s), nothing changed", source)
+		return
+	}
+
+	s.live.Set(fresh)
+	s.applyConfig()
+	logrus.Infof("Configuration reloaded (%s), changed: %s", source, strings.Join(changed, ", "))
+	s.broadcastSettingsChanged(source, changed)
+}
+
+// changeConfig makes change to a copy of the configuration, and puts the copy in use once change succeeds, e.g.
+// has saved it
+func (s *Server) changeConfig(change func(cfg *config.Config) error) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	cfg := s.config().Clone()
+	if err := change(cfg); err != nil {
+		return err
+	}
+	s.live.Set(cfg)
+	return nil
+}
+
+// applyConfig pushes the whole configuration to the notifier, the clients and the miners
+// Server, storage, TLS and client profile settings are only read at startup.
+func (s *Server) applyConfig() {
+	if err := s.notifier.SetURLs(s.config().NotificationURLs); err != nil {
+		logrus.Errorf("Ignoring notification URLs: %v", err)
+	}
+	if s.digest != nil {
+		if err := s.digest.SetConfig(digest.NewConfig(s.config())); err != nil {
+			logrus.Errorf("Ignoring digest email: %v", err)
+		}
+	}
+	if s.webhook != nil {
+		if err := s.webhook.SetConfig(webhook.NewConfig(s.config())); err != nil {
+			logrus.Errorf("Ignoring webhook settings: %v", err)
+		}
+	}
+	if s.backups != nil {
+		if err := s.backups.SetConfig(backup.NewConfig(s.config())); err != nil {
+			logrus.Errorf("Ignoring backup schedule: %v", err)
+		}
+	}
+	s.miner.SetConfig(drops.NewMinerConfig(s.config()))
+	s.twitchClient.SetDirectoryOptions(twitch.NewDirectoryOptions(s.config()))
+	s.twitchClient.SetStreamQuality(s.config().StreamQuality)
+	s.twitchClient.SetAuthScopes(s.config().AuthScopes)
+	if err := s.twitchClient.SetProxy(s.config().ProxyFor("")); err != nil {
+		logrus.Errorf("Ignoring proxy: %v", err)
+	}
+	if s.logBuffer != nil {
+		s.logBuffer.Resize(s.config().LogBufferSize)
+	}
+	if err := logging.Configure(s.config()); err != nil {
+		logrus.Errorf("Keeping the previous log settings: %v", err)
+	}
+	s.applyAccountsConfig()
+}
+
+// broadcastSettingsChanged sends settings_changed to every connection, whichever account it follows
+func (s *Server) broadcastSettingsChanged(source string, changed []string) {
+	encoded, err := encodeWSMessage("settings_changed", "", gin.H{"source": source, "changed": changed})
+	if err != nil {
+		logrus.Errorf("Failed to marshal settings_changed message: %v", err)
+		return
+	}
+
+	select {
+	case s.wsBroadcast <- wsMessage{global: true, data: encoded}:
+	case <-time.After(time.Second):
+		// Hub is stuck, dashboards pick the settings up on their next load
+	}
+}
+
+// changedSettings lists the JSON keys whose values differ between two configurations, sorted
+func changedSettings(before, after *config.Config) []string {
+	var a, b map[string]json.RawMessage
+	if data, err := json.Marshal(before); err == nil {
+		json.Unmarshal(data, &a)
+	}
+	if data, err := json.Marshal(after); err == nil {
+		json.Unmarshal(data, &b)
+	}
+
+	changed := []string{}
+	for key, value := range b {
+		if string(a[key]) != string(value) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
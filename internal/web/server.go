@@ -2,14 +2,26 @@ package web
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"twitchdropsfarmer/internal/accounts"
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/audit"
+	"twitchdropsfarmer/internal/backup"
 	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/digest"
 	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/errreport"
+	"twitchdropsfarmer/internal/logbuffer"
+	"twitchdropsfarmer/internal/notify"
+	"twitchdropsfarmer/internal/storage"
 	"twitchdropsfarmer/internal/twitch"
 	"twitchdropsfarmer/internal/util"
+	"twitchdropsfarmer/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -17,42 +29,75 @@ import (
 )
 
 type Server struct {
-	config       *config.Config
+	live         *config.Live
 	twitchClient *twitch.Client
 	miner        *drops.Miner
+	notifier     *notify.Manager
+	auditLog     *audit.Log
+	store        storage.Store
+	logBuffer    *logbuffer.Buffer
+	reporter     *errreport.Reporter
+	accounts     *accounts.Manager
+	digest       *digest.Digest
+	webhook      *webhook.Webhook
+	backups      *backup.Manager
 
 	// WebSocket upgrader
 	upgrader websocket.Upgrader
 
-	// WebSocket connections
-	wsConnections map[*websocket.Conn]bool
-	wsBroadcast   chan []byte
-	wsRegister    chan *websocket.Conn
+	// WebSocket connections; each follows one account ("" for the primary account)
+	wsConnections map[*websocket.Conn]*wsClient
+	wsBroadcast   chan wsMessage
+	wsRegister    chan *wsClient
 	wsUnregister  chan *websocket.Conn
+	wsSubscribe   chan wsSubscription
+	wsShutdown    chan chan struct{}
+	wsStopped     chan struct{} // closed once the hub has exited
+	wsOpen        atomic.Int64  // len(wsConnections), readable outside the hub
 
-	// Device code storage (in production use Redis/database)
-	deviceCodes map[string]*twitch.DeviceCodeResponse
+	// Dashboard sessions created with the password
+	sessions webSessions
+
+	// Device code logins in progress, by device code, until they expire
+	deviceCodesMu sync.Mutex
+	deviceCodes   map[string]deviceLogin
+
+	// Serializes configuration changes: ReloadConfig, which the file watcher and SIGHUP can fire together, and the
+	// handlers changing settings
+	reloadMu  sync.Mutex
+	configErr error // why config.json last failed to reload, nil once it loads again
+
+	// Twitch reachability, checked at most every readyzTwitchInterval
+	twitchReady readinessCache
 
 	// Miner context management
 	minerCtx    context.Context
 	minerCancel context.CancelFunc
 }
 
-func NewServer(cfg *config.Config, twitchClient *twitch.Client, miner *drops.Miner) *Server {
+func NewServer(live *config.Live, twitchClient *twitch.Client, miner *drops.Miner, notifier *notify.Manager, auditLog *audit.Log, store storage.Store) *Server {
 	server := &Server{
-		config:       cfg,
+		live:         live,
 		twitchClient: twitchClient,
 		miner:        miner,
+		notifier:     notifier,
+		auditLog:     auditLog,
+		store:        store,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // Allow all origins for now
 			},
+			Subprotocols: []string{wsEncodingMsgpack, wsEncodingJSON},
 		},
-		wsConnections: make(map[*websocket.Conn]bool),
-		wsBroadcast:   make(chan []byte),
-		wsRegister:    make(chan *websocket.Conn),
+		wsConnections: make(map[*websocket.Conn]*wsClient),
+		wsBroadcast:   make(chan wsMessage),
+		wsRegister:    make(chan *wsClient),
 		wsUnregister:  make(chan *websocket.Conn),
-		deviceCodes:   make(map[string]*twitch.DeviceCodeResponse),
+		wsSubscribe:   make(chan wsSubscription),
+		wsShutdown:    make(chan chan struct{}),
+		wsStopped:     make(chan struct{}),
+		deviceCodes:   make(map[string]deviceLogin),
+		sessions:      webSessions{store: store},
 	}
 
 	// Start WebSocket hub
@@ -61,17 +106,64 @@ func NewServer(cfg *config.Config, twitchClient *twitch.Client, miner *drops.Min
 	return server
 }
 
+// config returns the configuration in use; change a Clone of it and publish that with s.live.Set, holding reloadMu
+func (s *Server) config() *config.Config {
+	return s.live.Get()
+}
+
+// SetLogBuffer sets the ring buffer served by /api/logs
+func (s *Server) SetLogBuffer(logBuffer *logbuffer.Buffer) {
+	s.logBuffer = logBuffer
+}
+
+// SetErrorReporter sets where panics in handlers are reported; call it before serving requests
+func (s *Server) SetErrorReporter(reporter *errreport.Reporter) {
+	s.reporter = reporter
+}
+
+// SetDigest sets the digest sender settings are applied to and POST /api/notifications/digest sends with
+func (s *Server) SetDigest(digest *digest.Digest) {
+	s.digest = digest
+}
+
+// SetBackups sets the backup manager settings are applied to and /api/backups uses; call it before serving requests
+func (s *Server) SetBackups(backups *backup.Manager) {
+	s.backups = backups
+}
+
+// SetWebhook sets the webhook settings are applied to and /api/webhooks tests; call it before serving requests
+func (s *Server) SetWebhook(webhook *webhook.Webhook) {
+	s.webhook = webhook
+}
+
+// accessLogLine is gin's access log line without colors, ending with the request ID
+func accessLogLine(param gin.LogFormatterParams) string {
+	requestID, _ := param.Keys[requestIDContextKey].(string)
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | %s\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		requestID,
+		param.ErrorMessage,
+	)
+}
+
 func (s *Server) Router() *gin.Engine {
 	if gin.Mode() == gin.DebugMode {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	router := gin.New()
-	router.Use(gin.Logger())
+	router.Use(RequestIDMiddleware())
+	router.Use(TracingMiddleware())
+	router.Use(gin.LoggerWithFormatter(accessLogLine))
 	router.Use(gin.Recovery())
 	router.Use(CORSMiddleware())
 	router.Use(SecurityMiddleware())
-	router.Use(ErrorHandlingMiddleware())
+	router.Use(s.ErrorHandlingMiddleware())
 
 	// Serve static files
 	router.Static("/css", "./web/static/css")
@@ -87,11 +179,11 @@ func (s *Server) Router() *gin.Engine {
 
 		// Check if it's an API route or WebSocket
 		if len(path) >= 4 && path[:4] == "/api" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			respondError(c, apierror.ErrNotFound)
 			return
 		}
 		if len(path) >= 3 && path[:3] == "/ws" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			respondError(c, apierror.ErrNotFound)
 			return
 		}
 
@@ -99,139 +191,293 @@ func (s *Server) Router() *gin.Engine {
 		c.File("./web/static/index.html")
 	})
 
+	// Dashboard password sessions, reachable without one
+	session := router.Group("/api/session")
+	{
+		session.GET("", s.getSession)
+		session.POST("", s.createSession)
+		session.DELETE("", s.deleteSession)
+	}
+
 	// API routes
 	api := router.Group("/api")
+	api.Use(s.APIKeyMiddleware())
+	api.Use(RoleMiddleware())
 	{
 		// Authentication endpoints
-		auth := api.Group("/auth")
+		auth := api.Group("/auth", s.AccountScopeMiddleware())
 		{
 			auth.GET("/url", s.getAuthURL)
 			auth.POST("/callback", s.handleAuthCallback)
 			auth.POST("/logout", s.handleLogout)
 			auth.GET("/status", s.getAuthStatus)
+			auth.GET("/qr", s.getAuthQR)
 		}
 
-		// User endpoints
-		user := api.Group("/user")
-		{
-			user.GET("/profile", s.getUserProfile)
-			user.GET("/inventory", s.getUserInventory)
-		}
+		// Per-account endpoints for the primary account
+		s.registerAccountRoutes(api)
 
-		// Campaigns endpoints
-		campaigns := api.Group("/campaigns")
+		// Additional accounts, each with the same endpoints under /api/accounts/:accountID
+		accountsGroup := api.Group("/accounts")
 		{
-			campaigns.GET("/", s.getCampaigns)
-			campaigns.GET("/:id", s.getCampaign)
-			campaigns.GET("/:id/drops", s.getCampaignDrops)
-		}
+			accountsGroup.GET("/", s.listAccounts)
+			accountsGroup.POST("/", UnscopedMiddleware(), s.addAccount)
+			accountsGroup.POST("/callback", UnscopedMiddleware(), s.handleAccountCallback)
 
-		// Miner endpoints
-		miner := api.Group("/miner")
-		{
-			miner.GET("/status", s.getMinerStatus)
-			miner.GET("/current-drop", s.getCurrentDrop)
-			miner.GET("/progress", s.getDropProgress)
-			miner.POST("/start", s.startMiner)
-			miner.POST("/stop", s.stopMiner)
+			account := accountsGroup.Group("/:accountID", s.AccountMiddleware())
+			account.DELETE("", s.AccountScopeMiddleware(), s.removeAccount)
+			s.registerAccountRoutes(account)
 		}
 
 		// Config endpoints (renamed from settings for consistency with Vue frontend)
-		config := api.Group("/config")
+		config := api.Group("/config", UnscopedMiddleware())
 		{
 			config.GET("/", s.getSettings)
 			config.POST("/", s.updateSettings)
 			config.POST("/game", s.addGameWithSlug)
+			config.GET("/export", s.exportConfig)
+			config.POST("/import", s.importConfig)
+			config.POST("/game/aliases", s.setGameAliases)
+			config.POST("/game/channels", s.setGameChannels)
 		}
 
 		// Settings endpoints (keep for backward compatibility)
-		settings := api.Group("/settings")
+		settings := api.Group("/settings", UnscopedMiddleware())
 		{
 			settings.GET("/", s.getSettings)
 			settings.PUT("/", s.updateSettings)
 		}
 
 		// Games endpoints (keep for backward compatibility)
-		games := api.Group("/games")
+		games := api.Group("/games", UnscopedMiddleware())
 		{
 			games.POST("/add", s.addGameWithSlug)
 		}
 
-		// Streams endpoints
-		streams := api.Group("/streams")
+		// Notification endpoints
+		notifications := api.Group("/notifications", UnscopedMiddleware())
 		{
-			streams.GET("/game/:gameId", s.getStreamsForGame)
-			streams.GET("/current", s.getCurrentStream)
+			notifications.POST("/test", s.sendTestNotification)
+			notifications.POST("/digest", s.sendDigest)
+			notifications.GET("/webpush/key", s.getWebPushKey)
+			notifications.POST("/webpush/subscribe", s.subscribeWebPush)
+			notifications.POST("/webpush/unsubscribe", s.unsubscribeWebPush)
 		}
+
+		// Webhook endpoints
+		webhooks := api.Group("/webhooks", UnscopedMiddleware())
+		{
+			webhooks.POST("/test", s.testWebhook)
+			webhooks.GET("/dead-letters", s.getWebhookDeadLetters)
+		}
+
+		// Log endpoints
+		api.GET("/logs", UnscopedMiddleware(), s.getLogs)
+
+		// Audit endpoints
+		api.GET("/audit", UnscopedMiddleware(), s.getAuditLog)
+
+		// Diagnostics endpoints
+		api.GET("/debug/runtime", AdminMiddleware(), UnscopedMiddleware(), s.getDebugRuntime)
+
+		// State bundle endpoints (POST so they always require the admin role)
+		state := api.Group("/state", UnscopedMiddleware())
+		{
+			state.POST("/export", s.exportState)
+			state.POST("/import", s.importState)
+		}
+
+		// Backup endpoints
+		backups := api.Group("/backups", UnscopedMiddleware())
+		{
+			backups.GET("", s.listBackups)
+			backups.POST("", s.createBackup)
+			backups.POST("/restore", s.restoreBackup)
+		}
+
+		// Migration from TwitchDropsMiner
+		api.POST("/import/tdm", UnscopedMiddleware(), s.importTDM)
 	}
 
-	// WebSocket endpoint
-	router.GET("/ws", s.handleWebSocket)
+	// Health checks for Docker and Kubernetes, reachable without a password or API key
+	router.GET("/health", s.getHealth)
+	router.GET("/readyz", s.getReadyz)
+	router.GET("/livez", s.getLivez)
+
+	// Go profiler, only with the pprof setting on
+	router.GET("/debug/pprof/*profile", s.APIKeyMiddleware(), AdminMiddleware(), UnscopedMiddleware(), s.servePprof)
+
+	// WebSocket endpoints
+	router.GET("/ws", s.APIKeyMiddleware(), RoleMiddleware(), s.AccountScopeMiddleware(), s.handleWebSocket)
+	router.GET("/ws/accounts/:accountID", s.APIKeyMiddleware(), RoleMiddleware(), s.AccountMiddleware(), s.AccountScopeMiddleware(), s.handleWebSocket)
 
 	return router
 }
 
+// registerAccountRoutes adds the endpoints that act on a single Twitch account to group
+func (s *Server) registerAccountRoutes(group *gin.RouterGroup) {
+	// User endpoints
+	user := group.Group("/user", s.AccountScopeMiddleware())
+	{
+		user.GET("/profile", s.getUserProfile)
+		user.GET("/inventory", ETagMiddleware(), s.getUserInventory)
+		user.POST("/inventory/refresh", s.refreshUserInventory)
+	}
+
+	// Inventory endpoints
+	inventory := group.Group("/inventory", s.AccountScopeMiddleware())
+	{
+		inventory.GET("/export", s.exportInventory)
+	}
+
+	// Campaigns endpoints
+	campaigns := group.Group("/campaigns", s.AccountScopeMiddleware())
+	{
+		campaigns.GET("/", ETagMiddleware(), s.getCampaigns)
+		campaigns.GET("/upcoming", s.getUpcomingCampaigns)
+		campaigns.GET("/calendar.ics", s.getCampaignCalendar)
+		campaigns.PUT("/priority", s.setCampaignPriority)
+		campaigns.GET("/:id", s.getCampaign)
+		campaigns.GET("/:id/drops", s.getCampaignDrops)
+		campaigns.POST("/:id/pin", s.pinCampaign)
+		campaigns.POST("/:id/ignore", s.ignoreCampaign)
+	}
+
+	// Drop endpoints
+	dropsGroup := group.Group("/drops", s.AccountScopeMiddleware())
+	{
+		dropsGroup.POST("/:instanceID/claim", s.claimDrop)
+	}
+
+	// Claim endpoints
+	claims := group.Group("/claims", s.AccountScopeMiddleware())
+	{
+		claims.POST("/pending", s.claimPendingDrops)
+		claims.GET("/history", s.getClaimHistory)
+	}
+
+	// Session history endpoints
+	group.GET("/sessions", s.AccountScopeMiddleware(), s.getSessionHistory)
+
+	// Miner endpoints
+	miner := group.Group("/miner", s.AccountScopeMiddleware())
+	{
+		miner.GET("/status", ETagMiddleware(), s.getMinerStatus)
+		miner.GET("/current-drop", ETagMiddleware(), s.getCurrentDrop)
+		miner.GET("/progress", ETagMiddleware(), s.getDropProgress)
+		miner.GET("/logs", s.getMinerLogs)
+		miner.GET("/logs/stream", s.streamMinerLogs)
+		miner.GET("/points", s.getChannelPoints)
+		miner.POST("/start", s.startMiner)
+		miner.POST("/stop", s.stopMiner)
+		miner.POST("/pause", s.pauseMiner)
+		miner.POST("/resume", s.resumeMiner)
+		miner.POST("/switch", s.switchCampaign)
+		miner.DELETE("/switch", s.clearForcedCampaign)
+	}
+
+	// Streams endpoints
+	streams := group.Group("/streams", s.AccountScopeMiddleware())
+	{
+		streams.GET("/game/:gameId", s.getStreamsForGame)
+		streams.GET("/current", s.getCurrentStream)
+	}
+
+	// Stats endpoints
+	stats := group.Group("/stats")
+	{
+		stats.GET("", s.AccountScopeMiddleware(), s.getStats)
+		stats.GET("/games", s.AccountScopeMiddleware(), s.getGameStats)
+		stats.GET("/runtime", s.AccountScopeMiddleware(), s.getRuntimeStats)
+	}
+}
+
 func (s *Server) runWebSocketHub() {
-	// Listen for status updates from miner
-	go func() {
-		for status := range s.miner.GetStatusChannel() {
-			s.broadcastStatus(status)
-		}
-	}()
+	// Listen for status, log and campaign updates from miner
+	s.forwardMinerEvents("", s.twitchClient, s.miner)
+
+	// Latest status and campaign listing by account, sent to new subscribers
+	lastStatus := make(map[string]wsMessage)
+	lastCampaigns := make(map[string][]byte)
+
+	// Keep idle connections alive, and notice the ones that went away without closing
+	pingTicker := time.NewTicker(wsPingInterval)
+	defer pingTicker.Stop()
 
 	// Handle WebSocket connections
 	for {
 		select {
-		case conn := <-s.wsRegister:
-			s.wsConnections[conn] = true
+		case client := <-s.wsRegister:
+			// handleWebSocket checks too, this catches connections upgraded at the same time
+			if max := s.config().WSMaxClients; max > 0 && len(s.wsConnections) >= max {
+				client.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too many connections"),
+					time.Now().Add(wsWriteTimeout))
+				client.conn.Close()
+				logrus.Warnf("Refused WebSocket client, %d connections open", len(s.wsConnections))
+				continue
+			}
+			s.wsConnections[client.conn] = client
+			s.wsOpen.Store(int64(len(s.wsConnections)))
 			logrus.Info("WebSocket client connected")
 
+		case req := <-s.wsSubscribe:
+			if client, ok := s.wsConnections[req.conn]; ok {
+				s.subscribe(client, req, lastStatus, lastCampaigns)
+			}
+
 		case conn := <-s.wsUnregister:
 			if _, ok := s.wsConnections[conn]; ok {
-				delete(s.wsConnections, conn)
-				conn.Close()
+				s.dropWS(conn)
 				logrus.Info("WebSocket client disconnected")
 			}
 
+		case <-pingTicker.C:
+			s.pingWS()
+
+		case done := <-s.wsShutdown:
+			s.closeWebSockets()
+			close(s.wsStopped)
+			close(done)
+			return
+
 		case message := <-s.wsBroadcast:
-			for conn := range s.wsConnections {
-				select {
-				case <-time.After(time.Second):
-					// Write timeout, close connection
-					delete(s.wsConnections, conn)
-					conn.Close()
-				default:
-					if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
-						delete(s.wsConnections, conn)
-						conn.Close()
-					}
+			switch message.topic {
+			case wsTopicStatus:
+				lastStatus[message.accountID] = message
+			case wsTopicCampaigns:
+				lastCampaigns[message.accountID] = message.data
+			}
+
+			for _, client := range s.wsConnections {
+				if message.global || client.accountID == message.accountID {
+					s.deliver(client, message)
 				}
 			}
 		}
 	}
 }
 
-func (s *Server) broadcastStatus(status *drops.MinerStatus) {
+// broadcastAccountStatus sends a status update to the connections following accountID
+func (s *Server) broadcastAccountStatus(accountID string, client *twitch.Client, status *drops.MinerStatus) {
 	// Get enhanced progress data like the /api/miner/progress endpoint
-	enhancedData := s.getEnhancedStatusData(status)
+	enhancedData := s.getEnhancedStatusData(status, client)
 
-	data, err := json.Marshal(map[string]interface{}{
-		"type": "status_update",
-		"data": enhancedData,
-	})
+	data, err := encodeWSMessage("status_update", accountID, enhancedData)
 	if err != nil {
 		logrus.Errorf("Failed to marshal status: %v", err)
 		return
 	}
 
+	activeDrops, _ := enhancedData["active_drops"].([]drops.ActiveDrop)
 	select {
-	case s.wsBroadcast <- data:
+	case s.wsBroadcast <- wsMessage{accountID: accountID, topic: wsTopicStatus, data: data, activeDrops: activeDrops}:
 	default:
 		// Channel is full, skip this update
 	}
 }
 
-func (s *Server) getEnhancedStatusData(status *drops.MinerStatus) map[string]interface{} {
+func (s *Server) getEnhancedStatusData(status *drops.MinerStatus, client *twitch.Client) map[string]interface{} {
 	// Start with basic status
 	result := map[string]interface{}{
 		"is_running":       status.IsRunning,
@@ -243,7 +489,14 @@ func (s *Server) getEnhancedStatusData(status *drops.MinerStatus) map[string]int
 		"last_update":      status.LastUpdate,
 		"next_switch":      status.NextSwitch,
 		"error_message":    status.ErrorMessage,
+		"queue_estimate":   status.QueueEstimate,
+		"forecast":         status.Forecast,
 		"active_drops":     []drops.ActiveDrop{},
+
+		"outside_schedule":     status.OutsideSchedule,
+		"next_schedule_change": status.NextScheduleChange,
+		"paused":               status.Paused,
+		"paused_at":            status.PausedAt,
 	}
 
 	// If miner is running, get real-time progress data using utility functions
@@ -251,7 +504,7 @@ func (s *Server) getEnhancedStatusData(status *drops.MinerStatus) map[string]int
 		ctx := context.Background()
 
 		// Generate active drops with real-time progress using utility function
-		activeDrops, err := util.GenerateActiveDrops(ctx, s.twitchClient, status.CurrentCampaign, status.CurrentStream)
+		activeDrops, err := util.GenerateActiveDrops(ctx, client, status.CurrentCampaign, status.CurrentStream)
 		if err != nil {
 			logrus.Debugf("Failed to generate active drops for WebSocket: %v", err)
 			// Keep empty activeDrops array as fallback
@@ -265,34 +518,72 @@ func (s *Server) getEnhancedStatusData(status *drops.MinerStatus) map[string]int
 }
 
 func (s *Server) handleWebSocket(c *gin.Context) {
+	select {
+	case <-s.wsStopped:
+		respondError(c, apierror.ErrUnavailable.WithMessage("Server is shutting down"))
+		return
+	default:
+	}
+	if max := s.config().WSMaxClients; max > 0 && s.wsOpen.Load() >= int64(max) {
+		respondError(c, apierror.ErrUnavailable.WithMessage("Too many WebSocket connections").
+			WithDetails(fmt.Sprintf("at most %d are allowed, see ws_max_clients", max)))
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		logrus.Errorf("Failed to upgrade WebSocket: %v", err)
+		requestLog(c).Errorf("Failed to upgrade WebSocket: %v", err)
 		return
 	}
 
-	s.wsRegister <- conn
+	accountID := ""
+	if account := accountFromContext(c); account != nil {
+		accountID = account.ID
+	}
+	select {
+	case s.wsRegister <- &wsClient{conn: conn, accountID: accountID, encoding: wsEncoding(c, conn)}:
+	case <-s.wsStopped:
+		conn.Close()
+		return
+	}
 
-	// Handle incoming messages
+	// Handle incoming messages, the only ones understood are topic subscriptions
 	go func() {
 		defer func() {
-			s.wsUnregister <- conn
+			select {
+			case s.wsUnregister <- conn:
+			case <-s.wsStopped:
+			}
 		}()
 
+		// Browsers answer the hub's pings on their own, each pong gives the connection another wsPongWait
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+
 		for {
-			_, _, err := conn.ReadMessage()
+			messageType, data, err := conn.ReadMessage()
 			if err != nil {
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					logrus.Errorf("WebSocket error: %v", err)
 				}
 				break
 			}
+
+			req := wsSubscription{conn: conn}
+			req.err = decodeWSMessage(messageType, data, &req)
+			select {
+			case s.wsSubscribe <- req:
+			case <-s.wsStopped:
+				return
+			}
 		}
 	}()
 
 	// Send initial status
-	status := s.miner.GetStatus()
-	s.broadcastStatus(status)
+	status := s.minerFor(c).GetStatus()
+	s.broadcastAccountStatus(accountID, s.clientFor(c), status)
 }
 
 // Cleanup properly cancels the miner context and closes connections
@@ -302,8 +593,43 @@ func (s *Server) Cleanup() {
 		s.minerCancel()
 	}
 
+	if s.accounts != nil {
+		s.accounts.StopAll()
+	}
+
 	// Close all WebSocket connections
 	for conn := range s.wsConnections {
 		conn.Close()
 	}
 }
+
+// NotifyShutdown sends a shutting_down event to every WebSocket client, closes the connections and stops the hub;
+// connections attempted afterwards are refused
+func (s *Server) NotifyShutdown(ctx context.Context) {
+	done := make(chan struct{})
+	select {
+	case s.wsShutdown <- done:
+	case <-ctx.Done():
+		return
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// closeWebSockets says goodbye to every connection, from the hub
+func (s *Server) closeWebSockets() {
+	for conn, client := range s.wsConnections {
+		if encoded, err := encodeWSMessage("shutting_down", client.accountID, gin.H{}); err == nil {
+			s.writeWS(client, encoded)
+		}
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(wsWriteTimeout))
+		delete(s.wsConnections, conn)
+		conn.Close()
+	}
+	s.wsOpen.Store(0)
+	logrus.Info("Closed WebSocket connections for shutdown")
+}
@@ -0,0 +1,198 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/audit"
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Name of the storage document holding dashboard sessions
+const sessionsDocument = "web_sessions"
+
+// Cookie carrying the dashboard session
+const sessionCookie = "tdf_session"
+
+// How long a password login lasts
+const sessionLifetime = 30 * 24 * time.Hour
+
+// Principal recorded in the audit log for password sessions
+const sessionPrincipal = "dashboard"
+
+// Delay before answering a wrong password, so it can't be guessed quickly
+const failedLoginDelay = time.Second
+
+// webSessions are the dashboard sessions created with the password, by HMAC-SHA256 of the cookie value keyed
+// with the password, so changing or clearing the password ends every session
+type webSessions struct {
+	mu       sync.Mutex
+	store    storage.Store
+	loaded   bool
+	sessions map[string]time.Time // expiry by session hash
+}
+
+func hashSession(password, token string) string {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// loadLocked reads the sessions from storage on first use, the caller holds mu
+func (w *webSessions) loadLocked() {
+	if w.loaded {
+		return
+	}
+	w.loaded = true
+	w.sessions = make(map[string]time.Time)
+	if w.store == nil {
+		return
+	}
+	if err := w.store.Load(sessionsDocument, &w.sessions); err != nil {
+		logrus.Errorf("Failed to load dashboard sessions: %v", err)
+	}
+}
+
+// reload drops the sessions in memory, so the next use reads them from storage again
+func (w *webSessions) reload() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.loaded = false
+}
+
+// saveLocked drops expired sessions and writes the rest to storage, the caller holds mu
+func (w *webSessions) saveLocked() {
+	now := time.Now()
+	for hash, expiry := range w.sessions {
+		if expiry.Before(now) {
+			delete(w.sessions, hash)
+		}
+	}
+	if w.store == nil {
+		return
+	}
+	if err := w.store.Save(sessionsDocument, w.sessions); err != nil {
+		logrus.Errorf("Failed to save dashboard sessions: %v", err)
+	}
+}
+
+// create starts a session for the current password and returns its cookie value
+func (w *webSessions) create(password string) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	expiry := time.Now().Add(sessionLifetime)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.loadLocked()
+	w.sessions[hashSession(password, token)] = expiry
+	w.saveLocked()
+	return token, expiry, nil
+}
+
+// valid reports whether a cookie value belongs to a live session created with password
+func (w *webSessions) valid(password, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.loadLocked()
+	expiry, ok := w.sessions[hashSession(password, token)]
+	return ok && time.Now().Before(expiry)
+}
+
+// remove ends a session
+func (w *webSessions) remove(password, token string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.loadLocked()
+	delete(w.sessions, hashSession(password, token))
+	w.saveLocked()
+}
+
+// authRequired reports whether requests need a password session or API key
+func (s *Server) authRequired() bool {
+	return len(s.config().APIKeys) > 0 || s.config().WebPassword != ""
+}
+
+// hasSession reports whether the request carries a live password session
+func (s *Server) hasSession(c *gin.Context) bool {
+	if s.config().WebPassword == "" {
+		return false
+	}
+	token, err := c.Cookie(sessionCookie)
+	return err == nil && s.sessions.valid(s.config().WebPassword, token)
+}
+
+// setSessionCookie sets or, with an empty token, clears the session cookie
+func setSessionCookie(c *gin.Context, token string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookie, token, maxAge, "/", "", secure, true)
+}
+
+// Session handlers
+func (s *Server) getSession(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"auth_required":  s.authRequired(),
+		"password_login": s.config().WebPassword != "",
+		"authenticated":  !s.authRequired() || s.hasSession(c),
+	})
+}
+
+func (s *Server) createSession(c *gin.Context) {
+	if s.config().WebPassword == "" {
+		respondError(c, apierror.ErrNotFound.WithMessage("Password login is not enabled"))
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apierror.ErrInvalidRequest.WithDetails(err.Error()))
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(req.Password), []byte(s.config().WebPassword)) != 1 {
+		requestLog(c).Warnf("Failed dashboard login from %s", c.ClientIP())
+		time.Sleep(failedLoginDelay)
+		respondError(c, apierror.ErrUnauthorized.WithMessage("Wrong password"))
+		return
+	}
+
+	token, expiry, err := s.sessions.create(s.config().WebPassword)
+	if err != nil {
+		requestLog(c).Errorf("Failed to create dashboard session: %v", err)
+		respondError(c, apierror.ErrInternal.WithMessage("Failed to create session"))
+		return
+	}
+
+	setSessionCookie(c, token, int(sessionLifetime.Seconds()))
+	c.Set(principalContextKey, sessionPrincipal)
+	s.recordAudit(c, audit.ActionSessionCreate, "")
+	c.JSON(http.StatusOK, gin.H{"success": true, "expires_at": expiry})
+}
+
+func (s *Server) deleteSession(c *gin.Context) {
+	if token, err := c.Cookie(sessionCookie); err == nil {
+		s.sessions.remove(s.config().WebPassword, token)
+	}
+	setSessionCookie(c, "", -1)
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"twitchdropsfarmer/internal/config"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ListenAndServe runs server over HTTPS when a certificate or autocert hosts are configured, otherwise over HTTP
+// Like http.Server.ListenAndServe it returns http.ErrServerClosed after Shutdown
+func ListenAndServe(cfg *config.Config, server *http.Server) error {
+	switch {
+	case cfg.TLSCert != "" || cfg.TLSKey != "":
+		if cfg.TLSCert == "" || cfg.TLSKey == "" {
+			return fmt.Errorf("TLS_CERT and TLS_KEY must be set together")
+		}
+		logrus.Infof("Starting HTTPS server on %s", server.Addr)
+		return server.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+
+	case len(cfg.AutocertHosts) > 0:
+		manager := newAutocertManager(cfg)
+		server.TLSConfig = manager.TLSConfig()
+
+		if cfg.AutocertHTTP != "" {
+			// Answers HTTP-01 challenges and redirects everything else to HTTPS
+			challenges := &http.Server{Addr: cfg.AutocertHTTP, Handler: manager.HTTPHandler(nil)}
+			server.RegisterOnShutdown(func() { challenges.Close() })
+			go func() {
+				if err := challenges.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logrus.Errorf("Failed to start HTTP challenge server on %s, only TLS-ALPN challenges on port 443 will work: %v", cfg.AutocertHTTP, err)
+				}
+			}()
+		}
+
+		logrus.Infof("Starting HTTPS server on %s with Let's Encrypt certificates for %s", server.Addr, strings.Join(manager.hosts, ", "))
+		return server.ListenAndServeTLS("", "")
+
+	default:
+		logrus.Infof("Starting server on %s", server.Addr)
+		return server.ListenAndServe()
+	}
+}
+
+// autocertManager is an autocert.Manager with the hosts it may request certificates for
+type autocertManager struct {
+	*autocert.Manager
+	hosts []string
+}
+
+// newAutocertManager gets certificates for the allowed hosts only, so requests naming any other host
+// can't make the server request certificates on their behalf
+func newAutocertManager(cfg *config.Config) autocertManager {
+	var hosts []string
+	for _, host := range cfg.AutocertHosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return autocertManager{
+		Manager: &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(hosts...),
+			Cache:      autocert.DirCache(filepath.Join(cfg.DataDir, "autocert")),
+			Email:      cfg.AutocertEmail,
+		},
+		hosts: hosts,
+	}
+}
@@ -0,0 +1,77 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// WebSocket message encodings, chosen when connecting with the Sec-WebSocket-Protocol header or ?encoding=
+// for clients that can't set headers
+const (
+	wsEncodingJSON    = "json"    // text frames, the default
+	wsEncodingMsgpack = "msgpack" // MessagePack in binary frames, smaller and parsed without a JSON library
+)
+
+// wsEncoding returns the encoding a new connection asked for
+func wsEncoding(c *gin.Context, conn *websocket.Conn) string {
+	if conn.Subprotocol() == wsEncodingMsgpack || c.Query("encoding") == wsEncodingMsgpack {
+		return wsEncodingMsgpack
+	}
+	return wsEncodingJSON
+}
+
+// msgpackFromJSON re-encodes a JSON message as MessagePack; numbers without a fraction stay integers, so minutes
+// and counters pack into a byte or two
+func msgpackFromJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var message interface{}
+	if err := decoder.Decode(&message); err != nil {
+		return nil, err
+	}
+
+	var packed bytes.Buffer
+	encoder := msgpack.NewEncoder(&packed)
+	encoder.UseCompactInts(true)
+	encoder.UseCompactFloats(true)
+	if err := encoder.Encode(packNumbers(message)); err != nil {
+		return nil, err
+	}
+	return packed.Bytes(), nil
+}
+
+// packNumbers replaces the json.Numbers of a decoded message with integers or floats
+func packNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = packNumbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = packNumbers(value)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// decodeWSMessage reads a message from a client, JSON in text frames and MessagePack in binary ones, whatever
+// encoding the connection receives
+func decodeWSMessage(messageType int, data []byte, v interface{}) error {
+	if messageType == websocket.BinaryMessage {
+		decoder := msgpack.NewDecoder(bytes.NewReader(data))
+		decoder.SetCustomStructTag("json")
+		return decoder.Decode(v)
+	}
+	return json.Unmarshal(data, v)
+}
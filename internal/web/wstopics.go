@@ -0,0 +1,292 @@
+package web
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"twitchdropsfarmer/internal/apierror"
+	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/twitch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// WebSocket topics a connection can subscribe to with {"subscribe": [...]}
+const (
+	wsTopicStatus    = "status"    // "status_update" with the full status, on every change
+	wsTopicProgress  = "progress"  // "progress" with every active drop, then "progress_patch" with the changed ones
+	wsTopicLogs      = "logs"      // "log" for every miner log entry
+	wsTopicCampaigns = "campaigns" // "campaigns" with the campaign listing whenever it changes
+	wsTopicEvents    = "events"    // "event" for every event published on the bus
+)
+
+// How long a write to a connection may take before it is dropped
+const wsWriteTimeout = time.Second
+
+// The hub pings every connection every wsPingInterval; connections that stay silent for wsPongWait, pongs
+// included, are dropped
+const (
+	wsPingInterval = 30 * time.Second
+	wsPongWait     = 75 * time.Second
+)
+
+var wsTopics = map[string]bool{
+	wsTopicStatus:    true,
+	wsTopicProgress:  true,
+	wsTopicLogs:      true,
+	wsTopicCampaigns: true,
+	wsTopicEvents:    true,
+}
+
+// wsClient is a WebSocket connection following one account
+type wsClient struct {
+	conn      *websocket.Conn
+	accountID string
+	encoding  string // wsEncodingJSON or wsEncodingMsgpack
+
+	// Subscribed topics, nil until the first subscribe message, which means status only
+	topics map[string]bool
+	// Active drops last sent on the progress topic, by drop ID
+	progress map[string]drops.ActiveDrop
+}
+
+// wsMessage is a WebSocket payload for the connections following accountID
+type wsMessage struct {
+	accountID string
+	topic     string
+	data      []byte
+
+	// With wsTopicStatus, the active drops the progress topic is built from
+	activeDrops []drops.ActiveDrop
+
+	// Sent to every connection, whichever account and topics it follows
+	global bool
+}
+
+// wsSubscription is a subscribe or unsubscribe request read from a connection
+type wsSubscription struct {
+	conn        *websocket.Conn
+	Subscribe   []string `json:"subscribe"`
+	Unsubscribe []string `json:"unsubscribe"`
+	err         error
+}
+
+// subscribed reports whether the client receives a topic
+func (c *wsClient) subscribed(topic string) bool {
+	if c.topics == nil {
+		return topic == wsTopicStatus
+	}
+	return c.topics[topic]
+}
+
+// forwardMinerEvents broadcasts the status, log and campaign updates of a miner to the connections following accountID
+func (s *Server) forwardMinerEvents(accountID string, client *twitch.Client, miner *drops.Miner) {
+	go func() {
+		for status := range miner.GetStatusChannel() {
+			s.broadcastAccountStatus(accountID, client, status)
+		}
+	}()
+	go func() {
+		for entry := range miner.GetLogChannel() {
+			s.broadcastTopic(accountID, wsTopicLogs, "log", entry)
+		}
+	}()
+	go func() {
+		for campaigns := range miner.GetCampaignsChannel() {
+			s.broadcastTopic(accountID, wsTopicCampaigns, "campaigns", campaigns)
+		}
+	}()
+}
+
+// Handle forwards the events published on the bus to the subscribers of the events topic
+func (s *Server) Handle(event events.Event) {
+	s.broadcastTopic(event.AccountID, wsTopicEvents, "event", event)
+}
+
+// broadcastTopic sends data as a message of the given type to the subscribers of topic
+func (s *Server) broadcastTopic(accountID, topic, messageType string, data interface{}) {
+	encoded, err := encodeWSMessage(messageType, accountID, data)
+	if err != nil {
+		logrus.Errorf("Failed to marshal %s message: %v", messageType, err)
+		return
+	}
+
+	select {
+	case s.wsBroadcast <- wsMessage{accountID: accountID, topic: topic, data: encoded}:
+	default:
+		// Channel is full, skip this update
+	}
+}
+
+func encodeWSMessage(messageType, accountID string, data interface{}) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":       messageType,
+		"account_id": accountID,
+		"data":       data,
+	})
+}
+
+// deliver sends a broadcast to one client, if it is subscribed to the message's topic
+func (s *Server) deliver(client *wsClient, message wsMessage) {
+	if message.global || client.subscribed(message.topic) {
+		s.writeWS(client, message.data)
+	}
+	if message.topic == wsTopicStatus && client.subscribed(wsTopicProgress) {
+		s.sendProgress(client, message.activeDrops)
+	}
+}
+
+// sendProgress sends the active drops on the first call and only the drops that changed afterwards
+func (s *Server) sendProgress(client *wsClient, activeDrops []drops.ActiveDrop) {
+	current := make(map[string]drops.ActiveDrop, len(activeDrops))
+	for _, drop := range activeDrops {
+		current[drop.ID] = drop
+	}
+
+	var messageType string
+	var data interface{}
+	if client.progress == nil {
+		messageType = "progress"
+		data = gin.H{"drops": activeDrops}
+	} else {
+		updated := []drops.ActiveDrop{}
+		for _, drop := range activeDrops {
+			if previous, ok := client.progress[drop.ID]; !ok || progressChanged(previous, drop) {
+				updated = append(updated, drop)
+			}
+		}
+		removed := []string{}
+		for id := range client.progress {
+			if _, ok := current[id]; !ok {
+				removed = append(removed, id)
+			}
+		}
+		if len(updated) == 0 && len(removed) == 0 {
+			return
+		}
+		sort.Strings(removed)
+		messageType = "progress_patch"
+		data = gin.H{"updated": updated, "removed": removed}
+	}
+	client.progress = current
+
+	encoded, err := encodeWSMessage(messageType, client.accountID, data)
+	if err != nil {
+		logrus.Errorf("Failed to marshal progress: %v", err)
+		return
+	}
+	s.writeWS(client, encoded)
+}
+
+// progressChanged compares the progress of a drop, ignoring the times that move with every update
+func progressChanged(a, b drops.ActiveDrop) bool {
+	return a.CurrentMinutes != b.CurrentMinutes ||
+		a.RequiredMinutes != b.RequiredMinutes ||
+		a.RemainingMinutes != b.RemainingMinutes ||
+		a.IsClaimed != b.IsClaimed ||
+		(a.ClaimableAt == nil) != (b.ClaimableAt == nil)
+}
+
+// subscribe applies a subscription request and sends the current state of the newly subscribed topics
+func (s *Server) subscribe(client *wsClient, req wsSubscription, lastStatus map[string]wsMessage, lastCampaigns map[string][]byte) {
+	if req.err != nil {
+		s.writeWSError(client, "Invalid message: "+req.err.Error())
+		return
+	}
+	for _, topic := range append(append([]string{}, req.Subscribe...), req.Unsubscribe...) {
+		if !wsTopics[topic] {
+			s.writeWSError(client, "Unknown topic: "+topic)
+			return
+		}
+	}
+
+	if client.topics == nil {
+		client.topics = make(map[string]bool)
+	}
+	for _, topic := range req.Unsubscribe {
+		delete(client.topics, topic)
+		if topic == wsTopicProgress {
+			client.progress = nil
+		}
+	}
+
+	status, hasStatus := lastStatus[client.accountID]
+	for _, topic := range req.Subscribe {
+		if client.topics[topic] {
+			continue
+		}
+		client.topics[topic] = true
+
+		switch topic {
+		case wsTopicStatus:
+			if hasStatus {
+				s.writeWS(client, status.data)
+			}
+		case wsTopicProgress:
+			client.progress = nil
+			if hasStatus {
+				s.sendProgress(client, status.activeDrops)
+			}
+		case wsTopicCampaigns:
+			if campaigns, ok := lastCampaigns[client.accountID]; ok {
+				s.writeWS(client, campaigns)
+			}
+		}
+	}
+
+	topics := make([]string, 0, len(client.topics))
+	for topic := range client.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	if encoded, err := json.Marshal(gin.H{"type": "subscribed", "account_id": client.accountID, "topics": topics}); err == nil {
+		s.writeWS(client, encoded)
+	}
+}
+
+func (s *Server) writeWSError(client *wsClient, message string) {
+	if encoded, err := json.Marshal(gin.H{"type": "error", "code": apierror.ErrInvalidRequest.Code, "error": message}); err == nil {
+		s.writeWS(client, encoded)
+	}
+}
+
+// writeWS writes to a connection from the hub, dropping the connection when that fails
+func (s *Server) writeWS(client *wsClient, data []byte) {
+	if _, ok := s.wsConnections[client.conn]; !ok {
+		return
+	}
+	messageType := websocket.TextMessage
+	if client.encoding == wsEncodingMsgpack {
+		packed, err := msgpackFromJSON(data)
+		if err != nil {
+			logrus.Errorf("Failed to encode WebSocket message as MessagePack: %v", err)
+			return
+		}
+		data, messageType = packed, websocket.BinaryMessage
+	}
+
+	client.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := client.conn.WriteMessage(messageType, data); err != nil {
+		s.dropWS(client.conn)
+	}
+}
+
+// pingWS pings every connection from the hub, dropping the ones the ping can't be written to
+func (s *Server) pingWS() {
+	for conn := range s.wsConnections {
+		if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+			s.dropWS(conn)
+		}
+	}
+}
+
+// dropWS closes a connection and forgets it, from the hub
+func (s *Server) dropWS(conn *websocket.Conn) {
+	delete(s.wsConnections, conn)
+	s.wsOpen.Store(int64(len(s.wsConnections)))
+	conn.Close()
+}
@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"twitchdropsfarmer/internal/events"
+)
+
+// DefaultTemplate is the key of the template used for event types without their own
+const DefaultTemplate = "default"
+
+// templates are the parsed payload templates; without any the event is sent as JSON as is
+type templates struct {
+	byType   map[events.Type]*template.Template
+	fallback *template.Template
+}
+
+// templateFuncs are available in payload templates, json writing a value as a JSON literal, e.g.
+// {"content": {{json .Message}}}
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+// ValidateTemplates reports why webhook_templates can't be used, nil when they can
+func ValidateTemplates(sources map[string]string) error {
+	_, err := parseTemplates(sources)
+	return err
+}
+
+// parseTemplates parses the templates by event type and checks each renders valid JSON for a sample event
+func parseTemplates(sources map[string]string) (*templates, error) {
+	parsed := &templates{byType: make(map[events.Type]*template.Template)}
+	for name, source := range sources {
+		if name != DefaultTemplate && !ValidEventType(name) {
+			return nil, fmt.Errorf("template for unknown event type %q", name)
+		}
+		tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", name, err)
+		}
+		if name == DefaultTemplate {
+			parsed.fallback = tmpl
+		} else {
+			parsed.byType[events.Type(name)] = tmpl
+		}
+	}
+
+	for _, eventType := range events.Types {
+		if _, err := parsed.render(sampleEvent(eventType)); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// render builds the payload of an event
+func (t *templates) render(event events.Event) ([]byte, error) {
+	tmpl := t.byType[event.Type]
+	if tmpl == nil {
+		tmpl = t.fallback
+	}
+	if tmpl == nil {
+		return json.Marshal(event)
+	}
+
+	var payload bytes.Buffer
+	if err := tmpl.Execute(&payload, event); err != nil {
+		return nil, fmt.Errorf("%s template: %w", tmpl.Name(), err)
+	}
+	if !json.Valid(payload.Bytes()) {
+		return nil, fmt.Errorf("%s template doesn't render JSON for %s events", tmpl.Name(), event.Type)
+	}
+	return payload.Bytes(), nil
+}
+
+// sampleEvent is an event of eventType with every field its kind has, for test deliveries and template checks
+func sampleEvent(eventType events.Type) events.Event {
+	endsAt := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	campaign := &events.Campaign{ID: "test-campaign", Name: "Test Campaign", GameName: "Test Game", EndsAt: &endsAt}
+	event := events.Event{
+		Type:     eventType,
+		Time:     time.Now(),
+		Login:    "testuser",
+		Title:    "Test webhook",
+		Message:  fmt.Sprintf("Test %s event from TwitchDropsFarmer", eventType),
+		Campaign: campaign,
+	}
+	switch eventType {
+	case events.DropClaimed:
+		event.Drop = &events.Drop{ID: "test-drop", Name: "Test Drop", Rewards: []string{"Test Reward"}, Source: "watch"}
+	case events.CampaignStarted, events.StreamSwitched, events.MiningStalled:
+		event.Channel = "testchannel"
+	case events.AuthExpired:
+		event.Campaign = nil
+	case events.MinerError:
+		event.Campaign = nil
+		event.Error = event.Message
+	}
+	return event
+}
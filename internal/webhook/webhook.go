@@ -0,0 +1,322 @@
+// Package webhook posts the miner events to a generic webhook: signed JSON, optionally shaped by a Go template
+// per event type, retried with exponential backoff and kept as a dead letter when every attempt failed.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Headers sent with every delivery; the signature is only sent with a secret
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature" // sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+)
+
+// Attempts per delivery, waiting retryBackoff before the first retry and twice as long before each next one
+const (
+	maxAttempts  = 6
+	retryBackoff = 5 * time.Second
+)
+
+// Deliveries waiting to be sent; events published while it is full go straight to the dead letters
+const queueSize = 100
+
+// Name of the storage document holding the dead letters, and how many are kept
+const (
+	deadLetterDocument = "webhook_dead_letters"
+	maxDeadLetters     = 50
+)
+
+// Config holds the webhook settings
+type Config struct {
+	URL       string
+	Secret    string            // HMAC key deliveries are signed with, empty to send them unsigned
+	Events    []string          // event types sent, empty for all
+	Templates map[string]string // payload template by event type, "default" for the types without one
+}
+
+// NewConfig takes the webhook settings from the application configuration
+func NewConfig(cfg *config.Config) Config {
+	return Config{
+		URL:       cfg.WebhookURL,
+		Secret:    cfg.WebhookSecret,
+		Events:    cfg.WebhookEvents,
+		Templates: cfg.WebhookTemplates,
+	}
+}
+
+// DeadLetter is a delivery that failed every attempt
+type DeadLetter struct {
+	ID       string      `json:"id"`
+	Event    events.Type `json:"event"`
+	Time     time.Time   `json:"time"` // when it was given up on
+	Attempts int         `json:"attempts"`
+	Error    string      `json:"error"`
+	Payload  string      `json:"payload"`
+}
+
+// Webhook is the events sink posting to the configured URL
+type Webhook struct {
+	store      storage.Store
+	httpClient *http.Client
+	queue      chan delivery
+
+	mu          sync.RWMutex
+	config      Config
+	events      map[events.Type]bool // nil for all
+	templates   *templates
+	deadLetters []DeadLetter
+}
+
+// delivery is one event to post
+type delivery struct {
+	id      string
+	event   events.Type
+	url     string
+	secret  string
+	payload []byte
+}
+
+// statusError is a response deliveries may be retried after
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook returned status: %d", e.status)
+}
+
+// New creates the webhook sink; call SetConfig, subscribe it to the bus, then Run
+func New(store storage.Store) *Webhook {
+	w := &Webhook{
+		store:      store,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		queue:      make(chan delivery, queueSize),
+	}
+	if err := store.Load(deadLetterDocument, &w.deadLetters); err != nil {
+		logrus.Errorf("Failed to load webhook dead letters: %v", err)
+	}
+	return w
+}
+
+// SetConfig applies new settings, keeping the current ones when a template or event type is invalid
+func (w *Webhook) SetConfig(config Config) error {
+	templates, err := parseTemplates(config.Templates)
+	if err != nil {
+		return err
+	}
+	var types map[events.Type]bool
+	if len(config.Events) > 0 {
+		types = make(map[events.Type]bool, len(config.Events))
+		for _, name := range config.Events {
+			if !ValidEventType(name) {
+				return fmt.Errorf("unknown event type %q", name)
+			}
+			types[events.Type(name)] = true
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.config = config
+	w.events = types
+	w.templates = templates
+	return nil
+}
+
+// ValidEventType reports whether name is an event type webhooks can be sent for
+func ValidEventType(name string) bool {
+	for _, eventType := range events.Types {
+		if string(eventType) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle queues the event when a webhook URL is set and the event type is sent
+func (w *Webhook) Handle(event events.Event) {
+	w.mu.RLock()
+	config, types, templates := w.config, w.events, w.templates
+	w.mu.RUnlock()
+	if config.URL == "" || (types != nil && !types[event.Type]) {
+		return
+	}
+
+	d, err := newDelivery(config, templates, event)
+	if err != nil {
+		logrus.Errorf("Failed to build webhook payload for %s: %v", event.Type, err)
+		return
+	}
+	select {
+	case w.queue <- d:
+	default:
+		w.deadLetter(d, 0, errors.New("delivery queue full"))
+	}
+}
+
+// Run sends the queued deliveries one at a time, in order, until ctx is cancelled
+func (w *Webhook) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-w.queue:
+			w.deliver(ctx, d)
+		}
+	}
+}
+
+// deliver posts d until it succeeds, fails for good, or runs out of attempts
+func (w *Webhook) deliver(ctx context.Context, d delivery) {
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.post(ctx, d)
+		if err == nil {
+			logrus.Debugf("Sent webhook %s (%s)", d.id, d.event)
+			return
+		}
+		if attempt == maxAttempts || !retryable(err) || ctx.Err() != nil {
+			w.deadLetter(d, attempt, err)
+			return
+		}
+
+		logrus.Warnf("Webhook %s (%s) failed, retrying in %s: %v", d.id, d.event, backoff, err)
+		select {
+		case <-ctx.Done():
+			w.deadLetter(d, attempt, err)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Test posts a sample event of eventType right away with a single attempt, to check the webhook settings
+func (w *Webhook) Test(ctx context.Context, eventType events.Type) error {
+	w.mu.RLock()
+	config, templates := w.config, w.templates
+	w.mu.RUnlock()
+	if config.URL == "" {
+		return errors.New("no webhook_url configured")
+	}
+
+	d, err := newDelivery(config, templates, sampleEvent(eventType))
+	if err != nil {
+		return err
+	}
+	return w.post(ctx, d)
+}
+
+// DeadLetters returns the deliveries that failed, most recent last
+func (w *Webhook) DeadLetters() []DeadLetter {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return append([]DeadLetter{}, w.deadLetters...)
+}
+
+func newDelivery(config Config, templates *templates, event events.Event) (delivery, error) {
+	payload, err := templates.render(event)
+	if err != nil {
+		return delivery{}, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return delivery{}, err
+	}
+	return delivery{
+		id:      hex.EncodeToString(id),
+		event:   event.Type,
+		url:     config.URL,
+		secret:  config.Secret,
+		payload: payload,
+	}, nil
+}
+
+func (w *Webhook) post(ctx context.Context, d delivery) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url, bytes.NewReader(d.payload))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TwitchDropsFarmer")
+	req.Header.Set(HeaderEvent, string(d.event))
+	req.Header.Set(HeaderDelivery, d.id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if d.secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, d.payload))
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{status: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the signature header of a payload sent at timestamp, for receivers to compare against
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryable reports whether a failed delivery may succeed later: network errors, rate limits and server errors
+func retryable(err error) bool {
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.status == http.StatusTooManyRequests || status.status == http.StatusRequestTimeout || status.status >= 500
+	}
+	return true
+}
+
+// deadLetter logs a delivery that won't be sent and keeps it for GET /api/webhooks/dead-letters
+func (w *Webhook) deadLetter(d delivery, attempts int, err error) {
+	logrus.Errorf("Giving up on webhook %s (%s) after %d attempts: %v; payload: %s", d.id, d.event, attempts, err, d.payload)
+
+	w.mu.Lock()
+	w.deadLetters = append(w.deadLetters, DeadLetter{
+		ID:       d.id,
+		Event:    d.event,
+		Time:     time.Now(),
+		Attempts: attempts,
+		Error:    err.Error(),
+		Payload:  string(d.payload),
+	})
+	if len(w.deadLetters) > maxDeadLetters {
+		w.deadLetters = append([]DeadLetter(nil), w.deadLetters[len(w.deadLetters)-maxDeadLetters:]...)
+	}
+	deadLetters := append([]DeadLetter(nil), w.deadLetters...)
+	w.mu.Unlock()
+
+	if err := w.store.Save(deadLetterDocument, deadLetters); err != nil {
+		logrus.Errorf("Failed to save webhook dead letters: %v", err)
+	}
+}
@@ -9,10 +9,24 @@ import (
 	"syscall"
 	"time"
 
+	"twitchdropsfarmer/internal/accounts"
+	"twitchdropsfarmer/internal/audit"
+	"twitchdropsfarmer/internal/backup"
 	"twitchdropsfarmer/internal/config"
+	"twitchdropsfarmer/internal/digest"
 	"twitchdropsfarmer/internal/drops"
+	"twitchdropsfarmer/internal/errreport"
+	"twitchdropsfarmer/internal/events"
+	"twitchdropsfarmer/internal/logbuffer"
+	"twitchdropsfarmer/internal/logging"
+	"twitchdropsfarmer/internal/mqtt"
+	"twitchdropsfarmer/internal/notify"
+	"twitchdropsfarmer/internal/storage"
+	"twitchdropsfarmer/internal/telegram"
+	"twitchdropsfarmer/internal/telemetry"
 	"twitchdropsfarmer/internal/twitch"
 	"twitchdropsfarmer/internal/web"
+	"twitchdropsfarmer/internal/webhook"
 
 	"github.com/sirupsen/logrus"
 )
@@ -32,27 +46,124 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	if err := logging.Configure(cfg); err != nil {
+		// The settings API validates them, so only a hand-edited config.json gets here
+		logrus.Errorf("Ignoring invalid log levels: %v", err)
+		cfg.LogLevel, cfg.LogLevels = "info", map[string]string{}
+		logging.Configure(cfg)
+	}
+	defer logging.Close()
+
+	// Keep recent log lines in memory for the dashboard
+	logBuffer := logbuffer.New(cfg.LogBufferSize)
+	logrus.AddHook(logBuffer)
+
+	// Export traces when an OTLP collector is configured
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg)
+	if err != nil {
+		logrus.Errorf("Tracing disabled: %v", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	} else if cfg.OTLPEndpoint != "" {
+		logrus.Infof("Exporting traces to %s", cfg.OTLPEndpoint)
+	}
+
+	// Initialize persistent storage
+	store, err := storage.Open(storage.Options{
+		Backend: cfg.StorageBackend,
+		Dir:     cfg.DataDir,
+		DSN:     cfg.DatabaseURL,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	// Initialize notifications
+	notifier, err := notify.NewManager(cfg, store)
+	if err != nil {
+		log.Fatalf("Failed to initialize notifications: %v", err)
+	}
+
+	// Everything the miners report goes through the event bus to the notifications, the WebSocket hub and the
+	// other sinks
+	bus := events.NewBus()
+	bus.Subscribe(notifier)
+
+	// Initialize audit log
+	auditLog, err := audit.NewLog(store)
+	if err != nil {
+		log.Fatalf("Failed to initialize audit log: %v", err)
+	}
+
 	// Initialize Twitch client
-	twitchClient := twitch.NewClient(cfg.TwitchClientID)
+	twitchClient, err := twitch.NewClient(twitch.NewClientProfile(cfg), cfg.ProxyFor(""))
+	if err != nil {
+		log.Fatalf("Failed to initialize Twitch client: %v", err)
+	}
+	twitchClient.SetDirectoryOptions(twitch.NewDirectoryOptions(cfg))
+	twitchClient.SetStreamQuality(cfg.StreamQuality)
+	twitchClient.SetAuthScopes(cfg.AuthScopes)
+	twitchClient.SetReauthHandler(func(user *twitch.User) {
+		login := ""
+		if user != nil {
+			login = user.Login
+		}
+		bus.Publish(events.AuthExpiredEvent("", login))
+	})
+
+	// Report panics and repeated miner errors when Sentry or an error webhook is configured
+	reporter, err := errreport.New(cfg)
+	if err != nil {
+		logrus.Errorf("Error reporting disabled: %v", err)
+	}
 
 	// Initialize drop miner
 	miner := drops.NewMiner(twitchClient)
+	miner.SetEvents(bus, "")
+	miner.SetErrorReporter(reporter)
+	miner.SetStore(store)
 
 	// Set miner configuration from loaded config
-	minerConfig := &drops.MinerConfig{
-		CheckInterval:   time.Duration(cfg.CheckInterval) * time.Second,
-		WatchInterval:   20 * time.Second, // Like TDM - every ~20 seconds
-		SwitchThreshold: time.Duration(cfg.SwitchThreshold) * time.Minute,
-		MinimumPoints:   cfg.MinimumPoints,
-		MaximumStreams:  cfg.MaximumStreams,
-		PriorityGames:   cfg.PriorityGames,
-		ClaimDrops:      cfg.ClaimDrops,
-		WebhookURL:      cfg.WebhookURL,
-	}
-	miner.SetConfig(minerConfig)
+	miner.SetConfig(drops.NewMinerConfig(cfg))
+
+	// Settings changes from the dashboard, config.json or SIGHUP replace the configuration in use as a whole
+	live := config.NewLive(cfg)
 
 	// Initialize web server
-	webServer := web.NewServer(cfg, twitchClient, miner)
+	webServer := web.NewServer(live, twitchClient, miner, notifier, auditLog, store)
+	webServer.SetLogBuffer(logBuffer)
+	webServer.SetErrorReporter(reporter)
+	bus.Subscribe(webServer)
+
+	// Daily or weekly activity digest by email
+	activityDigest := digest.New(miner, store)
+	if err := activityDigest.SetConfig(digest.NewConfig(cfg)); err != nil {
+		logrus.Errorf("Ignoring digest email: %v", err)
+	}
+	webServer.SetDigest(activityDigest)
+
+	// Every event posted to the generic webhook, when one is set
+	eventWebhook := webhook.New(store)
+	if err := eventWebhook.SetConfig(webhook.NewConfig(cfg)); err != nil {
+		logrus.Errorf("Ignoring webhook settings: %v", err)
+	}
+	bus.Subscribe(eventWebhook)
+	webServer.SetWebhook(eventWebhook)
+
+	// Config and data backups on the backup schedule
+	backups := backup.New(config.BackupDir(), live, store)
+	if err := backups.SetConfig(backup.NewConfig(cfg)); err != nil {
+		logrus.Errorf("Ignoring backup schedule: %v", err)
+	}
+	webServer.SetBackups(backups)
+
+	// Restore additional accounts, each with its own client and miner
+	accountManager := accounts.NewManager(live, bus)
+	accountManager.SetErrorReporter(reporter)
+	webServer.SetAccounts(accountManager)
+	backups.SetAccounts(accountManager)
+	if err := accountManager.Load(); err != nil {
+		logrus.Errorf("Failed to load accounts: %v", err)
+	}
 
 	// Start web server
 	server := &http.Server{
@@ -62,20 +173,55 @@ func main() {
 
 	// Start server in goroutine
 	go func() {
-		logrus.Infof("Starting server on %s", cfg.ServerAddress)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := web.ListenAndServe(cfg, server); err != nil && err != http.ErrServerClosed {
 			logrus.Fatalf("Failed to start server: %v", err)
 		}
 	}()
 
 	// Start drop miner
 	ctx, cancel := context.WithCancel(context.Background())
+	go twitchClient.RunTokenValidation(ctx)
 	go func() {
 		if err := miner.Start(ctx); err != nil {
 			logrus.Errorf("Drop miner error: %v", err)
 		}
 	}()
 
+	// Remote control over Telegram, only for the chats listed in the config
+	if cfg.TelegramBotToken != "" {
+		if len(cfg.TelegramChatIDs) == 0 {
+			logrus.Warn("TELEGRAM_BOT_TOKEN is set but telegram_chat_ids is empty, the Telegram bot is disabled")
+		} else {
+			bot := telegram.NewBot(cfg.TelegramBotToken, cfg.TelegramChatIDs, miner)
+			notifier.AddProvider(bot)
+			go bot.Run(ctx)
+		}
+	}
+
+	// Mail a summary of the activity when a digest is scheduled
+	go activityDigest.Run(ctx)
+	go eventWebhook.Run(ctx)
+	go backups.Run(ctx)
+
+	// Sensors and a pause switch in Home Assistant
+	if cfg.MQTTURL != "" {
+		publisher := mqtt.NewPublisher(cfg, miner)
+		bus.Subscribe(publisher)
+		go publisher.Run(ctx)
+	}
+
+	// Settings edited in config/config.json, or re-read on SIGHUP, are applied without a restart
+	if err := config.WatchFile(ctx, func() { webServer.ReloadConfig(web.SettingsSourceFile) }); err != nil {
+		logrus.Warnf("Not watching the config file for changes: %v", err)
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			webServer.ReloadConfig(web.SettingsSourceSIGHUP)
+		}
+	}()
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -83,8 +229,17 @@ func main() {
 
 	logrus.Info("Shutting down server...")
 
-	// Cancel miner context
+	// Stop the miners first, closing their sessions and saving their final state
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := miner.Shutdown(shutdownCtx); err != nil {
+		logrus.Errorf("Drop miner didn't stop in time: %v", err)
+	}
+	accountManager.ShutdownAll(shutdownCtx)
+
+	// Then the background tasks, and the dashboards get told before their connections close
 	cancel()
+	webServer.NotifyShutdown(shutdownCtx)
 
 	// Shutdown server gracefully
 	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
@@ -93,5 +248,17 @@ func main() {
 		logrus.Errorf("Server forced to shutdown: %v", err)
 	}
 
+	// Nothing writes to storage any more
+	if err := storage.Close(); err != nil {
+		logrus.Errorf("Failed to close storage: %v", err)
+	}
+
+	// Send the spans of the shutdown itself too
+	tracingCtx, tracingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer tracingCancel()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		logrus.Errorf("Failed to flush traces: %v", err)
+	}
+
 	logrus.Info("Server exited")
 }
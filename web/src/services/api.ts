@@ -1,4 +1,27 @@
-import type { ApiResponse } from '@/types'
+import type { ApiResponse, ApiErrorBody } from '@/types'
+
+// ApiError carries the machine-readable code of a failed request, e.g. "miner_already_running"
+export class ApiError extends Error {
+  constructor(
+    message: string,
+    public status: number,
+    public code: string,
+    public details?: string,
+    public requestId?: string,
+  ) {
+    super(message)
+    this.name = 'ApiError'
+  }
+
+  static async fromResponse(response: Response): Promise<ApiError> {
+    try {
+      const body: ApiErrorBody = await response.json()
+      return new ApiError(body.error, response.status, body.code, body.details, body.request_id)
+    } catch {
+      return new ApiError(`HTTP error! status: ${response.status}`, response.status, 'unknown')
+    }
+  }
+}
 
 class ApiService {
   private baseUrl = ''
@@ -12,7 +35,7 @@ class ApiService {
     })
 
     if (!response.ok) {
-      throw new Error(`HTTP error! status: ${response.status}`)
+      throw await ApiError.fromResponse(response)
     }
 
     return response.json()
@@ -28,7 +51,7 @@ class ApiService {
     })
 
     if (!response.ok) {
-      throw new Error(`HTTP error! status: ${response.status}`)
+      throw await ApiError.fromResponse(response)
     }
 
     return response.json()
@@ -44,7 +67,7 @@ class ApiService {
     })
 
     if (!response.ok) {
-      throw new Error(`HTTP error! status: ${response.status}`)
+      throw await ApiError.fromResponse(response)
     }
 
     return response.json()
@@ -59,7 +82,7 @@ class ApiService {
     })
 
     if (!response.ok) {
-      throw new Error(`HTTP error! status: ${response.status}`)
+      throw await ApiError.fromResponse(response)
     }
 
     return response.json()
@@ -66,9 +66,17 @@ class WebSocketService {
       case 'config_update':
         minerStore.updateConfig(message.data as Config)
         break
+      case 'settings_changed':
+        minerStore.fetchConfig()
+        break
       case 'error':
         console.error('WebSocket error message:', message.data.message)
         break
+      case 'shutting_down':
+        // The server is restarting, give the reconnect its full set of attempts
+        console.log('Server is shutting down')
+        this.reconnectAttempts = 0
+        break
       default:
         console.warn('Unknown WebSocket message type:', message.type)
     }
@@ -1,6 +1,6 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
-import type { User, AuthStatus, DeviceCodeResponse } from '@/types'
+import type { User, AuthStatus, DeviceCodeResponse, SessionStatus } from '@/types'
 import { apiService } from '@/services/api'
 
 export const useAuthStore = defineStore('auth', () => {
@@ -10,10 +10,39 @@ export const useAuthStore = defineStore('auth', () => {
 
   const isAuthenticated = computed(() => !!user.value)
 
+  // Set when the dashboard password has to be entered before the API can be used
+  const passwordRequired = ref(false)
+
+  async function checkSession() {
+    const session = await apiService.get<SessionStatus>('/api/session')
+    passwordRequired.value = session.password_login && !session.authenticated
+  }
+
+  async function loginWithPassword(password: string) {
+    try {
+      isLoading.value = true
+      error.value = null
+
+      await apiService.post('/api/session', { password })
+      passwordRequired.value = false
+    } catch (err) {
+      error.value = 'Wrong password'
+      throw err
+    } finally {
+      isLoading.value = false
+    }
+  }
+
   async function checkAuthStatus() {
     try {
       isLoading.value = true
       error.value = null
+
+      await checkSession()
+      if (passwordRequired.value) {
+        user.value = null
+        return
+      }
       
       const response = await apiService.get<AuthStatus>('/api/auth/status')
       
@@ -78,7 +107,9 @@ export const useAuthStore = defineStore('auth', () => {
     isLoading,
     error,
     isAuthenticated,
+    passwordRequired,
     checkAuthStatus,
+    loginWithPassword,
     initiateDeviceFlow,
     startTokenPolling,
     logout,
@@ -246,16 +246,32 @@ export interface ApiResponse<T = any> {
   error?: string;
 }
 
+// Body of every failed API request
+export interface ApiErrorBody {
+  error: string;
+  code: string;
+  details?: string;
+  request_id?: string;
+}
+
 // Auth related types
 export interface AuthStatus {
   is_logged_in: boolean;
   user?: User;
 }
 
+export interface SessionStatus {
+  auth_required: boolean;
+  password_login: boolean;
+  authenticated: boolean;
+}
+
 export interface DeviceCodeResponse {
   device_code: string;
   user_code: string;
   verification_uri: string;
+  verification_uri_complete: string;
+  qr_url: string;
   expires_in: number;
   interval: number;
 }
@@ -283,5 +299,18 @@ export interface ErrorMessage extends WebSocketMessage {
   };
 }
 
+export interface SettingsChangedMessage extends WebSocketMessage {
+  type: 'settings_changed';
+  data: {
+    source: 'api' | 'file' | 'sighup';
+    changed: string[];
+  };
+}
+
+export interface ShuttingDownMessage extends WebSocketMessage {
+  type: 'shutting_down';
+  data: Record<string, never>;
+}
+
 // Union type for all WebSocket messages
-export type WSMessage = StatusUpdateMessage | ConfigUpdateMessage | ErrorMessage;
\ No newline at end of file
+export type WSMessage = StatusUpdateMessage | ConfigUpdateMessage | SettingsChangedMessage | ShuttingDownMessage | ErrorMessage;
\ No newline at end of file